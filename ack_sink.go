@@ -0,0 +1,481 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements at-least-once delivery to network sinks. AckSink frames every batch
+// with a session ID and a sequence number, keeps it until the receiver acknowledges it and
+// resends the unacknowledged batches after a reconnect; AckReceiver is the matching server
+// side, which acknowledges batches and discards the duplicates caused by resends.
+//
+// Wire format, over any stream connection:
+//
+//	sender:   UNOLOG1 <session> <seq> <count>\n, then per entry <length>\n<entry bytes>
+//	receiver: ACK <seq>\n, acknowledging every batch of the session up to seq
+
+package unologger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ackMagic starts every batch frame.
+const ackMagic = "UNOLOG1"
+
+// maxAckEntry bounds the length of one entry accepted by AckReceiver.
+const maxAckEntry = 16 << 20
+
+// Errors returned by AckSink.
+var (
+	// ErrAckWindowFull is returned by AckSink.WriteBatch when MaxPending batches are
+	// waiting for acknowledgment. The logger's retry policy then applies.
+	ErrAckWindowFull = errors.New("unologger: too many unacknowledged batches")
+	// ErrAckSinkClosed is returned by AckSink.WriteBatch after Close.
+	ErrAckSinkClosed = errors.New("unologger: ack sink closed")
+)
+
+// AckSinkConfig configures an AckSink.
+type AckSinkConfig struct {
+	// Network and Address name the receiver, e.g. "tcp" and "collector:7400".
+	Network string
+	Address string
+	// Dial, if set, opens the connections instead of net.Dialer, e.g. to use TLS.
+	Dial func(ctx context.Context) (net.Conn, error)
+	// MaxPending is the number of unacknowledged batches kept for resending. Defaults to
+	// 1024.
+	MaxPending int
+	// WriteTimeout bounds each frame write. Defaults to 5s.
+	WriteTimeout time.Duration
+	// ReconnectBackoff is the initial delay between connection attempts; it doubles up to
+	// 10s. Defaults to 100ms.
+	ReconnectBackoff time.Duration
+	// CloseTimeout is how long Close waits for pending batches to be acknowledged.
+	// Defaults to 5s.
+	CloseTimeout time.Duration
+}
+
+// AckSinkStats describes the delivery state of an AckSink.
+type AckSinkStats struct {
+	Pending    int   // Batches waiting for acknowledgment.
+	Acked      int64 // Batches acknowledged.
+	Resent     int64 // Frames sent again after a reconnect.
+	Reconnects int64 // Connections opened after the first one.
+}
+
+// ackBatch is a framed batch waiting for acknowledgment.
+type ackBatch struct {
+	seq   uint64
+	frame []byte
+}
+
+// AckSink is a BatchWriter delivering batches at least once to an AckReceiver. Writes do
+// not wait for the network: a batch is accepted as soon as it fits in the window of
+// unacknowledged batches, and a background goroutine sends it, reconnecting as needed.
+// Add it with Logger.AddExtraWriter; it is closed with the logger.
+type AckSink struct {
+	cfg     AckSinkConfig
+	session string
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []ackBatch // Unacknowledged batches, in sequence order.
+	sent     int        // Pending batches already sent on the current connection.
+	nextSeq  uint64
+	conn     net.Conn
+	closed   bool
+	stats    AckSinkStats
+	everSent map[uint64]bool // Sequence numbers sent at least once, to count resends.
+
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// NewAckSink returns an AckSink and starts connecting to the receiver in the background.
+func NewAckSink(cfg AckSinkConfig) *AckSink {
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 1024
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	if cfg.ReconnectBackoff <= 0 {
+		cfg.ReconnectBackoff = 100 * time.Millisecond
+	}
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = 5 * time.Second
+	}
+	s := &AckSink{
+		cfg:      cfg,
+		session:  newUUID(),
+		nextSeq:  1,
+		everSent: make(map[uint64]bool),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.run()
+	return s
+}
+
+// Write implements io.Writer by sending p as a batch of one entry.
+func (s *AckSink) Write(p []byte) (int, error) {
+	if err := s.WriteBatch([][]byte{p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBatch implements BatchWriter. It frames the entries and queues them for delivery.
+func (s *AckSink) WriteBatch(entries [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrAckSinkClosed
+	}
+	if len(s.pending) >= s.cfg.MaxPending {
+		return ErrAckWindowFull
+	}
+	seq := s.nextSeq
+	s.nextSeq++
+	s.pending = append(s.pending, ackBatch{seq: seq, frame: encodeAckFrame(s.session, seq, entries)})
+	s.cond.Broadcast()
+	return nil
+}
+
+// Stats returns the delivery state of the sink.
+func (s *AckSink) Stats() AckSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stats
+	st.Pending = len(s.pending)
+	return st
+}
+
+// Flush waits until every accepted batch has been acknowledged or ctx is done.
+func (s *AckSink) Flush(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("unologger: %d batches not acknowledged: %w", len(s.pending), err)
+		}
+		s.cond.Wait()
+	}
+	return nil
+}
+
+// Close waits up to CloseTimeout for pending batches to be acknowledged, then closes the
+// connection. It returns an error if batches were left unacknowledged.
+func (s *AckSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.CloseTimeout)
+	defer cancel()
+	flushErr := s.Flush(ctx)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		<-s.done
+		return nil
+	}
+	s.closed = true
+	close(s.closing)
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	<-s.done
+	return flushErr
+}
+
+// run connects to the receiver and sends the pending batches until the sink is closed.
+func (s *AckSink) run() {
+	defer close(s.done)
+	backoff := s.cfg.ReconnectBackoff
+	connected := false
+	for {
+		select {
+		case <-s.closing:
+			return
+		default:
+		}
+		conn, err := s.dial(context.Background())
+		if err != nil {
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, 10*time.Second)
+			continue
+		}
+		backoff = s.cfg.ReconnectBackoff
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		if connected {
+			s.stats.Reconnects++
+		}
+		connected = true
+		s.conn, s.sent = conn, 0 // Resend every unacknowledged batch on the new connection.
+		s.mu.Unlock()
+
+		go s.readAcks(conn)
+		s.sendLoop(conn)
+		s.drop(conn)
+	}
+}
+
+// dial opens a connection to the receiver, within WriteTimeout.
+func (s *AckSink) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.WriteTimeout)
+	defer cancel()
+	if s.cfg.Dial != nil {
+		return s.cfg.Dial(ctx)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, s.cfg.Network, s.cfg.Address)
+}
+
+// HealthCheck implements HealthChecker. It succeeds if the sink is connected to the
+// receiver, or else if a new connection can be opened; that connection is closed at once.
+func (s *AckSink) HealthCheck(ctx context.Context) error {
+	s.mu.Lock()
+	closed, connected := s.closed, s.conn != nil
+	s.mu.Unlock()
+	switch {
+	case closed:
+		return ErrAckSinkClosed
+	case connected:
+		return nil
+	}
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// sendLoop writes the pending batches to conn until conn fails or the sink is closed.
+func (s *AckSink) sendLoop(conn net.Conn) {
+	for {
+		s.mu.Lock()
+		for !s.closed && s.conn == conn && s.sent >= len(s.pending) {
+			s.cond.Wait()
+		}
+		if s.closed || s.conn != conn {
+			s.mu.Unlock()
+			return
+		}
+		b := s.pending[s.sent]
+		s.sent++
+		if s.everSent[b.seq] {
+			s.stats.Resent++
+		}
+		s.everSent[b.seq] = true
+		s.mu.Unlock()
+
+		_ = conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+		if _, err := conn.Write(b.frame); err != nil {
+			return
+		}
+	}
+}
+
+// readAcks processes the acknowledgments received on conn until it fails.
+func (s *AckSink) readAcks(conn net.Conn) {
+	defer s.drop(conn)
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		seqStr, ok := strings.CutPrefix(strings.TrimSpace(line), "ACK ")
+		if !ok {
+			return
+		}
+		seq, err := strconv.ParseUint(seqStr, 10, 64)
+		if err != nil {
+			return
+		}
+		s.ack(seq)
+	}
+}
+
+// ack removes the batches acknowledged up to seq from the window.
+func (s *AckSink) ack(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for n < len(s.pending) && s.pending[n].seq <= seq {
+		delete(s.everSent, s.pending[n].seq)
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	clear(s.pending[:n])
+	s.pending = s.pending[n:]
+	s.sent = max(s.sent-n, 0)
+	s.stats.Acked += int64(n)
+	s.cond.Broadcast()
+}
+
+// drop closes conn and makes the run loop reconnect, unless it was already replaced.
+func (s *AckSink) drop(conn net.Conn) {
+	_ = conn.Close()
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// encodeAckFrame frames a batch for the wire.
+func encodeAckFrame(session string, seq uint64, entries [][]byte) []byte {
+	size := len(ackMagic) + len(session) + 48
+	for _, e := range entries {
+		size += len(e) + 12
+	}
+	b := make([]byte, 0, size)
+	b = fmt.Appendf(b, "%s %s %d %d\n", ackMagic, session, seq, len(entries))
+	for _, e := range entries {
+		b = strconv.AppendInt(b, int64(len(e)), 10)
+		b = append(b, '\n')
+		b = append(b, e...)
+	}
+	return b
+}
+
+// AckBatch is a batch received by AckReceiver.
+type AckBatch struct {
+	Session string   // Random ID of the sending AckSink, new for every process.
+	Seq     uint64   // Sequence number of the batch within the session, starting at 1.
+	Entries [][]byte // The formatted entries.
+}
+
+// AckReceiver is the server side of AckSink. It hands every batch to Handle once,
+// acknowledges it, and discards resent duplicates by tracking the last sequence number of
+// each session.
+type AckReceiver struct {
+	// Handle processes a batch. If it returns an error, the batch is not acknowledged and
+	// the connection is closed, so the sender resends it after reconnecting.
+	Handle func(AckBatch) error
+
+	mu         sync.Mutex
+	last       map[string]uint64
+	duplicates int64
+}
+
+// Duplicates returns the number of resent batches that were discarded.
+func (r *AckReceiver) Duplicates() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.duplicates
+}
+
+// Serve accepts connections on ln and serves each on its own goroutine until ln fails.
+func (r *AckReceiver) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() { _ = r.ServeConn(conn) }()
+	}
+}
+
+// ServeConn reads batches from conn until it fails or the peer disconnects, then closes
+// conn. It returns nil when the peer disconnects cleanly.
+func (r *AckReceiver) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	for {
+		batch, err := readAckFrame(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		if r.last == nil {
+			r.last = make(map[string]uint64)
+		}
+		dup := batch.Seq <= r.last[batch.Session]
+		if dup {
+			r.duplicates++
+		}
+		r.mu.Unlock()
+		if !dup {
+			if r.Handle != nil {
+				if err := r.Handle(batch); err != nil {
+					return err
+				}
+			}
+			r.mu.Lock()
+			r.last[batch.Session] = max(r.last[batch.Session], batch.Seq)
+			r.mu.Unlock()
+		}
+		if _, err := fmt.Fprintf(conn, "ACK %d\n", batch.Seq); err != nil {
+			return err
+		}
+	}
+}
+
+// readAckFrame reads one batch frame.
+func readAckFrame(br *bufio.Reader) (AckBatch, error) {
+	header, err := br.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && header == "" {
+			return AckBatch{}, io.EOF
+		}
+		return AckBatch{}, err
+	}
+	parts := strings.Fields(header)
+	if len(parts) != 4 || parts[0] != ackMagic {
+		return AckBatch{}, fmt.Errorf("unologger: malformed batch header %q", strings.TrimSpace(header))
+	}
+	seq, err1 := strconv.ParseUint(parts[2], 10, 64)
+	count, err2 := strconv.Atoi(parts[3])
+	if err := errors.Join(err1, err2); err != nil || count < 0 {
+		return AckBatch{}, fmt.Errorf("unologger: malformed batch header %q", strings.TrimSpace(header))
+	}
+	batch := AckBatch{Session: parts[1], Seq: seq, Entries: make([][]byte, 0, min(count, 4096))}
+	for range count {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return AckBatch{}, err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err != nil || n < 0 || n > maxAckEntry {
+			return AckBatch{}, fmt.Errorf("unologger: malformed entry length %q", strings.TrimSpace(line))
+		}
+		entry := make([]byte, n)
+		if _, err := io.ReadFull(br, entry); err != nil {
+			return AckBatch{}, err
+		}
+		batch.Entries = append(batch.Entries, entry)
+	}
+	return batch, nil
+}
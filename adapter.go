@@ -66,7 +66,9 @@ func (a *Adapter) Context() context.Context {
 // The underlying logger remains the same, but the new adapter will use the new context
 // for all subsequent log calls.
 func (a *Adapter) WithContext(ctx context.Context) *Adapter {
-	return &Adapter{lw: LoggerWithCtx{l: a.lw.l, ctx: ctx}}
+	lw := a.lw
+	lw.ctx = orBackground(ctx)
+	return &Adapter{lw: lw}
 }
 
 // WithModule returns a new Adapter instance with the specified module name in its context.
@@ -0,0 +1,515 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the handoff of entries to a local logging agent over a unix socket.
+// The application only frames its batches and passes them on, while the agent owns shipping
+// and retries. The agent signals backpressure with PAUSE and RESUME messages, and entries
+// can travel through a shared-memory ring instead of the socket to save copies.
+//
+// Protocol, over a stream connection:
+//
+//	app:   HELLO stream\n                   entries follow as batch frames (see AckSink)
+//	app:   HELLO shm <path> <capacity>\n    entries are written to the ring at path
+//	app:   SHM <write offset>\n             doorbell after new entries were put in the ring
+//	agent: PAUSE\n / RESUME\n                backpressure
+//	agent: FREE\n                           ring space was released
+//
+// The ring file holds a 64-byte header (magic, capacity, write offset, read offset as
+// little-endian uint64 values) followed by the data area, in which every entry is stored
+// as a little-endian uint32 length and the entry bytes, wrapping around at the end.
+
+package unologger
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Errors returned by AgentSink.
+var (
+	// ErrAgentBusy is returned when the agent asked to pause, or the shared-memory ring
+	// is full, for longer than AgentConfig.BlockTimeout.
+	ErrAgentBusy = errors.New("unologger: logging agent busy")
+	// ErrSharedMemoryUnsupported is returned when shared memory is requested on a
+	// platform without mmap.
+	ErrSharedMemoryUnsupported = errors.New("unologger: shared memory not supported on this platform")
+)
+
+// shmMagic identifies a ring file.
+const shmMagic = "UNOSHM1\x00"
+
+// shmHeader is the size of the ring file header.
+const shmHeader = 64
+
+// AgentConfig configures an AgentSink.
+type AgentConfig struct {
+	// Socket is the path of the agent's unix socket.
+	Socket string
+	// SharedMemory, if set, is the path of the ring file shared with the agent, preferably
+	// on a memory file system such as /dev/shm. Entries are then written to the ring and
+	// the socket only carries doorbells.
+	SharedMemory string
+	// RingSize is the data capacity of the ring in bytes. Defaults to 4 MiB.
+	RingSize int
+	// BlockTimeout is how long a write waits while the agent is paused or the ring is
+	// full before failing with ErrAgentBusy. Zero fails immediately.
+	BlockTimeout time.Duration
+	// DialTimeout bounds connection attempts. Defaults to 1s.
+	DialTimeout time.Duration
+}
+
+// AgentSink is a BatchWriter handing entries to a local logging agent. It connects lazily
+// and reconnects on the next write after a failure; the logger's retry policy applies to
+// failed writes. Add it with Logger.AddExtraWriter; it is closed with the logger.
+type AgentSink struct {
+	cfg     AgentConfig
+	session string
+	ring    *shmRing
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	conn   net.Conn
+	paused bool
+	seq    uint64
+	closed bool
+}
+
+// NewAgentSink returns an AgentSink. With SharedMemory set it creates the ring file.
+func NewAgentSink(cfg AgentConfig) (*AgentSink, error) {
+	if cfg.RingSize <= 0 {
+		cfg.RingSize = 4 << 20
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = time.Second
+	}
+	s := &AgentSink{cfg: cfg, session: newUUID()}
+	s.cond = sync.NewCond(&s.mu)
+	if cfg.SharedMemory != "" {
+		ring, err := createShmRing(cfg.SharedMemory, cfg.RingSize)
+		if err != nil {
+			return nil, err
+		}
+		s.ring = ring
+	}
+	return s, nil
+}
+
+// Write implements io.Writer by handing p over as a batch of one entry.
+func (s *AgentSink) Write(p []byte) (int, error) {
+	if err := s.WriteBatch([][]byte{p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBatch implements BatchWriter.
+func (s *AgentSink) WriteBatch(entries [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	if err := s.connectLocked(); err != nil {
+		return err
+	}
+	var deadline time.Time
+	if s.cfg.BlockTimeout > 0 {
+		deadline = time.Now().Add(s.cfg.BlockTimeout)
+		t := time.AfterFunc(s.cfg.BlockTimeout, func() {
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		})
+		defer t.Stop()
+	}
+	// wait blocks until the agent resumes or the ring has room, until the deadline.
+	wait := func() bool {
+		if deadline.IsZero() || !time.Now().Before(deadline) || s.closed || s.conn == nil {
+			return false
+		}
+		s.cond.Wait()
+		return true
+	}
+	for s.paused {
+		if !wait() {
+			return ErrAgentBusy
+		}
+	}
+	if s.conn == nil || s.closed {
+		return ErrAgentBusy
+	}
+
+	var msg []byte
+	if s.ring != nil {
+		for {
+			off, ok, err := s.ring.put(entries)
+			if err != nil {
+				return err
+			}
+			if ok {
+				msg = fmt.Appendf(nil, "SHM %d\n", off)
+				break
+			}
+			if !wait() {
+				return ErrAgentBusy
+			}
+		}
+	} else {
+		s.seq++
+		msg = encodeAckFrame(s.session, s.seq, entries)
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.DialTimeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.dropLocked()
+		return err
+	}
+	return nil
+}
+
+// connectLocked connects to the agent if there is no connection. s.mu must be held.
+func (s *AgentSink) connectLocked() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", s.cfg.Socket, s.cfg.DialTimeout)
+	if err != nil {
+		return err
+	}
+	hello := "HELLO stream\n"
+	if s.ring != nil {
+		hello = fmt.Sprintf("HELLO shm %s %d\n", s.ring.path, s.ring.capacity)
+	}
+	if _, err := conn.Write([]byte(hello)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	s.conn, s.paused = conn, false
+	go s.readControl(conn)
+	return nil
+}
+
+// HealthCheck implements HealthChecker by connecting to the agent if the sink is not
+// connected. The connection is kept for the following writes.
+func (s *AgentSink) HealthCheck(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	return s.connectLocked()
+}
+
+// readControl processes the backpressure messages of the agent until conn fails.
+func (s *AgentSink) readControl(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		s.mu.Lock()
+		if err != nil {
+			if s.conn == conn {
+				s.dropLocked()
+			}
+			s.mu.Unlock()
+			return
+		}
+		if s.conn == conn {
+			switch strings.TrimSpace(line) {
+			case "PAUSE":
+				s.paused = true
+			case "RESUME":
+				s.paused = false
+			}
+		}
+		s.cond.Broadcast() // Also wakes writers waiting for ring space.
+		s.mu.Unlock()
+	}
+}
+
+// dropLocked closes the connection. s.mu must be held.
+func (s *AgentSink) dropLocked() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	s.paused = false
+	s.cond.Broadcast()
+}
+
+// Paused reports whether the agent currently asks the application to hold back.
+func (s *AgentSink) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Close closes the connection and unmaps the ring. The ring file is left for the agent
+// to drain; it is recreated by the next AgentSink.
+func (s *AgentSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.dropLocked()
+	if s.ring != nil {
+		return s.ring.close()
+	}
+	return nil
+}
+
+// AgentServer is the agent side of the handoff protocol, for agents written in Go. It
+// hands the entries of every connection to Handle and broadcasts backpressure with Pause
+// and Resume.
+type AgentServer struct {
+	// Handle receives the entries of the application. If it returns an error, the
+	// connection is closed.
+	Handle func(entries [][]byte) error
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	paused bool
+}
+
+// Pause asks every connected application to hold back its entries.
+func (a *AgentServer) Pause() { a.signal(true) }
+
+// Resume lets the applications send entries again.
+func (a *AgentServer) Resume() { a.signal(false) }
+
+// signal records the backpressure state and sends it to every connection.
+func (a *AgentServer) signal(paused bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.paused = paused
+	msg := "RESUME\n"
+	if paused {
+		msg = "PAUSE\n"
+	}
+	for c := range a.conns {
+		_, _ = c.Write([]byte(msg))
+	}
+}
+
+// Serve accepts connections on ln and serves each on its own goroutine until ln fails.
+func (a *AgentServer) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() { _ = a.ServeConn(conn) }()
+	}
+}
+
+// ServeConn serves one application connection until it disconnects, then closes it.
+func (a *AgentServer) ServeConn(conn net.Conn) error {
+	a.mu.Lock()
+	if a.conns == nil {
+		a.conns = make(map[net.Conn]struct{})
+	}
+	a.conns[conn] = struct{}{}
+	if a.paused {
+		_, _ = conn.Write([]byte("PAUSE\n"))
+	}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.conns, conn)
+		a.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	br := bufio.NewReader(conn)
+	hello, err := br.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	f := strings.Fields(hello)
+	switch {
+	case len(f) == 2 && f[0] == "HELLO" && f[1] == "stream":
+		for {
+			batch, err := readAckFrame(br)
+			if err != nil {
+				return ignoreEOF(err)
+			}
+			if err := a.handle(batch.Entries); err != nil {
+				return err
+			}
+		}
+	case len(f) == 4 && f[0] == "HELLO" && f[1] == "shm":
+		ring, err := openShmRing(f[2])
+		if err != nil {
+			return err
+		}
+		defer ring.close()
+		for {
+			if _, err := br.ReadString('\n'); err != nil {
+				// Drain what was written before the application disconnected.
+				if herr := a.handle(ring.take()); herr != nil {
+					return herr
+				}
+				return ignoreEOF(err)
+			}
+			if err := a.handle(ring.take()); err != nil {
+				return err
+			}
+			// Wake writers waiting for ring space; the application ignores other lines.
+			_, _ = conn.Write([]byte("FREE\n"))
+		}
+	}
+	return fmt.Errorf("unologger: unexpected agent handshake %q", strings.TrimSpace(hello))
+}
+
+// handle passes entries to Handle, if there are any.
+func (a *AgentServer) handle(entries [][]byte) error {
+	if len(entries) == 0 || a.Handle == nil {
+		return nil
+	}
+	return a.Handle(entries)
+}
+
+// ignoreEOF maps the end of a connection to a nil error.
+func ignoreEOF(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// shmRing is a single-producer, single-consumer ring of entries in shared memory.
+type shmRing struct {
+	path     string
+	file     *os.File
+	mem      []byte
+	data     []byte
+	capacity uint64
+}
+
+// writeOff and readOff point at the offsets in the header. Both count the bytes written
+// and consumed since the ring was created, so their difference is the used space.
+func (r *shmRing) writeOff() *uint64 { return (*uint64)(unsafe.Pointer(&r.mem[16])) }
+func (r *shmRing) readOff() *uint64  { return (*uint64)(unsafe.Pointer(&r.mem[24])) }
+
+// createShmRing creates (or truncates) the ring file at path and maps it.
+func createShmRing(path string, size int) (*shmRing, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(int64(shmHeader + size)); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	mem, err := mapShm(f, shmHeader+size)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	copy(mem, shmMagic)
+	binary.LittleEndian.PutUint64(mem[8:], uint64(size))
+	return &shmRing{path: path, file: f, mem: mem, data: mem[shmHeader:], capacity: uint64(size)}, nil
+}
+
+// openShmRing maps an existing ring file, on the agent side.
+func openShmRing(path string) (*shmRing, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil || st.Size() <= shmHeader {
+		_ = f.Close()
+		return nil, fmt.Errorf("unologger: invalid ring file %s", path)
+	}
+	mem, err := mapShm(f, int(st.Size()))
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	r := &shmRing{path: path, file: f, mem: mem, data: mem[shmHeader:]}
+	r.capacity = binary.LittleEndian.Uint64(mem[8:])
+	if string(mem[:8]) != shmMagic || r.capacity != uint64(len(r.data)) {
+		_ = r.close()
+		return nil, fmt.Errorf("unologger: invalid ring file %s", path)
+	}
+	return r, nil
+}
+
+// put appends entries to the ring and returns the new write offset. It reports false if
+// the ring lacks space, and an error if the entries can never fit.
+func (r *shmRing) put(entries [][]byte) (uint64, bool, error) {
+	need := uint64(0)
+	for _, e := range entries {
+		need += 4 + uint64(len(e))
+	}
+	if need > r.capacity {
+		return 0, false, fmt.Errorf("unologger: batch of %d bytes exceeds the ring size", need)
+	}
+	w := atomic.LoadUint64(r.writeOff())
+	if need > r.capacity-(w-atomic.LoadUint64(r.readOff())) {
+		return 0, false, nil
+	}
+	var n [4]byte
+	for _, e := range entries {
+		binary.LittleEndian.PutUint32(n[:], uint32(len(e)))
+		r.copyIn(w, n[:])
+		r.copyIn(w+4, e)
+		w += 4 + uint64(len(e))
+	}
+	atomic.StoreUint64(r.writeOff(), w) // Publishes the entries to the agent.
+	return w, true, nil
+}
+
+// take removes and returns every entry written so far.
+func (r *shmRing) take() [][]byte {
+	w := atomic.LoadUint64(r.writeOff())
+	rd := atomic.LoadUint64(r.readOff())
+	var out [][]byte
+	var n [4]byte
+	for rd < w {
+		r.copyOut(rd, n[:])
+		e := make([]byte, binary.LittleEndian.Uint32(n[:]))
+		r.copyOut(rd+4, e)
+		out = append(out, e)
+		rd += 4 + uint64(len(e))
+	}
+	atomic.StoreUint64(r.readOff(), rd) // Releases the space to the application.
+	return out
+}
+
+// copyIn writes b at the ring offset off, wrapping around.
+func (r *shmRing) copyIn(off uint64, b []byte) {
+	n := copy(r.data[off%r.capacity:], b)
+	copy(r.data, b[n:])
+}
+
+// copyOut reads len(b) bytes at the ring offset off, wrapping around.
+func (r *shmRing) copyOut(off uint64, b []byte) {
+	n := copy(b, r.data[off%r.capacity:])
+	copy(b[n:], r.data)
+}
+
+// close unmaps the ring and closes its file.
+func (r *shmRing) close() error {
+	err := unmapShm(r.mem)
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+//go:build !unix
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file stubs the shared-memory ring of the agent handoff on platforms without mmap.
+
+package unologger
+
+import "os"
+
+// mapShm reports that shared memory is not supported.
+func mapShm(*os.File, int) ([]byte, error) {
+	return nil, ErrSharedMemoryUnsupported
+}
+
+// unmapShm is a no-op.
+func unmapShm([]byte) error {
+	return nil
+}
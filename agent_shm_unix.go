@@ -0,0 +1,24 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+//go:build unix
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file maps the shared-memory ring of the agent handoff on unix platforms.
+
+package unologger
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapShm maps size bytes of f for reading and writing, shared with other processes.
+func mapShm(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmapShm releases a mapping returned by mapShm.
+func unmapShm(mem []byte) error {
+	return syscall.Munmap(mem)
+}
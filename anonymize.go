@@ -0,0 +1,160 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file provides anonymization helpers for client metadata. IP addresses are truncated
+// to a network prefix and user agents are hashed before they are attached to entries, with
+// a policy per deployment region so that, for example, EU deployments apply GDPR-friendly
+// defaults while others keep more detail.
+
+package unologger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RegionEnvVar is the environment variable read by PolicyFromEnv.
+const RegionEnvVar = "UNOLOGGER_REGION"
+
+// UserAgentMode selects how AnonymizationPolicy treats user agents.
+type UserAgentMode int
+
+const (
+	// UserAgentHash replaces the user agent with a hash, which still allows grouping.
+	UserAgentHash UserAgentMode = iota
+	// UserAgentKeep keeps the user agent unchanged.
+	UserAgentKeep
+	// UserAgentDrop removes the user agent.
+	UserAgentDrop
+)
+
+// AnonymizationPolicy describes how client IPs and user agents are anonymized.
+type AnonymizationPolicy struct {
+	// IPv4Bits is the prefix length kept of IPv4 addresses; the host bits are zeroed.
+	// Zero means 24, 32 keeps the address, and a negative value drops it.
+	IPv4Bits int
+	// IPv6Bits is the prefix length kept of IPv6 addresses. Zero means 48, 128 keeps the
+	// address, and a negative value drops it.
+	IPv6Bits int
+	// UserAgent selects whether user agents are hashed (the default), kept or dropped.
+	UserAgent UserAgentMode
+	// Salt keys the user agent hash (HMAC-SHA256). Without a salt, common user agents can
+	// be recovered from their hash by trying known values.
+	Salt string
+	// TrustProxy takes the client IP from the first X-Forwarded-For entry instead of the
+	// connection's remote address. Only enable it behind a proxy that sets the header.
+	TrustProxy bool
+}
+
+// DefaultAnonymizationPolicy is the policy of regions without a registered policy:
+// IPv4 truncated to /24, IPv6 to /48, and user agents hashed.
+var DefaultAnonymizationPolicy = AnonymizationPolicy{}
+
+var (
+	regionPoliciesMu sync.RWMutex
+	regionPolicies   = map[string]AnonymizationPolicy{}
+)
+
+// SetRegionPolicy registers the anonymization policy of a deployment region, e.g. "eu".
+// Region names are case-insensitive.
+func SetRegionPolicy(region string, p AnonymizationPolicy) {
+	regionPoliciesMu.Lock()
+	regionPolicies[strings.ToLower(region)] = p
+	regionPoliciesMu.Unlock()
+}
+
+// RegionPolicy returns the policy registered for region, or DefaultAnonymizationPolicy.
+func RegionPolicy(region string) AnonymizationPolicy {
+	regionPoliciesMu.RLock()
+	defer regionPoliciesMu.RUnlock()
+	if p, ok := regionPolicies[strings.ToLower(region)]; ok {
+		return p
+	}
+	return DefaultAnonymizationPolicy
+}
+
+// PolicyFromEnv returns the policy of the region named by the UNOLOGGER_REGION
+// environment variable.
+func PolicyFromEnv() AnonymizationPolicy {
+	return RegionPolicy(os.Getenv(RegionEnvVar))
+}
+
+// AnonymizeIP returns the anonymized form of an IP address, or "" if the policy drops it.
+// Values that are not IP addresses (with or without a port) are dropped as well, since
+// they cannot be truncated safely.
+func (p AnonymizationPolicy) AnonymizeIP(s string) string {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	bits, width := p.IPv6Bits, 128
+	if bits == 0 {
+		bits = 48
+	}
+	if addr.Is4() {
+		bits, width = p.IPv4Bits, 32
+		if bits == 0 {
+			bits = 24
+		}
+	}
+	if bits < 0 {
+		return ""
+	}
+	prefix, err := addr.Prefix(min(bits, width))
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
+
+// AnonymizeUserAgent returns the anonymized form of a user agent, or "" if the policy
+// drops it. Hashes are 16 hexadecimal characters.
+func (p AnonymizationPolicy) AnonymizeUserAgent(ua string) string {
+	switch {
+	case ua == "" || p.UserAgent == UserAgentDrop:
+		return ""
+	case p.UserAgent == UserAgentKeep:
+		return ua
+	}
+	var sum []byte
+	if p.Salt != "" {
+		mac := hmac.New(sha256.New, []byte(p.Salt))
+		mac.Write([]byte(ua))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte(ua))
+		sum = h[:]
+	}
+	return hex.EncodeToString(sum[:8])
+}
+
+// ClientFields returns the anonymized client_ip and user_agent of r, omitting the values
+// the policy drops. Attach them with WithAttrs or as call-site fields.
+func (p AnonymizationPolicy) ClientFields(r *http.Request) Fields {
+	fields := Fields{}
+	addr := r.RemoteAddr
+	if p.TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			addr, _, _ = strings.Cut(xff, ",")
+		}
+	}
+	if ip := p.AnonymizeIP(addr); ip != "" {
+		fields["client_ip"] = ip
+	}
+	if ua := p.AnonymizeUserAgent(r.UserAgent()); ua != "" {
+		fields["user_agent"] = ua
+	}
+	return fields
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the audit trail of configuration changes. Every mutation made through
+// the dynamic configuration API is recorded with its time, the previous and new values and
+// an optional actor, so that a change of logging behavior can be traced back after the fact.
+
+package unologger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configAuditSize is the number of configuration changes kept by ConfigChanges.
+const configAuditSize = 256
+
+// ConfigChange records a change of the dynamic configuration. Values are summaries meant for
+// people: masking rules are listed by name or pattern, hooks are counted and writers are
+// identified by name and type.
+type ConfigChange struct {
+	Time    time.Time `json:"time"`
+	Setting string    `json:"setting"`         // e.g. "min_level", "regex_rules", "outputs".
+	Old     string    `json:"old"`             // Value before the change.
+	New     string    `json:"new"`             // Value after the change.
+	Actor   string    `json:"actor,omitempty"` // Actor given to Configure, if any.
+}
+
+// configAudit holds the most recent configuration changes in a ring buffer.
+type configAudit struct {
+	mu      sync.Mutex
+	changes []ConfigChange
+	next    int // Index of the oldest change once the buffer is full.
+}
+
+// record appends a change made by actor and returns it.
+func (a *configAudit) record(actor, setting, old, new string) ConfigChange {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := ConfigChange{Time: time.Now(), Setting: setting, Old: old, New: new, Actor: actor}
+	if len(a.changes) < configAuditSize {
+		a.changes = append(a.changes, c)
+	} else {
+		a.changes[a.next] = c
+		a.next = (a.next + 1) % configAuditSize
+	}
+	return c
+}
+
+// ConfigEditor changes the dynamic configuration of a logger on behalf of an actor. Its
+// setters are those of Logger, and attribute each change to the actor in ConfigChanges and
+// in LifecycleConfigChanged events.
+type ConfigEditor struct {
+	l     *Logger
+	actor string
+}
+
+// Configure returns an editor attributing the configuration changes made through it to
+// actor, e.g. a user name, "deploy" or "remote-config". The actor goes with each change, so
+// changes made meanwhile by other goroutines keep their own actor:
+//
+//	l.Configure("alice").SetMinLevel(unologger.DEBUG)
+func (l *Logger) Configure(actor string) ConfigEditor {
+	return ConfigEditor{l: l, actor: actor}
+}
+
+// ConfigChanges returns the most recent changes of the dynamic configuration, oldest first.
+// The last 256 changes made since the logger started are kept; changes made while the logger
+// was being built from its Config are not recorded.
+func (l *Logger) ConfigChanges() []ConfigChange {
+	a := &l.audit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]ConfigChange, 0, len(a.changes))
+	out = append(out, a.changes[a.next:]...)
+	return append(out, a.changes[:a.next]...)
+}
+
+// configEdit is a configuration change being made by a setter. Setters create it and defer
+// commit before taking their locks, fill in the old and new values under the locks, and
+// cancel it if nothing changed, so that the change is recorded once the locks are released.
+type configEdit struct {
+	l        *Logger
+	actor    string
+	setting  string
+	old, new string
+	canceled bool
+}
+
+// changing starts a configuration change of setting by the actor of ed.
+func (ed ConfigEditor) changing(setting string) *configEdit {
+	return &configEdit{l: ed.l, actor: ed.actor, setting: setting}
+}
+
+// configChanged records a change of setting by the actor of ed.
+func (ed ConfigEditor) configChanged(setting, old, new string) {
+	ed.l.configChanged(ed.actor, setting, old, new)
+}
+
+// cancel drops the change, for setters that turned out to change nothing.
+func (c *configEdit) cancel() { c.canceled = true }
+
+// commit records the change, unless it was canceled.
+func (c *configEdit) commit() {
+	if !c.canceled {
+		c.l.configChanged(c.actor, c.setting, c.old, c.new)
+	}
+}
+
+// describeRegexRules summarizes regex masking rules by name, or by pattern if unnamed.
+func describeRegexRules(rules []MaskRuleRegex) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		s := r.Name
+		if s == "" && r.Pattern != nil {
+			s = r.Pattern.String()
+		}
+		if r.Priority != 0 {
+			s += "@" + strconv.Itoa(r.Priority)
+		}
+		parts = append(parts, s)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// describeFieldRules summarizes JSON field masking rules by name, or by keys if unnamed.
+func describeFieldRules(rules []MaskFieldRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		s := r.Name
+		if s == "" {
+			s = strings.Join(r.Keys, ",")
+		}
+		if r.Priority != 0 {
+			s += "@" + strconv.Itoa(r.Priority)
+		}
+		parts = append(parts, s)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// describeHooks summarizes a list of n hooks.
+func describeHooks(n int) string {
+	return strconv.Itoa(n) + " hooks"
+}
+
+// describeDynamicConfig summarizes a DynamicConfig.
+func describeDynamicConfig(dc *DynamicConfig) string {
+	return fmt.Sprintf("min_level=%s regex_rules=%s json_field_rules=%s retry=%+v batch=%+v hooks=%d hooks2=%d filtered_hooks=%s",
+		dc.MinLevel, describeRegexRules(dc.RegexRules), describeFieldRules(dc.JSONFieldRules),
+		dc.Retry, dc.Batch, len(dc.Hooks), len(dc.Hooks2), describeFilteredHooks(dc.FilteredHooks))
+}
+
+// describeOutputsLocked summarizes the writers of the logger. outputsMu must be held.
+func (l *Logger) describeOutputsLocked() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "stdout=%T stderr=%T", l.stdOut, l.errOut)
+	if l.rotationSink != nil {
+		fmt.Fprintf(&b, " rotation=%T", l.rotationSink.Writer)
+	}
+	for _, s := range l.extraW {
+		if s.temp == 0 {
+			fmt.Fprintf(&b, " %s=%T", s.Name, s.Writer)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements blob summaries. Field values that are oversized or binary, such as an
+// uploaded file or a base64 image attached to an error, are replaced by their length and
+// SHA-256 digest: the entry stays small, and the digest still correlates the payload with
+// the copy stored elsewhere.
+
+package unologger
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// base64MinLen is the length from which a string made only of base64 characters is treated
+// as binary data. Shorter strings are too likely to be identifiers or words.
+const base64MinLen = 256
+
+// BlobRule selects field values that are replaced by a BlobSummary.
+type BlobRule struct {
+	// Keys lists the fields the rule applies to. An empty list applies it to every field.
+	Keys []string `json:"keys,omitempty"`
+	// MaxLen summarizes string and byte slice values longer than MaxLen bytes. Zero
+	// disables the size check.
+	MaxLen int `json:"max_len,omitempty"`
+	// Binary summarizes binary values of any size: byte slices, strings that are not
+	// valid UTF-8, and base64 strings of at least 256 characters.
+	Binary bool `json:"binary,omitempty"`
+}
+
+// BlobSummary replaces a summarized field value. It is written as {"len":…,"sha256":…} by
+// the JSON formatter and as "len=… sha256=…" by the text formatter.
+type BlobSummary struct {
+	Len    int    `json:"len"`    // Length of the original value in bytes.
+	SHA256 string `json:"sha256"` // Hex-encoded SHA-256 digest of the original value.
+}
+
+// String implements fmt.Stringer.
+func (s BlobSummary) String() string {
+	return fmt.Sprintf("len=%d sha256=%s", s.Len, s.SHA256)
+}
+
+// SummarizeBlob returns the BlobSummary of b.
+func SummarizeBlob(b []byte) BlobSummary {
+	sum := sha256.Sum256(b)
+	return BlobSummary{Len: len(b), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// SetBlobRules replaces the blob rules at runtime. A nil or empty slice disables them.
+func (l *Logger) SetBlobRules(rules []BlobRule) {
+	if len(rules) == 0 {
+		l.blobRules.Store(nil)
+		return
+	}
+	rules = slices.Clone(rules)
+	l.blobRules.Store(&rules)
+}
+
+// BlobRules returns a copy of the blob rules currently in effect.
+func (l *Logger) BlobRules() []BlobRule {
+	if p := l.blobRules.Load(); p != nil {
+		return slices.Clone(*p)
+	}
+	return nil
+}
+
+// summarizeBlobs replaces, in place, the values of fields selected by the blob rules with
+// their BlobSummary, including the values nested in groups (see WithGroup).
+func (l *Logger) summarizeBlobs(fields Fields) {
+	p := l.blobRules.Load()
+	if p == nil || len(fields) == 0 {
+		return
+	}
+	ownGroups(fields, func(g Fields) { summarizeGroup(*p, g) })
+}
+
+// summarizeGroup replaces the values of the group fields selected by rules.
+func summarizeGroup(rules []BlobRule, fields Fields) {
+	for k, v := range fields {
+		var b []byte
+		switch val := v.(type) {
+		case string:
+			b = []byte(val)
+		case []byte:
+			b = val
+		case json.RawMessage:
+			b = val
+		default:
+			continue
+		}
+		for _, r := range rules {
+			if r.matches(k, v, b) {
+				fields[k] = SummarizeBlob(b)
+				break
+			}
+		}
+	}
+}
+
+// matches reports whether the rule selects the value v, whose bytes are b, of field k.
+func (r *BlobRule) matches(k string, v interface{}, b []byte) bool {
+	if len(r.Keys) > 0 && !slices.Contains(r.Keys, k) {
+		return false
+	}
+	if r.MaxLen > 0 && len(b) > r.MaxLen {
+		return true
+	}
+	if !r.Binary {
+		return false
+	}
+	switch v.(type) {
+	case []byte:
+		return true
+	case json.RawMessage:
+		return !json.Valid(b)
+	}
+	return !utf8.Valid(b) || isBase64Blob(string(b))
+}
+
+// isBase64Blob reports whether s is a long string in one of the base64 encodings.
+func isBase64Blob(s string) bool {
+	if len(s) < base64MinLen {
+		return false
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding,
+	} {
+		if _, err := enc.DecodeString(s); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements cost-aware sampling. A budget controller measures the volume of
+// formatted output per level and periodically recomputes per-level sampling rates so that
+// the projected daily volume stays under a target. The remaining budget is granted to the
+// most severe levels first; ERROR and FATAL entries are never sampled.
+
+package unologger
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults applied to a BudgetConfig.
+const (
+	defaultBudgetInterval = 10 * time.Second
+	defaultBudgetMinRate  = 0.01
+)
+
+// BudgetConfig configures cost-aware sampling against a daily volume target. The volume
+// is the length of the formatted output of the written entries, counted once per entry
+// regardless of how many sinks receive it. Days follow the logger's timezone.
+type BudgetConfig struct {
+	// DailyBytes is the target volume per day across all modules. Zero disables sampling.
+	DailyBytes int64
+	// Interval is how often the sampling rates are recomputed from the volume observed
+	// since the previous computation. Defaults to 10 seconds.
+	Interval time.Duration
+	// MinRate is the lowest sampling rate applied to DEBUG, INFO and WARN, between 0 and 1.
+	// It keeps a trickle of low-level entries even when the budget is exhausted.
+	// Defaults to 0.01.
+	MinRate float64
+}
+
+// BudgetStats is a snapshot of the budget controller.
+type BudgetStats struct {
+	Target     int64             `json:"target"`      // Daily volume target in bytes, or 0 if sampling is disabled.
+	BytesToday int64             `json:"bytes_today"` // Bytes written since the start of the current day.
+	Projected  int64             `json:"projected"`   // Projected volume for the day at the current sampling rates.
+	Rates      map[Level]float64 `json:"rates"`       // Current sampling rate of each level.
+	Sampled    int64             `json:"sampled"`     // Total entries discarded by sampling.
+}
+
+// budgetState holds the measurements of the budget controller.
+type budgetState struct {
+	mu          sync.Mutex
+	day         int
+	bytesToday  int64
+	window      [FATAL + 1]int64 // Bytes written per level since windowStart.
+	windowStart time.Time
+	projected   int64
+
+	// drop holds, per level, the probability that an entry is discarded as float64 bits.
+	// The zero value keeps every entry, so a logger without a budget never samples.
+	drop    [FATAL + 1]atomic.Uint64
+	sampled atomicI64
+}
+
+// SetBudget replaces the budget settings at runtime. Disabling the budget restores a
+// sampling rate of 1 for every level immediately.
+func (l *Logger) SetBudget(bc BudgetConfig) {
+	l.Configure("").SetBudget(bc)
+}
+
+// SetBudget is Logger.SetBudget on behalf of the actor of ed.
+func (ed ConfigEditor) SetBudget(bc BudgetConfig) {
+	l := ed.l
+	c := ed.changing("budget")
+	defer c.commit()
+	if bc.Interval <= 0 {
+		bc.Interval = defaultBudgetInterval
+	}
+	if bc.MinRate <= 0 || bc.MinRate > 1 {
+		bc.MinRate = defaultBudgetMinRate
+	}
+	var old BudgetConfig
+	if p := l.budgetCfg.Swap(&bc); p != nil {
+		old = *p
+	}
+	c.old, c.new = fmt.Sprintf("%+v", old), fmt.Sprintf("%+v", bc)
+	if bc.DailyBytes <= 0 {
+		for i := range l.budget.drop {
+			l.budget.drop[i].Store(0)
+		}
+	}
+}
+
+// GetBudget returns the budget settings currently in effect.
+func (l *Logger) GetBudget() BudgetConfig {
+	if bc := l.budgetCfg.Load(); bc != nil {
+		return *bc
+	}
+	return BudgetConfig{}
+}
+
+// sampledOut reports whether an entry at level must be discarded by sampling, and
+// counts it if so.
+func (l *Logger) sampledOut(level Level) bool {
+	if level = budgetLevel(level); level > FATAL {
+		return false
+	}
+	p := math.Float64frombits(l.budget.drop[level].Load())
+	if p <= 0 || rand.Float64() >= p {
+		return false
+	}
+	l.budget.sampled.Add(1)
+	return true
+}
+
+// budgetLevel returns the level whose sampling rate applies to level: its base level, with
+// TRACE sampled as DEBUG.
+func budgetLevel(level Level) Level {
+	return max(level.Base(), DEBUG)
+}
+
+// rate returns the current sampling rate of level.
+func (b *budgetState) rate(level Level) float64 {
+	return 1 - math.Float64frombits(b.drop[level].Load())
+}
+
+// recordBudget adds an entry of n formatted bytes written at time t to the budget
+// measurements, and recomputes the sampling rates once the interval has elapsed.
+func (l *Logger) recordBudget(t time.Time, level Level, n int) {
+	bc := l.budgetCfg.Load()
+	if level = budgetLevel(level); bc == nil || bc.DailyBytes <= 0 || level > FATAL {
+		return
+	}
+	b := &l.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if day := dayKey(t); b.day != day {
+		b.day = day
+		b.bytesToday = 0
+		for i := range b.drop {
+			b.drop[i].Store(0)
+		}
+		b.window = [FATAL + 1]int64{}
+		b.windowStart = t
+	}
+	b.bytesToday += int64(n)
+	b.window[level] += int64(n)
+	if elapsed := t.Sub(b.windowStart); elapsed >= bc.Interval {
+		b.adjustLocked(bc, t, elapsed)
+		b.window = [FATAL + 1]int64{}
+		b.windowStart = t
+	}
+}
+
+// adjustLocked recomputes the sampling rates from the volume of the last window. The
+// demand of each level is its observed throughput divided by its sampling rate, and the
+// throughput the remaining budget allows until the end of the day is granted from FATAL
+// down to DEBUG. The caller must hold b.mu.
+func (b *budgetState) adjustLocked(bc *BudgetConfig, t time.Time, elapsed time.Duration) {
+	y, m, d := t.Date()
+	left := time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Sub(t).Seconds()
+	secs := elapsed.Seconds()
+
+	allowance := 0.0
+	if remaining := bc.DailyBytes - b.bytesToday; remaining > 0 && left > 0 {
+		allowance = float64(remaining) / left
+	}
+	throughput := 0.0
+	for level := FATAL; level >= DEBUG; level-- {
+		demand := float64(b.window[level]) / secs / b.rate(level)
+		rate := 1.0
+		if level < ERROR && demand > 0 {
+			rate = math.Max(bc.MinRate, math.Min(1, allowance/demand))
+		}
+		allowance = math.Max(0, allowance-demand*rate)
+		throughput += demand * rate
+		b.drop[level].Store(math.Float64bits(1 - rate))
+	}
+	b.projected = b.bytesToday + int64(throughput*left)
+}
+
+// BudgetStats returns a snapshot of the budget controller.
+func (l *Logger) BudgetStats() BudgetStats {
+	b := &l.budget
+	bs := BudgetStats{
+		Target:  l.GetBudget().DailyBytes,
+		Rates:   make(map[Level]float64, FATAL+1),
+		Sampled: b.sampled.Load(),
+	}
+	for level := DEBUG; level <= FATAL; level++ {
+		bs.Rates[level] = b.rate(level)
+	}
+	today := dayKey(l.now())
+	b.mu.Lock()
+	if b.day == today {
+		bs.BytesToday = b.bytesToday
+		bs.Projected = b.projected
+	}
+	b.mu.Unlock()
+	return bs
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements BufferedLogger, a goroutine-local logger that accumulates entries
+// without touching the shared queue and hands them to the pipeline as a single unit on Commit.
+// It is intended for high-frequency inner loops and for emitting multi-line reports atomically.
+
+package unologger
+
+import (
+	"context"
+	"time"
+)
+
+// BufferedLogger collects log entries locally and enqueues them as one group when Commit
+// is called. The committed entries are formatted in order and written with a single write
+// per destination, so lines from other goroutines never interleave with them.
+//
+// A BufferedLogger is owned by one goroutine and is not safe for concurrent use. Level
+// filtering happens when an entry is recorded, exactly as for the regular logging methods.
+type BufferedLogger struct {
+	l       *Logger
+	ctx     context.Context
+	fields  Fields // Fields bound by LoggerWithCtx.With, added to every entry.
+	entries []*logEntry
+}
+
+// Buffered returns a new BufferedLogger that records entries with the given context.
+func (l *Logger) Buffered(ctx context.Context) *BufferedLogger {
+	return &BufferedLogger{l: l, ctx: orBackground(ctx)}
+}
+
+// Buffered returns a new BufferedLogger bound to the logger, context and fields of lw.
+func (lw LoggerWithCtx) Buffered() *BufferedLogger {
+	return &BufferedLogger{l: lw.l, ctx: lw.ctx, fields: lw.fields}
+}
+
+// record acquires a pooled entry and appends it to the local buffer.
+func (b *BufferedLogger) record(level Level, fields Fields, format string, args []interface{}) {
+	if !b.l.levelEnabled(b.ctx, level, true) || b.l.traceThrottle(b.ctx, level) || b.l.sampledOut(level) ||
+		b.l.rateSampledOut(b.ctx, level, format) {
+		return
+	}
+	ctx := b.ctx
+	if b.l.enableOTel.Load() {
+		ctx = AttachOTelTrace(ctx)
+	}
+	e := poolEntry.Get().(*logEntry)
+	e.lvl = level
+	e.ctx = ctx
+	e.t = time.Now()
+	e.tmpl = format
+	e.args, e.literal = splitLiteral(args)
+	e.fields = withFields(b.fields, fields)
+	b.l.captureCaller(e)
+	b.l.stampBackfill(e)
+	b.l.stampModule(e)
+	b.entries = append(b.entries, e)
+}
+
+// Debug records a formatted message at DEBUG level.
+func (b *BufferedLogger) Debug(format string, args ...interface{}) {
+	b.record(DEBUG, nil, format, args)
+}
+
+// Info records a formatted message at INFO level.
+func (b *BufferedLogger) Info(format string, args ...interface{}) { b.record(INFO, nil, format, args) }
+
+// Warn records a formatted message at WARN level.
+func (b *BufferedLogger) Warn(format string, args ...interface{}) { b.record(WARN, nil, format, args) }
+
+// Error records a formatted message at ERROR level.
+func (b *BufferedLogger) Error(format string, args ...interface{}) {
+	b.record(ERROR, nil, format, args)
+}
+
+// Len returns the number of entries recorded since the last Commit or Discard.
+func (b *BufferedLogger) Len() int {
+	return len(b.entries)
+}
+
+// Commit hands all recorded entries to the logger as a single group and resets the
+// buffer so it can be reused. Committing an empty buffer is a no-op. The group is
+// subject to the logger's normal blocking or non-blocking enqueue policy; if it is
+// dropped, every entry in it is counted as dropped.
+func (b *BufferedLogger) Commit() {
+	if len(b.entries) == 0 {
+		return
+	}
+	for _, d := range b.l.delegateTargets() {
+		d.commitCopies(b.ctx, b.entries)
+	}
+	g := poolEntry.Get().(*logEntry)
+	g.lvl = b.entries[len(b.entries)-1].lvl
+	g.ctx = b.ctx
+	g.t = time.Now()
+	g.group = b.entries
+	b.entries = nil
+	b.l.enqueue(g)
+}
+
+// Discard drops all recorded entries without emitting them.
+func (b *BufferedLogger) Discard() {
+	for i, e := range b.entries {
+		recycleEntry(e)
+		b.entries[i] = nil
+	}
+	b.entries = b.entries[:0]
+}
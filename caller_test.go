@@ -0,0 +1,158 @@
+// Copyright 2025 Nguyen Thanh Phuong. All rights reserved.
+
+// The caller and stack tests run outside the package, so that the test functions are seen
+// as the callers of the logger like application code is.
+package unologger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phuonguno98/unologger"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of the workers.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func TestReportCaller(t *testing.T) {
+	var buf lockedBuffer
+	var mu sync.Mutex
+	var callers []unologger.CallerInfo
+	l := unologger.NewDetachedLogger(unologger.Config{
+		Stdout:       &buf,
+		Stderr:       &buf,
+		ReportCaller: true,
+		Hooks: []unologger.HookFunc{func(ev unologger.HookEvent) error {
+			mu.Lock()
+			callers = append(callers, ev.Caller)
+			mu.Unlock()
+			return nil
+		}},
+	})
+	ctx := context.Background()
+
+	_, file, line, _ := runtime.Caller(0)
+	l.WithContext(ctx).Info("via LoggerWithCtx")
+	require.NoError(t, l.InfoSync(ctx, "sync"))
+	want := fmt.Sprintf(" caller=%s:%d func=github.com/phuonguno98/unologger_test.TestReportCaller ",
+		filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file), line+1)
+	require.Eventually(t, func() bool { return strings.Contains(buf.String(), want+"via LoggerWithCtx\n") },
+		time.Second, time.Millisecond)
+	require.Contains(t, buf.String(), fmt.Sprintf(":%d func=", line+2))
+
+	// With a skip depth of 1, the caller of a wrapper is reported.
+	logf := func(msg string) { require.NoError(t, l.WarnSync(ctx, msg)) }
+	l.SetReportCaller(true, 1)
+	enabled, skip := l.ReportCaller()
+	require.True(t, enabled)
+	require.Equal(t, 1, skip)
+	buf.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	logf("wrapped")
+	require.Contains(t, buf.String(), fmt.Sprintf(":%d func=", line+1))
+
+	l.SetReportCaller(true, 0)
+	l.SetFormatter(&unologger.JSONFormatter{})
+	require.NoError(t, l.ErrorSync(ctx, "json"))
+	var obj map[string]any
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &obj))
+	require.Contains(t, obj["caller"], "caller_test.go:")
+	require.Equal(t, "github.com/phuonguno98/unologger_test.TestReportCaller", obj["function"])
+
+	l.SetReportCaller(false, 0)
+	buf.Reset()
+	require.NoError(t, l.InfoSync(ctx, "plain"))
+	require.NotContains(t, buf.String(), `"caller"`)
+	require.NoError(t, unologger.CloseDetached(l, 2*time.Second))
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, callers, 5)
+	require.Equal(t, line+1, callers[2].Line)
+	require.True(t, callers[4].IsZero())
+}
+
+func TestModuleFromCaller(t *testing.T) {
+	var mu sync.Mutex
+	var modules []string
+	cfg := unologger.Config{MinLevel: unologger.INFO, Timezone: "UTC", Stdout: &lockedBuffer{}, Stderr: &lockedBuffer{},
+		ModuleFromCaller: true,
+		Hooks2: []unologger.HookFunc2{func(_ context.Context, ev unologger.HookEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			modules = append(modules, ev.Module)
+			return nil
+		}}}
+	l := unologger.NewDetachedLogger(cfg)
+	require.True(t, l.ModuleFromCaller())
+	ctx := context.Background()
+	require.NoError(t, l.WithContext(ctx).InfoSync("detected"))
+	require.NoError(t, unologger.WithModule(unologger.WithLogger(ctx, l), "explicit").InfoSync("from context"))
+	require.NoError(t, unologger.GetLogger(unologger.WithLogger(ctx, l)).InfoSync("completed by GetLogger"))
+
+	l.SetModuleFromCaller(true, func(pkgPath string) string { return "pkg:" + pkgPath })
+	require.NoError(t, l.WithContext(ctx).InfoSync("custom"))
+	l.WithDefaults("billing", nil)
+	require.NoError(t, l.WithContext(ctx).InfoSync("default module wins"))
+	l.WithDefaults("", nil)
+	l.SetModuleFromCaller(false, nil)
+	require.NoError(t, l.WithContext(ctx).InfoSync("disabled"))
+	require.NoError(t, unologger.GetLogger(unologger.WithLogger(ctx, l)).InfoSync("unknown module"))
+	require.NoError(t, unologger.CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"unologger_test", "explicit", "unologger_test", "pkg:github.com/phuonguno98/unologger_test",
+		"billing", "", "unknown"}, modules)
+}
+
+func TestErrorStacks(t *testing.T) {
+	var buf lockedBuffer
+	events := make(chan unologger.HookEvent, 1)
+	l := unologger.NewDetachedLogger(unologger.Config{Stdout: &buf, Stderr: &buf, JSON: true, ErrorStacks: true,
+		Hooks: []unologger.HookFunc{func(ev unologger.HookEvent) error { events <- ev; return nil }}})
+	l.ErrorErr(context.Background(), fmt.Errorf("load config: %w", os.ErrNotExist), "startup failed", nil)
+	require.NoError(t, unologger.CloseDetached(l, 2*time.Second))
+
+	ev := <-events
+	require.True(t, strings.HasPrefix(ev.Stack, "github.com/phuonguno98/unologger_test.TestErrorStacks\n\t"), ev.Stack)
+	require.Contains(t, ev.Stack, "caller_test.go:")
+	var entry struct {
+		Error struct {
+			Stack string `json:"stack"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(buf.String()), &entry))
+	require.Equal(t, ev.Stack, entry.Error.Stack)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements CaptureOutput, which returns the formatted output of the global
+// logger during a callback, for golden-file testing of log output in application tests.
+
+package unologger
+
+import (
+	"bytes"
+	"sync"
+)
+
+// CaptureOutput runs fn and returns the output written by the global logger while it ran,
+// as formatted and masked by the logger's current configuration. It is built on
+// WithTemporarySink, so the entries of every goroutine logging meanwhile are included and
+// the result is complete when CaptureOutput returns. Golden files comparing the output
+// should use a fixed timezone and formatter, or strip timestamps.
+func CaptureOutput(fn func()) string {
+	var c captureBuffer
+	GlobalLogger().WithTemporarySink(&c, fn)
+	return c.String()
+}
+
+// captureBuffer is a bytes.Buffer safe for the concurrent writes of several workers.
+type captureBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the buffer.
+func (c *captureBuffer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(p)
+}
+
+// String returns the contents of the buffer.
+func (c *captureBuffer) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
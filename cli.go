@@ -0,0 +1,105 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the integration with command-line frameworks. CLI sets up the global
+// logger from the logging flags before a command runs, names the module after the command,
+// and flushes and closes the logger once it returns. It depends on no framework: cobra
+// commands satisfy the Command interface as they are, and other frameworks, such as
+// urfave/cli, call Start and Finish from their own hooks.
+
+package unologger
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// defaultCLICloseTimeout is the default of CLI.CloseTimeout.
+const defaultCLICloseTimeout = 5 * time.Second
+
+// Command is the part of a command of a CLI framework used by CLI.PreRun. *cobra.Command
+// implements it.
+type Command interface {
+	// CommandPath returns the names of the command and its parents, e.g. "tool db migrate".
+	CommandPath() string
+	Context() context.Context
+	SetContext(ctx context.Context)
+}
+
+// CLI wires the global logger into the lifecycle of a command-line tool. With cobra:
+//
+//	lf := unologger.NewLogFlags()
+//	for _, d := range lf.Flags() {
+//		root.PersistentFlags().Var(d.Value, d.Name, d.Usage)
+//	}
+//	cli := &unologger.CLI{Flags: lf, Base: unologger.Config{Timezone: "UTC"}}
+//	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error { return cli.PreRun(cmd) }
+//	root.PersistentPostRunE = func(cmd *cobra.Command, _ []string) error { return cli.PostRun(cmd) }
+//
+// cobra does not run PersistentPostRunE when the command fails, so tools should also call
+// Finish after Execute returns; Finish is idempotent.
+type CLI struct {
+	// Flags holds the values of the logging flags applied to Base. Nil uses the defaults
+	// of NewLogFlags.
+	Flags *LogFlags
+	// Base is the configuration the flags are applied to.
+	Base Config
+	// CloseTimeout bounds the time Finish waits for the entries to be written. Defaults to
+	// 5 seconds.
+	CloseTimeout time.Duration
+}
+
+// Start replaces the global logger with one configured from the flags, and returns ctx
+// bound to the module of the command: the command path without the name of the tool, with
+// dots between the subcommands, e.g. "db.migrate" for "tool db migrate", or the name of
+// the tool for the root command. The error is that of closing the previous global logger;
+// the new one is in place regardless.
+func (c *CLI) Start(ctx context.Context, commandPath string) (context.Context, error) {
+	flags := c.Flags
+	if flags == nil {
+		flags = NewLogFlags()
+	}
+	_, err := ReinitGlobalLogger(flags.Config(c.Base), c.closeTimeout())
+	return WithModule(ctx, commandModule(commandPath)).Context(), err
+}
+
+// Finish flushes and closes the global logger. It is safe to call more than once.
+func (c *CLI) Finish() error {
+	return Close(c.closeTimeout())
+}
+
+// PreRun calls Start with the context and the path of cmd, and sets the returned context
+// on cmd, so that the command logs with GetLogger(cmd.Context()) under its module.
+func (c *CLI) PreRun(cmd Command) error {
+	ctx, err := c.Start(cmd.Context(), cmd.CommandPath())
+	cmd.SetContext(ctx)
+	return err
+}
+
+// PostRun calls Finish.
+func (c *CLI) PostRun(Command) error {
+	return c.Finish()
+}
+
+// closeTimeout returns CloseTimeout, or its default.
+func (c *CLI) closeTimeout() time.Duration {
+	if c.CloseTimeout > 0 {
+		return c.CloseTimeout
+	}
+	return defaultCLICloseTimeout
+}
+
+// commandModule returns the module of a command path.
+func commandModule(path string) string {
+	names := strings.Fields(path)
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	default:
+		return strings.Join(names[1:], ".")
+	}
+}
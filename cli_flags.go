@@ -0,0 +1,159 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the command-line flag helpers. They register the standard logging
+// switches of a tool (-log-level, -log-format, -log-file) on a flag set of the standard
+// library or of a compatible package such as pflag, and turn their values into a Config,
+// so that every tool of an organization exposes the same switches.
+
+package unologger
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Names of the standard logging flags.
+const (
+	FlagLogLevel  = "log-level"
+	FlagLogFormat = "log-format"
+	FlagLogFile   = "log-file"
+)
+
+// Values of the -log-format flag.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LogFlags holds the values of the standard logging flags.
+type LogFlags struct {
+	// Level is the value of -log-level: trace, debug, info, warn, error, fatal or the name of
+	// a custom level. Defaults to info.
+	Level Level
+	// Format is the value of -log-format: FormatText or FormatJSON. Defaults to FormatText.
+	Format string
+	// File is the value of -log-file, the path of a rotated log file written in addition
+	// to the console. Empty by default.
+	File string
+}
+
+// FlagValue is a flag value accepted by flag.FlagSet.Var and by the Var method of pflag,
+// whose values also report their type.
+type FlagValue interface {
+	flag.Value
+	Type() string
+}
+
+// FlagDef describes a flag, for registration with flag packages other than the standard
+// library.
+type FlagDef struct {
+	Name  string
+	Usage string
+	Value FlagValue
+}
+
+// NewLogFlags returns the flag values with their defaults.
+func NewLogFlags() *LogFlags {
+	return &LogFlags{Level: INFO, Format: FormatText}
+}
+
+// RegisterFlags registers the standard logging flags on fs, or on flag.CommandLine if fs is
+// nil, and returns their values, to be read after fs is parsed:
+//
+//	lf := unologger.RegisterFlags(nil)
+//	flag.Parse()
+//	unologger.InitLoggerWithConfig(lf.Config(unologger.Config{}))
+func RegisterFlags(fs *flag.FlagSet) *LogFlags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	f := NewLogFlags()
+	for _, d := range f.Flags() {
+		fs.Var(d.Value, d.Name, d.Usage)
+	}
+	return f
+}
+
+// Flags returns the definitions of the standard logging flags, bound to f. They suit
+// pflag, and thus cobra, whose values satisfy FlagValue:
+//
+//	lf := unologger.NewLogFlags()
+//	for _, d := range lf.Flags() {
+//		cmd.PersistentFlags().Var(d.Value, d.Name, d.Usage)
+//	}
+func (f *LogFlags) Flags() []FlagDef {
+	return []FlagDef{
+		{FlagLogLevel, "minimum log level: trace, debug, info, warn, error or fatal", (*levelFlag)(&f.Level)},
+		{FlagLogFormat, "log format: text or json", (*formatFlag)(&f.Format)},
+		{FlagLogFile, "path of a rotated log file, written in addition to the console", (*stringFlag)(&f.File)},
+	}
+}
+
+// Config returns base with the flag values applied: the minimum level, the JSON formatter
+// for FormatJSON, and a rotated file when File is set, keeping the rotation limits of base.
+func (f *LogFlags) Config(base Config) Config {
+	base.MinLevel = f.Level
+	base.JSON = f.Format == FormatJSON
+	if f.File != "" {
+		base.Rotation.Enable = true
+		base.Rotation.Filename = f.File
+	}
+	return base
+}
+
+// levelFlag is the flag value of a Level.
+type levelFlag Level
+
+// String implements flag.Value.
+func (v *levelFlag) String() string { return strings.ToLower(Level(*v).String()) }
+
+// Set implements flag.Value.
+func (v *levelFlag) Set(s string) error {
+	lvl, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*v = levelFlag(lvl)
+	return nil
+}
+
+// Type implements FlagValue.
+func (v *levelFlag) Type() string { return "level" }
+
+// formatFlag is the flag value of a log format.
+type formatFlag string
+
+// String implements flag.Value.
+func (v *formatFlag) String() string { return string(*v) }
+
+// Set implements flag.Value.
+func (v *formatFlag) Set(s string) error {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case FormatText, FormatJSON:
+		*v = formatFlag(s)
+		return nil
+	default:
+		return fmt.Errorf("unologger: unknown log format %q, want %q or %q", s, FormatText, FormatJSON)
+	}
+}
+
+// Type implements FlagValue.
+func (v *formatFlag) Type() string { return "format" }
+
+// stringFlag is the flag value of a string.
+type stringFlag string
+
+// String implements flag.Value.
+func (v *stringFlag) String() string { return string(*v) }
+
+// Set implements flag.Value.
+func (v *stringFlag) Set(s string) error {
+	*v = stringFlag(s)
+	return nil
+}
+
+// Type implements FlagValue.
+func (v *stringFlag) Type() string { return "string" }
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the detection of wall-clock jumps. Every entry timestamp carries a
+// monotonic clock reading; comparing it with the wall clock reveals NTP steps and VM pauses,
+// after which entries are annotated so that timelines can still be ordered reliably.
+
+package unologger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fields added to entries once a wall-clock jump has been detected.
+const (
+	// ClockEpochKey is the number of the clock epoch of the entry: 0 before the first
+	// jump, then one more for every distinct offset between the wall and monotonic clocks.
+	ClockEpochKey = "clock_epoch"
+	// ClockDriftKey is the offset of the wall clock relative to the monotonic clock since
+	// the logger started, in milliseconds.
+	ClockDriftKey = "clock_drift_ms"
+	// ClockMonoKey is the monotonic time of the entry since the logger started, in
+	// nanoseconds. Unlike timestamps, it orders the entries of one process across jumps.
+	ClockMonoKey = "clock_mono_ns"
+)
+
+// maxClockEpochs bounds the number of remembered epochs; further jumps reuse the last one.
+const maxClockEpochs = 64
+
+// clockState tracks the offsets between the wall and monotonic clocks seen by a logger.
+type clockState struct {
+	threshold atomicI64               // Jump threshold in nanoseconds; 0 disables detection.
+	startMono time.Time               // Monotonic reference taken when the logger was created.
+	startWall atomicI64               // Wall clock of the reference, in Unix nanoseconds.
+	epochs    atomic.Pointer[[]int64] // Offsets of the epochs seen so far, in order.
+	mu        sync.Mutex              // Serializes the creation of epochs.
+	jumps     atomicI64               // Jumps detected.
+}
+
+// SetClockJumpThreshold enables the detection of wall-clock jumps at runtime. A change of
+// the offset between the wall and monotonic clocks of at least d starts a new clock epoch,
+// and from then on every entry carries the clock_epoch, clock_drift_ms and clock_mono_ns
+// fields. Zero or a negative d disables detection.
+func (l *Logger) SetClockJumpThreshold(d time.Duration) {
+	l.clock.threshold.Store(int64(max(d, 0)))
+}
+
+// ClockJumps returns the number of wall-clock jumps detected.
+func (l *Logger) ClockJumps() int64 {
+	return l.clock.jumps.Load()
+}
+
+// init takes the reference reading of the clocks.
+func (c *clockState) init() {
+	c.startMono = time.Now()
+	c.startWall.Store(c.startMono.UnixNano())
+	c.epochs.Store(&[]int64{0})
+}
+
+// annotateClock adds the clock fields to fields if a jump has been detected. t must be the
+// original time.Now() reading of the entry, which carries the monotonic clock.
+func (l *Logger) annotateClock(t time.Time, fields Fields) {
+	c := &l.clock
+	threshold := c.threshold.Load()
+	if threshold == 0 || c.startMono.IsZero() || t == t.Round(0) {
+		return
+	}
+	mono := t.Sub(c.startMono)
+	offset := t.UnixNano() - c.startWall.Load() - int64(mono)
+	epoch := c.epoch(offset, threshold)
+	if c.jumps.Load() == 0 {
+		return
+	}
+	fields[ClockEpochKey] = epoch
+	fields[ClockDriftKey] = offset / int64(time.Millisecond)
+	fields[ClockMonoKey] = int64(mono)
+}
+
+// epoch returns the epoch whose offset is within threshold of offset, creating it if
+// there is none. Matching against every known epoch, rather than only the latest, keeps
+// entries taken before a jump but processed after it in their original epoch.
+func (c *clockState) epoch(offset, threshold int64) int {
+	if i := findEpoch(*c.epochs.Load(), offset, threshold); i >= 0 {
+		return i
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	epochs := *c.epochs.Load()
+	if i := findEpoch(epochs, offset, threshold); i >= 0 {
+		return i
+	}
+	c.jumps.Add(1)
+	if len(epochs) >= maxClockEpochs {
+		return len(epochs) - 1
+	}
+	next := append(epochs[:len(epochs):len(epochs)], offset)
+	c.epochs.Store(&next)
+	return len(next) - 1
+}
+
+// findEpoch returns the index of the epoch matching offset, or -1.
+func findEpoch(epochs []int64, offset, threshold int64) int {
+	for i := len(epochs) - 1; i >= 0; i-- {
+		d := offset - epochs[i]
+		if d < threshold && -d < threshold {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Command unologgervet reports unologger field keys that are not constants, to keep field
+// naming consistent across a codebase. It runs the fieldkeys Analyzer, which checks the keys
+// of unologger.Fields literals, the keys assigned into Fields maps and the key arguments of
+// the unit-safe field helpers (DurationMS, Bytes, Percent).
+//
+// Usage:
+//
+//	go run github.com/phuonguno98/unologger/cmd/unologgervet [-literals] [packages]
+//
+// With -literals, string literals are reported too, so that only named constants such as
+// unologger.FieldUserID are accepted. The command accepts the usual flags of go/analysis
+// checkers, such as -json, and exits with a non-zero status if it reported anything. It
+// also runs as a vet tool:
+//
+//	go vet -vettool=$(which unologgervet) ./...
+package main
+
+import (
+	"github.com/phuonguno98/unologger/fieldkeys"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(fieldkeys.Analyzer)
+}
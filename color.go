@@ -0,0 +1,102 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements colored console output. The TextFormatter can color the level of
+// every entry with ANSI escape codes, always or only when the process writes to a
+// terminal, and honors the NO_COLOR convention, so that mixed-level logs are easy to scan
+// during local development.
+
+package unologger
+
+import (
+	"os"
+	"sync"
+)
+
+// ColorMode selects whether the TextFormatter colors its output.
+type ColorMode int
+
+const (
+	// ColorNever writes plain text. This is the default.
+	ColorNever ColorMode = iota
+	// ColorAuto colors the output when both stdout and stderr are terminals, unless the
+	// NO_COLOR environment variable is set to a non-empty value or TERM is "dumb".
+	ColorAuto
+	// ColorAlways colors the output, whatever the destination and the environment.
+	ColorAlways
+)
+
+// ANSI escape codes used to color the levels.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiGray    = "\x1b[90m"
+	ansiCyan    = "\x1b[36m"
+	ansiYellow  = "\x1b[33m"
+	ansiRed     = "\x1b[31m"
+	ansiBoldRed = "\x1b[1;31m"
+)
+
+// LevelStyle customizes how the TextFormatter writes a level, e.g. for a TUI:
+//
+//	unologger.TextFormatter{Color: unologger.ColorAuto, LevelStyles: map[unologger.Level]unologger.LevelStyle{
+//		unologger.ERROR: {Label: "ERR", Color: "1;35", Emoji: "🔥"},
+//	}}
+type LevelStyle struct {
+	// Label replaces the level name between the brackets. The severity number, if written
+	// instead of the name, is kept.
+	Label string
+	// Color is the ANSI SGR parameter string of the level, e.g. "35" for magenta or
+	// "1;31" for bold red, used when colors are enabled. Empty keeps the default color.
+	Color string
+	// Emoji, if set, is written before the level, whether colors are enabled or not.
+	Emoji string
+}
+
+// escape returns the escape sequence that colors lvl, styled by s.
+func (s LevelStyle) escape(lvl Level) string {
+	if s.Color != "" {
+		return "\x1b[" + s.Color + "m"
+	}
+	return levelColor(lvl)
+}
+
+// levelColor returns the escape code of the default color of lvl.
+func levelColor(lvl Level) string {
+	switch lvl.Base() {
+	case TRACE, DEBUG:
+		return ansiGray
+	case INFO:
+		return ansiCyan
+	case WARN:
+		return ansiYellow
+	case ERROR:
+		return ansiRed
+	default:
+		return ansiBoldRed
+	}
+}
+
+// enabled reports whether output must be colored in mode m.
+func (m ColorMode) enabled() bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && consoleIsTerminal()
+	default:
+		return false
+	}
+}
+
+// consoleIsTerminal reports whether both stdout and stderr are terminals. It is checked
+// once, since the standard streams of a process do not change.
+var consoleIsTerminal = sync.OnceValue(func() bool {
+	return isTerminal(os.Stdout) && isTerminal(os.Stderr)
+})
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements configuration files with hot reload. A JSON or YAML file in the
+// FileConfig format configures the global logger, and a watcher applies later versions of
+// the file as they are saved, so operators can tune levels, masking and sinks without a
+// code change or a redeploy.
+
+package unologger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of ConfigFileWatch.
+const (
+	defaultConfigFileInterval     = 2 * time.Second
+	defaultConfigFileCloseTimeout = 5 * time.Second
+)
+
+// LoadConfigFromFile reads a configuration file in the FileConfig format and returns the
+// Config it describes, with ConfigSources set to path. Files ending in ".yaml" or ".yml"
+// are read as YAML, with the same keys as the JSON form; any other file is read as JSON.
+// Unknown keys are reported as errors. Fields that cannot be set from files (writers,
+// hooks, formatters) are left zero; set them on the result before initializing a logger:
+//
+//	cfg, err := unologger.LoadConfigFromFile("/etc/app/logger.yaml")
+//	cfg.Hooks = hooks
+//	unologger.InitLoggerWithConfig(cfg)
+func LoadConfigFromFile(path string) (Config, error) {
+	return loadConfigFile(path, Config{})
+}
+
+// loadConfigFile reads path and applies it on top of base.
+func loadConfigFile(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, fmt.Errorf("unologger: read config file: %w", err)
+	}
+	return parseConfigFile(path, data, base)
+}
+
+// parseConfigFile applies the content of the configuration file path on top of base.
+func parseConfigFile(path string, data []byte, base Config) (Config, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return base, fmt.Errorf("unologger: config file %s: %w", path, err)
+		}
+		if doc == nil {
+			doc = map[string]interface{}{} // An empty YAML document sets nothing.
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return base, fmt.Errorf("unologger: config file %s: %w", path, err)
+		}
+	}
+	lc := &LayeredConfig{}
+	if err := lc.AddLayer(path, data); err != nil {
+		return base, err
+	}
+	return lc.Apply(base)
+}
+
+// ConfigFileWatch configures WatchConfigFile.
+type ConfigFileWatch struct {
+	// Base holds the settings that cannot be set from files, such as writers, hooks and
+	// formatters. The file is applied on top of it, as by LayeredConfig.Apply.
+	Base Config
+	// Interval is the period at which the file is checked for changes. Defaults to 2s.
+	Interval time.Duration
+	// Reinit selects the runtime state carried over when a change requires the global
+	// logger to be reinitialized. See ReinitGlobalLoggerWithOptions.
+	Reinit ReinitOptions
+	// CloseTimeout bounds the shutdown of the replaced logger on reinitialization.
+	// Defaults to 5s.
+	CloseTimeout time.Duration
+	// OnReload, if set, is called after every version of the file is applied, with the
+	// resulting Config and whether the global logger was reinitialized.
+	OnReload func(cfg Config, reinitialized bool)
+	// OnError, if set, receives the errors of reading, parsing and applying the file; the
+	// last valid configuration stays in effect.
+	OnError func(error)
+}
+
+// WatchConfigFile watches the configuration file path and applies every new version of it
+// to the global logger, until ctx is done. The version present when it starts is taken as
+// the configuration in effect, so the global logger is expected to have been initialized
+// from it, typically with LoadConfigFromFile; an error reading or parsing that version is
+// returned immediately. WatchConfigFile blocks, so it is usually started in its own
+// goroutine:
+//
+//	go unologger.WatchConfigFile(ctx, "/etc/app/logger.yaml", unologger.ConfigFileWatch{Base: base})
+//
+// Only the settings that changed between two versions are applied, through the dynamic
+// configuration API, so runtime changes made to other settings are kept; they are
+// attributed to "file:" followed by path in ConfigChanges. Changes of
+// non_blocking, drop_oldest or single_writer, which cannot be changed on a running logger,
+// reinitialize the global logger instead. The file is polled, which also works on file
+// systems without change notifications and with editors that replace the file on save.
+func WatchConfigFile(ctx context.Context, path string, w ConfigFileWatch) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultConfigFileInterval
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unologger: read config file: %w", err)
+	}
+	cfg, err := parseConfigFile(path, data, w.Base)
+	if err != nil {
+		return err
+	}
+	st, _ := os.Stat(path)
+	for sleepCtx(ctx, interval) {
+		cur, statErr := os.Stat(path)
+		if statErr == nil && st != nil && cur.ModTime().Equal(st.ModTime()) && cur.Size() == st.Size() {
+			continue
+		}
+		st = cur
+		next, readErr := os.ReadFile(path)
+		if readErr == nil && bytes.Equal(next, data) {
+			continue
+		}
+		var newCfg Config
+		if readErr == nil {
+			newCfg, readErr = parseConfigFile(path, next, w.Base)
+		}
+		if readErr == nil {
+			data = next
+			var reinit bool
+			reinit, readErr = reloadGlobal(path, cfg, newCfg, w)
+			// A rejected version does not become the baseline, so that the next version
+			// is compared with, and applies the changes from, the configuration in effect.
+			if readErr == nil {
+				cfg = newCfg
+				if w.OnReload != nil {
+					w.OnReload(cfg, reinit)
+				}
+			}
+		} else {
+			readErr = fmt.Errorf("unologger: reload config file: %w", readErr)
+		}
+		if readErr != nil && w.OnError != nil {
+			w.OnError(readErr)
+		}
+	}
+	return ctx.Err()
+}
+
+// reloadGlobal moves the global logger from the configuration old to cfg, read from path,
+// and reports whether it had to be reinitialized.
+func reloadGlobal(path string, old, cfg Config, w ConfigFileWatch) (bool, error) {
+	if old.NonBlocking != cfg.NonBlocking || old.DropOldest != cfg.DropOldest || old.SingleWriter != cfg.SingleWriter {
+		timeout := w.CloseTimeout
+		if timeout <= 0 {
+			timeout = defaultConfigFileCloseTimeout
+		}
+		_, err := ReinitGlobalLoggerWithOptions(cfg, timeout, w.Reinit)
+		return true, err
+	}
+	return false, GlobalLogger().Configure("file:"+path).applyConfigChanges(old, cfg)
+}
+
+// applyConfigChanges applies the file settings that differ between old and cfg. The
+// changes are validated first, and the queue, the only setting that can still fail, is
+// resized before any other is applied: on error, none of them are applied.
+func (ed ConfigEditor) applyConfigChanges(old, cfg Config) error {
+	l := ed.l
+	for _, pat := range slices.Sorted(maps.Keys(cfg.RegexPatternMap)) {
+		if _, err := regexp.Compile(pat); err != nil {
+			return fmt.Errorf("unologger: invalid masking pattern %q: %w", pat, err)
+		}
+	}
+	if cfg.Timezone != old.Timezone {
+		if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("unologger: invalid timezone %q: %w", cfg.Timezone, err)
+		}
+	}
+	from, to := fileConfigFrom(old), fileConfigFrom(cfg)
+	resize := to.Buffer != from.Buffer && cfg.Buffer > 0
+	if resize && l.direct {
+		return ErrSingleWriterMode
+	}
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+
+	if resize {
+		if err := ed.SetBufferSize(cfg.Buffer); err != nil {
+			return err
+		}
+	}
+	if to.MinLevel != from.MinLevel {
+		ed.SetMinLevel(cfg.MinLevel)
+	}
+	if to.Timezone != from.Timezone {
+		_ = ed.SetTimezone(cfg.Timezone)
+	}
+	if to.JSON != from.JSON {
+		ed.SetJSONFormat(cfg.JSON)
+	}
+	if to.Workers != from.Workers && cfg.Workers > 0 {
+		ed.SetWorkers(cfg.Workers)
+	}
+	if to.EnableOTel != from.EnableOTel {
+		ed.SetEnableOTEL(cfg.EnableOTel)
+	}
+	if to.Batch != from.Batch {
+		ed.SetBatchConfig(cfg.Batch)
+	}
+	if to.Retry != from.Retry {
+		ed.SetRetryPolicy(cfg.Retry)
+	}
+	if to.Hook != from.Hook {
+		ed.SetHookConfig(cfg.Hook)
+	}
+	if !maps.Equal(to.RegexPatterns, from.RegexPatterns) {
+		ed.SetRegexRules(append(slices.Clip(cfg.RegexRules), compileMaskRegexes(cfg.RegexPatternMap)...))
+	}
+	if !reflect.DeepEqual(to.JSONFieldRules, from.JSONFieldRules) {
+		ed.SetJSONFieldRules(cfg.JSONFieldRules)
+	}
+	if to.Rotation != from.Rotation {
+		ed.SetRotation(cfg.Rotation)
+	}
+	if !reflect.DeepEqual(to.Quota, from.Quota) {
+		ed.SetQuota(cfg.Quota)
+	}
+	if to.Budget != from.Budget {
+		ed.SetBudget(cfg.Budget)
+	}
+	return nil
+}
@@ -13,20 +13,31 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"os"
-	"time"
 )
 
+// orBackground returns ctx, or context.Background if ctx is nil. Every function taking a
+// context treats nil as an empty context: the entry is logged with the default module and
+// attributes of the logger, if any (see Logger.WithDefaults), instead of panicking.
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
 // WithLogger attaches a specific *Logger instance to the context.
 // This is an advanced feature for when a non-global logger instance needs to be
 // propagated through a specific request or goroutine chain.
 func WithLogger(ctx context.Context, l *Logger) context.Context {
-	return context.WithValue(ctx, ctxLoggerKey, l)
+	return context.WithValue(orBackground(ctx), ctxLoggerKey, l)
 }
 
 // LoggerFromContext retrieves a *Logger instance from the context, if one exists.
 // It returns the logger and a boolean indicating if it was found.
 func LoggerFromContext(ctx context.Context) (*Logger, bool) {
+	if ctx == nil {
+		return nil, false
+	}
 	l, ok := ctx.Value(ctxLoggerKey).(*Logger)
 	return l, ok
 }
@@ -36,27 +47,29 @@ func LoggerFromContext(ctx context.Context) (*Logger, bool) {
 // It ensures the global logger is initialized if it hasn't been already.
 func WithModule(ctx context.Context, module string) LoggerWithCtx {
 	ensureInit() // Ensure global logger is available.
-	ctx = context.WithValue(ctx, ctxModuleKey, module)
+	ctx = context.WithValue(orBackground(ctx), ctxModuleKey, module)
 	return GetLogger(ctx) // Return a new context-aware logger.
 }
 
 // WithTraceID returns a new context with the provided trace ID attached.
 // Trace IDs are essential for correlating logs across distributed services.
 func WithTraceID(ctx context.Context, traceID string) context.Context {
-	return context.WithValue(ctx, ctxTraceIDKey, traceID)
+	return context.WithValue(orBackground(ctx), ctxTraceIDKey, traceID)
 }
 
 // WithFlowID returns a new context with the provided flow ID attached.
 // Flow IDs can be used for custom tracking of business processes or requests.
 func WithFlowID(ctx context.Context, flowID string) context.Context {
-	return context.WithValue(ctx, ctxFlowIDKey, flowID)
+	return context.WithValue(orBackground(ctx), ctxFlowIDKey, flowID)
 }
 
 // WithAttrs returns a new context containing the provided key-value attributes (Fields).
 // If the context already contains attributes, the new attributes are merged with the
 // existing ones. If a key exists in both, the new value overwrites the old one.
 // This allows for enriching log entries with dynamic, request-specific data.
+// If ctx has a group (see WithGroup), the attributes are nested under it.
 func WithAttrs(ctx context.Context, attrs Fields) context.Context {
+	ctx = orBackground(ctx)
 	if attrs == nil {
 		return ctx
 	}
@@ -67,9 +80,7 @@ func WithAttrs(ctx context.Context, attrs Fields) context.Context {
 	for k, v := range existing {
 		newMap[k] = v
 	}
-	for k, v := range attrs {
-		newMap[k] = v
-	}
+	mergeAtGroup(newMap, fieldGroup(ctx), attrs)
 	return context.WithValue(ctx, ctxFieldsKey, newMap)
 }
 
@@ -82,15 +93,13 @@ func WithAttrs(ctx context.Context, attrs Fields) context.Context {
 //
 // This guarantees that logs will have a trace ID for correlation.
 func EnsureTraceIDCtx(ctx context.Context) context.Context {
+	ctx = orBackground(ctx)
 	// 1. Check if a trace ID already exists.
 	if id, ok := ctx.Value(ctxTraceIDKey).(string); ok && id != "" {
 		return ctx
 	}
 	// 2. Check for OpenTelemetry trace ID.
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil && l.enableOTel.Load() {
+	if l := globalLogger.Load(); l != nil && l.enableOTel.Load() {
 		if tid := extractOTelTraceID(ctx); tid != "" { // Assuming extractOTELTraceID exists
 			return context.WithValue(ctx, ctxTraceIDKey, tid)
 		}
@@ -119,22 +128,26 @@ func newUUID() string {
 
 // GetLogger retrieves a LoggerWithCtx from the context.
 // If a logger is not found in the context, it falls back to the global logger.
-// It also ensures a module name is present, defaulting to "unknown" if not set.
+// It also ensures a module name is present, defaulting to the default module of the
+// logger (see Logger.WithDefaults), then to the module of the caller if the logger detects
+// it (see Logger.SetModuleFromCaller), or to "unknown".
 func GetLogger(ctx context.Context) LoggerWithCtx {
-	ensureInit() // Ensure global logger is available.
-	var base *Logger
+	ctx = orBackground(ctx)
 	// Prefer the logger instance from the context if available.
-	if l, ok := ctx.Value(ctxLoggerKey).(*Logger); ok && l != nil {
-		base = l
-	} else {
-		// Fallback to the global logger.
-		globalMu.RLock()
-		base = globalLogger
-		globalMu.RUnlock()
+	base, ok := ctx.Value(ctxLoggerKey).(*Logger)
+	if !ok || base == nil {
+		// Fall back to the global logger: a single atomic load once it is initialized.
+		base = loadGlobal()
 	}
 	// Ensure module name is present for categorization.
 	if module, ok := ctx.Value(ctxModuleKey).(string); !ok || module == "" {
-		ctx = context.WithValue(ctx, ctxModuleKey, "unknown")
+		module = "unknown"
+		if d := base.defaults.Load(); d != nil && d.module != "" {
+			module = d.module
+		} else if m := base.callerModule(findCaller(base.moduleSkip()).Function); m != "" {
+			module = m
+		}
+		ctx = context.WithValue(ctx, ctxModuleKey, module)
 	}
 	return LoggerWithCtx{l: base, ctx: ctx}
 }
@@ -154,28 +167,26 @@ func (lw LoggerWithCtx) WithAttrs(attrs Fields) LoggerWithCtx {
 
 // Debug logs a formatted message at DEBUG level using the logger's context.
 func (lw LoggerWithCtx) Debug(format string, args ...interface{}) {
-	lw.l.log(lw.ctx, DEBUG, format, args...)
+	lw.l.logFields(lw.ctx, DEBUG, lw.fields, format, args...)
 }
 
 // Info logs a formatted message at INFO level using the logger's context.
 func (lw LoggerWithCtx) Info(format string, args ...interface{}) {
-	lw.l.log(lw.ctx, INFO, format, args...)
+	lw.l.logFields(lw.ctx, INFO, lw.fields, format, args...)
 }
 
 // Warn logs a formatted message at WARN level using the logger's context.
 func (lw LoggerWithCtx) Warn(format string, args ...interface{}) {
-	lw.l.log(lw.ctx, WARN, format, args...)
+	lw.l.logFields(lw.ctx, WARN, lw.fields, format, args...)
 }
 
 // Error logs a formatted message at ERROR level using the logger's context.
 func (lw LoggerWithCtx) Error(format string, args ...interface{}) {
-	lw.l.log(lw.ctx, ERROR, format, args...)
+	lw.l.logFields(lw.ctx, ERROR, lw.fields, format, args...)
 }
 
 // Fatal logs a formatted message at FATAL level, then attempts to flush logs
 // and terminates the application with exit code 1.
 func (lw LoggerWithCtx) Fatal(format string, args ...interface{}) {
-	lw.l.log(lw.ctx, FATAL, format, args...)
-	_ = CloseDetached(lw.l, 2*time.Second) // Assuming CloseDetached exists
-	os.Exit(1)
+	lw.l.fatal(lw.ctx, lw.fields, format, args...)
 }
@@ -0,0 +1,167 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the debug HTTP endpoint. It serves a JSON snapshot of the pipeline
+// (queue, workers, counters, per-writer and per-module statistics) and a CPU profile whose
+// samples carry the worker profile labels, for attributing logging cost to modules.
+
+package unologger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxProfileSeconds bounds the duration of a CPU profile requested from the debug endpoint.
+const maxProfileSeconds = 60
+
+// debugWriter is the JSON form of WriterStats.
+type debugWriter struct {
+	Errors              int64     `json:"errors"`
+	ConsecutiveFailures int64     `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorTime       time.Time `json:"last_error_time,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	BytesWritten        int64     `json:"bytes_written"`
+	EntriesWritten      int64     `json:"entries_written"`
+}
+
+// debugSnapshot is the JSON document served by the debug endpoint.
+type debugSnapshot struct {
+	Workers       int                    `json:"workers"`
+	QueueLen      int                    `json:"queue_len"`
+	QueueCap      int                    `json:"queue_cap"`
+	Written       int64                  `json:"written"`
+	Dropped       int64                  `json:"dropped"`
+	Batches       int64                  `json:"batches"`
+	WriteErrors   int64                  `json:"write_errors"`
+	HookErrors    int64                  `json:"hook_errors"`
+	ProfileLabels bool                   `json:"profile_labels"`
+	Writers       map[string]debugWriter `json:"writers"`
+	Modules       map[string]ModuleStats `json:"modules"`
+	Budget        BudgetStats            `json:"budget"`
+	Sampling      SamplingStats          `json:"sampling"`
+	Validation    ValidationStats        `json:"validation"`
+	ClockJumps    int64                  `json:"clock_jumps"`
+	Stale         StaleStats             `json:"stale"`
+	Panics        int64                  `json:"panics"`
+	MaskingRules  []MaskRuleInfo         `json:"masking_rules"`
+	Migration     *MigrationStats        `json:"migration,omitempty"`
+	Shadow        *ShadowStats           `json:"shadow,omitempty"`
+	Config        FileConfig             `json:"config"`
+	ConfigSources []string               `json:"config_sources,omitempty"`
+	ConfigChanges []ConfigChange         `json:"config_changes,omitempty"`
+}
+
+// DebugHandler returns an HTTP handler exposing the logger's internals. It can be mounted
+// under any prefix, for example:
+//
+//	mux.Handle("/debug/unologger/", http.StripPrefix("/debug/unologger", l.DebugHandler()))
+//
+// A request whose path ends in "/profile" records a CPU profile for the number of seconds
+// given by the "seconds" query parameter (default 10, at most 60) and returns it in pprof
+// format; enable profile labels with SetProfileLabels to attribute samples to modules and
+// levels. The "/shadow" path returns the ShadowStats of the shadow configuration (GET) or
+// detaches it (DELETE), and a POST to "/shadow/promote" promotes it. Any other path returns
+// a JSON snapshot of the pipeline statistics. The handler exposes internal details and can
+// change the configuration, so it should only be reachable by operators.
+func (l *Logger) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/profile"):
+			l.serveProfile(w, r)
+			return
+		case strings.HasSuffix(r.URL.Path, "/shadow"):
+			l.serveShadow(w, r, false)
+			return
+		case strings.HasSuffix(r.URL.Path, "/shadow/promote"):
+			l.serveShadow(w, r, true)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(l.debugSnapshot())
+	})
+}
+
+// debugSnapshot collects the statistics served by DebugHandler.
+func (l *Logger) debugSnapshot() debugSnapshot {
+	q := l.queue()
+	s := debugSnapshot{
+		Workers:       l.Workers(),
+		QueueLen:      l.queueLen(),
+		QueueCap:      cap(q) + cap(l.hi),
+		Written:       l.writtenCount.Load(),
+		Dropped:       l.droppedCount.Load(),
+		Batches:       l.batchCount.Load(),
+		WriteErrors:   l.writeErrCount.Load(),
+		HookErrors:    l.hookErrCount.Load(),
+		ProfileLabels: l.profLabels.Load(),
+		Writers:       make(map[string]debugWriter),
+		Modules:       l.ModuleStats(),
+		Budget:        l.BudgetStats(),
+		Sampling:      l.SamplingStats(),
+		Validation:    l.ValidationStats(),
+		ClockJumps:    l.ClockJumps(),
+		Stale:         l.StaleStats(),
+		Panics:        l.Panics(),
+		MaskingRules:  l.MaskingRules(),
+		Config:        l.EffectiveConfig(),
+		ConfigSources: l.ConfigSources(),
+		ConfigChanges: l.ConfigChanges(),
+	}
+	if ms, ok := l.MigrationStats(); ok {
+		s.Migration = &ms
+	}
+	if ss, ok := l.ShadowStats(); ok {
+		s.Shadow = &ss
+	}
+	for name, ws := range l.WriterStats() {
+		dw := debugWriter{
+			Errors:              ws.Errors,
+			ConsecutiveFailures: ws.ConsecutiveFailures,
+			LastErrorTime:       ws.LastErrorTime,
+			LastSuccess:         ws.LastSuccess,
+			BytesWritten:        ws.BytesWritten,
+			EntriesWritten:      ws.EntriesWritten,
+		}
+		if ws.LastError != nil {
+			dw.LastError = ws.LastError.Error()
+		}
+		s.Writers[name] = dw
+	}
+	return s
+}
+
+// serveProfile records and returns a CPU profile.
+func (l *Logger) serveProfile(w http.ResponseWriter, r *http.Request) {
+	secs := 10
+	if v := r.URL.Query().Get("seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid seconds parameter", http.StatusBadRequest)
+			return
+		}
+		secs = min(n, maxProfileSeconds)
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="unologger.pprof"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// Headers have not been written yet: StartCPUProfile fails before writing.
+		w.Header().Del("Content-Disposition")
+		http.Error(w, fmt.Sprintf("cannot start CPU profile: %v", err), http.StatusConflict)
+		return
+	}
+	select {
+	case <-time.After(time.Duration(secs) * time.Second):
+	case <-r.Context().Done():
+	}
+	pprof.StopCPUProfile()
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the default metadata of a logger. Entries logged without a module or
+// attributes in their context, including those logged with a nil or background context
+// from code that has no request at hand, still carry the module and attributes identifying
+// where they come from.
+
+package unologger
+
+// loggerDefaults holds the default metadata of entries. It is immutable once stored.
+type loggerDefaults struct {
+	module string
+	attrs  Fields
+}
+
+// WithDefaults sets the default module and attributes of the entries of l, and returns l
+// for chaining:
+//
+//	l := unologger.NewDetachedLogger(cfg).WithDefaults("billing", unologger.Fields{"region": "eu"})
+//
+// The module applies to entries whose context has none, and to the contexts completed by
+// GetLogger instead of "unknown". The attributes are added to every entry under the
+// context attributes and call-site fields, which override them. An empty module and nil
+// attributes remove the defaults. The attributes are copied.
+func (l *Logger) WithDefaults(module string, attrs Fields) *Logger {
+	if module == "" && len(attrs) == 0 {
+		l.defaults.Store(nil)
+		return l
+	}
+	l.defaults.Store(&loggerDefaults{module: module, attrs: MergeFields(attrs)})
+	return l
+}
+
+// Defaults returns the default module and a copy of the default attributes of l.
+func (l *Logger) Defaults() (module string, attrs Fields) {
+	d := l.defaults.Load()
+	if d == nil {
+		return "", nil
+	}
+	return d.module, MergeFields(d.attrs)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements delegation. A detached logger with a delegate forwards every entry
+// to it, usually the global logger, and only writes its own sinks on top, so that a library
+// can add, say, a DEBUG file of its own while the application keeps receiving its entries
+// through its usual configuration.
+
+package unologger
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// maxDelegateDepth bounds the walk of the delegation graph done to detect cycles.
+const maxDelegateDepth = 16
+
+// delegateTargets returns the loggers to which entries are forwarded: the Delegate, the
+// members of a Tee, or the global logger with DelegateGlobal, resolved on every call so
+// that delegation follows ReinitGlobalLogger. A closed logger forwards nothing. A target
+// that forwards back to l, directly or through other loggers, e.g. the global logger when
+// it delegates to l, is left out: the entries would otherwise go round forever.
+func (l *Logger) delegateTargets() []*Logger {
+	if l.closed.Load() {
+		return nil
+	}
+	targets := l.resolveDelegates()
+	for _, d := range targets {
+		if d.forwardsTo(l, 0) {
+			return slices.DeleteFunc(slices.Clone(targets), func(d *Logger) bool { return d.forwardsTo(l, 0) })
+		}
+	}
+	return targets
+}
+
+// resolveDelegates returns the delegates of l, the global logger with DelegateGlobal.
+func (l *Logger) resolveDelegates() []*Logger {
+	if len(l.delegates) == 0 && l.delegateGlobal {
+		if g := GlobalLogger(); g != nil {
+			return g.self
+		}
+	}
+	return l.delegates
+}
+
+// forwardsTo reports whether l is target or forwards its entries to target, directly or
+// through its delegates, within maxDelegateDepth steps. Closed loggers forward nothing.
+func (l *Logger) forwardsTo(target *Logger, depth int) bool {
+	if l == target {
+		return true
+	}
+	if depth >= maxDelegateDepth || l.closed.Load() {
+		return false
+	}
+	for _, d := range l.resolveDelegates() {
+		if d.forwardsTo(target, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// commitCopies enqueues, as a single group, copies of the buffered entries that l accepts,
+// and forwards them to the delegates of l. The entries themselves are left to the caller.
+func (l *Logger) commitCopies(ctx context.Context, entries []*logEntry) {
+	if l.closed.Load() {
+		return
+	}
+	for _, d := range l.delegateTargets() {
+		d.commitCopies(ctx, entries)
+	}
+	var group []*logEntry
+	for _, e := range entries {
+		if !l.levelEnabled(e.ctx, e.lvl, true) || l.traceThrottle(e.ctx, e.lvl) || l.sampledOut(e.lvl) ||
+			l.rateSampledOut(e.ctx, e.lvl, e.tmpl) {
+			continue
+		}
+		c := poolEntry.Get().(*logEntry)
+		c.lvl, c.ctx, c.t, c.tmpl, c.args, c.fields = e.lvl, e.ctx, e.t, e.tmpl, e.args, e.fields
+		c.literal = e.literal
+		c.caller = e.caller
+		if l.enableOTel.Load() {
+			c.ctx = AttachOTelTrace(c.ctx)
+		}
+		group = append(group, c)
+	}
+	if len(group) == 0 {
+		return
+	}
+	g := poolEntry.Get().(*logEntry)
+	g.lvl = group[len(group)-1].lvl
+	g.ctx = ctx
+	g.t = time.Now()
+	g.group = group
+	l.enqueue(g)
+}
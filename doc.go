@@ -60,7 +60,7 @@ Example Usage:
 		ctx := context.Background()
 		ctx = unologger.WithModule(ctx, "payment-service").Context()
 		ctx = unologger.WithFlowID(ctx, "req-12345")
-		ctx = unologger.WithAttrs(ctx, unologger.Fields{"user_id": "u007", "transaction_id": "tx-abc"})
+		ctx = unologger.WithAttrs(ctx, unologger.Fields{unologger.FieldUserID: "u007", "transaction_id": "tx-abc"})
 
 		// Get a context-aware logger and log messages
 		log := unologger.GetLogger(ctx)
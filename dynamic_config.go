@@ -10,7 +10,11 @@
 package unologger
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -27,16 +31,28 @@ func (l *Logger) GetDynamicConfig() *DynamicConfig {
 		JSONFieldRules: append([]MaskFieldRule(nil), l.dynConfig.JSONFieldRules...),
 		Retry:          l.dynConfig.Retry,
 		Hooks:          append([]HookFunc(nil), l.dynConfig.Hooks...),
+		Hooks2:         append([]HookFunc2(nil), l.dynConfig.Hooks2...),
+		FilteredHooks:  append([]FilteredHook(nil), l.dynConfig.FilteredHooks...),
 		Batch:          l.dynConfig.Batch,
 	}
 	return copyCfg
 }
 
 // SetMinLevel atomically updates the minimum log level required for a message to be processed.
-// Messages with a level lower than this will be discarded.
+// Messages with a level lower than this will be discarded. A custom level sets its base level.
 func (l *Logger) SetMinLevel(level Level) {
+	l.Configure("").SetMinLevel(level)
+}
+
+// SetMinLevel is Logger.SetMinLevel on behalf of the actor of ed.
+func (ed ConfigEditor) SetMinLevel(level Level) {
+	l := ed.l
+	level = level.Base()
+	c := ed.changing("min_level")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = l.dynConfig.MinLevel.String(), level.String()
 	l.dynConfig.MinLevel = level
 	l.minLevel.Store(int32(level))
 }
@@ -46,33 +62,227 @@ func (l *Logger) SetMinLevel(level Level) {
 func (l *Logger) ShouldLog(level Level) bool {
 	l.dynConfig.mu.RLock()
 	defer l.dynConfig.mu.RUnlock()
-	return level >= l.dynConfig.MinLevel
+	return level.Base() >= l.dynConfig.MinLevel
 }
 
 // SetRegexRules replaces the existing regex-based masking rules with a new set.
 // These rules are used to find and mask sensitive information in log messages.
+// They are applied by decreasing Priority, rules of equal priority in the given order.
 func (l *Logger) SetRegexRules(rules []MaskRuleRegex) {
+	l.Configure("").SetRegexRules(rules)
+}
+
+// SetRegexRules is Logger.SetRegexRules on behalf of the actor of ed.
+func (ed ConfigEditor) SetRegexRules(rules []MaskRuleRegex) {
+	l := ed.l
+	rules = orderRegexRules(rules)
+	c := ed.changing("regex_rules")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = describeRegexRules(l.dynConfig.RegexRules), describeRegexRules(rules)
 	l.dynConfig.RegexRules = rules
 	l.regexRules = rules
 }
 
 // SetJSONFieldRules replaces the existing JSON field-based masking rules.
 // These rules are applied to mask sensitive fields in structured (JSON) log entries
-// by matching field keys.
+// by matching field keys. When several rules name a key, the replacement of the rule
+// with the highest Priority is used, the first one given among equal priorities.
 func (l *Logger) SetJSONFieldRules(rules []MaskFieldRule) {
+	l.Configure("").SetJSONFieldRules(rules)
+}
+
+// SetJSONFieldRules is Logger.SetJSONFieldRules on behalf of the actor of ed.
+func (ed ConfigEditor) SetJSONFieldRules(rules []MaskFieldRule) {
+	l := ed.l
+	rules = orderFieldRules(rules)
+	c := ed.changing("json_field_rules")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = describeFieldRules(l.dynConfig.JSONFieldRules), describeFieldRules(rules)
 	l.dynConfig.JSONFieldRules = rules
 	l.jsonFieldRules = rules
 }
 
+// errEmptyRuleName is returned when a named masking rule is added without a name.
+var errEmptyRuleName = errors.New("unologger: masking rule name is empty")
+
+// AddRegexRule compiles pattern and adds it as the regex masking rule called name,
+// replacing the rule of the same name if there is one, in place and with its priority;
+// new rules have priority 0 and are applied after the existing rules of that priority.
+// Unlike SetRegexRules, it leaves the rules of other components untouched.
+func (l *Logger) AddRegexRule(name, pattern, repl string) error {
+	return l.Configure("").AddRegexRule(name, pattern, repl)
+}
+
+// AddRegexRule is Logger.AddRegexRule on behalf of the actor of ed.
+func (ed ConfigEditor) AddRegexRule(name, pattern, repl string) error {
+	l := ed.l
+	if name == "" {
+		return errEmptyRuleName
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("unologger: invalid masking pattern %q: %w", pattern, err)
+	}
+	rule := MaskRuleRegex{Pattern: re, Replacement: repl, Name: name}
+	c := ed.changing("regex_rules")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	// The slices are replaced rather than modified: maskers read them outside the lock.
+	rules := append([]MaskRuleRegex(nil), l.dynConfig.RegexRules...)
+	if i := slices.IndexFunc(rules, func(r MaskRuleRegex) bool { return r.Name == name }); i >= 0 {
+		rule.Priority = rules[i].Priority
+		rules[i] = rule
+	} else {
+		rules = append(rules, rule)
+	}
+	rules = orderRegexRules(rules)
+	c.old, c.new = describeRegexRules(l.dynConfig.RegexRules), describeRegexRules(rules)
+	l.dynConfig.RegexRules = rules
+	l.regexRules = rules
+	return nil
+}
+
+// RemoveRegexRule removes the regex masking rule called name, and reports whether it existed.
+func (l *Logger) RemoveRegexRule(name string) bool {
+	return l.Configure("").RemoveRegexRule(name)
+}
+
+// RemoveRegexRule is Logger.RemoveRegexRule on behalf of the actor of ed.
+func (ed ConfigEditor) RemoveRegexRule(name string) bool {
+	l := ed.l
+	c := ed.changing("regex_rules")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	rules := slices.DeleteFunc(append([]MaskRuleRegex(nil), l.dynConfig.RegexRules...),
+		func(r MaskRuleRegex) bool { return r.Name == name })
+	if len(rules) == len(l.dynConfig.RegexRules) {
+		c.cancel()
+		return false
+	}
+	c.old, c.new = describeRegexRules(l.dynConfig.RegexRules), describeRegexRules(rules)
+	l.dynConfig.RegexRules = rules
+	l.regexRules = rules
+	return true
+}
+
+// AddFieldRule adds the JSON field masking rule called name, masking the given keys with
+// repl, and replaces the rule of the same name if there is one. See AddRegexRule.
+func (l *Logger) AddFieldRule(name string, keys []string, repl string) error {
+	return l.Configure("").AddFieldRule(name, keys, repl)
+}
+
+// AddFieldRule is Logger.AddFieldRule on behalf of the actor of ed.
+func (ed ConfigEditor) AddFieldRule(name string, keys []string, repl string) error {
+	l := ed.l
+	if name == "" {
+		return errEmptyRuleName
+	}
+	rule := MaskFieldRule{Keys: append([]string(nil), keys...), Replacement: repl, Name: name}
+	c := ed.changing("json_field_rules")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	rules := append([]MaskFieldRule(nil), l.dynConfig.JSONFieldRules...)
+	if i := slices.IndexFunc(rules, func(r MaskFieldRule) bool { return r.Name == name }); i >= 0 {
+		rule.Priority = rules[i].Priority
+		rules[i] = rule
+	} else {
+		rules = append(rules, rule)
+	}
+	rules = orderFieldRules(rules)
+	c.old, c.new = describeFieldRules(l.dynConfig.JSONFieldRules), describeFieldRules(rules)
+	l.dynConfig.JSONFieldRules = rules
+	l.jsonFieldRules = rules
+	return nil
+}
+
+// RemoveFieldRule removes the JSON field masking rule called name, and reports whether it
+// existed.
+func (l *Logger) RemoveFieldRule(name string) bool {
+	return l.Configure("").RemoveFieldRule(name)
+}
+
+// RemoveFieldRule is Logger.RemoveFieldRule on behalf of the actor of ed.
+func (ed ConfigEditor) RemoveFieldRule(name string) bool {
+	l := ed.l
+	c := ed.changing("json_field_rules")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	rules := slices.DeleteFunc(append([]MaskFieldRule(nil), l.dynConfig.JSONFieldRules...),
+		func(r MaskFieldRule) bool { return r.Name == name })
+	if len(rules) == len(l.dynConfig.JSONFieldRules) {
+		c.cancel()
+		return false
+	}
+	c.old, c.new = describeFieldRules(l.dynConfig.JSONFieldRules), describeFieldRules(rules)
+	l.dynConfig.JSONFieldRules = rules
+	l.jsonFieldRules = rules
+	return true
+}
+
+// SetMaskRulePriority sets the priority of the regex and field masking rules called name
+// and reorders the rules accordingly. It reports whether such a rule exists.
+func (l *Logger) SetMaskRulePriority(name string, priority int) bool {
+	return l.Configure("").SetMaskRulePriority(name, priority)
+}
+
+// SetMaskRulePriority is Logger.SetMaskRulePriority on behalf of the actor of ed.
+func (ed ConfigEditor) SetMaskRulePriority(name string, priority int) bool {
+	l := ed.l
+	c := ed.changing("masking_priority")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	found := false
+	regexRules := slices.Clone(l.dynConfig.RegexRules)
+	for i := range regexRules {
+		if regexRules[i].Name == name {
+			c.old = name + "=" + strconv.Itoa(regexRules[i].Priority)
+			regexRules[i].Priority = priority
+			found = true
+		}
+	}
+	fieldRules := slices.Clone(l.dynConfig.JSONFieldRules)
+	for i := range fieldRules {
+		if fieldRules[i].Name == name {
+			c.old = name + "=" + strconv.Itoa(fieldRules[i].Priority)
+			fieldRules[i].Priority = priority
+			found = true
+		}
+	}
+	if !found {
+		c.cancel()
+		return false
+	}
+	c.new = name + "=" + strconv.Itoa(priority)
+	l.dynConfig.RegexRules = orderRegexRules(regexRules)
+	l.dynConfig.JSONFieldRules = orderFieldRules(fieldRules)
+	l.regexRules = l.dynConfig.RegexRules
+	l.jsonFieldRules = l.dynConfig.JSONFieldRules
+	return true
+}
+
 // SetRetryPolicy updates the retry policy for transient output writer errors.
 // This policy dictates if and how the logger should attempt to resend failed log batches.
 func (l *Logger) SetRetryPolicy(rp RetryPolicy) {
+	l.Configure("").SetRetryPolicy(rp)
+}
+
+// SetRetryPolicy is Logger.SetRetryPolicy on behalf of the actor of ed.
+func (ed ConfigEditor) SetRetryPolicy(rp RetryPolicy) {
+	l := ed.l
+	c := ed.changing("retry")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = fmt.Sprintf("%+v", l.dynConfig.Retry), fmt.Sprintf("%+v", rp)
 	l.dynConfig.Retry = rp
 	l.retryPolicy = rp
 }
@@ -80,102 +290,219 @@ func (l *Logger) SetRetryPolicy(rp RetryPolicy) {
 // SetHooks replaces the existing list of hook functions with a new set.
 // Hooks are functions executed for each log entry, allowing for custom processing.
 // If asynchronous hooks are enabled, this method will also ensure the hook runner
-// goroutine is active if it's not already.
+// goroutine is active if it's not already. Libraries sharing a logger should register
+// named hooks with AddHook instead, which leaves the hooks of others in place.
 func (l *Logger) SetHooks(hooks []HookFunc) {
+	l.Configure("").SetHooks(hooks)
+}
+
+// SetHooks is Logger.SetHooks on behalf of the actor of ed.
+func (ed ConfigEditor) SetHooks(hooks []HookFunc) {
+	l := ed.l
+	c := ed.changing("hooks")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = describeHooks(len(l.dynConfig.Hooks)), describeHooks(len(hooks))
 	l.dynConfig.Hooks = hooks
 
 	l.hooksMu.Lock()
 	l.hooks = hooks
-	shouldStart := l.hookAsync && l.hookQueueCh == nil && len(hooks) > 0
 	l.hooksMu.Unlock()
 
-	if shouldStart {
-		l.startHookRunner()
-	}
+	// startHookRunner is a no-op unless the runner is stopped and async hooks apply.
+	l.startHookRunner()
+}
+
+// SetHooks2 replaces the existing list of context-aware hook functions with a new set.
+// Legacy hooks registered through SetHooks are left untouched. If asynchronous hooks
+// are enabled, the hook runner is started if it is not already running.
+func (l *Logger) SetHooks2(hooks []HookFunc2) {
+	l.Configure("").SetHooks2(hooks)
+}
+
+// SetHooks2 is Logger.SetHooks2 on behalf of the actor of ed.
+func (ed ConfigEditor) SetHooks2(hooks []HookFunc2) {
+	l := ed.l
+	c := ed.changing("hooks2")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = describeHooks(len(l.dynConfig.Hooks2)), describeHooks(len(hooks))
+	l.dynConfig.Hooks2 = hooks
+
+	l.hooksMu.Lock()
+	l.hooks2 = hooks
+	l.hooksMu.Unlock()
+
+	l.startHookRunner()
 }
 
 // SetBatchConfig updates the batching configuration (size and max wait time).
 // This controls how log entries are grouped together before being sent to output writers,
 // which can significantly improve performance under high load.
 func (l *Logger) SetBatchConfig(bc BatchConfig) {
+	l.Configure("").SetBatchConfig(bc)
+}
+
+// SetBatchConfig is Logger.SetBatchConfig on behalf of the actor of ed.
+func (ed ConfigEditor) SetBatchConfig(bc BatchConfig) {
+	l := ed.l
+	c := ed.changing("batch")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = fmt.Sprintf("%+v", l.dynConfig.Batch), fmt.Sprintf("%+v", bc)
 	l.dynConfig.Batch = bc
+	l.storeBatch(bc)
+}
+
+// storeBatch publishes batch settings to the atomics read by the workers.
+func (l *Logger) storeBatch(bc BatchConfig) {
 	l.batchSizeA.Store(int64(bc.Size))
 	l.batchWaitA.Store(int64(bc.MaxWait))
+	if bc.FlushOnLevel {
+		l.batchFlush.Store(int64(bc.FlushLevel))
+	} else {
+		l.batchFlush.Store(levelOff)
+	}
 }
 
 // ResetDynamicConfig reverts the logger's dynamic configuration to a provided initial state.
 // This is useful for restoring a known-good configuration at runtime.
 func (l *Logger) ResetDynamicConfig(initial *DynamicConfig) {
+	l.Configure("").ResetDynamicConfig(initial)
+}
+
+// ResetDynamicConfig is Logger.ResetDynamicConfig on behalf of the actor of ed.
+func (ed ConfigEditor) ResetDynamicConfig(initial *DynamicConfig) {
+	l := ed.l
+	c := ed.changing("dynamic_config")
+	defer c.commit()
 	l.dynConfig.mu.Lock()
 	defer l.dynConfig.mu.Unlock()
+	c.old, c.new = describeDynamicConfig(&l.dynConfig), describeDynamicConfig(initial)
 
 	l.dynConfig.MinLevel = initial.MinLevel
-	l.dynConfig.RegexRules = append([]MaskRuleRegex(nil), initial.RegexRules...)
-	l.dynConfig.JSONFieldRules = append([]MaskFieldRule(nil), initial.JSONFieldRules...)
+	l.dynConfig.RegexRules = orderRegexRules(initial.RegexRules)
+	l.dynConfig.JSONFieldRules = orderFieldRules(initial.JSONFieldRules)
 	l.dynConfig.Retry = initial.Retry
 	l.dynConfig.Hooks = append([]HookFunc(nil), initial.Hooks...)
+	l.dynConfig.Hooks2 = append([]HookFunc2(nil), initial.Hooks2...)
+	l.dynConfig.FilteredHooks = append([]FilteredHook(nil), initial.FilteredHooks...)
 	l.dynConfig.Batch = initial.Batch
 	l.minLevel.Store(int32(initial.MinLevel))
-	l.regexRules = initial.RegexRules
-	l.jsonFieldRules = initial.JSONFieldRules
+	l.regexRules = l.dynConfig.RegexRules
+	l.jsonFieldRules = l.dynConfig.JSONFieldRules
 	l.retryPolicy = initial.Retry
 
 	// Safely update hooks.
 	l.hooksMu.Lock()
 	l.hooks = initial.Hooks
+	l.hooks2 = initial.Hooks2
+	l.filteredHooks = l.dynConfig.FilteredHooks
 	l.hooksMu.Unlock()
 
-	l.batchSizeA.Store(int64(initial.Batch.Size))
-	l.batchWaitA.Store(int64(initial.Batch.MaxWait))
+	l.storeBatch(initial.Batch)
 }
 
 // SetJSONFormat enables or disables JSON-structured logging at runtime.
 // When enabled, log entries are formatted as JSON objects.
 func (l *Logger) SetJSONFormat(enabled bool) {
-	l.jsonFmtFlag.Store(enabled)
+	l.Configure("").SetJSONFormat(enabled)
+}
+
+// SetJSONFormat is Logger.SetJSONFormat on behalf of the actor of ed.
+func (ed ConfigEditor) SetJSONFormat(enabled bool) {
+	l := ed.l
+	c := ed.changing("json")
+	defer c.commit()
+	c.old, c.new = strconv.FormatBool(l.jsonFmtFlag.Swap(enabled)), strconv.FormatBool(enabled)
 	if enabled {
-		l.SetFormatter(&JSONFormatter{})
+		l.storeFormatter(&JSONFormatter{})
 	} else {
-		l.SetFormatter(&TextFormatter{})
+		l.storeFormatter(&TextFormatter{})
 	}
 }
 
 // SetFormatter allows for dynamically changing the log formatter at runtime.
 // This can be used to switch between text, JSON, or custom formatters.
 func (l *Logger) SetFormatter(f Formatter) {
+	l.Configure("").SetFormatter(f)
+}
+
+// SetFormatter is Logger.SetFormatter on behalf of the actor of ed.
+func (ed ConfigEditor) SetFormatter(f Formatter) {
+	l := ed.l
+	c := ed.changing("formatter")
+	defer c.commit()
+	c.old, c.new = fmt.Sprintf("%T", l.storeFormatter(f)), fmt.Sprintf("%T", f)
+}
+
+// storeFormatter replaces the formatter and returns the previous one.
+func (l *Logger) storeFormatter(f Formatter) Formatter {
 	l.formatterMu.Lock()
 	defer l.formatterMu.Unlock()
+	old := l.formatter
 	l.formatter = f
+	return old
 }
 
 // SetTimezone updates the timezone used for formatting timestamps in log entries.
 // The timezone must be a valid IANA Time Zone database name (e.g., "UTC", "America/New_York").
 func (l *Logger) SetTimezone(tz string) error {
+	return l.Configure("").SetTimezone(tz)
+}
+
+// SetTimezone is Logger.SetTimezone on behalf of the actor of ed.
+func (ed ConfigEditor) SetTimezone(tz string) error {
+	l := ed.l
 	loc, err := time.LoadLocation(tz)
 	if err != nil {
 		return err
 	}
 	l.locMu.Lock()
+	old := l.loc
 	l.loc = loc
 	l.locMu.Unlock()
+	ed.configChanged("timezone", old.String(), loc.String())
 	return nil
 }
 
 // SetEnableOTEL enables or disables the automatic extraction of OpenTelemetry
 // Trace and Span IDs from the context.
 func (l *Logger) SetEnableOTEL(enabled bool) {
-	l.enableOTel.Store(enabled)
+	l.Configure("").SetEnableOTEL(enabled)
+}
+
+// SetEnableOTEL is Logger.SetEnableOTEL on behalf of the actor of ed.
+func (ed ConfigEditor) SetEnableOTEL(enabled bool) {
+	l := ed.l
+	old := l.enableOTel.Swap(enabled)
+	ed.configChanged("otel", strconv.FormatBool(old), strconv.FormatBool(enabled))
 }
 
 // SetOutputs replaces the logger's output destinations (standard out, standard error,
 // and any extra writers). This operation will clear all previously configured extra writers.
 func (l *Logger) SetOutputs(stdOut, errOut io.Writer, writers []io.Writer, names []string) {
+	l.Configure("").SetOutputs(stdOut, errOut, writers, names)
+}
+
+// SetOutputs is Logger.SetOutputs on behalf of the actor of ed.
+func (ed ConfigEditor) SetOutputs(stdOut, errOut io.Writer, writers []io.Writer, names []string) {
+	l := ed.l
+	var added []string
+	c := ed.changing("outputs")
+	defer func() {
+		c.commit()
+		for _, name := range added {
+			l.sinkAdded(name)
+		}
+	}()
 	l.outputsMu.Lock()
 	defer l.outputsMu.Unlock()
+	c.old = l.describeOutputsLocked()
+	defer func() { c.new = l.describeOutputsLocked() }()
 
 	if stdOut != nil {
 		l.stdOut = stdOut
@@ -199,6 +526,7 @@ func (l *Logger) SetOutputs(stdOut, errOut io.Writer, writers []io.Writer, names
 			s.Closer = c
 		}
 		l.extraW = append(l.extraW, s)
+		added = append(added, name)
 	}
 }
 
@@ -207,25 +535,44 @@ func (l *Logger) SetOutputs(stdOut, errOut io.Writer, writers []io.Writer, names
 // potentially leading to duplicated output unless the old one is removed first.
 // If the name is empty, a default name is assigned.
 func (l *Logger) AddExtraWriter(name string, w io.Writer) {
+	l.Configure("").AddExtraWriter(name, w)
+}
+
+// AddExtraWriter is Logger.AddExtraWriter on behalf of the actor of ed.
+func (ed ConfigEditor) AddExtraWriter(name string, w io.Writer) {
+	l := ed.l
 	if w == nil {
 		return
 	}
 	if name == "" {
 		name = "extra"
 	}
+	defer l.sinkAdded(name)
+	c := ed.changing("outputs")
+	defer c.commit()
 	l.outputsMu.Lock()
 	defer l.outputsMu.Unlock()
+	c.old = l.describeOutputsLocked()
 	s := writerSink{Name: name, Writer: w}
-	if c, ok := w.(io.Closer); ok {
-		s.Closer = c
+	if cl, ok := w.(io.Closer); ok {
+		s.Closer = cl
 	}
 	l.extraW = append(l.extraW, s)
+	c.new = l.describeOutputsLocked()
 }
 
 // RemoveExtraWriter removes an output writer by its name.
 // If the writer is found and implements io.Closer, its Close method is called.
 // It returns true if a writer was found and removed, and false otherwise.
 func (l *Logger) RemoveExtraWriter(name string) bool {
+	return l.Configure("").RemoveExtraWriter(name)
+}
+
+// RemoveExtraWriter is Logger.RemoveExtraWriter on behalf of the actor of ed.
+func (ed ConfigEditor) RemoveExtraWriter(name string) bool {
+	l := ed.l
+	c := ed.changing("outputs")
+	defer c.commit()
 	l.outputsMu.Lock()
 	defer l.outputsMu.Unlock()
 	idx := -1
@@ -236,14 +583,17 @@ func (l *Logger) RemoveExtraWriter(name string) bool {
 		}
 	}
 	if idx < 0 {
+		c.cancel()
 		return false
 	}
+	c.old = l.describeOutputsLocked()
+	defer func() { c.new = l.describeOutputsLocked() }()
 
 	// Close the writer if it implements io.Closer.
 	if l.extraW[idx].Closer != nil {
 		if err := l.extraW[idx].Closer.Close(); err != nil {
 			l.writeErrCount.Add(1)
-			l.incWriterErr(l.extraW[idx].Name)
+			l.incWriterErr(l.extraW[idx].Name, err)
 		}
 	}
 	l.extraW = append(l.extraW[:idx], l.extraW[idx+1:]...)
@@ -255,14 +605,29 @@ func (l *Logger) RemoveExtraWriter(name string) bool {
 // configuration is applied. Enabling rotation initializes a new writer based on the
 // provided settings.
 func (l *Logger) SetRotation(cfg RotationConfig) {
+	l.Configure("").SetRotation(cfg)
+}
+
+// SetRotation is Logger.SetRotation on behalf of the actor of ed.
+func (ed ConfigEditor) SetRotation(cfg RotationConfig) {
+	l := ed.l
+	c := ed.changing("rotation")
+	defer func() {
+		c.commit()
+		if cfg.Enable {
+			l.sinkAdded("rotation")
+		}
+	}()
 	l.outputsMu.Lock()
 	defer l.outputsMu.Unlock()
+	c.old = l.describeOutputsLocked()
+	defer func() { c.new = l.describeOutputsLocked() }()
 
 	// Close the previous rotation writer if it exists.
 	if l.rotationSink != nil && l.rotationSink.Closer != nil {
 		if err := l.rotationSink.Closer.Close(); err != nil {
 			l.writeErrCount.Add(1)
-			l.incWriterErr(l.rotationSink.Name)
+			l.incWriterErr(l.rotationSink.Name, err)
 		}
 		l.rotationSink = nil
 	}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the emergency path used for FATAL messages and recovered panics.
+// It formats and writes the entry synchronously on the caller's goroutine, bypassing the
+// queue and the workers, so the last message before the process dies reaches stderr and
+// the rotation file even when the asynchronous pipeline is wedged.
+
+package unologger
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// emergency writes an entry synchronously to the stderr writer and the rotation file,
+// then offers it to the regular pipeline so hooks and extra writers still see it when
+// the workers are healthy. The offer never blocks: if the queue is full the entry is
+// counted as dropped for those destinations only. If exiting, for the entry of a FATAL
+// call, and FatalConfig.WaitForSinks is set, the entry is delivered and waited for instead.
+func (l *Logger) emergency(ctx context.Context, level Level, exiting bool, fields Fields, format string, args ...interface{}) {
+	for _, d := range l.delegateTargets() {
+		d.emergency(ctx, level, exiting, fields, format, args...)
+	}
+	if level < Level(l.minLevel.Load()) {
+		return
+	}
+	ctx = orBackground(ctx)
+	if l.enableOTel.Load() {
+		ctx = AttachOTelTrace(ctx)
+	}
+	e := poolEntry.Get().(*logEntry)
+	e.lvl = level
+	e.ctx = ctx
+	e.t = time.Now()
+	e.tmpl = format
+	e.args, e.literal = splitLiteral(args)
+	e.fields = fields
+	e.emergency = true
+	l.captureCaller(e)
+	l.stampBackfill(e)
+	l.stampModule(e)
+
+	if l.closed.Load() {
+		l.rejectAfterClose(e)
+		return
+	}
+	l.stampSeq(e)
+	ev := l.buildEvent(e, l.timeSettings())
+	if b, err := l.formatEvent(ev); err == nil {
+		l.writeEmergency(l.signEntry(b), ev)
+	}
+	if fc := l.GetFatalConfig(); exiting && fc.WaitForSinks {
+		l.deliverEmergency(e, fc.timeout())
+		return
+	}
+	l.offerEmergency(e)
+}
+
+// writeEmergency writes p once, without retries, to the stderr writer and the rotation
+// file. Writes are serialized so that concurrent emergency messages never interleave.
+func (l *Logger) writeEmergency(p []byte, ev HookEvent) {
+	l.outputsMu.RLock()
+	errw := l.errOut
+	rotSink := l.rotationSink
+	l.outputsMu.RUnlock()
+
+	l.emergencyMu.Lock()
+	defer l.emergencyMu.Unlock()
+	if errw != nil {
+		if _, err := errw.Write(p); err != nil {
+			l.writeErrCount.Add(1)
+			l.incWriterErr("stderr", err)
+			l.reportWriteError("stderr", err, ev)
+		} else {
+			l.writerSucceeded("stderr", len(p), 1)
+		}
+	}
+	if rotSink != nil && rotSink.Writer != nil {
+		if _, err := rotSink.Writer.Write(p); err != nil {
+			l.writeErrCount.Add(1)
+			l.incWriterErr(rotSink.Name, err)
+			l.reportWriteError(rotSink.Name, err, ev)
+		} else {
+			l.writerSucceeded(rotSink.Name, len(p), 1)
+		}
+	}
+}
+
+// offerEmergency hands an emergency entry to the pipeline without ever blocking.
+// In single-writer mode it is processed inline unless another write holds the pipeline.
+func (l *Logger) offerEmergency(e *logEntry) {
+	if l.direct {
+		if !l.directMu.TryLock() {
+			l.dropEntry(e)
+			return
+		}
+		defer l.directMu.Unlock()
+		if l.closed.Load() {
+			l.rejectAfterClose(e)
+			return
+		}
+		l.processBatch([]*logEntry{e})
+		l.batchCount.Add(1)
+		return
+	}
+
+	l.chMu.RLock()
+	defer l.chMu.RUnlock()
+	if l.closed.Load() {
+		l.rejectAfterClose(e)
+		return
+	}
+	select {
+	case l.tierFor(e) <- e:
+	default:
+		l.dropEntry(e)
+	}
+}
+
+// fatal logs through the emergency path, attempts to flush the logger and exits with the
+// exit code of the FatalConfig, 1 by default.
+func (l *Logger) fatal(ctx context.Context, fields Fields, format string, args ...interface{}) {
+	l.fatalCode(ctx, l.GetFatalConfig().exitCode(), fields, format, args...)
+}
+
+// fatalCode logs through the emergency path, attempts to flush the logger and exits with
+// code.
+func (l *Logger) fatalCode(ctx context.Context, code int, fields Fields, format string, args ...interface{}) {
+	l.emergency(ctx, FATAL, true, fields, format, args...)
+	l.exitAfterFatal(code)
+}
+
+// Recover logs a panic in progress through the emergency path, together with the stack
+// trace, and then re-panics with the same value. It must be called directly by defer:
+//
+//	defer lw.Recover()
+//
+// The original panic is preserved, so the program still crashes (or is recovered further
+// up the stack) exactly as it would without the call.
+func (lw LoggerWithCtx) Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	lw.l.emergency(lw.ctx, FATAL, false, withFields(lw.fields, Fields{FieldStack: string(debug.Stack())}), "panic: %v", r)
+	panic(r)
+}
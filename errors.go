@@ -0,0 +1,218 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements error logging. The *Err methods take an error value instead of
+// formatting it into the message, so that hooks receive the error itself and the
+// formatters write its message, type, chain of wrapped errors and, optionally, the stack
+// of the log call as separate fields that can be searched and grouped.
+
+package unologger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth bounds the number of frames captured by SetErrorStacks.
+const maxStackDepth = 32
+
+// errorInfo is the error of an entry logged by an *Err method, carried by its context.
+type errorInfo struct {
+	err   error
+	stack string
+}
+
+// ctxErrorKey is the context key of the errorInfo of an entry.
+var ctxErrorKey ctxKey = "unologger_error"
+
+// SetErrorStacks enables or disables capturing the stack of the log calls made by the
+// *Err methods. The stack is walked on every such call, so it is best kept for services
+// that log errors rarely, or for development.
+func (l *Logger) SetErrorStacks(enabled bool) {
+	l.errorStacks.Store(enabled)
+}
+
+// ErrorStacks reports whether the *Err methods capture the stack of the call.
+func (l *Logger) ErrorStacks() bool {
+	return l.errorStacks.Load()
+}
+
+// WarnErr logs err with a literal message and structured fields at WARN level. See ErrorErr.
+func (l *Logger) WarnErr(ctx context.Context, err error, msg string, fields Fields) {
+	l.logErr(ctx, WARN, err, fields, msg)
+}
+
+// ErrorErr logs err with a literal message and structured fields at ERROR level:
+//
+//	l.ErrorErr(ctx, err, "payment failed", unologger.Fields{"order_id": id})
+//
+// Hooks receive err in HookEvent.Error, so that they can inspect it with errors.Is and
+// errors.As, and the formatters write its message, type and chain of wrapped errors. A nil
+// err logs the message like ErrorW.
+func (l *Logger) ErrorErr(ctx context.Context, err error, msg string, fields Fields) {
+	l.logErr(ctx, ERROR, err, fields, msg)
+}
+
+// FatalErr logs err with a literal message and structured fields at FATAL level, then
+// flushes the logger and exits like Fatal.
+func (l *Logger) FatalErr(ctx context.Context, err error, msg string, fields Fields) {
+	l.logErr(ctx, FATAL, err, fields, msg)
+}
+
+// WarnErr logs err with a literal message at WARN level with the bound fields and fields.
+func (fl FieldLogger) WarnErr(ctx context.Context, err error, msg string, fields Fields) {
+	fl.l.logErr(ctx, WARN, err, withFields(fl.fields, fields), msg)
+}
+
+// ErrorErr logs err with a literal message at ERROR level with the bound fields and fields.
+func (fl FieldLogger) ErrorErr(ctx context.Context, err error, msg string, fields Fields) {
+	fl.l.logErr(ctx, ERROR, err, withFields(fl.fields, fields), msg)
+}
+
+// FatalErr logs err with a literal message at FATAL level with the bound fields and fields,
+// then flushes the logger and exits like Logger.Fatal.
+func (fl FieldLogger) FatalErr(ctx context.Context, err error, msg string, fields Fields) {
+	fl.l.logErr(ctx, FATAL, err, withFields(fl.fields, fields), msg)
+}
+
+// WarnErr logs err with a literal message and structured fields at WARN level using the
+// logger's context.
+func (lw LoggerWithCtx) WarnErr(err error, msg string, fields Fields) {
+	lw.l.logErr(lw.ctx, WARN, err, withFields(lw.fields, fields), msg)
+}
+
+// ErrorErr logs err with a literal message and structured fields at ERROR level using the
+// logger's context.
+func (lw LoggerWithCtx) ErrorErr(err error, msg string, fields Fields) {
+	lw.l.logErr(lw.ctx, ERROR, err, withFields(lw.fields, fields), msg)
+}
+
+// FatalErr logs err with a literal message and structured fields at FATAL level using the
+// logger's context, then flushes the logger and exits like Logger.Fatal.
+func (lw LoggerWithCtx) FatalErr(err error, msg string, fields Fields) {
+	lw.l.logErr(lw.ctx, FATAL, err, withFields(lw.fields, fields), msg)
+}
+
+// logErr logs msg with err attached to the context of the entry, which carries it through
+// the delegation and emergency paths unchanged.
+func (l *Logger) logErr(ctx context.Context, level Level, err error, fields Fields, msg string) {
+	ctx = orBackground(ctx)
+	if err != nil {
+		info := &errorInfo{err: err}
+		if l.errorStacks.Load() {
+			info.stack = captureStack()
+		}
+		ctx = context.WithValue(ctx, ctxErrorKey, info)
+	}
+	if level == FATAL {
+		l.fatal(ctx, fields, msg, literalArgs...)
+		return
+	}
+	l.logFields(ctx, level, fields, msg, literalArgs...)
+}
+
+// captureStack returns the stack of the log call, from the first frame outside this
+// package, one "function\n\tfile:line\n" record per frame like runtime/debug.Stack.
+func captureStack() string {
+	var pcs [maxStackDepth + 8]uintptr
+	n := runtime.Callers(3, pcs[:]) // Skip runtime.Callers, captureStack and logErr.
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	outside, depth := false, 0
+	for depth < maxStackDepth {
+		f, more := frames.Next()
+		if !outside {
+			outside = !strings.HasPrefix(f.Function, callerPkgPrefix)
+		}
+		if outside {
+			b.WriteString(f.Function)
+			b.WriteString("\n\t")
+			b.WriteString(f.File)
+			b.WriteString(":")
+			b.WriteString(strconv.Itoa(f.Line))
+			b.WriteString("\n")
+			depth++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// ErrorChain returns the message of err followed by those of the errors it wraps, found
+// with errors.Unwrap and, for errors joined with errors.Join or wrapping several errors
+// with fmt.Errorf, depth first. It returns nil for a nil error.
+func ErrorChain(err error) []string {
+	var chain []string
+	var walk func(error)
+	walk = func(err error) {
+		for err != nil && len(chain) < maxStackDepth {
+			chain = append(chain, err.Error())
+			if m, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range m.Unwrap() {
+					walk(e)
+				}
+				return
+			}
+			err = errors.Unwrap(err)
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// errorType returns the dynamic type of err, e.g. "*fs.PathError".
+func errorType(err error) string {
+	return fmt.Sprintf("%T", err)
+}
+
+// annotateError sets the error fields of ev from the errorInfo of the entry, if any. The
+// messages of the chain are masked like the message of the entry.
+func (l *Logger) annotateError(e *logEntry, ev *HookEvent) {
+	info, ok := e.ctx.Value(ctxErrorKey).(*errorInfo)
+	if !ok {
+		return
+	}
+	ev.Error = info.err
+	ev.ErrorChain = ErrorChain(info.err)
+	for i, s := range ev.ErrorChain {
+		ev.ErrorChain[i] = l.applyMasking(s, ev.JSONMode)
+	}
+	ev.Stack = info.stack
+}
+
+// eventErrorChain returns the chain of the error of ev, computed from Error for events
+// built outside the pipeline.
+func eventErrorChain(ev HookEvent) []string {
+	if len(ev.ErrorChain) > 0 {
+		return ev.ErrorChain
+	}
+	return ErrorChain(ev.Error)
+}
+
+// errorJSON is the JSON form of the error of an entry.
+type errorJSON struct {
+	Message string   `json:"message"`
+	Type    string   `json:"type,omitempty"`
+	Chain   []string `json:"chain,omitempty"`
+	Stack   string   `json:"stack,omitempty"`
+}
+
+// newErrorJSON returns the JSON form of the error of ev, or nil if it has none.
+func newErrorJSON(ev HookEvent) *errorJSON {
+	chain := eventErrorChain(ev)
+	if len(chain) == 0 {
+		return nil
+	}
+	e := &errorJSON{Message: chain[0], Chain: chain[1:], Stack: ev.Stack}
+	if ev.Error != nil {
+		e.Type = errorType(ev.Error)
+	}
+	return e
+}
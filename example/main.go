@@ -74,7 +74,7 @@ func main() {
 	ctx = unologger.WithModule(ctx, "payment-service").Context()
 	ctx = unologger.WithFlowID(ctx, "flow-abc-123")
 	ctx = unologger.EnsureTraceIDCtx(ctx) // Ensure a trace ID exists.
-	log := unologger.GetLogger(ctx).WithAttrs(unologger.Fields{"user_id": "u001"})
+	log := unologger.GetLogger(ctx).WithAttrs(unologger.Fields{unologger.FieldUserID: "u001"})
 
 	fmt.Println("---" + " Logging with initial text format ---")
 	log.Info("Processing payment for order %d", 1001)
@@ -120,7 +120,7 @@ func main() {
 	// IMPORTANT: After ReinitGlobalLogger, the 'log' variable (LoggerWithCtx)
 	// still points to the OLD logger instance. To log with the NEW configuration,
 	// you must re-create the LoggerWithCtx instance.
-	newLog := unologger.GetLogger(ctx).WithAttrs(unologger.Fields{"user_id": "u001"})
+	newLog := unologger.GetLogger(ctx).WithAttrs(unologger.Fields{unologger.FieldUserID: "u001"})
 	newLog.Info("This log is in text format again after re-initialization.")
 
 	// 8. Final Stats
@@ -0,0 +1,85 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the failure hook. When a write to a sink fails after all retries, the
+// affected entry is re-delivered to the failure hook with the sink name and the error, so
+// that the application can fall back, e.g. persist critical audit entries to a database.
+// Unlike OnWriteError, the failure hook runs like the other hooks: on the hook workers when
+// hooks are asynchronous, with the hook timeout, and with its errors recorded as hook errors.
+
+package unologger
+
+import (
+	"context"
+)
+
+// WriteFailure describes an entry whose write to a sink failed after all retries.
+type WriteFailure struct {
+	Sink  string    // Name of the sink, as in WriteErrorHandler.
+	Err   error     // Error of the last attempt.
+	Event HookEvent // The affected entry.
+}
+
+// FailureHookFunc receives the entries whose write to a sink failed. The returned error is
+// recorded as a hook error.
+type FailureHookFunc func(ctx context.Context, f WriteFailure) error
+
+// SetFailureHook sets the hook that receives every entry whose write to a sink failed after
+// all retries, once per failed sink, or removes it if fn is nil.
+//
+// With asynchronous hooks (HookConfig.Async), the failure hook is queued for the hook
+// workers alongside the other hooks, so a slow fallback does not stall the writers; it is
+// only queued while at least one regular hook is registered, since the hook workers run only
+// then. Otherwise it runs on the goroutine that wrote the entry. The hook timeout applies,
+// and errors, timeouts and panics are recorded like those of the other hooks.
+func (l *Logger) SetFailureHook(fn FailureHookFunc) {
+	if fn == nil {
+		l.failureFn.Store(nil)
+		return
+	}
+	l.failureFn.Store(&fn)
+}
+
+// keepsEvents reports whether the events of the entries must be kept with their bytes, for
+// the write error handler or the failure hook.
+func (l *Logger) keepsEvents() bool {
+	return l.writeErrFn.Load() != nil || l.failureFn.Load() != nil
+}
+
+// deliverFailure hands a failed entry to the failure hook, if any.
+func (l *Logger) deliverFailure(sink string, err error, ev HookEvent) {
+	if l.failureFn.Load() == nil {
+		return
+	}
+	f := &WriteFailure{Sink: sink, Err: err, Event: ev}
+	ctx := context.Background()
+	l.hooksMu.RLock()
+	if ch := l.hookQueueCh; l.hookAsync && ch != nil {
+		// The read lock is held across the send so the runner cannot close the channel.
+		select {
+		case ch <- hookTask{ctx: ctx, event: ev, failure: f}:
+		default:
+			l.recordHookError(ev, ErrHookQueueFull)
+		}
+		l.hooksMu.RUnlock()
+		return
+	}
+	l.hooksMu.RUnlock()
+	l.runFailureHook(ctx, f)
+}
+
+// runFailureHook runs the failure hook for f like a regular hook.
+func (l *Logger) runFailureHook(ctx context.Context, f *WriteFailure) {
+	fn := l.failureFn.Load()
+	if fn == nil {
+		return
+	}
+	l.hooksMu.RLock()
+	timeout := l.hookTimeout
+	l.hooksMu.RUnlock()
+	hk := func(ctx context.Context, _ HookEvent) error { return (*fn)(ctx, *f) }
+	clock := newHookClock(timeout)
+	defer clock.stop()
+	l.runHook(ctx, hk, f.Event, clock)
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the exit of FATAL calls. The exit code and the exit function can be
+// chosen, the latter so that tests can observe Fatal without ending the test binary, exit
+// hooks run the cleanup of the application before the process ends, and the process can be
+// made to wait until every sink, hooks included, has received the last entry, since the
+// default path only offers it to the pipeline before exiting.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultFatalTimeout is the default of FatalConfig.Timeout.
+const defaultFatalTimeout = 2 * time.Second
+
+// FatalConfig configures how the FATAL calls end the process.
+type FatalConfig struct {
+	// WaitForSinks delivers the FATAL entry to every sink through the pipeline and waits
+	// for their confirmation before closing the logger. By default the entry is written to
+	// stderr and the rotation file and only offered to the other sinks and the hooks,
+	// which miss it if the queue is full.
+	WaitForSinks bool
+	// Timeout bounds the wait for the confirmation, then the exit hooks, and then the close
+	// of the logger, which drains the queue and waits for the running hooks. Defaults to
+	// 2 seconds.
+	Timeout time.Duration
+	// ExitCode is the exit code of Fatal and of the other FATAL calls without a code.
+	// Defaults to 1 unless ExitCodeSet is true; FatalCode overrides it.
+	ExitCode int
+	// ExitCodeSet makes ExitCode be used even if it is 0, which is otherwise taken as unset.
+	ExitCodeSet bool
+}
+
+// ExitHook is called with the exit code by a FATAL call before the process exits.
+type ExitHook func(code int)
+
+// ExitPanic is the value PanicExit panics with.
+type ExitPanic struct {
+	Code int // The exit code of the FATAL call.
+}
+
+// Error implements error.
+func (p ExitPanic) Error() string {
+	return fmt.Sprintf("unologger: fatal exit with code %d", p.Code)
+}
+
+// PanicExit is an exit function that panics with ExitPanic instead of exiting, so that a
+// test can assert that a FATAL call was made, and with which code, with recover or
+// require.PanicsWithValue:
+//
+//	l.SetExitFunc(unologger.PanicExit)
+//	require.PanicsWithValue(t, unologger.ExitPanic{Code: 1}, func() { l.Fatal(ctx, "boom") })
+//
+// The logger is closed when the panic is raised.
+func PanicExit(code int) {
+	panic(ExitPanic{Code: code})
+}
+
+// exitHooks holds the exit hooks of a logger.
+type exitHooks struct {
+	mu     sync.Mutex
+	nextID uint64
+	fns    []exitHookEntry
+}
+
+// exitHookEntry is a registered exit hook and the ID used to remove it.
+type exitHookEntry struct {
+	id uint64
+	fn ExitHook
+}
+
+// timeout returns Timeout, or its default.
+func (c FatalConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultFatalTimeout
+}
+
+// exitCode returns ExitCode, or its default.
+func (c FatalConfig) exitCode() int {
+	if c.ExitCode != 0 || c.ExitCodeSet {
+		return c.ExitCode
+	}
+	return 1
+}
+
+// SetFatalConfig sets how the FATAL calls end the process.
+func (l *Logger) SetFatalConfig(cfg FatalConfig) {
+	l.fatalCfg.Store(&cfg)
+}
+
+// GetFatalConfig returns the settings of the FATAL calls. A logger that delegates, such as
+// a Tee, uses those of its first delegate that has some until it is given its own.
+func (l *Logger) GetFatalConfig() FatalConfig {
+	if c := l.fatalConfig(); c != nil {
+		return *c
+	}
+	return FatalConfig{}
+}
+
+// fatalConfig returns the settings of the FATAL calls of l or of its delegates, or nil.
+func (l *Logger) fatalConfig() *FatalConfig {
+	if c := l.fatalCfg.Load(); c != nil {
+		return c
+	}
+	for _, d := range l.delegateTargets() {
+		if c := d.fatalConfig(); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// SetExitFunc replaces the function called with the exit code once a FATAL call has closed
+// the logger, or restores os.Exit if fn is nil. Tests use it to record the exit code, or
+// PanicExit to turn the FATAL calls into panics; an exit function that returns lets the
+// FATAL call return to its caller, with the logger closed. A logger that delegates, such
+// as a Tee, calls the exit function of its first delegate that has one until it is given
+// its own.
+func (l *Logger) SetExitFunc(fn func(code int)) {
+	if fn == nil {
+		l.exitFn.Store(nil)
+		return
+	}
+	l.exitFn.Store(&fn)
+}
+
+// OnExit registers fn to be called with the exit code by the FATAL calls of the logger, and
+// returns a function that removes it. The hooks run in registration order once the FATAL
+// entry is logged and before the logger is closed, so they can still log, e.g. the result
+// of a cleanup. The hooks of the delegates, such as the members of a Tee, run after those
+// of the logger. Together they are bounded by FatalConfig.Timeout: hooks still running then
+// are abandoned and the process exits. A hook that panics is reported on stderr.
+func (l *Logger) OnExit(fn ExitHook) (remove func()) {
+	if fn == nil {
+		return func() {}
+	}
+	eh := &l.exitHooks
+	eh.mu.Lock()
+	eh.nextID++
+	id := eh.nextID
+	eh.fns = append(eh.fns, exitHookEntry{id: id, fn: fn})
+	eh.mu.Unlock()
+	return func() {
+		eh.mu.Lock()
+		defer eh.mu.Unlock()
+		for i, e := range eh.fns {
+			if e.id == id {
+				eh.fns = append(eh.fns[:i:i], eh.fns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// runExitHooks calls the exit hooks of l and of its delegates with code, and returns once
+// they are done or timeout has expired.
+func (l *Logger) runExitHooks(code int, timeout time.Duration) {
+	fns := l.collectExitHooks(nil, make(map[*Logger]bool))
+	if len(fns) == 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, e := range fns {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Fprintf(os.Stderr, "unologger: exit hook panic: %v\n", r)
+					}
+				}()
+				e.fn(code)
+			}()
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
+
+// collectExitHooks appends the exit hooks of l, then those of its delegates, to fns. A
+// logger reached twice contributes its hooks once.
+func (l *Logger) collectExitHooks(fns []exitHookEntry, seen map[*Logger]bool) []exitHookEntry {
+	if seen[l] {
+		return fns
+	}
+	seen[l] = true
+	l.exitHooks.mu.Lock()
+	fns = append(fns, l.exitHooks.fns...)
+	l.exitHooks.mu.Unlock()
+	for _, d := range l.delegateTargets() {
+		fns = d.collectExitHooks(fns, seen)
+	}
+	return fns
+}
+
+// exitFunc returns the exit function of l or of its delegates, or nil for os.Exit.
+func (l *Logger) exitFunc() *func(int) {
+	if fn := l.exitFn.Load(); fn != nil {
+		return fn
+	}
+	for _, d := range l.delegateTargets() {
+		if fn := d.exitFunc(); fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
+
+// exit calls the exit function fn with code, or os.Exit if fn is nil.
+func exit(fn *func(int), code int) {
+	if fn != nil {
+		(*fn)(code)
+		return
+	}
+	os.Exit(code)
+}
+
+// FatalCode logs a message at FATAL level like Fatal, then exits with code instead of 1.
+func (l *Logger) FatalCode(ctx context.Context, code int, format string, args ...interface{}) {
+	l.fatalCode(ctx, code, nil, format, args...)
+}
+
+// FatalCode logs a message at FATAL level using the logger's context, then exits with code
+// like Logger.FatalCode.
+func (lw LoggerWithCtx) FatalCode(code int, format string, args ...interface{}) {
+	lw.l.fatalCode(lw.ctx, code, lw.fields, format, args...)
+}
+
+// FatalCode logs a formatted message at FATAL level using the global logger, then exits
+// with code like Logger.FatalCode.
+func FatalCode(code int, format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.fatalCode(lw.ctx, code, nil, format, args...)
+}
+
+// deliverEmergency queues an emergency entry for the remaining sinks and waits, up to
+// timeout, until it is written. Unlike offerEmergency, it waits for queue space.
+func (l *Logger) deliverEmergency(e *logEntry, timeout time.Duration) {
+	ack := make(chan error, 1)
+	e.ack = ack
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	if l.direct {
+		// The lock may be held by a stuck write; the timer still bounds the wait.
+		go func() {
+			l.directMu.Lock()
+			defer l.directMu.Unlock()
+			if l.closed.Load() {
+				l.rejectAfterClose(e)
+				return
+			}
+			l.processBatch([]*logEntry{e})
+			l.batchCount.Add(1)
+		}()
+	} else {
+		l.chMu.RLock()
+		if l.closed.Load() {
+			l.chMu.RUnlock()
+			l.rejectAfterClose(e)
+			return
+		}
+		select {
+		case l.tierFor(e) <- e:
+			l.chMu.RUnlock()
+		case <-timer.C:
+			l.chMu.RUnlock()
+			l.dropEntry(e)
+			return
+		}
+	}
+	select {
+	case <-ack:
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file declares the well-known field keys. Using these constants instead of string
+// literals keeps field names consistent across a codebase; the unologgervet command
+// (cmd/unologgervet) reports field keys that are not constants.
+
+package unologger
+
+// Well-known field keys. The keys written by the logger itself (HTTP middleware, SQL
+// logging, panic recovery, OpenTelemetry) use these names.
+const (
+	FieldUserID     = "user_id"
+	FieldTenant     = "tenant"
+	FieldRequestID  = "request_id"
+	FieldSessionID  = "session_id"
+	FieldClientIP   = "client_ip"
+	FieldUserAgent  = "user_agent"
+	FieldSpanID     = "span_id"
+	FieldError      = "error"
+	FieldStack      = "stack"
+	FieldDurationMS = "duration_ms"
+	FieldHTTPMethod = "http_method"
+	FieldHTTPPath   = "http_path"
+	FieldHTTPStatus = "http_status"
+	FieldHTTPBytes  = "http_bytes"
+	FieldSQLQuery   = "sql_query"
+	FieldSQLArgs    = "sql_args"
+)
+
+// WellKnownFields returns the well-known field keys, for building validation schemas or
+// key normalization aliases.
+func WellKnownFields() []string {
+	return []string{
+		FieldUserID, FieldTenant, FieldRequestID, FieldSessionID, FieldClientIP, FieldUserAgent,
+		FieldSpanID, FieldError, FieldStack, FieldDurationMS, FieldHTTPMethod, FieldHTTPPath,
+		FieldHTTPStatus, FieldHTTPBytes, FieldSQLQuery, FieldSQLArgs,
+	}
+}
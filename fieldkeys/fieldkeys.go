@@ -0,0 +1,119 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package fieldkeys defines an Analyzer that reports unologger field keys that are not
+// constants, to keep field naming consistent across a codebase. It checks the keys of
+// unologger.Fields literals, the keys assigned into Fields maps and the key arguments of
+// the unit-safe field helpers (DurationMS, Bytes, Percent).
+//
+// With the -literals flag, string literals are reported too, so that only named constants
+// such as unologger.FieldUserID are accepted. The Analyzer runs standalone as the
+// unologgervet command, or alongside other analyzers in a multichecker or a linter runner.
+package fieldkeys
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// unologgerPath is the import path of the package whose field keys are checked.
+const unologgerPath = "github.com/phuonguno98/unologger"
+
+// keyFuncs are the functions of the package whose first argument is a field key.
+var keyFuncs = map[string]bool{"DurationMS": true, "Bytes": true, "Percent": true}
+
+// Analyzer reports the unologger field keys that are not constants.
+var Analyzer = &analysis.Analyzer{
+	Name:     "fieldkeys",
+	Doc:      "report unologger field keys that are not constants",
+	URL:      "https://pkg.go.dev/github.com/phuonguno98/unologger/fieldkeys",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+	// Keys whose type is unknown are simply not checked, so a package that does not
+	// compile is still checked as far as possible.
+	RunDespiteErrors: true,
+}
+
+// literals is the -literals flag of the Analyzer.
+var literals bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&literals, "literals", false, "also report string literal keys")
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if pass.Pkg.Path() == unologgerPath {
+		// The package builds keys of its own, e.g. in the unit-safe helpers.
+		return nil, nil
+	}
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodes := []ast.Node{(*ast.CompositeLit)(nil), (*ast.AssignStmt)(nil), (*ast.CallExpr)(nil)}
+	ins.Preorder(nodes, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			if isFields(pass, n) {
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						checkKey(pass, kv.Key)
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if ix, ok := ast.Unparen(lhs).(*ast.IndexExpr); ok && isFields(pass, ix.X) {
+					checkKey(pass, ix.Index)
+				}
+			}
+		case *ast.CallExpr:
+			if isKeyFunc(pass, n.Fun) && len(n.Args) > 0 {
+				checkKey(pass, n.Args[0])
+			}
+		}
+	})
+	return nil, nil
+}
+
+// isFields reports whether the type of e is unologger.Fields.
+func isFields(pass *analysis.Pass, e ast.Expr) bool {
+	named, ok := pass.TypesInfo.TypeOf(e).(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == "Fields" && obj.Pkg() != nil && obj.Pkg().Path() == unologgerPath
+}
+
+// isKeyFunc reports whether fun is one of the unit-safe field helpers.
+func isKeyFunc(pass *analysis.Pass, fun ast.Expr) bool {
+	sel, ok := ast.Unparen(fun).(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == unologgerPath && keyFuncs[fn.Name()]
+}
+
+// checkKey reports key if it is not a constant, or if it is a string literal and literals
+// are reported.
+func checkKey(pass *analysis.Pass, key ast.Expr) {
+	tv, ok := pass.TypesInfo.Types[key]
+	switch {
+	case !ok:
+		return
+	case tv.Value == nil:
+		pass.Reportf(key.Pos(), "field key is not a constant")
+	case literals:
+		if lit, ok := ast.Unparen(key).(*ast.BasicLit); ok {
+			pass.Report(analysis.Diagnostic{
+				Pos:     key.Pos(),
+				End:     key.End(),
+				Message: fmt.Sprintf("field key %s is a string literal; use a named constant", lit.Value),
+			})
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package fieldkeys_test
+
+import (
+	"testing"
+
+	"github.com/phuonguno98/unologger/fieldkeys"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), fieldkeys.Analyzer, "a")
+}
+
+func TestAnalyzerLiterals(t *testing.T) {
+	if err := fieldkeys.Analyzer.Flags.Set("literals", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = fieldkeys.Analyzer.Flags.Set("literals", "false") }()
+	analysistest.Run(t, analysistest.TestData(), fieldkeys.Analyzer, "b")
+}
@@ -0,0 +1,27 @@
+package a
+
+import (
+	"time"
+
+	"github.com/phuonguno98/unologger"
+)
+
+const orderID = "order_id"
+
+func keys(dynamic string) {
+	_ = unologger.Fields{
+		unologger.FieldUserID: 1,
+		orderID:               2,
+		"literal":             3,
+		dynamic:               4, // want "field key is not a constant"
+	}
+	f := unologger.Fields{}
+	f[orderID] = 1
+	f[dynamic] = 2                                 // want "field key is not a constant"
+	_ = unologger.DurationMS(dynamic, time.Second) // want "field key is not a constant"
+	_ = unologger.Bytes(orderID, 1)
+	_ = unologger.Percent("ratio", 1, 2)
+
+	m := map[string]interface{}{}
+	m[dynamic] = 1
+}
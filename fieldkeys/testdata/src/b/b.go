@@ -0,0 +1,11 @@
+package b
+
+import "github.com/phuonguno98/unologger"
+
+func keys() {
+	_ = unologger.Fields{
+		unologger.FieldUserID: 1,
+		"literal":             2, // want `field key "literal" is a string literal; use a named constant`
+	}
+	_ = unologger.Bytes("size", 1) // want `field key "size" is a string literal; use a named constant`
+}
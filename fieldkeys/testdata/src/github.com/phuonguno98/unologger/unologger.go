@@ -0,0 +1,14 @@
+// Package unologger is a stub of the real package for the fieldkeys tests.
+package unologger
+
+import "time"
+
+type Fields map[string]interface{}
+
+const FieldUserID = "user_id"
+
+func DurationMS(key string, d time.Duration) Fields { return Fields{key: d.Milliseconds()} }
+
+func Bytes(key string, n int64) Fields { return Fields{key: n} }
+
+func Percent(key string, part, total float64) Fields { return Fields{key: part / total * 100} }
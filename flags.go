@@ -0,0 +1,267 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements feature flag integration. A FlagProvider is asked for the level,
+// sampling rate and debug window of the module and tenant of each entry, so verbosity can
+// be raised for one tenant or silenced for one module from a flag service. Evaluations are
+// cached per module and tenant for a short time to keep the logging path cheap.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Flag keys evaluated through the FlagProvider.
+const (
+	// FlagLevel is a string flag holding the minimum level, e.g. "DEBUG". An empty value
+	// keeps the logger's minimum level.
+	FlagLevel = "unologger.level"
+	// FlagSampleRate is a number flag holding the fraction of entries below ERROR that are
+	// kept, between 0 and 1.
+	FlagSampleRate = "unologger.sample_rate"
+	// FlagDebugUntil is a string flag holding an RFC 3339 time until which DEBUG entries are
+	// enabled, which opens a temporary debug window without a later change to switch it off.
+	FlagDebugUntil = "unologger.debug_until"
+)
+
+// Defaults of FlagConfig.
+const (
+	defaultFlagTTL        = 30 * time.Second
+	defaultFlagTenantAttr = "tenant"
+	maxFlagTargets        = 4096 // Targets cached before the cache is pruned.
+)
+
+// FlagTarget identifies the entries a flag is evaluated for.
+type FlagTarget struct {
+	Module string // The module of the entry's context.
+	Tenant string // The tenant attribute of the entry's context, if any.
+}
+
+// FlagProvider evaluates feature flags for a target. It is called on the logging path when
+// a cached evaluation expires, so it should evaluate locally rather than over the network.
+// Providers return def when a flag is not set or cannot be evaluated.
+type FlagProvider interface {
+	StringFlag(ctx context.Context, key, def string, target FlagTarget) string
+	FloatFlag(ctx context.Context, key string, def float64, target FlagTarget) float64
+}
+
+// FlagConfig configures feature flag evaluation.
+type FlagConfig struct {
+	// Provider evaluates the flags. A nil Provider disables flag evaluation.
+	Provider FlagProvider
+	// TenantAttr is the context attribute (see WithAttrs) holding the tenant.
+	// Defaults to "tenant".
+	TenantAttr string
+	// CacheTTL is how long the evaluations of a target are reused. Defaults to 30 seconds.
+	CacheTTL time.Duration
+}
+
+// flagDecision is the cached evaluation of the flags of one target.
+type flagDecision struct {
+	level      Level // Minimum level, or -1 to use the logger's.
+	rate       float64
+	debugUntil time.Time
+	expires    time.Time
+}
+
+// flagState holds the flag configuration and the cached decisions.
+type flagState struct {
+	cfg     FlagConfig
+	cache   sync.Map // FlagTarget -> *flagDecision
+	cached  atomic.Int64
+	pruneMu sync.Mutex // Held by the goroutine pruning the cache.
+}
+
+// SetFlags replaces the feature flag configuration at runtime and discards cached
+// evaluations. While a provider is set, its level flag overrides the logger's minimum level
+// for the entries of the targets it is set for, in both directions.
+func (l *Logger) SetFlags(fc FlagConfig) {
+	if fc.Provider == nil {
+		l.flags.Store(nil)
+		return
+	}
+	if fc.TenantAttr == "" {
+		fc.TenantAttr = defaultFlagTenantAttr
+	}
+	if fc.CacheTTL <= 0 {
+		fc.CacheTTL = defaultFlagTTL
+	}
+	l.flags.Store(&flagState{cfg: fc})
+}
+
+// levelEnabled reports whether an entry at level with ctx passes the minimum level and,
+// when a flag provider is set, the flags of its module and tenant. Sampling is applied
+// only if sample is true.
+func (l *Logger) levelEnabled(ctx context.Context, level Level, sample bool) bool {
+	level = level.Base()
+	fs := l.flags.Load()
+	if fs == nil {
+		return level >= Level(l.minLevel.Load())
+	}
+	now := time.Now()
+	d := fs.decision(ctx, now)
+	minLevel := Level(l.minLevel.Load())
+	if d.level >= 0 {
+		minLevel = d.level
+	}
+	if now.Before(d.debugUntil) {
+		minLevel = DEBUG
+	}
+	if level < minLevel {
+		return false
+	}
+	return !sample || level >= ERROR || d.rate >= 1 || rand.Float64() < d.rate
+}
+
+// decision returns the cached evaluation of the target of ctx, refreshing it if expired.
+func (fs *flagState) decision(ctx context.Context, now time.Time) *flagDecision {
+	target := FlagTarget{}
+	if ctx != nil {
+		target.Module, _ = ctx.Value(ctxModuleKey).(string)
+		if attrs, ok := ctx.Value(ctxFieldsKey).(Fields); ok {
+			if v, ok := attrs[fs.cfg.TenantAttr]; ok {
+				target.Tenant = fmt.Sprint(v)
+			}
+		}
+	} else {
+		ctx = context.Background()
+	}
+	if v, ok := fs.cache.Load(target); ok {
+		if d := v.(*flagDecision); now.Before(d.expires) {
+			return d
+		}
+	}
+	d := fs.evaluate(ctx, target, now)
+	if _, loaded := fs.cache.Swap(target, d); !loaded && fs.cached.Add(1) > maxFlagTargets {
+		fs.prune(now)
+	}
+	return d
+}
+
+// prune makes room in the cache once it holds more than maxFlagTargets targets. It drops
+// the expired decisions and, if that is not enough, those that expire first, down to three
+// quarters of the limit so that the cost of a prune is shared by many evaluations. A
+// goroutine that finds another one pruning leaves the work to it.
+func (fs *flagState) prune(now time.Time) {
+	if !fs.pruneMu.TryLock() {
+		return
+	}
+	defer fs.pruneMu.Unlock()
+	type cached struct {
+		target FlagTarget
+		d      *flagDecision
+	}
+	var live []cached
+	fs.cache.Range(func(k, v any) bool {
+		c := cached{k.(FlagTarget), v.(*flagDecision)}
+		if now.Before(c.d.expires) {
+			live = append(live, c)
+		} else {
+			fs.drop(c.target, c.d)
+		}
+		return true
+	})
+	keep := maxFlagTargets * 3 / 4
+	if len(live) <= keep {
+		return
+	}
+	slices.SortFunc(live, func(a, b cached) int { return a.d.expires.Compare(b.d.expires) })
+	for _, c := range live[:len(live)-keep] {
+		fs.drop(c.target, c.d)
+	}
+}
+
+// drop removes the decision d of target, unless it was refreshed in the meantime.
+func (fs *flagState) drop(target FlagTarget, d *flagDecision) {
+	if fs.cache.CompareAndDelete(target, d) {
+		fs.cached.Add(-1)
+	}
+}
+
+// evaluate asks the provider for the flags of target.
+func (fs *flagState) evaluate(ctx context.Context, target FlagTarget, now time.Time) *flagDecision {
+	p := fs.cfg.Provider
+	d := &flagDecision{level: -1, rate: 1, expires: now.Add(fs.cfg.CacheTTL)}
+	if s := p.StringFlag(ctx, FlagLevel, "", target); s != "" {
+		if lvl, err := ParseLevel(s); err == nil {
+			d.level = lvl
+		}
+	}
+	if r := p.FloatFlag(ctx, FlagSampleRate, 1, target); r >= 0 && r < 1 {
+		d.rate = r
+	}
+	if s := p.StringFlag(ctx, FlagDebugUntil, "", target); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			d.debugUntil = t
+		}
+	}
+	return d
+}
+
+// OpenFeatureFlags adapts an OpenFeature client to FlagProvider without making the
+// OpenFeature SDK a dependency of this package. Wire the client methods in:
+//
+//	client := openfeature.NewClient("logging")
+//	flags := unologger.OpenFeatureFlags{
+//		StringValue: func(ctx context.Context, flag, def, key string,
+//			attrs map[string]interface{}) (string, error) {
+//			return client.StringValue(ctx, flag, def, openfeature.NewEvaluationContext(key, attrs))
+//		},
+//		FloatValue: func(ctx context.Context, flag string, def float64, key string,
+//			attrs map[string]interface{}) (float64, error) {
+//			return client.FloatValue(ctx, flag, def, openfeature.NewEvaluationContext(key, attrs))
+//		},
+//	}
+//	l.SetFlags(unologger.FlagConfig{Provider: flags})
+//
+// The targeting key is the tenant, or the module if there is no tenant, and the evaluation
+// context carries the "module" and "tenant" attributes. Evaluation errors yield the default.
+type OpenFeatureFlags struct {
+	StringValue func(ctx context.Context, flag, def, targetingKey string,
+		attrs map[string]interface{}) (string, error)
+	FloatValue func(ctx context.Context, flag string, def float64, targetingKey string,
+		attrs map[string]interface{}) (float64, error)
+}
+
+// openFeatureContext returns the targeting key and attributes of target.
+func openFeatureContext(target FlagTarget) (string, map[string]interface{}) {
+	key := target.Tenant
+	if key == "" {
+		key = target.Module
+	}
+	return key, map[string]interface{}{"module": target.Module, "tenant": target.Tenant}
+}
+
+// StringFlag implements FlagProvider.
+func (o OpenFeatureFlags) StringFlag(ctx context.Context, key, def string, target FlagTarget) string {
+	if o.StringValue == nil {
+		return def
+	}
+	tk, attrs := openFeatureContext(target)
+	v, err := o.StringValue(ctx, key, def, tk, attrs)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// FloatFlag implements FlagProvider.
+func (o OpenFeatureFlags) FloatFlag(ctx context.Context, key string, def float64, target FlagTarget) float64 {
+	if o.FloatValue == nil {
+		return def
+	}
+	tk, attrs := openFeatureContext(target)
+	v, err := o.FloatValue(ctx, key, def, tk, attrs)
+	if err != nil {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements Flush, which writes every pending entry without closing the logger,
+// e.g. before a checkpoint or a snapshot, or at the end of each invocation in serverless
+// environments, where the process may be frozen until the next one.
+
+package unologger
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFlushTimeout is returned by Flush when the pending entries are not written in time.
+var ErrFlushTimeout = errors.New("unologger: flush timed out")
+
+// Flush returns once every entry logged before the call has been passed to the writers:
+// the queue is drained and every worker writes its current batch, while the logger keeps
+// accepting entries. It returns ErrFlushTimeout if this takes longer than timeout, in which
+// case the flush goes on in the background; a timeout of zero or less waits indefinitely.
+// Entries held by a paused logger stay held, and the async hooks are not waited for. It is
+// a no-op on a closed logger, and flushes the delegates too, e.g. every member of a Tee.
+func (l *Logger) Flush(timeout time.Duration) error {
+	if l.closed.Load() {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.flushAll()
+	}()
+	if timeout <= 0 {
+		<-done
+		return nil
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrFlushTimeout
+	}
+}
+
+// Flush writes the pending entries of the global logger. See Logger.Flush.
+func Flush(timeout time.Duration) error {
+	l := GlobalLogger()
+	if l == nil {
+		return nil
+	}
+	return l.Flush(timeout)
+}
+
+// flushAll flushes the queue of l and of each of its delegates.
+func (l *Logger) flushAll() {
+	for _, d := range l.delegateTargets() {
+		if !d.closed.Load() {
+			d.flushAll()
+		}
+	}
+	l.flushQueued()
+}
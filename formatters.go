@@ -11,49 +11,181 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// TimeLayoutRFC3339Milli is RFC 3339 with millisecond precision, a TextFormatter.TimeLayout
+// suited to high-frequency debugging.
+const TimeLayoutRFC3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
 // TextFormatter formats log entries into a human-readable, plain text string.
 // This formatter is useful for development environments or console output.
-type TextFormatter struct{}
+// The zero value writes the default layout; the options below customize it.
+type TextFormatter struct {
+	// Severity selects whether the level name, the severity number or both are written,
+	// as "[ERROR]", "[17]" or "[ERROR] severity=17". Defaults to the level name.
+	Severity SeverityMode
+	// SeverityScale selects the numbering of the severity number. Defaults to OpenTelemetry.
+	SeverityScale SeverityScale
+	// TimeLayout is the layout of the timestamp, as for time.Format. Defaults to
+	// time.RFC3339, which has no sub-second precision; see TimeLayoutRFC3339Milli.
+	TimeLayout string
+	// KeySeparator separates the keys of the key-value pairs from their values.
+	// Defaults to "=".
+	KeySeparator string
+	// PairSeparator separates the sections and key-value pairs of the line. Defaults to
+	// a space.
+	PairSeparator string
+	// HideModule omits the "(module)" section.
+	HideModule bool
+	// HideTrace omits the trace and flow IDs.
+	HideTrace bool
+	// ExpandFields writes every field as its own key-value pair, instead of the attrs and
+	// fields maps. The keys listed in FieldOrder come first, in that order, and the others
+	// follow in lexical order. Setting FieldOrder implies ExpandFields.
+	ExpandFields bool
+	// FieldOrder lists the field keys written first when fields are expanded.
+	FieldOrder []string
+	// Color colors the level with ANSI escape codes: gray DEBUG, cyan INFO, yellow WARN,
+	// red ERROR and bold red FATAL. The formatter is shared by every sink, so colors are
+	// best kept for loggers that only write to the console. Defaults to ColorNever.
+	Color ColorMode
+	// LevelStyles customizes the label, color and emoji of levels; levels without a style
+	// keep their defaults. See LevelStyle.
+	LevelStyles map[Level]LevelStyle
+}
 
 // Format converts a log event into a byte slice representing a single log line.
-// The output format is: "TIMESTAMP [LEVEL] (MODULE) KEY=VALUE... MESSAGE\n".
+// The default output format is: "TIMESTAMP [LEVEL] (MODULE) KEY=VALUE... MESSAGE\n".
 // Metadata like trace ID, flow ID, and other attributes are included as key-value pairs.
+// The error of an entry is written as the error, error_type and causes pairs, and its
+// stack, if captured, on the lines following the message.
 func (f *TextFormatter) Format(ev HookEvent) ([]byte, error) {
 	// Use a buffer for efficient string building.
 	var buf bytes.Buffer
+	layout, kv, sep := f.TimeLayout, f.KeySeparator, f.PairSeparator
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	if kv == "" {
+		kv = "="
+	}
+	if sep == "" {
+		sep = " "
+	}
+	pair := func(key, value string) {
+		buf.WriteString(sep)
+		buf.WriteString(key)
+		buf.WriteString(kv)
+		buf.WriteString(value)
+	}
 
-	// Format the timestamp with milliseconds and timezone.
-	buf.WriteString(ev.Time.Format(time.RFC3339))
-	buf.WriteString(" [")
-	buf.WriteString(ev.Level.String())
-	buf.WriteString("] (")
-	buf.WriteString(ev.Module)
-	buf.WriteString(")")
+	// Format the timestamp and the level.
+	buf.WriteString(ev.Time.Format(layout))
+	buf.WriteString(sep)
+	style, ok := f.LevelStyles[ev.Level]
+	if !ok {
+		style = f.LevelStyles[ev.Level.Base()]
+	}
+	if style.Emoji != "" {
+		buf.WriteString(style.Emoji)
+		buf.WriteString(" ")
+	}
+	color := f.Color.enabled()
+	if color {
+		buf.WriteString(style.escape(ev.Level))
+	}
+	buf.WriteString("[")
+	if f.Severity.writesText() && style.Label != "" {
+		buf.WriteString(style.Label)
+	} else if f.Severity.writesText() {
+		buf.WriteString(ev.Level.String())
+	} else {
+		buf.WriteString(strconv.Itoa(f.SeverityScale.Severity(ev.Level)))
+	}
+	buf.WriteString("]")
+	if color {
+		buf.WriteString(ansiReset)
+	}
+	if !f.HideModule {
+		buf.WriteString(sep)
+		buf.WriteString("(")
+		buf.WriteString(ev.Module)
+		buf.WriteString(")")
+	}
 
 	// Append metadata if present.
-	if ev.TraceID != "" {
-		buf.WriteString(" trace=")
-		buf.WriteString(ev.TraceID)
+	if f.Severity == SeverityTextAndNumber {
+		pair("severity", strconv.Itoa(f.SeverityScale.Severity(ev.Level)))
+	}
+	if ev.Seq != 0 {
+		pair("seq", strconv.FormatUint(ev.Seq, 10))
+	}
+	if ev.TraceID != "" && !f.HideTrace {
+		pair("trace", ev.TraceID)
 	}
-	if ev.FlowID != "" {
-		buf.WriteString(" flow=")
-		buf.WriteString(ev.FlowID)
+	if ev.FlowID != "" && !f.HideTrace {
+		pair("flow", ev.FlowID)
 	}
-	if len(ev.Attrs) > 0 {
+	if !ev.Caller.IsZero() {
+		pair("caller", ev.Caller.String())
+		pair("func", ev.Caller.Function)
+	}
+	if f.ExpandFields || len(f.FieldOrder) > 0 {
+		fields := ev.Fields
+		if len(fields) == 0 {
+			fields = ev.Attrs
+		}
+		// The fields of a group are written as "group.key".
+		for _, k := range f.FieldOrder {
+			if v, ok := fields[k]; ok {
+				expandField(k, v, pair)
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(fields)) {
+			if !slices.Contains(f.FieldOrder, k) {
+				expandField(k, fields[k], pair)
+			}
+		}
+	} else {
 		// A simple, though not perfectly escaped, representation for text logs.
-		buf.WriteString(fmt.Sprintf(" attrs=%v", ev.Attrs))
+		if len(ev.Attrs) > 0 {
+			pair("attrs", fmt.Sprintf("%v", ev.Attrs))
+		}
+		if len(ev.Fields) > 0 {
+			pair("fields", fmt.Sprintf("%v", ev.Fields))
+		}
 	}
-	if len(ev.Fields) > 0 {
-		buf.WriteString(fmt.Sprintf(" fields=%v", ev.Fields))
+
+	// Append the error of the entry, quoted since error messages often contain spaces.
+	if chain := eventErrorChain(ev); len(chain) > 0 {
+		pair("error", strconv.Quote(chain[0]))
+		if ev.Error != nil {
+			pair("error_type", errorType(ev.Error))
+		}
+		if len(chain) > 1 {
+			causes := make([]string, len(chain)-1)
+			for i, c := range chain[1:] {
+				causes[i] = strconv.Quote(c)
+			}
+			pair("causes", "["+strings.Join(causes, ", ")+"]")
+		}
 	}
 
-	// Append the main message and a newline.
-	buf.WriteString(" ")
+	// Append the main message and a newline, followed by the stack of the error, if any.
+	buf.WriteString(sep)
 	buf.WriteString(ev.Message)
 	buf.WriteString("\n")
+	if ev.Stack != "" {
+		buf.WriteString(ev.Stack)
+		if !strings.HasSuffix(ev.Stack, "\n") {
+			buf.WriteString("\n")
+		}
+	}
 
 	return buf.Bytes(), nil
 }
@@ -61,47 +193,110 @@ func (f *TextFormatter) Format(ev HookEvent) ([]byte, error) {
 // JSONFormatter formats log entries into a structured, machine-readable JSON string.
 // This is the recommended formatter for production environments that forward logs
 // to a log aggregation service (e.g., ELK, Datadog, Splunk).
-type JSONFormatter struct{}
+type JSONFormatter struct {
+	// Schema selects the registered schema version used for key names and the time
+	// layout. Defaults to DefaultSchemaVersion. See RegisterSchema.
+	Schema string
+	// SchemaVersion writes the schema version first in every entry, under
+	// SchemaVersionKey, for parsers that read several schemas. Off by default, which keeps
+	// the entries of existing setups unchanged.
+	SchemaVersion bool
+	// Severity selects whether the level name, under the level key, the severity number,
+	// under the severity key, or both are written. Defaults to the level name.
+	Severity SeverityMode
+	// SeverityScale selects the numbering of the severity number. Defaults to OpenTelemetry.
+	SeverityScale SeverityScale
+}
 
 // Format converts a log event into a byte slice representing a JSON object,
-// followed by a newline. It includes all metadata from the event.
+// followed by a newline. It includes all metadata from the event and, if SchemaVersion is
+// set, the schema version under SchemaVersionKey. The error of an entry is written as an
+// object with its message, type, chain of wrapped errors and stack.
 func (f *JSONFormatter) Format(ev HookEvent) ([]byte, error) {
-	// jsonEntry defines the structure of the JSON output.
-	// Using `omitempty` ensures that empty fields are not included in the output,
-	// keeping the log entries clean.
-	type jsonEntry struct {
-		Time    string `json:"time"`
-		Level   string `json:"level"`
-		Module  string `json:"module,omitempty"`
-		TraceID string `json:"trace_id,omitempty"`
-		FlowID  string `json:"flow_id,omitempty"`
-		Attrs   Fields `json:"attrs,omitempty"`
-		Message string `json:"message"`
-		Fields  Fields `json:"fields,omitempty"`
+	version := f.Schema
+	if version == "" {
+		version = DefaultSchemaVersion
 	}
-
-	// Populate the entry from the event.
-	entry := jsonEntry{
-		Time:    ev.Time.Format(time.RFC3339),
-		Level:   ev.Level.String(),
-		Module:  ev.Module,
-		Message: ev.Message,
-		TraceID: ev.TraceID,
-		FlowID:  ev.FlowID,
-		Attrs:   ev.Attrs,
-		Fields:  ev.Fields,
+	schema, ok := LookupSchema(version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSchema, version)
 	}
+	keys := schema.Keys
 
-	// Marshal the entry to JSON.
-	// Using a buffer from a sync.Pool could be a future optimization.
+	// Keys are written in a fixed order. Empty optional values are omitted,
+	// keeping the log entries clean.
 	buf := &bytes.Buffer{}
 	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false) // Disable HTML escaping for characters like '<_>_<_>_, '>', '&'_._
+	enc.SetEscapeHTML(false) // Disable HTML escaping for characters like '<', '>', '&'.
+	first := true
+	put := func(key string, v interface{}) error {
+		if key == "-" {
+			return nil
+		}
+		if first {
+			buf.WriteByte('{')
+			first = false
+		} else {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(key); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1) // The encoder terminates each value with a newline.
+		buf.WriteByte(':')
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1)
+		return nil
+	}
 
-	if err := enc.Encode(entry); err != nil {
+	var err error
+	if f.SchemaVersion {
+		err = put(SchemaVersionKey, schema.Version)
+	}
+	if err == nil {
+		err = put(keys.Time, ev.Time.Format(schema.TimeFormat))
+	}
+	if err == nil && f.Severity.writesText() {
+		err = put(keys.Level, ev.Level.String())
+	}
+	if err == nil && f.Severity.writesNumber() {
+		err = put(keys.Severity, f.SeverityScale.Severity(ev.Level))
+	}
+	if err == nil && ev.Seq != 0 {
+		err = put(keys.Seq, ev.Seq)
+	}
+	if err == nil && ev.Module != "" {
+		err = put(keys.Module, ev.Module)
+	}
+	if err == nil && ev.TraceID != "" {
+		err = put(keys.TraceID, ev.TraceID)
+	}
+	if err == nil && ev.FlowID != "" {
+		err = put(keys.FlowID, ev.FlowID)
+	}
+	if err == nil && !ev.Caller.IsZero() {
+		err = put(keys.Caller, ev.Caller.String())
+		if err == nil {
+			err = put(keys.Function, ev.Caller.Function)
+		}
+	}
+	if err == nil && len(ev.Attrs) > 0 {
+		err = put(keys.Attrs, ev.Attrs)
+	}
+	if err == nil {
+		err = put(keys.Message, ev.Message)
+	}
+	if err == nil && len(ev.Fields) > 0 {
+		err = put(keys.Fields, ev.Fields)
+	}
+	if e := newErrorJSON(ev); err == nil && e != nil {
+		err = put(keys.Error, e)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("unologger: failed to encode log entry to JSON: %w", err)
 	}
-
-	// The encoder already adds a newline, so we don't need to add another.
+	buf.WriteString("}\n")
 	return buf.Bytes(), nil
 }
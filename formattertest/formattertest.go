@@ -0,0 +1,304 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package formattertest is a conformance suite for unologger.Formatter implementations.
+// Run formats a set of canonical events covering escaping, field ordering and edge cases,
+// checks the properties every formatter must have, and compares the output with golden
+// files:
+//
+//	func TestMyFormatter(t *testing.T) {
+//		formattertest.Run(t, &MyFormatter{})
+//	}
+//
+// The golden files live in testdata/formattertest/<test name>/<case>.golden, relative to
+// the package under test. Create or refresh them with:
+//
+//	go test -run TestMyFormatter -formattertest.update
+//
+// and review the result before committing it.
+package formattertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phuonguno98/unologger"
+)
+
+// update rewrites the golden files instead of comparing with them.
+var update = flag.Bool("formattertest.update", false, "rewrite the formattertest golden files")
+
+// Case is a canonical event of the suite.
+type Case struct {
+	Name  string
+	Event unologger.HookEvent
+}
+
+// Cases returns the canonical events formatted by Run. Every call returns fresh events,
+// so a formatter that mutates them cannot affect later cases.
+func Cases() []Case {
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 678000000, time.UTC)
+	ev := func(msg string) unologger.HookEvent {
+		return unologger.HookEvent{Time: ts, Level: unologger.INFO, Module: "conformance", Message: msg}
+	}
+	with := func(e unologger.HookEvent, f func(*unologger.HookEvent)) unologger.HookEvent {
+		f(&e)
+		return e
+	}
+	return []Case{
+		{"minimal", ev("hello")},
+		{"zero_event", unologger.HookEvent{}},
+		{"full", with(ev("payment accepted"), func(e *unologger.HookEvent) {
+			e.TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+			e.FlowID = "flow-1"
+			e.Seq = 42
+			e.JSONMode = true
+			e.Attrs = unologger.Fields{unologger.FieldUserID: "u1", unologger.FieldTenant: "acme"}
+			e.Fields = unologger.Fields{unologger.FieldDurationMS: 12.5, "ok": true, "count": 3}
+		})},
+		{"level_debug", with(ev("debug"), func(e *unologger.HookEvent) { e.Level = unologger.DEBUG })},
+		{"level_warn", with(ev("warn"), func(e *unologger.HookEvent) { e.Level = unologger.WARN })},
+		{"level_error", with(ev("error"), func(e *unologger.HookEvent) { e.Level = unologger.ERROR })},
+		{"level_fatal", with(ev("fatal"), func(e *unologger.HookEvent) { e.Level = unologger.FATAL })},
+		{"level_unknown", with(ev("unknown"), func(e *unologger.HookEvent) { e.Level = unologger.Level(42) })},
+		{"time_zone", with(ev("zoned"), func(e *unologger.HookEvent) {
+			e.Time = ts.In(time.FixedZone("ICT", 7*3600))
+		})},
+		{"escape_quotes", ev(`say "hi" to C:\path\file`)},
+		{"escape_newlines", ev("line1\nline2\r\n\tindented")},
+		{"escape_control", ev("nul\x00 bell\x07 esc\x1b[31mred\x1b[0m del\x7f")},
+		{"escape_html", ev("<b>a & b</b>")},
+		{"unicode", ev("Xin chào 🌏 — ünïcödé \u2028 separator")},
+		{"invalid_utf8", ev("bad \xff\xfe bytes")},
+		{"percent", ev("100% %s %d")},
+		{"empty_message", ev("")},
+		{"long_message", ev(strings.Repeat("0123456789abcdef", 512))},
+		{"empty_maps", with(ev("empty maps"), func(e *unologger.HookEvent) {
+			e.Attrs = unologger.Fields{}
+			e.Fields = unologger.Fields{}
+		})},
+		{"field_ordering", with(ev("ordering"), func(e *unologger.HookEvent) {
+			e.Fields = unologger.Fields{"z": 1, "a": 2, "m": 3, "B": 4, "_x": 5, "a1": 6, "a10": 7, "a2": 8}
+		})},
+		{"field_values", with(ev("values"), func(e *unologger.HookEvent) {
+			e.Fields = unologger.Fields{
+				"nil":    nil,
+				"str":    "v\"al\nue",
+				"int":    -7,
+				"uint":   uint64(18446744073709551615),
+				"float":  0.1,
+				"bool":   false,
+				"bytes":  []byte("raw"),
+				"time":   ts,
+				"dur":    1500 * time.Millisecond,
+				"list":   []interface{}{1, "two", nil},
+				"nested": map[string]interface{}{"b": 2, "a": map[string]interface{}{"c": "deep"}},
+				"error":  errors.New("boom"),
+			}
+		})},
+		{"field_keys", with(ev("keys"), func(e *unologger.HookEvent) {
+			e.Fields = unologger.Fields{"key with spaces": 1, `quote"key`: 2, "eq=key": 3, "ünï": 4, "new\nline": 5}
+		})},
+	}
+}
+
+// Run formats every canonical event with f and reports, per case:
+//   - formatting errors and panics;
+//   - empty output, or output not ending with a newline;
+//   - output that changes between calls or under concurrent use;
+//   - events whose fields were modified by the formatter;
+//   - for JSON output (starting with '{'): invalid JSON, raw newlines inside the entry, a
+//     message that does not round-trip, or missing field keys;
+//   - differences from the golden files, unless -formattertest.update is set.
+func Run(t *testing.T, f unologger.Formatter) {
+	t.Helper()
+	cases := Cases()
+	outputs := make([][]byte, len(cases))
+	for i, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			outputs[i] = checkCase(t, f, c)
+		})
+	}
+	t.Run("concurrent", func(t *testing.T) {
+		checkConcurrent(t, f, outputs)
+	})
+}
+
+// checkCase runs the checks of one case and returns its output.
+func checkCase(t *testing.T, f unologger.Formatter, c Case) []byte {
+	t.Helper()
+	attrs, fields := maps.Clone(c.Event.Attrs), maps.Clone(c.Event.Fields)
+	out, err := format(f, c.Event)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if !reflect.DeepEqual(attrs, c.Event.Attrs) || !reflect.DeepEqual(fields, c.Event.Fields) {
+		t.Errorf("Format modified the fields of the event")
+	}
+	if len(out) == 0 || out[len(out)-1] != '\n' {
+		t.Fatalf("output must be non-empty and end with a newline, got %q", out)
+	}
+	for i := 0; i < 2; i++ {
+		again, err := format(f, c.Event)
+		if err != nil || !bytes.Equal(again, out) {
+			t.Fatalf("output is not deterministic:\n%q\n%q (err %v)", out, again, err)
+		}
+	}
+	if out[0] == '{' {
+		checkJSON(t, c.Event, out)
+	}
+	checkGolden(t, out)
+	return out
+}
+
+// format calls f.Format, turning a panic into an error.
+func format(f unologger.Formatter, ev unologger.HookEvent) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("panic: " + strings.TrimSpace(strings.ReplaceAll(stringOf(r), "\n", " ")))
+		}
+	}()
+	return f.Format(ev)
+}
+
+// stringOf describes a recovered panic value.
+func stringOf(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// checkJSON checks an entry formatted as a JSON object.
+func checkJSON(t *testing.T, ev unologger.HookEvent, out []byte) {
+	t.Helper()
+	body := out[:len(out)-1]
+	if bytes.IndexByte(body, '\n') >= 0 {
+		t.Errorf("JSON entry spans several lines: %q", out)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		t.Fatalf("invalid JSON: %v: %q", err, out)
+	}
+	if utf8.ValidString(ev.Message) && !hasString(obj, ev.Message) {
+		t.Errorf("message %q does not round-trip through the JSON output", ev.Message)
+	}
+	for k := range ev.Fields {
+		if utf8.ValidString(k) && !hasKey(obj, k) {
+			t.Errorf("field key %q missing from the JSON output", k)
+		}
+	}
+}
+
+// hasString reports whether v holds the string s, at any depth.
+func hasString(v interface{}, s string) bool {
+	switch v := v.(type) {
+	case string:
+		return v == s
+	case map[string]interface{}:
+		for _, e := range v {
+			if hasString(e, s) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if hasString(e, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasKey reports whether v holds an object with the key k, at any depth.
+func hasKey(v interface{}, k string) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if _, ok := v[k]; ok {
+			return true
+		}
+		for _, e := range v {
+			if hasKey(e, k) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if hasKey(e, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkGolden compares out with the golden file of the running test, or rewrites it.
+func checkGolden(t *testing.T, out []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "formattertest", filepath.FromSlash(t.Name())+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing golden file %s; create it with -formattertest.update", path)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("output differs from %s:\ngot  %q\nwant %q", path, out, want)
+	}
+}
+
+// checkConcurrent formats every case from several goroutines and compares the results with
+// the sequential outputs. Run with -race to detect data races in the formatter.
+func checkConcurrent(t *testing.T, f unologger.Formatter, want [][]byte) {
+	t.Helper()
+	cases := Cases()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, c := range cases {
+				if want[i] == nil {
+					continue // The case already failed.
+				}
+				got, err := format(f, c.Event)
+				if err != nil || !bytes.Equal(got, want[i]) {
+					mu.Lock()
+					failed = append(failed, c.Name)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		t.Errorf("concurrent use changed the output of %v", failed)
+	}
+}
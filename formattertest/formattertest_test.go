@@ -0,0 +1,30 @@
+package formattertest_test
+
+import (
+	"testing"
+
+	"github.com/phuonguno98/unologger"
+	"github.com/phuonguno98/unologger/formattertest"
+)
+
+func TestTextFormatter(t *testing.T) {
+	formattertest.Run(t, &unologger.TextFormatter{})
+}
+
+func TestJSONFormatter(t *testing.T) {
+	formattertest.Run(t, &unologger.JSONFormatter{})
+}
+
+func TestTextFormatterCustomLayout(t *testing.T) {
+	formattertest.Run(t, &unologger.TextFormatter{
+		TimeLayout:    unologger.TimeLayoutRFC3339Milli,
+		KeySeparator:  ":",
+		PairSeparator: " | ",
+		HideTrace:     true,
+		FieldOrder:    []string{"user"},
+	})
+}
+
+func TestTextFormatterColor(t *testing.T) {
+	formattertest.Run(t, &unologger.TextFormatter{Color: unologger.ColorAlways})
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file defines context-free, package-level logging functions that write through the
+// global logger. They are intended for small tools and for migrating code from the standard
+// library's log package before context.Context plumbing is in place.
+
+package unologger
+
+import "context"
+
+// backgroundLogger returns a LoggerWithCtx bound to the global logger and
+// context.Background(). Module defaults are applied exactly as GetLogger does.
+func backgroundLogger() LoggerWithCtx {
+	return GetLogger(context.Background())
+}
+
+// Debugf logs a formatted message at DEBUG level using the global logger.
+func Debugf(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.log(lw.ctx, DEBUG, format, args...)
+}
+
+// Infof logs a formatted message at INFO level using the global logger.
+func Infof(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.log(lw.ctx, INFO, format, args...)
+}
+
+// Warnf logs a formatted message at WARN level using the global logger.
+func Warnf(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.log(lw.ctx, WARN, format, args...)
+}
+
+// Errorf logs a formatted message at ERROR level using the global logger.
+func Errorf(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.log(lw.ctx, ERROR, format, args...)
+}
+
+// Fatalf logs a formatted message at FATAL level using the global logger, attempts
+// to flush all buffered logs, and then terminates the application with os.Exit(1).
+func Fatalf(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.fatal(lw.ctx, nil, format, args...)
+}
+
+// DebugW logs a literal message with structured fields at DEBUG level using the global logger.
+// The message is not treated as a format string.
+func DebugW(msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.logFields(lw.ctx, DEBUG, fields, msg, literalArgs...)
+}
+
+// InfoW logs a literal message with structured fields at INFO level using the global logger.
+func InfoW(msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.logFields(lw.ctx, INFO, fields, msg, literalArgs...)
+}
+
+// WarnW logs a literal message with structured fields at WARN level using the global logger.
+func WarnW(msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.logFields(lw.ctx, WARN, fields, msg, literalArgs...)
+}
+
+// ErrorW logs a literal message with structured fields at ERROR level using the global logger.
+func ErrorW(msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.logFields(lw.ctx, ERROR, fields, msg, literalArgs...)
+}
+
+// ErrorErr logs err with a literal message and structured fields at ERROR level using the
+// global logger. See Logger.ErrorErr.
+func ErrorErr(err error, msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.logErr(lw.ctx, ERROR, err, fields, msg)
+}
+
+// FatalW logs a literal message with structured fields at FATAL level using the global logger,
+// attempts to flush all buffered logs, and then terminates the application with os.Exit(1).
+func FatalW(msg string, fields Fields) {
+	lw := backgroundLogger()
+	lw.l.fatal(lw.ctx, fields, msg, literalArgs...)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file provides context propagation helpers for concurrent code. They carry the logging
+// metadata of a parent context (logger, module, trace and flow IDs, attributes) into child
+// goroutines and log panics with that metadata, so background work stays correlated with the
+// request that started it.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PanicError is the error returned by a function adapted with GoErr when it panics.
+type PanicError struct {
+	Value interface{} // The value passed to panic.
+	Stack []byte      // The stack trace of the panicking goroutine.
+}
+
+// Error returns the panic value formatted as an error message.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Detach returns a new context that carries the logging metadata of ctx, including the
+// OpenTelemetry span context, but none of its other values and none of its cancellation
+// or deadline. It suits background work that must outlive the request that started it.
+func Detach(ctx context.Context) context.Context {
+	out := context.Background()
+	for _, key := range []ctxKey{ctxLoggerKey, ctxModuleKey, ctxTraceIDKey, ctxFlowIDKey, ctxFieldsKey, ctxGroupKey} {
+		if v := ctx.Value(key); v != nil {
+			out = context.WithValue(out, key, v)
+		}
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		out = trace.ContextWithSpanContext(out, sc)
+	}
+	return out
+}
+
+// Go runs fn in a new goroutine with ctx. If fn panics, the panic is logged at ERROR level
+// with the stack trace and the logging metadata of ctx (module, trace and flow IDs), and
+// the goroutine ends without crashing the process. Use Detach first if the goroutine must
+// not be cancelled together with ctx.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logPanic(ctx, r, debug.Stack())
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// GoErr adapts fn for errgroup.Group.Go and similar APIs that run a func() error:
+//
+//	g, gctx := errgroup.WithContext(ctx)
+//	g.Go(unologger.GoErr(gctx, fetch))
+//
+// The returned function calls fn with ctx. If fn panics, the panic is logged like in Go
+// and returned as a *PanicError, so the group reports it instead of crashing the process.
+func GoErr(ctx context.Context, fn func(ctx context.Context) error) func() error {
+	return func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				stack := debug.Stack()
+				logPanic(ctx, r, stack)
+				err = &PanicError{Value: r, Stack: stack}
+			}
+		}()
+		return fn(ctx)
+	}
+}
+
+// logPanic logs a recovered panic with the logger and metadata of ctx.
+func logPanic(ctx context.Context, r interface{}, stack []byte) {
+	lw := GetLogger(ctx)
+	lw.l.panics.Add(1)
+	lw.l.logFields(lw.ctx, ERROR, Fields{FieldStack: string(stack)}, "panic in goroutine: %v", r)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements field groups, after the groups of log/slog. Once a context is given a
+// group, the attributes added to it and the call-site fields of the entries logged with it
+// are nested under the group name, so that the layers of an application can use the same
+// keys without collisions, e.g. {"db":{"duration_ms":3},"http":{"duration_ms":41}}.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// WithGroup returns a context in which the attributes added by WithAttrs, and the call-site
+// fields of the entries logged with it, are nested under name, within the groups ctx already
+// has:
+//
+//	ctx = unologger.WithGroup(ctx, "db")
+//	unologger.GetLogger(ctx).InfoW("query done", unologger.Fields{"duration_ms": 3})
+//	// "fields":{"db":{"duration_ms":3}}
+//
+// The attributes added to ctx before the call stay where they are. An empty name returns
+// ctx unchanged. The group is a Fields value, so hooks see ev.Fields["db"].(Fields).
+func WithGroup(ctx context.Context, name string) context.Context {
+	ctx = orBackground(ctx)
+	if name == "" {
+		return ctx
+	}
+	group := fieldGroup(ctx)
+	return context.WithValue(ctx, ctxGroupKey, append(group[:len(group):len(group)], name))
+}
+
+// WithGroup returns a LoggerWithCtx whose context has the group name, like WithGroup. The
+// fields bound to lw are moved to the context attributes first, so that they are not nested.
+func (lw LoggerWithCtx) WithGroup(name string) LoggerWithCtx {
+	if name == "" {
+		return lw
+	}
+	if len(lw.fields) > 0 {
+		lw.ctx = WithAttrs(lw.ctx, lw.fields)
+		lw.fields = nil
+	}
+	lw.ctx = WithGroup(lw.ctx, name)
+	return lw
+}
+
+// fieldGroup returns the path of the groups of ctx, outermost first.
+func fieldGroup(ctx context.Context) []string {
+	group, _ := ctx.Value(ctxGroupKey).([]string)
+	return group
+}
+
+// mergeAtGroup copies src into dst under the groups of path. The groups along the path are
+// copied rather than modified, since they may be shared with a context, and a value of dst
+// that is in the way of a group is replaced by it. Nothing is added if src is empty.
+func mergeAtGroup(dst Fields, path []string, src Fields) {
+	if len(src) == 0 {
+		return
+	}
+	if len(path) == 0 {
+		maps.Copy(dst, src)
+		return
+	}
+	cur, _ := dst[path[0]].(Fields)
+	m := make(Fields, len(cur)+len(src))
+	maps.Copy(m, cur)
+	mergeAtGroup(m, path[1:], src)
+	dst[path[0]] = m
+}
+
+// expandField calls fn with the field key and its value, or, if the value is a group, with
+// every field of the group in lexical order, named after the group with a dot, e.g.
+// "db.duration_ms".
+func expandField(key string, value interface{}, fn func(key, value string)) {
+	g, ok := value.(Fields)
+	if !ok {
+		fn(key, fmt.Sprint(value))
+		return
+	}
+	for _, k := range slices.Sorted(maps.Keys(g)) {
+		expandField(key+"."+k, g[k], fn)
+	}
+}
+
+// ownGroups calls fn with fields and with each of its groups, nested ones included. The
+// groups are replaced by copies first, since they may be shared with a context, so that fn
+// may modify them in place.
+func ownGroups(fields Fields, fn func(Fields)) {
+	fn(fields)
+	for k, v := range fields {
+		if g, ok := v.(Fields); ok {
+			g = maps.Clone(g)
+			fields[k] = g
+			ownGroups(g, fn)
+		}
+	}
+}
+
+// lookupField returns the value of the field key. A key that is not a field of fields may
+// name a field of a group with a dotted path, e.g. "db.duration_ms".
+func lookupField(fields Fields, key string) (interface{}, bool) {
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	name, rest, ok := strings.Cut(key, ".")
+	if !ok {
+		return nil, false
+	}
+	g, ok := fields[name].(Fields)
+	if !ok {
+		return nil, false
+	}
+	return lookupField(g, rest)
+}
+
+// setField sets the field key, which may be a dotted path as for lookupField, to v, or
+// deletes it if del is true. The groups along the path are copied, since they may be shared
+// with a context, and the missing ones are created when setting.
+func setField(fields Fields, key string, v interface{}, del bool) {
+	name, rest, ok := strings.Cut(key, ".")
+	if _, exists := fields[key]; exists || !ok {
+		if del {
+			delete(fields, key)
+		} else {
+			fields[key] = v
+		}
+		return
+	}
+	cur, exists := fields[name]
+	g, isGroup := cur.(Fields)
+	if !isGroup && (del || exists) {
+		return
+	}
+	g = maps.Clone(g)
+	if g == nil {
+		g = Fields{}
+	}
+	setField(g, rest, v, del)
+	fields[name] = g
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the health check used by readiness and liveness probes. The logger
+// is unhealthy when its queue is saturated or when a sink keeps failing, so that an
+// orchestrator can take a pod out of rotation or restart it when logging is irrecoverably
+// broken, instead of letting it run without logs.
+
+package unologger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Defaults of HealthConfig.
+const (
+	defaultHealthQueueSaturation = 0.9
+	defaultHealthSinkFailures    = 10
+	defaultHealthFailureWindow   = time.Minute
+)
+
+var (
+	// ErrQueueSaturated is wrapped by the error of Healthy when the queue is saturated.
+	ErrQueueSaturated = errors.New("unologger: queue saturated")
+	// ErrSinkFailing is wrapped by the error of Healthy for every failing sink.
+	ErrSinkFailing = errors.New("unologger: sink failing")
+)
+
+// HealthConfig sets the thresholds of Logger.Healthy.
+type HealthConfig struct {
+	// QueueSaturation is the fill ratio of the queue, between 0 and 1, at which the logger
+	// is unhealthy. Defaults to 0.9.
+	QueueSaturation float64
+	// SinkFailures is the number of consecutive failed write attempts after which a sink
+	// is considered quarantined and the logger unhealthy. Defaults to 10.
+	SinkFailures int64
+	// FailureWindow is the time a sink may fail without a successful write before the
+	// logger is unhealthy, however few attempts failed. Defaults to 1m. A sink that never
+	// succeeded is only judged by SinkFailures.
+	FailureWindow time.Duration
+	// IgnoreSinks lists sinks, by WriterStats name, whose failures do not affect health,
+	// e.g. best-effort network sinks.
+	IgnoreSinks []string
+}
+
+// normalizeHealth applies defaults to a HealthConfig.
+func normalizeHealth(hc HealthConfig) HealthConfig {
+	if hc.QueueSaturation <= 0 || hc.QueueSaturation > 1 {
+		hc.QueueSaturation = defaultHealthQueueSaturation
+	}
+	if hc.SinkFailures <= 0 {
+		hc.SinkFailures = defaultHealthSinkFailures
+	}
+	if hc.FailureWindow <= 0 {
+		hc.FailureWindow = defaultHealthFailureWindow
+	}
+	return hc
+}
+
+// SetHealthConfig replaces the thresholds of Healthy. Zero fields take their defaults.
+func (l *Logger) SetHealthConfig(hc HealthConfig) {
+	hc = normalizeHealth(hc)
+	l.healthCfg.Store(&hc)
+}
+
+// Healthy returns nil if the logger can deliver entries, and otherwise an error joining
+// the reasons it cannot: the logger is closed (ErrLoggerClosed), its queue is filled to
+// the QueueSaturation ratio (ErrQueueSaturated), or a sink failed SinkFailures times in a
+// row or has not written successfully for FailureWindow while failing (ErrSinkFailing,
+// once per sink). The sink state comes from WriterStats, so a sink that recovers makes the
+// logger healthy again with its next successful write. A logger that forwards its entries,
+// such as a Tee, is healthy only if its delegates are too.
+func (l *Logger) Healthy() error {
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+	hc := defaultHealthConfig
+	if p := l.healthCfg.Load(); p != nil {
+		hc = *p
+	}
+
+	var errs []error
+	if c := cap(l.queue()) + cap(l.hi); c > 0 {
+		if n := l.queueLen(); float64(n) >= hc.QueueSaturation*float64(c) {
+			errs = append(errs, fmt.Errorf("%w: %d of %d entries queued", ErrQueueSaturated, n, c))
+		}
+	}
+	stats := l.WriterStats()
+	now := time.Now()
+	for _, name := range slices.Sorted(maps.Keys(stats)) {
+		ws := stats[name]
+		if ws.ConsecutiveFailures == 0 || slices.Contains(hc.IgnoreSinks, name) {
+			continue
+		}
+		switch {
+		case ws.ConsecutiveFailures >= hc.SinkFailures:
+			errs = append(errs, fmt.Errorf("%w: %s: %d consecutive failures: %v",
+				ErrSinkFailing, name, ws.ConsecutiveFailures, ws.LastError))
+		case !ws.LastSuccess.IsZero() && now.Sub(ws.LastSuccess) >= hc.FailureWindow:
+			errs = append(errs, fmt.Errorf("%w: %s: no successful write for %v: %v",
+				ErrSinkFailing, name, now.Sub(ws.LastSuccess).Round(time.Second), ws.LastError))
+		}
+	}
+	for _, d := range l.delegateTargets() {
+		errs = append(errs, d.Healthy())
+	}
+	return errors.Join(errs...)
+}
+
+// defaultHealthConfig is used by Healthy when no HealthConfig was set.
+var defaultHealthConfig = normalizeHealth(HealthConfig{})
+
+// HealthHandler returns an HTTP handler for readiness and liveness probes. It responds
+// 200 "ok" if Healthy returns nil, and 503 with the error otherwise:
+//
+//	mux.Handle("/healthz", l.HealthHandler())
+func (l *Logger) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := l.Healthy(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, err.Error()+"\n")
+			return
+		}
+		_, _ = io.WriteString(w, "ok\n")
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements filtered hooks. A hook registered with a HookFilter only receives
+// the entries of the levels and modules it asks for, and entries that no hook wants are
+// never queued for the hook runner, so an alerting hook for payment errors costs nothing
+// for the DEBUG traffic of every other module.
+
+package unologger
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HookFilter selects the entries passed to a hook registered with AddHook.
+type HookFilter struct {
+	// Name identifies the hook for RemoveHook. AddHook replaces the hook of the same name.
+	Name string
+	// MinLevel is the lowest level passed to the hook. DEBUG, the zero value, passes every
+	// level, TRACE included, so that a zero filter passes every entry. Custom levels are
+	// compared by their base level.
+	MinLevel Level
+	// Levels, if set, restricts the hook to the entries of these levels, e.g. a custom AUDIT
+	// level registered with RegisterLevel.
+	Levels []Level
+	// Modules, if set, restricts the hook to the entries of these modules.
+	Modules []string
+	// Match, if set, is called with every entry that passes MinLevel and Modules, and the
+	// hook only runs if it returns true. It runs where the hook runs, on a hook worker when
+	// hooks are asynchronous, so it must not log synchronously to the same logger.
+	Match func(HookEvent) bool
+}
+
+// FilteredHook is a hook together with the filter selecting its entries.
+type FilteredHook struct {
+	Hook   HookFunc2
+	Filter HookFilter
+}
+
+// accepts reports whether an entry of the given level and module passes the level and
+// module conditions of the filter. Match is left to hookMatches.
+func (f *HookFilter) accepts(level Level, module string) bool {
+	if f.MinLevel != DEBUG && level.Base() < f.MinLevel {
+		return false
+	}
+	return (len(f.Levels) == 0 || slices.Contains(f.Levels, level)) &&
+		(len(f.Modules) == 0 || slices.Contains(f.Modules, module))
+}
+
+// hookMatches reports whether ev passes f. A panic of Match is recorded like a hook panic,
+// and the hook is skipped.
+func (l *Logger) hookMatches(f *HookFilter, ev HookEvent) (ok bool) {
+	if !f.accepts(ev.Level, ev.Module) {
+		return false
+	}
+	if f.Match == nil {
+		return true
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			l.recordHookError(ev, fmt.Errorf("%w: filter %q: %v", ErrHookPanic, f.Name, r))
+			ok = false
+		}
+	}()
+	return f.Match(ev)
+}
+
+// AddHook registers a context-aware hook that only runs for the entries selected by
+// filter, after the hooks of SetHooks and SetHooks2:
+//
+//	l.AddHook(alert, unologger.HookFilter{
+//		Name: "pager", MinLevel: unologger.ERROR, Modules: []string{"payment"},
+//	})
+//
+// A named hook replaces the hook registered under the same name, and can be removed with
+// RemoveHook, so that independent libraries can manage their own hooks on a shared
+// logger. A filter with only a Name, as in HookFilter{Name: "audit"}, passes every entry.
+// Use AdaptHook for a legacy HookFunc. If asynchronous hooks are enabled, the hook runner
+// is started if it is not already running.
+func (l *Logger) AddHook(hook HookFunc2, filter HookFilter) {
+	l.Configure("").AddHook(hook, filter)
+}
+
+// AddHook is Logger.AddHook on behalf of the actor of ed.
+func (ed ConfigEditor) AddHook(hook HookFunc2, filter HookFilter) {
+	l := ed.l
+	if hook == nil {
+		return
+	}
+	filter.Modules = slices.Clone(filter.Modules)
+	c := ed.changing("filtered_hooks")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	hooks := slices.Clone(l.dynConfig.FilteredHooks)
+	if filter.Name != "" {
+		hooks = slices.DeleteFunc(hooks, func(h FilteredHook) bool { return h.Filter.Name == filter.Name })
+	}
+	hooks = append(hooks, FilteredHook{Hook: hook, Filter: filter})
+	c.old, c.new = describeFilteredHooks(l.dynConfig.FilteredHooks), describeFilteredHooks(hooks)
+	l.storeFilteredHooksLocked(hooks)
+	l.startHookRunner()
+}
+
+// RemoveHook removes the hook registered with AddHook under name, and reports whether
+// there was one. Hooks registered under other names are left in place.
+func (l *Logger) RemoveHook(name string) bool {
+	return l.Configure("").RemoveHook(name)
+}
+
+// RemoveHook is Logger.RemoveHook on behalf of the actor of ed.
+func (ed ConfigEditor) RemoveHook(name string) bool {
+	l := ed.l
+	c := ed.changing("filtered_hooks")
+	defer c.commit()
+	l.dynConfig.mu.Lock()
+	defer l.dynConfig.mu.Unlock()
+	hooks := slices.DeleteFunc(slices.Clone(l.dynConfig.FilteredHooks),
+		func(h FilteredHook) bool { return h.Filter.Name == name })
+	if name == "" || len(hooks) == len(l.dynConfig.FilteredHooks) {
+		c.cancel()
+		return false
+	}
+	c.old, c.new = describeFilteredHooks(l.dynConfig.FilteredHooks), describeFilteredHooks(hooks)
+	l.storeFilteredHooksLocked(hooks)
+	return true
+}
+
+// ListHooks returns the names of the hooks registered with AddHook, in the order they
+// run. Unnamed hooks and the hooks of SetHooks and SetHooks2 are not listed.
+func (l *Logger) ListHooks() []string {
+	l.hooksMu.RLock()
+	defer l.hooksMu.RUnlock()
+	var names []string
+	for _, h := range l.filteredHooks {
+		if h.Filter.Name != "" {
+			names = append(names, h.Filter.Name)
+		}
+	}
+	return names
+}
+
+// storeFilteredHooksLocked publishes a new list of filtered hooks. The caller must hold
+// dynConfig.mu. The list is never modified afterwards, so readers may keep it.
+func (l *Logger) storeFilteredHooksLocked(hooks []FilteredHook) {
+	l.dynConfig.FilteredHooks = hooks
+	l.hooksMu.Lock()
+	l.filteredHooks = hooks
+	l.hooksMu.Unlock()
+}
+
+// wantsHookLocked reports whether any hook may run for an entry of the given level and
+// module. The caller must hold hooksMu.
+func (l *Logger) wantsHookLocked(level Level, module string) bool {
+	if len(l.hooks) > 0 || len(l.hooks2) > 0 {
+		return true
+	}
+	for i := range l.filteredHooks {
+		if l.filteredHooks[i].Filter.accepts(level, module) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeFilteredHooks summarizes filtered hooks by name and filter.
+func describeFilteredHooks(hooks []FilteredHook) string {
+	parts := make([]string, 0, len(hooks))
+	for _, h := range hooks {
+		s := h.Filter.Name
+		if s == "" {
+			s = "unnamed"
+		}
+		s += ">=" + h.Filter.MinLevel.String()
+		for _, lvl := range h.Filter.Levels {
+			s += "=" + lvl.String()
+		}
+		if len(h.Filter.Modules) > 0 {
+			s += ":" + strings.Join(h.Filter.Modules, ",")
+		}
+		parts = append(parts, s)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
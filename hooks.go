@@ -11,127 +11,371 @@ package unologger
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+// hookRunnerState is the lifecycle state of the asynchronous hook runner.
+//
+// Transitions are serialized by Logger.hookRunMu and follow the cycle
+// stopped -> starting -> running -> stopping -> stopped. The queue channel is only
+// published (under hooksMu) while the runner is running, so enqueueHook never sends on
+// a channel that is being closed.
+type hookRunnerState int64
+
+const (
+	hookStopped hookRunnerState = iota
+	hookStarting
+	hookRunning
+	hookStopping
+)
+
+// String returns a human-readable name for the runner state.
+func (s hookRunnerState) String() string {
+	switch s {
+	case hookStarting:
+		return "starting"
+	case hookRunning:
+		return "running"
+	case hookStopping:
+		return "stopping"
+	default:
+		return "stopped"
+	}
+}
+
 // startHookRunner starts the worker pool for processing hooks asynchronously.
 // This method is called internally when the logger is configured with async hooks
-// and there is at least one hook registered.
+// and there is at least one hook registered. It is a no-op if the runner is not
+// stopped or the logger is closed.
 func (l *Logger) startHookRunner() {
-	l.hooksMu.RLock()
-	hasHooks := len(l.hooks) > 0
-	l.hooksMu.RUnlock()
-	if !l.hookAsync || !hasHooks {
+	l.hookRunMu.Lock()
+	defer l.hookRunMu.Unlock()
+	l.startHookRunnerLocked()
+}
+
+// startHookRunnerLocked implements startHookRunner. The caller must hold hookRunMu.
+func (l *Logger) startHookRunnerLocked() {
+	l.hooksMu.Lock()
+	if hookRunnerState(l.hookState.Load()) != hookStopped || !l.hookAsync || !l.hasHooksLocked() || l.closed.Load() {
+		l.hooksMu.Unlock()
 		return
 	}
+	l.hookState.Store(int64(hookStarting))
+	ch := make(chan hookTask, l.hookQueue)
+	workers := l.hookWorkers
+	l.hooksMu.Unlock()
 
-	l.hookQueueCh = make(chan hookTask, l.hookQueue)
-	for i := 0; i < l.hookWorkers; i++ {
+	for i := 0; i < workers; i++ {
 		l.hookWg.Add(1)
 		go func() {
 			defer l.hookWg.Done()
-			for task := range l.hookQueueCh {
-				l.runHooks(task.event)
+			for task := range ch {
+				if task.failure != nil {
+					l.runFailureHook(task.ctx, task.failure)
+					continue
+				}
+				l.runHooks(task.ctx, task.event)
 			}
 		}()
 	}
+
+	// Publish the channel only once the workers exist.
+	l.hooksMu.Lock()
+	l.hookQueueCh = ch
+	l.hookState.Store(int64(hookRunning))
+	l.hooksMu.Unlock()
+}
+
+// stopHookRunnerLocked unpublishes the queue, closes it, and waits for the workers to
+// drain every pending task. The caller must hold hookRunMu.
+func (l *Logger) stopHookRunnerLocked() {
+	l.hooksMu.Lock()
+	if hookRunnerState(l.hookState.Load()) != hookRunning {
+		l.hooksMu.Unlock()
+		return
+	}
+	l.hookState.Store(int64(hookStopping))
+	ch := l.hookQueueCh
+	l.hookQueueCh = nil
+	l.hooksMu.Unlock()
+
+	// No sender can hold the channel anymore: senders read it under hooksMu.
+	close(ch)
+	l.hookWg.Wait()
+	l.hookState.Store(int64(hookStopped))
+}
+
+// SetHookConfig replaces the hook execution settings (async mode, worker count, queue
+// size and timeout) at runtime. A running async runner is stopped first, draining its
+// pending tasks, and a new runner is started with the new settings when applicable.
+// Zero or negative Workers and Queue values are replaced by their defaults.
+func (l *Logger) SetHookConfig(hc HookConfig) {
+	l.Configure("").SetHookConfig(hc)
+}
+
+// SetHookConfig is Logger.SetHookConfig on behalf of the actor of ed.
+func (ed ConfigEditor) SetHookConfig(hc HookConfig) {
+	l := ed.l
+	c := ed.changing("hook_config")
+	defer c.commit()
+	if hc.Workers <= 0 {
+		hc.Workers = 1
+	}
+	if hc.Queue <= 0 {
+		hc.Queue = 1024
+	}
+
+	l.hookRunMu.Lock()
+	defer l.hookRunMu.Unlock()
+	l.stopHookRunnerLocked()
+
+	l.hooksMu.Lock()
+	c.old = fmt.Sprintf("%+v", HookConfig{Async: l.hookAsync, Workers: l.hookWorkers, Queue: l.hookQueue, Timeout: l.hookTimeout})
+	c.new = fmt.Sprintf("%+v", hc)
+	l.hookAsync = hc.Async
+	l.hookWorkers = hc.Workers
+	l.hookQueue = hc.Queue
+	l.hookTimeout = hc.Timeout
+	l.hooksMu.Unlock()
+
+	l.startHookRunnerLocked()
+}
+
+// GetHookConfig returns the hook execution settings currently in effect.
+func (l *Logger) GetHookConfig() HookConfig {
+	l.hooksMu.RLock()
+	defer l.hooksMu.RUnlock()
+	return HookConfig{
+		Async:   l.hookAsync,
+		Workers: l.hookWorkers,
+		Queue:   l.hookQueue,
+		Timeout: l.hookTimeout,
+	}
 }
 
 // enqueueHook processes a log event with the registered hooks.
 // If async mode is enabled, it adds the event to a non-blocking queue.
-// If the queue is full, an error is recorded. If async is disabled,
-// it executes the hooks synchronously in the same goroutine.
-func (l *Logger) enqueueHook(ev HookEvent) {
+// If the queue is full, an error is recorded. If async is disabled, or the
+// runner is between states (e.g. during SetHookConfig), it executes the hooks
+// synchronously in the same goroutine so that no event is lost.
+func (l *Logger) enqueueHook(ctx context.Context, ev HookEvent) {
 	l.hooksMu.RLock()
-	hasHooks := len(l.hooks) > 0
-	l.hooksMu.RUnlock()
-	if !hasHooks {
-		return // No-op if no hooks are registered.
+	if !l.wantsHookLocked(ev.Level, ev.Module) {
+		l.hooksMu.RUnlock()
+		return // No-op if no hook is registered for the entry.
 	}
-
-	if l.hookAsync {
+	if ch := l.hookQueueCh; l.hookAsync && ch != nil {
+		// The read lock is held across the send so the runner cannot close the channel.
 		select {
-		case l.hookQueueCh <- hookTask{event: ev}:
+		case ch <- hookTask{ctx: ctx, event: ev}:
 			// Task successfully enqueued.
 		default:
 			// Queue is full.
 			l.recordHookError(ev, ErrHookQueueFull)
 		}
-	} else {
-		// Execute synchronously.
-		l.runHooks(ev)
+		l.hooksMu.RUnlock()
+		return
 	}
+	l.hooksMu.RUnlock()
+
+	// Execute synchronously.
+	l.runHooks(ctx, ev)
+}
+
+// AdaptHook converts a legacy HookFunc into a HookFunc2. The returned hook ignores
+// its context, so it cannot be interrupted when the hook timeout expires: it runs to
+// completion, and its overrun is then recorded as ErrHookTimeout.
+func AdaptHook(hk HookFunc) HookFunc2 {
+	return func(_ context.Context, ev HookEvent) error { return hk(ev) }
 }
 
-// snapshotHooks creates and returns a copy of the current hook functions.
+// LegacyHook converts a HookFunc2 into a legacy HookFunc that is invoked with
+// context.Background(). It is useful for APIs that still accept HookFunc.
+func LegacyHook(hk HookFunc2) HookFunc {
+	return func(ev HookEvent) error { return hk(context.Background(), ev) }
+}
+
+// hasHooksLocked reports whether any hook is registered. The caller must hold hooksMu.
+func (l *Logger) hasHooksLocked() bool {
+	return len(l.hooks) > 0 || len(l.hooks2) > 0 || len(l.filteredHooks) > 0
+}
+
+// snapshotHooks creates and returns a copy of the current hook functions, with legacy
+// hooks adapted to the context-aware signature, together with the hook timeout in effect.
+// Filtered hooks are included when their filter matches ev; Match is called once the
+// lock is released.
 // This is a crucial step to prevent deadlocks. By iterating over a copy,
 // we avoid holding a read lock on l.hooksMu while executing the hooks,
 // which might themselves try to acquire a lock on the logger.
-func (l *Logger) snapshotHooks() []HookFunc {
+func (l *Logger) snapshotHooks(ev HookEvent) ([]HookFunc2, time.Duration) {
 	l.hooksMu.RLock()
-	defer l.hooksMu.RUnlock()
-	if len(l.hooks) == 0 {
-		return nil
+	if !l.hasHooksLocked() {
+		l.hooksMu.RUnlock()
+		return nil, l.hookTimeout
+	}
+	filtered, timeout := l.filteredHooks, l.hookTimeout
+	cp := make([]HookFunc2, 0, len(l.hooks)+len(l.hooks2)+len(filtered))
+	for _, hk := range l.hooks {
+		if hk != nil {
+			cp = append(cp, AdaptHook(hk))
+		}
 	}
-	cp := make([]HookFunc, len(l.hooks))
-	copy(cp, l.hooks)
-	return cp
+	for _, hk := range l.hooks2 {
+		if hk != nil {
+			cp = append(cp, hk)
+		}
+	}
+	l.hooksMu.RUnlock()
+
+	for i := range filtered {
+		if l.hookMatches(&filtered[i].Filter, ev) {
+			cp = append(cp, filtered[i].Hook)
+		}
+	}
+	return cp, timeout
 }
 
-// runHooks executes all registered hooks for a given event.
-// Each hook is executed in a panic-safe manner. If a timeout is configured,
-// each hook's execution is constrained by it. Errors and panics are captured
-// and recorded.
-func (l *Logger) runHooks(ev HookEvent) {
-	hooks := l.snapshotHooks()
+// runHooks executes all registered hooks for a given event, one after another on the
+// calling goroutine. Each hook is executed in a panic-safe manner. Errors and panics
+// are captured and recorded.
+//
+// Timeouts are cooperative: each hook receives a context whose deadline is the configured
+// timeout, expired by a single timer shared by the hooks of the call rather than by a
+// goroutine per hook. A hook that is still running when the deadline passes is not
+// abandoned; once it returns, the overrun is recorded as ErrHookTimeout. Context-aware
+// hooks (HookFunc2) should watch ctx.Done() and return early, while legacy HookFunc hooks
+// simply run to completion.
+func (l *Logger) runHooks(ctx context.Context, ev HookEvent) {
+	hooks, timeout := l.snapshotHooks(ev)
 	if len(hooks) == 0 {
 		return
 	}
 
+	clock := newHookClock(timeout)
+	defer clock.stop()
 	for _, hk := range hooks {
-		// IIFE to scope the defer for panic recovery.
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					l.recordHookError(ev, fmt.Errorf("%w: %v", ErrHookPanic, r))
-				}
-			}()
+		l.runHook(ctx, hk, ev, clock)
+	}
+}
 
-			if l.hookTimeout > 0 {
-				l.runHookWithTimeout(hk, ev)
-			} else {
-				l.runHookWithoutTimeout(hk, ev)
-			}
-		}()
+// runHook executes a single hook with panic recovery and, if clock is not nil, a deadline.
+// The hook context is derived from the context of the original log call, so it carries
+// the same values (trace spans, request metadata) for outbound calls the hook makes,
+// but it is detached from that context's cancellation: a finished request must not
+// cancel hooks that are still processing its log entries.
+func (l *Logger) runHook(entryCtx context.Context, hk HookFunc2, ev HookEvent, clock *hookClock) {
+	ctx := context.Background()
+	if entryCtx != nil {
+		ctx = context.WithoutCancel(entryCtx)
+	}
+	if clock == nil {
+		if err := callHook(ctx, hk, ev); err != nil {
+			l.recordHookError(ev, err)
+		}
+		return
+	}
+
+	err := callHook(clock.start(ctx), hk, ev)
+	if clock.finish() {
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %v", ErrHookTimeout, err)
+		} else {
+			err = ErrHookTimeout
+		}
+	}
+	if err != nil {
+		l.recordHookError(ev, err)
 	}
 }
 
-// runHookWithTimeout executes a single hook with a timeout.
-func (l *Logger) runHookWithTimeout(hk HookFunc, ev HookEvent) {
-	ctx, cancel := context.WithTimeout(context.Background(), l.hookTimeout)
-	defer cancel()
+// hookClock gives the hooks of one runHooks call their deadlines with a single runtime
+// timer, re-armed for each hook, instead of a timer or a goroutine per hook.
+type hookClock struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cur     atomic.Pointer[hookRun] // The hook currently running, if any.
+}
 
-	done := make(chan error, 1)
-	go func() {
-		done <- hk(ev)
-	}()
+// hookRun is the deadline of one hook and the cancellation of its context.
+type hookRun struct {
+	deadline time.Time
+	cancel   context.CancelFunc
+}
 
-	select {
-	case <-ctx.Done():
-		l.recordHookError(ev, ErrHookTimeout)
-	case err := <-done:
-		if err != nil {
-			l.recordHookError(ev, err)
-		}
+// hookCtx is the context of a hook run under a hookClock. It reports the hook's deadline,
+// and context.DeadlineExceeded once the clock has expired it. Contexts derived from it
+// are canceled with it without a goroutine, since it is backed by a standard cancelCtx.
+type hookCtx struct {
+	context.Context
+	deadline time.Time
+}
+
+// Deadline implements context.Context.
+func (c hookCtx) Deadline() (time.Time, bool) { return c.deadline, true }
+
+// Err implements context.Context.
+func (c hookCtx) Err() error {
+	if c.Context.Err() != nil {
+		return context.DeadlineExceeded
 	}
+	return nil
 }
 
-// runHookWithoutTimeout executes a single hook without a timeout.
-func (l *Logger) runHookWithoutTimeout(hk HookFunc, ev HookEvent) {
-	if err := hk(ev); err != nil {
-		l.recordHookError(ev, err)
+// newHookClock returns a clock for hooks bounded by timeout, or nil if timeout is 0.
+func newHookClock(timeout time.Duration) *hookClock {
+	if timeout <= 0 {
+		return nil
 	}
+	return &hookClock{timeout: timeout}
+}
+
+// start returns the context of the next hook, derived from parent, and arms the timer
+// for its deadline.
+func (c *hookClock) start(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	run := &hookRun{deadline: time.Now().Add(c.timeout), cancel: cancel}
+	c.cur.Store(run)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.timeout, c.fire)
+	} else {
+		c.timer.Reset(c.timeout)
+	}
+	return hookCtx{Context: ctx, deadline: run.deadline}
+}
+
+// finish ends the run of the current hook and reports whether it overran its deadline.
+func (c *hookClock) finish() bool {
+	run := c.cur.Swap(nil)
+	run.cancel()
+	return !time.Now().Before(run.deadline)
+}
+
+// fire expires the context of the running hook once its deadline has passed. A late call
+// left over from a previous hook finds no hook or a deadline still ahead, and does nothing.
+func (c *hookClock) fire() {
+	if run := c.cur.Load(); run != nil && !time.Now().Before(run.deadline) {
+		run.cancel()
+	}
+}
+
+// stop releases the timer of c. It is a no-op on a nil clock.
+func (c *hookClock) stop() {
+	if c != nil && c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// callHook calls hk, returning a panic of the hook as an ErrHookPanic error.
+func callHook(ctx context.Context, hk HookFunc2, ev HookEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHookPanic, r)
+		}
+	}()
+	return hk(ctx, ev)
 }
 
 // recordHookError atomically increments the hook error counter and adds a
@@ -171,14 +415,12 @@ func (l *Logger) GetHookErrors() []HookError {
 }
 
 // closeHookRunner gracefully shuts down the asynchronous hook processing system.
-// It closes the queue and waits for all worker goroutines to finish their tasks.
-// It resets the queue channel to nil, allowing the runner to be restarted later.
+// It closes the queue and waits for all worker goroutines to finish their tasks,
+// leaving the runner in the stopped state.
 func (l *Logger) closeHookRunner() {
-	if l.hookAsync && l.hookQueueCh != nil {
-		close(l.hookQueueCh)
-		l.hookWg.Wait()
-		l.hookQueueCh = nil // Allow runner to be restarted.
-	}
+	l.hookRunMu.Lock()
+	defer l.hookRunMu.Unlock()
+	l.stopHookRunnerLocked()
 }
 
 // ErrHookQueueFull signifies that a log event could not be processed by an
@@ -186,7 +428,7 @@ func (l *Logger) closeHookRunner() {
 var ErrHookQueueFull = fmt.Errorf("hook queue full")
 
 // ErrHookTimeout signifies that a hook function failed to complete within
-// its configured timeout.
+// its configured timeout. It is recorded after the hook returns.
 var ErrHookTimeout = fmt.Errorf("hook timeout")
 
 // ErrHookPanic signifies that a hook function panicked during execution.
@@ -0,0 +1,265 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the HTTP middleware. It binds the logger to the request context and
+// logs one entry per request with its method, path, status, size and duration, optionally
+// with the request and response bodies, capped in size, limited to textual content types
+// and masked with the logger's masking rules, and the request and response headers, with
+// the values of credential headers redacted.
+
+package unologger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultBodyMaxBytes is the default number of body bytes captured per request and response.
+const defaultBodyMaxBytes = 4096
+
+// defaultBodyContentTypes lists the media types captured by default.
+var defaultBodyContentTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"application/xml",
+	"text/",
+}
+
+// HTTPConfig configures the HTTP middleware.
+type HTTPConfig struct {
+	// Module is the module of the request entries and of the request context.
+	// Defaults to "http".
+	Module string
+	// FlowIDHeader is the request header whose value becomes the flow ID of the request
+	// context. Defaults to "X-Request-ID".
+	FlowIDHeader string
+	// Body enables request and response body capture.
+	Body BodyCapture
+	// Headers enables request and response header capture.
+	Headers HeaderCapture
+	// Client, if set, adds the client_ip and user_agent of the request, anonymized by the
+	// policy, to the attributes of the request context.
+	Client *AnonymizationPolicy
+}
+
+// BodyCapture configures the capture of request and response bodies. Captured bodies are
+// masked like messages: JSON bodies with JSONFieldRules and the regex rules, other bodies
+// with the regex rules only. A body truncated by MaxBytes is not valid JSON anymore and
+// therefore only gets the regex rules.
+type BodyCapture struct {
+	Request  bool // Capture the request body, as far as the handler reads it.
+	Response bool // Capture the response body.
+	// MaxBytes caps the captured bytes of each body; the rest is counted but not kept.
+	// Defaults to 4096.
+	MaxBytes int
+	// ContentTypes lists the media types whose bodies are captured. An entry ending in "/"
+	// matches a whole type, e.g. "text/". Defaults to JSON, XML, form and text types.
+	ContentTypes []string
+}
+
+// HeaderCapture configures the capture of request and response headers. Headers are logged
+// as sorted "Name: value" lines, with the values of the Sensitive headers redacted and the
+// regex rules of the logger applied to the whole block.
+type HeaderCapture struct {
+	Request  bool // Capture the request headers.
+	Response bool // Capture the response headers.
+	// Sensitive lists the headers whose values are redacted, matched case-insensitively.
+	// Defaults to DefaultSensitiveHeaders.
+	Sensitive []string
+	// Replacement replaces the redacted values. Defaults to "[REDACTED]".
+	Replacement string
+}
+
+// HTTPMiddleware returns a middleware that logs every request with l:
+//
+//	handler := l.HTTPMiddleware(unologger.HTTPConfig{
+//		Body: unologger.BodyCapture{Request: true, Response: true, MaxBytes: 2048},
+//	})(mux)
+//
+// The request context carries l, the module and the flow ID, so handlers can log with
+// GetLogger(r.Context()). Requests are logged at INFO, or at ERROR for 5xx responses, with
+// the fields http_method, http_path, http_status, http_bytes and duration_ms, plus
+// request_body and response_body (and *_truncated), request_headers and response_headers
+// when captured.
+func (l *Logger) HTTPMiddleware(cfg HTTPConfig) func(http.Handler) http.Handler {
+	if cfg.Module == "" {
+		cfg.Module = "http"
+	}
+	if cfg.FlowIDHeader == "" {
+		cfg.FlowIDHeader = "X-Request-ID"
+	}
+	if cfg.Body.MaxBytes <= 0 {
+		cfg.Body.MaxBytes = defaultBodyMaxBytes
+	}
+	if cfg.Body.ContentTypes == nil {
+		cfg.Body.ContentTypes = defaultBodyContentTypes
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := context.WithValue(WithLogger(r.Context(), l), ctxModuleKey, cfg.Module)
+			if id := r.Header.Get(cfg.FlowIDHeader); id != "" {
+				ctx = WithFlowID(ctx, id)
+			}
+			if cfg.Client != nil {
+				ctx = WithAttrs(ctx, cfg.Client.ClientFields(r))
+			}
+			r = r.WithContext(ctx)
+
+			var reqBody *bodyBuffer
+			if cfg.Body.Request && r.Body != nil && cfg.Body.captures(r.Header.Get("Content-Type")) {
+				reqBody = &bodyBuffer{max: cfg.Body.MaxBytes}
+				r.Body = &teeBody{ReadCloser: r.Body, buf: reqBody}
+			}
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK, cfg: &cfg.Body}
+
+			next.ServeHTTP(rw, r)
+
+			fields := Fields{
+				FieldHTTPMethod: r.Method,
+				FieldHTTPPath:   r.URL.Path,
+				FieldHTTPStatus: rw.status,
+				FieldHTTPBytes:  rw.bytes,
+				FieldDurationMS: time.Since(start).Milliseconds(),
+			}
+			if reqBody != nil {
+				reqBody.addTo(l, fields, "request_body", r.Header.Get("Content-Type"))
+			}
+			if rw.body != nil {
+				rw.body.addTo(l, fields, "response_body", rw.contentType)
+			}
+			if cfg.Headers.Request {
+				fields["request_headers"] = l.MaskPayload(cfg.Headers.headerBlock(r.Header), "text/plain")
+			}
+			if cfg.Headers.Response {
+				fields["response_headers"] = l.MaskPayload(cfg.Headers.headerBlock(rw.Header()), "text/plain")
+			}
+			level := INFO
+			if rw.status >= http.StatusInternalServerError {
+				level = ERROR
+			}
+			l.logFields(ctx, level, fields, "%s %s %d", r.Method, r.URL.Path, rw.status)
+		})
+	}
+}
+
+// captures reports whether bodies of the given Content-Type are captured.
+func (bc *BodyCapture) captures(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range bc.ContentTypes {
+		allowed = strings.ToLower(allowed)
+		if mt == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mt, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyBuffer keeps the first max bytes of a body and counts the rest.
+type bodyBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int
+}
+
+// write records p.
+func (b *bodyBuffer) write(p []byte) {
+	b.total += len(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+// addTo adds the body, masked according to its content type (see MaskPayload), to fields
+// under key, with key+"_truncated" if it was capped.
+func (b *bodyBuffer) addTo(l *Logger, fields Fields, key, contentType string) {
+	fields[key] = l.MaskPayload(b.buf.String(), contentType)
+	if b.total > b.buf.Len() {
+		fields[key+"_truncated"] = true
+	}
+}
+
+// teeBody records the bytes read from a request body.
+type teeBody struct {
+	io.ReadCloser
+	buf *bodyBuffer
+}
+
+// Read implements io.Reader.
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.write(p[:n])
+	return n, err
+}
+
+// responseRecorder records the status, size and, if enabled, the body of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	cfg         *BodyCapture
+	status      int
+	bytes       int64
+	body        *bodyBuffer
+	contentType string // Content type of the captured body.
+	wroteHeader bool
+	wroteBody   bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (rw *responseRecorder) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter. The capture decision is taken on the first write,
+// sniffing the content type like net/http when the handler did not set one.
+func (rw *responseRecorder) Write(p []byte) (int, error) {
+	if !rw.wroteBody {
+		rw.wroteBody = true
+		if rw.cfg.Response {
+			ct := rw.Header().Get("Content-Type")
+			if ct == "" {
+				ct = http.DetectContentType(p)
+			}
+			if rw.cfg.captures(ct) {
+				rw.body = &bodyBuffer{max: rw.cfg.MaxBytes}
+				rw.contentType = ct
+			}
+		}
+	}
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += int64(n)
+	if rw.body != nil {
+		rw.body.write(p[:n])
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (rw *responseRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
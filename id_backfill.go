@@ -0,0 +1,146 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the back-fill of trace and flow IDs. Legacy code paths often log with
+// a context that carries neither; with a back-fill scope set, such entries receive a
+// generated ID shared by the entries of the same goroutine or of the same batch, so that
+// they can still be correlated with each other.
+
+package unologger
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+)
+
+// BackfilledKey is set to true on an entry whose trace or flow ID was back-filled.
+const BackfilledKey = "id_backfilled"
+
+// BackfillScope selects which entries share a back-filled ID.
+type BackfillScope int
+
+const (
+	// BackfillOff disables the back-fill.
+	BackfillOff BackfillScope = iota
+	// BackfillGoroutine gives the same ID to the entries logged by the same goroutine. The
+	// ID is the prefix returned by the generator, followed by the goroutine number, which the
+	// runtime never reuses. Finding the goroutine number costs about a microsecond per entry
+	// that lacks an ID.
+	BackfillGoroutine
+	// BackfillBatch gives the same ID to the entries processed in the same batch by a
+	// worker, which groups entries logged at about the same time. Entries of the emergency
+	// path form a batch of their own.
+	BackfillBatch
+)
+
+// IDBackfillConfig configures the back-fill of trace and flow IDs.
+type IDBackfillConfig struct {
+	// Scope selects which entries share an ID. BackfillOff, the zero value, disables the
+	// back-fill.
+	Scope BackfillScope
+	// Generator returns a new ID: one per batch with BackfillBatch, and the prefix of the
+	// IDs of the logger with BackfillGoroutine. Defaults to a random UUID.
+	Generator func() string
+}
+
+// idBackfill is the back-fill configuration in effect.
+type idBackfill struct {
+	scope  BackfillScope
+	gen    func() string
+	prefix string // Prefix of the IDs of BackfillGoroutine.
+}
+
+// SetIDBackfill sets the back-fill of trace and flow IDs, or disables it if cfg.Scope is
+// BackfillOff. An entry whose context has no trace ID, or no flow ID, receives the
+// generated ID in place of the missing ones, with the BackfilledKey field set to true so
+// that the ID is not mistaken for one propagated by a caller. Entries that carry both IDs
+// are left as they are.
+func (l *Logger) SetIDBackfill(cfg IDBackfillConfig) {
+	if cfg.Scope == BackfillOff {
+		l.idFill.Store(nil)
+		return
+	}
+	b := &idBackfill{scope: cfg.Scope, gen: cfg.Generator}
+	if b.gen == nil {
+		b.gen = newUUID
+	}
+	if b.scope == BackfillGoroutine {
+		b.prefix = b.gen()
+	}
+	l.idFill.Store(b)
+}
+
+// GetIDBackfill returns the back-fill scope in effect.
+func (l *Logger) GetIDBackfill() BackfillScope {
+	if b := l.idFill.Load(); b != nil {
+		return b.scope
+	}
+	return BackfillOff
+}
+
+// stampBackfill records in e the ID of its goroutine if the back-fill is per goroutine, or a
+// new ID for an emergency entry if it is per batch, when the context of e lacks an ID. It
+// must run on the goroutine of the log call, after e.ctx and e.emergency are set.
+func (l *Logger) stampBackfill(e *logEntry) {
+	b := l.idFill.Load()
+	if b == nil || !missingIDs(e.ctx) {
+		return
+	}
+	switch {
+	case b.scope == BackfillGoroutine:
+		e.idFill = b.prefix + "-" + strconv.FormatUint(goroutineID(), 10)
+	case e.emergency:
+		e.idFill = b.gen()
+	}
+}
+
+// backfillBatch records in e the ID of the batch out, generating it for the first entry of
+// the batch that lacks an ID, if the back-fill is per batch.
+func (l *Logger) backfillBatch(out *batchOutput, e *logEntry) {
+	b := out.idFill
+	if b == nil || b.scope != BackfillBatch || e.idFill != "" || !missingIDs(e.ctx) {
+		return
+	}
+	if out.batchID == "" {
+		out.batchID = b.gen()
+	}
+	e.idFill = out.batchID
+}
+
+// annotateBackfill replaces the missing trace and flow IDs with the back-filled ID of e, if
+// any, and sets the BackfilledKey field.
+func annotateBackfill(e *logEntry, traceID, flowID *string, fields Fields) {
+	if e.idFill == "" {
+		return
+	}
+	if *traceID == "" {
+		*traceID = e.idFill
+	}
+	if *flowID == "" {
+		*flowID = e.idFill
+	}
+	fields[BackfilledKey] = true
+}
+
+// missingIDs reports whether ctx lacks a trace ID or a flow ID.
+func missingIDs(ctx context.Context) bool {
+	traceID, _ := ctx.Value(ctxTraceIDKey).(string)
+	flowID, _ := ctx.Value(ctxFlowIDKey).(string)
+	return traceID == "" || flowID == ""
+}
+
+// goroutineID returns the number of the calling goroutine, read from the header of its
+// stack trace, "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
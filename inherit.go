@@ -0,0 +1,167 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements detached loggers derived from an existing logger. A library that
+// wants an isolated pipeline, with its own queue, workers and statistics, but otherwise the
+// outputs, masking and hooks of the application, derives it from the application's logger
+// instead of re-specifying the whole configuration.
+
+package unologger
+
+// NewDetachedLoggerFrom creates and starts a detached logger that inherits the settings of
+// parent, except those set in overrides. A setting is inherited when its field in
+// overrides has its zero value:
+//
+//   - MinLevel (DEBUG, the zero value, inherits; use SetMinLevel to lower the level to
+//     DEBUG), Timezone, Formatter and JSON (both unset), Batch, Retry, EnableOTel,
+//     OnWriteError and FailureHook.
+//   - Stdout, Stderr, the rotation file (Rotation.Enable unset), the retention sinks
+//     (Retention.Sinks and Retention.Files empty) and the extra writers of parent whose
+//     name is not used by overrides.Writers. Inherited sinks are shared, not reopened: the
+//     child writes to them but does not close them, so parent must outlive the child. No
+//     sink is inherited if overrides sets Delegate or DelegateGlobal, since the entries
+//     already reach the sinks of the delegate.
+//   - The masking rules (RegexRules, RegexPatternMap and JSONFieldRules all empty), the
+//     blob rules, crypto-shredding, key normalization and the default module and attributes.
+//   - Caller reporting (ReportCaller unset) and its skip depth, the detection of the module
+//     from the caller (ModuleFromCaller unset), and error stacks (ErrorStacks unset).
+//   - The exit of the FATAL calls (Fatal, ExitFunc and ExitHooks, each when unset).
+//   - The hooks (Hooks and Hooks2 both empty) and the hook configuration (Hook unset).
+//
+// The queue and worker settings (Buffer, Workers, NonBlocking, DropOldest, PriorityQueue,
+// AutoScale, SingleWriter) and the remaining features are not inherited; they are taken
+// from overrides with their usual defaults. The settings are copied at creation; later
+// changes of parent do not affect the child.
+func NewDetachedLoggerFrom(parent *Logger, overrides Config) *Logger {
+	cfg := overrides
+	dc := parent.GetDynamicConfig()
+	if cfg.MinLevel == DEBUG {
+		cfg.MinLevel = dc.MinLevel
+	}
+	if cfg.Timezone == "" {
+		parent.locMu.RLock()
+		cfg.Timezone = parent.loc.String()
+		parent.locMu.RUnlock()
+	}
+	if cfg.Timestamps == (TimestampConfig{}) {
+		cfg.Timestamps = parent.GetTimestampConfig()
+	}
+	if cfg.Formatter == nil && !cfg.JSON {
+		parent.formatterMu.RLock()
+		cfg.Formatter = parent.formatter
+		parent.formatterMu.RUnlock()
+		cfg.JSON = parent.jsonFmtFlag.Load()
+	}
+	if cfg.Batch == (BatchConfig{}) {
+		cfg.Batch = dc.Batch
+	}
+	if cfg.Retry == (RetryPolicy{}) {
+		cfg.Retry = dc.Retry
+	}
+	if !cfg.EnableOTel {
+		cfg.EnableOTel = parent.enableOTel.Load()
+	}
+	if cfg.OnWriteError == nil {
+		if fn := parent.writeErrFn.Load(); fn != nil {
+			cfg.OnWriteError = *fn
+		}
+	}
+	if cfg.FailureHook == nil {
+		if fn := parent.failureFn.Load(); fn != nil {
+			cfg.FailureHook = *fn
+		}
+	}
+	if len(cfg.RegexRules) == 0 && len(cfg.RegexPatternMap) == 0 && len(cfg.JSONFieldRules) == 0 {
+		cfg.RegexRules = dc.RegexRules
+		cfg.JSONFieldRules = dc.JSONFieldRules
+	}
+	if cfg.BlobRules == nil {
+		cfg.BlobRules = parent.BlobRules()
+	}
+	if len(cfg.Hooks) == 0 && len(cfg.Hooks2) == 0 && len(cfg.FilteredHooks) == 0 {
+		parent.hooksMu.RLock()
+		cfg.Hooks = append([]HookFunc(nil), parent.hooks...)
+		cfg.Hooks2 = append([]HookFunc2(nil), parent.hooks2...)
+		cfg.FilteredHooks = parent.filteredHooks
+		parent.hooksMu.RUnlock()
+	}
+	if cfg.Hook == (HookConfig{}) {
+		cfg.Hook = parent.GetHookConfig()
+	}
+
+	// A delegating child already reaches the sinks of its delegate, usually parent.
+	delegating := cfg.Delegate != nil || cfg.DelegateGlobal
+	parent.outputsMu.RLock()
+	sharedStdout, sharedStderr := !delegating && cfg.Stdout == nil, !delegating && cfg.Stderr == nil
+	if sharedStdout {
+		cfg.Stdout = parent.stdOut
+	}
+	if sharedStderr {
+		cfg.Stderr = parent.errOut
+	}
+	var rotation *writerSink
+	if !delegating && !cfg.Rotation.Enable && parent.rotationSink != nil {
+		rotation = &writerSink{Name: parent.rotationSink.Name, Writer: parent.rotationSink.Writer}
+	}
+	extras := make([]writerSink, 0, len(parent.extraW))
+	for _, s := range parent.extraW {
+		if !delegating && s.temp == 0 {
+			extras = append(extras, writerSink{Name: s.Name, Writer: s.Writer})
+		}
+	}
+	parent.outputsMu.RUnlock()
+
+	l := newLoggerFromConfig(cfg)
+	l.sharedStdout, l.sharedStderr = sharedStdout, sharedStderr
+	if rotation != nil {
+		l.rotationSink = rotation
+	}
+	names := make(map[string]bool, len(l.extraW))
+	for _, s := range l.extraW {
+		names[s.Name] = true
+	}
+	for _, s := range extras {
+		if !names[s.Name] {
+			l.extraW = append(l.extraW, s)
+		}
+	}
+	if !delegating && len(overrides.Retention.Sinks) == 0 && len(overrides.Retention.Files) == 0 && len(parent.retentionSinks) > 0 {
+		l.retentionSinks = make(map[string]writerSink, len(parent.retentionSinks))
+		for class, s := range parent.retentionSinks {
+			l.retentionSinks[class] = writerSink{Name: s.Name, Writer: s.Writer}
+		}
+		l.retentionDefault = parent.retentionDefault
+	}
+	if overrides.Shredding == nil {
+		l.shredding.Store(parent.shredding.Load())
+	}
+	if !overrides.KeyNormalization.enabled() {
+		l.keyNorm.Store(parent.keyNorm.Load())
+	}
+	l.defaults.Store(parent.defaults.Load())
+	if !overrides.ReportCaller {
+		l.callerSkip.Store(parent.callerSkip.Load())
+	}
+	if !overrides.ModuleFromCaller {
+		l.callerMod.Store(parent.callerMod.Load())
+	}
+	if !overrides.ErrorStacks {
+		l.errorStacks.Store(parent.errorStacks.Load())
+	}
+	if overrides.Fatal == (FatalConfig{}) {
+		l.fatalCfg.Store(parent.fatalCfg.Load())
+	}
+	if overrides.ExitFunc == nil {
+		l.exitFn.Store(parent.exitFn.Load())
+	}
+	if len(overrides.ExitHooks) == 0 {
+		parent.exitHooks.mu.Lock()
+		for _, e := range parent.exitHooks.fns {
+			l.OnExit(e.fn)
+		}
+		parent.exitHooks.mu.Unlock()
+	}
+	l.start()
+	return l
+}
@@ -8,16 +8,19 @@
 package unologger
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	globalLogger   *Logger
-	globalMu       sync.RWMutex
+	// globalLogger holds the shared logger. It is read on every log call that does not carry
+	// its own logger, so it is an atomic pointer rather than a mutex-guarded variable.
+	globalLogger   atomic.Pointer[Logger]
 	ensureInitOnce sync.Once
 )
 
@@ -43,9 +46,7 @@ func InitLogger(minLevel Level, timezone string) {
 	}
 	// Create and set the global logger from the constructed config.
 	l := newLoggerFromConfig(cfg)
-	globalMu.Lock()
-	globalLogger = l
-	globalMu.Unlock()
+	globalLogger.Store(l)
 	l.start()
 }
 
@@ -54,9 +55,7 @@ func InitLogger(minLevel Level, timezone string) {
 // over all features like batching, rotation, hooks, and masking.
 func InitLoggerWithConfig(cfg Config) {
 	l := newLoggerFromConfig(cfg)
-	globalMu.Lock()
-	globalLogger = l
-	globalMu.Unlock()
+	globalLogger.Store(l)
 	l.start()
 }
 
@@ -74,29 +73,228 @@ func NewDetachedLogger(cfg Config) *Logger {
 // Finally, it attempts to gracefully close the old logger within the given timeout.
 // This is useful for applying a completely new configuration at runtime without downtime.
 func ReinitGlobalLogger(cfg Config, closeOldTimeout time.Duration) (*Logger, error) {
-	ensureInit()
+	return ReinitGlobalLoggerWithOptions(cfg, closeOldTimeout, ReinitOptions{})
+}
+
+// ReinitOptions selects which runtime state ReinitGlobalLoggerWithOptions migrates from
+// the old global logger to the new one. Without it, anything registered at runtime by
+// other components (extra writers, hooks, dynamic overrides) is silently discarded.
+type ReinitOptions struct {
+	// CarryStats adds the old logger's counters, per-writer error, byte and entry counts,
+	// per-module totals and recent hook errors to the new logger once the old one has been closed.
+	CarryStats bool
+	// CarryHooks replaces the hooks from the new Config with the hooks currently registered on
+	// the old logger (including those set through SetHooks/SetHooks2), and the settings of the
+	// hook runner (Config.Hook, or the last SetHookConfig) with them. The write error handler
+	// registered with OnWriteError, the failure hook, the lifecycle listeners registered with
+	// OnLifecycle and the exit hooks registered with OnExit are carried as well.
+	CarryHooks bool
+	// CarryWriters moves the old logger's extra writers to the new logger. The old logger
+	// keeps writing its queued entries to them until it is closed, but closing it no longer
+	// closes them. Writers whose name already exists in the new configuration are left with
+	// the old logger.
+	CarryWriters bool
+	// CarryDynamic applies the old logger's runtime overrides (min level, masking rules, retry
+	// policy, batch settings, formatter, timezone, OTel flag, quotas, budget, key
+	// normalization, feature flags, crypto-shredding, signing, clock jump threshold, sequence
+	// numbering, blob rules, sink rate limits, health thresholds, default module and
+	// attributes, rate-based sampling with its counters, caller reporting, entry TTL, pause
+	// policy, error stack capture, ID back-fill, module detection from the caller, the
+	// validation schema, and the settings and exit function of the FATAL calls) to the new
+	// logger.
+	CarryDynamic bool
+}
+
+// ReinitGlobalLoggerWithOptions behaves like ReinitGlobalLogger but migrates the runtime
+// state selected by opts from the old global logger to the new one.
+func ReinitGlobalLoggerWithOptions(cfg Config, closeOldTimeout time.Duration, opts ReinitOptions) (*Logger, error) {
 	oldLogger := GlobalLogger()
 
-	// Create and start the new logger before acquiring the lock.
+	// Create and start the new logger before publishing it.
 	newLogger := newLoggerFromConfig(cfg)
+	if oldLogger != nil {
+		carryBeforeStart(oldLogger, newLogger, opts)
+	}
 	newLogger.start()
 
-	globalMu.Lock()
-	globalLogger = newLogger
-	globalMu.Unlock()
+	// Swap returns the logger actually replaced, which differs from oldLogger only if
+	// another goroutine reinitialized concurrently; that one must be closed too.
+	if prev := globalLogger.Swap(newLogger); prev != nil {
+		oldLogger = prev
+	}
 
 	var err error
 	if oldLogger != nil {
 		err = closeLogger(oldLogger, closeOldTimeout)
+		if opts.CarryStats {
+			carryStats(oldLogger, newLogger)
+		}
 	}
 	return newLogger, err
 }
 
+// carryBeforeStart copies hooks, extra writers and dynamic overrides from src to dst.
+// dst has not been started yet, so its fields can be assigned directly.
+func carryBeforeStart(src, dst *Logger, opts ReinitOptions) {
+	if opts.CarryHooks {
+		src.hooksMu.RLock()
+		dst.hooks = append([]HookFunc(nil), src.hooks...)
+		dst.hooks2 = append([]HookFunc2(nil), src.hooks2...)
+		dst.filteredHooks = src.filteredHooks
+		dst.hookAsync = src.hookAsync
+		dst.hookWorkers = src.hookWorkers
+		dst.hookQueue = src.hookQueue
+		dst.hookTimeout = src.hookTimeout
+		src.hooksMu.RUnlock()
+		dst.dynConfig.Hooks = dst.hooks
+		dst.dynConfig.Hooks2 = dst.hooks2
+		dst.dynConfig.FilteredHooks = dst.filteredHooks
+		if fn := src.writeErrFn.Load(); fn != nil {
+			dst.writeErrFn.Store(fn)
+		}
+		if fn := src.failureFn.Load(); fn != nil {
+			dst.failureFn.Store(fn)
+		}
+		src.lifecycle.mu.Lock()
+		dst.lifecycle.fns = append([]lifecycleEntry(nil), src.lifecycle.fns...)
+		dst.lifecycle.nextID = src.lifecycle.nextID
+		src.lifecycle.mu.Unlock()
+		src.exitHooks.mu.Lock()
+		dst.exitHooks.fns = append([]exitHookEntry(nil), src.exitHooks.fns...)
+		dst.exitHooks.nextID = src.exitHooks.nextID
+		src.exitHooks.mu.Unlock()
+	}
+
+	if opts.CarryWriters {
+		existing := make(map[string]bool, len(dst.extraW))
+		for _, s := range dst.extraW {
+			existing[s.Name] = true
+		}
+		src.outputsMu.Lock()
+		for i, s := range src.extraW {
+			if existing[s.Name] {
+				continue
+			}
+			dst.extraW = append(dst.extraW, s)
+			// The old logger keeps writing to the sink while it drains, but no longer owns it.
+			src.extraW[i].Closer = nil
+		}
+		src.outputsMu.Unlock()
+	}
+
+	if opts.CarryDynamic {
+		dc := src.GetDynamicConfig()
+		dst.minLevel.Store(int32(dc.MinLevel))
+		dst.regexRules = dc.RegexRules
+		dst.jsonFieldRules = dc.JSONFieldRules
+		dst.retryPolicy = dc.Retry
+		dst.storeBatch(dc.Batch)
+		dst.dynConfig.MinLevel = dc.MinLevel
+		dst.dynConfig.RegexRules = dc.RegexRules
+		dst.dynConfig.JSONFieldRules = dc.JSONFieldRules
+		dst.dynConfig.Retry = dc.Retry
+		dst.dynConfig.Batch = dc.Batch
+
+		src.formatterMu.RLock()
+		dst.formatter = src.formatter
+		src.formatterMu.RUnlock()
+		dst.jsonFmtFlag.Store(src.jsonFmtFlag.Load())
+		src.locMu.RLock()
+		dst.loc = src.loc
+		dst.timestamps = src.timestamps
+		src.locMu.RUnlock()
+		dst.enableOTel.Store(src.enableOTel.Load())
+		dst.traceDebug.Store(src.traceDebug.Load())
+		dst.spanEventLvl.Store(src.spanEventLvl.Load())
+		dst.quota.Store(src.quota.Load())
+		dst.budgetCfg.Store(src.budgetCfg.Load())
+		dst.keyNorm.Store(src.keyNorm.Load())
+		dst.flags.Store(src.flags.Load())
+		dst.shredding.Store(src.shredding.Load())
+		dst.signing.Store(src.signing.Load())
+		dst.clock.threshold.Store(src.clock.threshold.Load())
+		dst.seqEntries.Store(src.seqEntries.Load())
+		dst.seqSinks.Store(src.seqSinks.Load())
+		dst.blobRules.Store(src.blobRules.Load())
+		dst.shareSinkRates(src)
+		dst.healthCfg.Store(src.healthCfg.Load())
+		dst.defaults.Store(src.defaults.Load())
+		dst.sampler.Store(src.sampler.Load())
+		dst.callerSkip.Store(src.callerSkip.Load())
+		dst.entryTTL.Store(src.entryTTL.Load())
+		src.pause.mu.Lock()
+		dst.pause.cfg = src.pause.cfg
+		src.pause.mu.Unlock()
+		dst.errorStacks.Store(src.errorStacks.Load())
+		dst.idFill.Store(src.idFill.Load())
+		dst.callerMod.Store(src.callerMod.Load())
+		dst.validation.Store(src.validation.Load())
+		if c := src.fatalCfg.Load(); c != nil {
+			dst.fatalCfg.Store(c)
+		}
+		if fn := src.exitFn.Load(); fn != nil {
+			dst.exitFn.Store(fn)
+		}
+	}
+}
+
+// carryStats adds the counters of a closed logger to dst.
+func carryStats(src, dst *Logger) {
+	dst.writtenCount.Add(src.writtenCount.Load())
+	dst.droppedCount.Add(src.droppedCount.Load())
+	dst.batchCount.Add(src.batchCount.Load())
+	dst.writeErrCount.Add(src.writeErrCount.Load())
+	dst.hookErrCount.Add(src.hookErrCount.Load())
+	dst.afterClose.Add(src.afterClose.Load())
+	dst.panics.Add(src.panics.Load())
+	for lvl := range src.levelCounts {
+		dst.levelCounts[lvl].Add(src.levelCounts[lvl].Load())
+	}
+	src.writerErrs.Range(func(key, value any) bool {
+		s := value.(*writerHealth).snapshot()
+		h := dst.health(key.(string))
+		h.errors.Add(s.Errors)
+		h.bytes.Add(s.BytesWritten)
+		h.entries.Add(s.EntriesWritten)
+		return true
+	})
+	src.modules.Range(func(key, value any) bool {
+		su := value.(*moduleUsage)
+		du := dst.usage(key.(string))
+		su.mu.Lock()
+		du.mu.Lock()
+		du.bytes += su.bytes
+		du.entries += su.entries
+		du.suppressed += su.suppressed
+		du.suppressedBytes += su.suppressedBytes
+		du.mu.Unlock()
+		su.mu.Unlock()
+		return true
+	})
+
+	old := src.GetHookErrors()
+	dst.hookErrMu.Lock()
+	merged := append(old, dst.hookErrLog...)
+	if limit := dst.hookErrMax; limit > 0 && len(merged) > limit {
+		merged = merged[len(merged)-limit:]
+	}
+	dst.hookErrLog = merged
+	dst.hookErrMu.Unlock()
+}
+
 // newLoggerFromConfig is the core factory function for creating a Logger instance.
 // It takes a user-provided Config, applies sane defaults and validation,
 // and initializes all internal components of the logger.
 func newLoggerFromConfig(cfg Config) *Logger {
 	// --- Apply Defaults ---
+	if cfg.Delegate != nil || cfg.DelegateGlobal {
+		if cfg.Stdout == nil {
+			cfg.Stdout = io.Discard
+		}
+		if cfg.Stderr == nil {
+			cfg.Stderr = io.Discard
+		}
+	}
 	if cfg.Stdout == nil {
 		cfg.Stdout = os.Stdout
 	}
@@ -110,6 +308,8 @@ func newLoggerFromConfig(cfg Config) *Logger {
 	if len(cfg.RegexPatternMap) > 0 {
 		cfg.RegexRules = append(cfg.RegexRules, compileMaskRegexes(cfg.RegexPatternMap)...)
 	}
+	cfg.RegexRules = orderRegexRules(cfg.RegexRules)
+	cfg.JSONFieldRules = orderFieldRules(cfg.JSONFieldRules)
 
 	// --- Clamp Values to Safe Ranges ---
 	if cfg.Buffer <= 0 {
@@ -149,12 +349,13 @@ func newLoggerFromConfig(cfg Config) *Logger {
 		errOut:         cfg.Stderr,
 		loc:            loc,
 		formatter:      formatter,
-		ch:             make(chan *logEntry, cfg.Buffer),
-		workers:        cfg.Workers,
 		nonBlocking:    cfg.NonBlocking,
 		dropOldest:     cfg.DropOldest,
+		direct:         cfg.SingleWriter,
 		retryPolicy:    cfg.Retry,
 		hooks:          cfg.Hooks,
+		hooks2:         cfg.Hooks2,
+		filteredHooks:  cfg.FilteredHooks,
 		hookAsync:      cfg.Hook.Async,
 		hookWorkers:    cfg.Hook.Workers,
 		hookQueue:      cfg.Hook.Queue,
@@ -162,25 +363,96 @@ func newLoggerFromConfig(cfg Config) *Logger {
 		regexRules:     cfg.RegexRules,
 		jsonFieldRules: cfg.JSONFieldRules,
 		hookErrMax:     defaultHookErrMax,
+		delegateGlobal: cfg.DelegateGlobal,
+	}
+	l.self = []*Logger{l}
+	if cfg.Delegate != nil {
+		l.delegates = []*Logger{cfg.Delegate}
+	}
+
+	// The queue only exists when entries are handed off to workers.
+	if !l.direct {
+		l.ch = make(chan *logEntry, cfg.Buffer)
+		l.chLink = &queueLink{ch: l.ch}
+		if pq := normalizePriorityQueue(cfg.PriorityQueue, cfg.Buffer); pq.Enable {
+			l.hi = make(chan *logEntry, pq.Buffer)
+			l.hiLevel = pq.Level
+		}
+		l.closing = make(chan struct{})
+		l.workers = cfg.Workers
+		l.autoScale = normalizeAutoScale(cfg.AutoScale, cfg.Workers)
+	} else {
+		l.workers = 0
 	}
 
 	// --- Initialize Atomic and Dynamic Config ---
-	l.minLevel.Store(int32(cfg.MinLevel))
+	l.minLevel.Store(int32(cfg.MinLevel.Base()))
 	l.jsonFmtFlag.Store(cfg.JSON)
 	l.enableOTel.Store(cfg.EnableOTel)
-	l.batchSizeA.Store(int64(cfg.Batch.Size))
-	l.batchWaitA.Store(int64(cfg.Batch.MaxWait))
+	l.closeDiag = cfg.CloseDiagnostics
+	l.OnWriteError(cfg.OnWriteError)
+	l.SetFailureHook(cfg.FailureHook)
+	l.storeBatch(cfg.Batch)
+	l.SetQuota(cfg.Quota)
+	l.SetBudget(cfg.Budget)
+	l.SetKeyNormalization(cfg.KeyNormalization)
+	l.SetProfileLabels(cfg.ProfileLabels)
+	l.SetTraceAwareDebug(cfg.TraceAwareDebug)
+	l.SetSpanEvents(cfg.SpanEvents, cfg.SpanEventLevel)
+	l.SetValidation(cfg.Validation)
+	l.SetFlags(cfg.Flags)
+	l.SetShredding(cfg.Shredding)
+	l.SetBlobRules(cfg.BlobRules)
+	l.SetSampling(cfg.Sampling)
+	l.SetReportCaller(cfg.ReportCaller, cfg.CallerSkip)
+	l.SetModuleFromCaller(cfg.ModuleFromCaller, cfg.CallerModuleFunc)
+	l.SetErrorStacks(cfg.ErrorStacks)
+	if cfg.Fatal != (FatalConfig{}) {
+		l.SetFatalConfig(cfg.Fatal)
+	}
+	l.SetExitFunc(cfg.ExitFunc)
+	for _, fn := range cfg.ExitHooks {
+		l.OnExit(fn)
+	}
+	for _, fn := range cfg.LifecycleListeners {
+		l.OnLifecycle(fn)
+	}
+	l.SetSigning(cfg.Signing)
+	l.clock.init()
+	l.SetClockJumpThreshold(cfg.ClockJumpThreshold)
+	l.SetTimestampConfig(cfg.Timestamps)
+	l.SetEntryTTL(cfg.EntryTTL)
+	l.SetIDBackfill(cfg.IDBackfill)
+	l.SetPauseConfig(cfg.Pause)
+	l.SetSequence(cfg.Sequence)
+	for sink, lim := range cfg.SinkRates {
+		l.SetSinkRateLimit(sink, &lim)
+	}
+	l.SetMigration(cfg.Migration)
+	l.SetShadow(cfg.Shadow)
+	if err := l.SetOTLP(cfg.OTLP); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if err := l.SetSyslog(cfg.Syslog); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	l.SetHealthConfig(cfg.Health)
+	l.configSources = cfg.ConfigSources
 
 	// Initialize dynamic config for runtime changes.
-	l.dynConfig.MinLevel = cfg.MinLevel
+	l.dynConfig.MinLevel = cfg.MinLevel.Base()
 	l.dynConfig.RegexRules = cfg.RegexRules
 	l.dynConfig.JSONFieldRules = cfg.JSONFieldRules
 	l.dynConfig.Retry = cfg.Retry
 	l.dynConfig.Hooks = cfg.Hooks
+	l.dynConfig.Hooks2 = cfg.Hooks2
+	l.dynConfig.FilteredHooks = cfg.FilteredHooks
 	l.dynConfig.Batch = cfg.Batch
 
 	// --- Initialize Writers ---
 	l.extraW = buildExtraSinks(cfg.Writers, cfg.WriterNames)
+	l.retentionSinks = buildRetentionSinks(cfg.Retention)
+	l.retentionDefault = cfg.Retention.Default
 	if cfg.Rotation.Enable {
 		if w := initRotationWriter(cfg.Rotation); w != nil {
 			l.rotationSink = &writerSink{
@@ -219,18 +491,32 @@ func buildExtraSinks(ws []io.Writer, names []string) []writerSink {
 
 // start begins the logger's background processing goroutines (workers and hooks).
 func (l *Logger) start() {
+	l.startedAt.Store(time.Now().UnixNano())
 	l.startWorkers()
-	if l.hookAsync {
-		l.startHookRunner()
-	}
+	l.startHookRunner()
+	l.started.Store(true)
+	l.emitLifecycle(LifecycleEvent{Type: LifecycleStarted})
 }
 
-// startWorkers launches the worker goroutines that process and write log entries.
+// startWorkers launches the worker goroutines that process and write log entries,
+// and the auto-scaler if it is enabled.
 func (l *Logger) startWorkers() {
+	l.workersMu.Lock()
 	for i := 0; i < l.workers; i++ {
-		l.wg.Add(1)
-		go l.workerLoop()
+		l.spawnWorkerLocked()
+	}
+	l.workersMu.Unlock()
+	l.startAutoScaler()
+}
+
+// loadGlobal returns the global logger, initializing it with defaults on first use.
+// Once a logger is installed this is a single atomic load, with no lock acquisition.
+func loadGlobal() *Logger {
+	if l := globalLogger.Load(); l != nil {
+		return l
 	}
+	ensureInit()
+	return globalLogger.Load()
 }
 
 // ensureInit guarantees that the global logger is initialized, preventing nil panics.
@@ -238,11 +524,11 @@ func (l *Logger) startWorkers() {
 // this function will initialize it once with default settings (INFO level, UTC timezone).
 // This allows the logger to work out-of-the-box with zero configuration.
 func ensureInit() {
+	if globalLogger.Load() != nil {
+		return // Fast path: already initialized, no synchronization beyond the atomic load.
+	}
 	ensureInitOnce.Do(func() {
-		globalMu.RLock()
-		alreadyInitialized := globalLogger != nil
-		globalMu.RUnlock()
-		if alreadyInitialized {
+		if globalLogger.Load() != nil {
 			return
 		}
 		// If no logger is configured, initialize with basic defaults.
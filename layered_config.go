@@ -0,0 +1,418 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements layered configuration files. A base JSON file is merged with an
+// optional per-environment override (for example logger.json and logger.prod.json), read
+// from the file system or an embedded FS, and applied on top of a programmatic Config. The
+// effective configuration of a running logger can be inspected at any time.
+
+package unologger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// EnvVar is the environment variable read by LoadLayeredConfig when no environment is given.
+const EnvVar = "UNOLOGGER_ENV"
+
+// ParseLevel returns the level named s, case-insensitively. "WARNING" is accepted as WARN,
+// and the names of the custom levels registered with RegisterLevel are accepted.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	switch name {
+	case "TRACE":
+		return TRACE, nil
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN", "WARNING":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "FATAL":
+		return FATAL, nil
+	default:
+		if lvl, ok := customLevelByName(name); ok {
+			return lvl, nil
+		}
+		return 0, fmt.Errorf("unologger: unknown level %q", s)
+	}
+}
+
+// Duration is a time.Duration written in configuration files as a string such as "1.5s".
+// A JSON number is read as nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("unologger: invalid duration %s", b)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("unologger: invalid duration %q: %w", s, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// FileConfig is the part of Config that can be set from configuration files. Its JSON form
+// is the file format read by LoadLayeredConfig.
+type FileConfig struct {
+	MinLevel       string              `json:"min_level"`
+	Timezone       string              `json:"timezone"`
+	JSON           bool                `json:"json"`
+	Buffer         int                 `json:"buffer"`
+	Workers        int                 `json:"workers"`
+	NonBlocking    bool                `json:"non_blocking"`
+	DropOldest     bool                `json:"drop_oldest"`
+	SingleWriter   bool                `json:"single_writer"`
+	EnableOTel     bool                `json:"enable_otel"`
+	Batch          FileBatchConfig     `json:"batch"`
+	Retry          FileRetryPolicy     `json:"retry"`
+	Hook           FileHookConfig      `json:"hook"`
+	RegexPatterns  map[string]string   `json:"regex_patterns,omitempty"`
+	JSONFieldRules []FileMaskFieldRule `json:"json_field_rules,omitempty"`
+	Rotation       FileRotationConfig  `json:"rotation"`
+	Quota          FileQuotaConfig     `json:"quota"`
+	Budget         FileBudgetConfig    `json:"budget"`
+}
+
+// FileBatchConfig is the file form of BatchConfig. An empty FlushLevel disables
+// FlushOnLevel.
+type FileBatchConfig struct {
+	Size       int      `json:"size"`
+	MaxWait    Duration `json:"max_wait"`
+	FlushLevel string   `json:"flush_level,omitempty"`
+}
+
+// FileRetryPolicy is the file form of RetryPolicy.
+type FileRetryPolicy struct {
+	MaxRetries  int      `json:"max_retries"`
+	Backoff     Duration `json:"backoff"`
+	Jitter      Duration `json:"jitter"`
+	Exponential bool     `json:"exponential"`
+}
+
+// FileHookConfig is the file form of HookConfig.
+type FileHookConfig struct {
+	Async   bool     `json:"async"`
+	Workers int      `json:"workers"`
+	Queue   int      `json:"queue"`
+	Timeout Duration `json:"timeout"`
+}
+
+// FileMaskFieldRule is the file form of MaskFieldRule.
+type FileMaskFieldRule struct {
+	Keys        []string `json:"keys"`
+	Replacement string   `json:"replacement"`
+	Name        string   `json:"name,omitempty"`
+	Priority    int      `json:"priority,omitempty"`
+}
+
+// FileRotationConfig is the file form of RotationConfig.
+type FileRotationConfig struct {
+	Enable     bool   `json:"enable"`
+	Filename   string `json:"filename"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxAge     int    `json:"max_age"`
+	MaxBackups int    `json:"max_backups"`
+	Compress   bool   `json:"compress"`
+}
+
+// FileQuotaConfig is the file form of QuotaConfig.
+type FileQuotaConfig struct {
+	Daily           map[string]int64 `json:"daily,omitempty"`
+	Default         int64            `json:"default"`
+	SummaryInterval Duration         `json:"summary_interval"`
+}
+
+// FileBudgetConfig is the file form of BudgetConfig.
+type FileBudgetConfig struct {
+	DailyBytes int64    `json:"daily_bytes"`
+	Interval   Duration `json:"interval"`
+	MinRate    float64  `json:"min_rate"`
+}
+
+// fileConfigFrom returns the file form of the settings of cfg.
+func fileConfigFrom(cfg Config) FileConfig {
+	fc := FileConfig{
+		MinLevel:     cfg.MinLevel.String(),
+		Timezone:     cfg.Timezone,
+		JSON:         cfg.JSON,
+		Buffer:       cfg.Buffer,
+		Workers:      cfg.Workers,
+		NonBlocking:  cfg.NonBlocking,
+		DropOldest:   cfg.DropOldest,
+		SingleWriter: cfg.SingleWriter,
+		EnableOTel:   cfg.EnableOTel,
+		Batch:        FileBatchConfig{Size: cfg.Batch.Size, MaxWait: Duration(cfg.Batch.MaxWait)},
+		Retry: FileRetryPolicy{
+			MaxRetries:  cfg.Retry.MaxRetries,
+			Backoff:     Duration(cfg.Retry.Backoff),
+			Jitter:      Duration(cfg.Retry.Jitter),
+			Exponential: cfg.Retry.Exponential,
+		},
+		Hook: FileHookConfig{
+			Async:   cfg.Hook.Async,
+			Workers: cfg.Hook.Workers,
+			Queue:   cfg.Hook.Queue,
+			Timeout: Duration(cfg.Hook.Timeout),
+		},
+		Rotation: FileRotationConfig(cfg.Rotation),
+		Quota: FileQuotaConfig{
+			Daily:           cfg.Quota.Daily,
+			Default:         cfg.Quota.Default,
+			SummaryInterval: Duration(cfg.Quota.SummaryInterval),
+		},
+		Budget: FileBudgetConfig{
+			DailyBytes: cfg.Budget.DailyBytes,
+			Interval:   Duration(cfg.Budget.Interval),
+			MinRate:    cfg.Budget.MinRate,
+		},
+	}
+	if cfg.Batch.FlushOnLevel {
+		fc.Batch.FlushLevel = cfg.Batch.FlushLevel.String()
+	}
+	if len(cfg.RegexPatternMap) > 0 {
+		fc.RegexPatterns = make(map[string]string, len(cfg.RegexPatternMap))
+		for k, v := range cfg.RegexPatternMap {
+			fc.RegexPatterns[k] = v
+		}
+	}
+	for _, r := range cfg.JSONFieldRules {
+		fc.JSONFieldRules = append(fc.JSONFieldRules, FileMaskFieldRule(r))
+	}
+	return fc
+}
+
+// apply writes the settings of fc to cfg.
+func (fc FileConfig) apply(cfg *Config) error {
+	minLevel, err := ParseLevel(fc.MinLevel)
+	if err != nil {
+		return err
+	}
+	cfg.MinLevel = minLevel
+	cfg.Timezone = fc.Timezone
+	cfg.JSON = fc.JSON
+	cfg.Buffer = fc.Buffer
+	cfg.Workers = fc.Workers
+	cfg.NonBlocking = fc.NonBlocking
+	cfg.DropOldest = fc.DropOldest
+	cfg.SingleWriter = fc.SingleWriter
+	cfg.EnableOTel = fc.EnableOTel
+	cfg.Batch.Size = fc.Batch.Size
+	cfg.Batch.MaxWait = time.Duration(fc.Batch.MaxWait)
+	cfg.Batch.FlushOnLevel = fc.Batch.FlushLevel != ""
+	if cfg.Batch.FlushOnLevel {
+		if cfg.Batch.FlushLevel, err = ParseLevel(fc.Batch.FlushLevel); err != nil {
+			return err
+		}
+	}
+	cfg.Retry = RetryPolicy{
+		MaxRetries:  fc.Retry.MaxRetries,
+		Backoff:     time.Duration(fc.Retry.Backoff),
+		Jitter:      time.Duration(fc.Retry.Jitter),
+		Exponential: fc.Retry.Exponential,
+	}
+	cfg.Hook = HookConfig{
+		Async:   fc.Hook.Async,
+		Workers: fc.Hook.Workers,
+		Queue:   fc.Hook.Queue,
+		Timeout: time.Duration(fc.Hook.Timeout),
+	}
+	cfg.RegexPatternMap = fc.RegexPatterns
+	cfg.JSONFieldRules = nil
+	for _, r := range fc.JSONFieldRules {
+		cfg.JSONFieldRules = append(cfg.JSONFieldRules, MaskFieldRule(r))
+	}
+	cfg.Rotation = RotationConfig(fc.Rotation)
+	cfg.Quota = QuotaConfig{
+		Daily:           fc.Quota.Daily,
+		Default:         fc.Quota.Default,
+		SummaryInterval: time.Duration(fc.Quota.SummaryInterval),
+	}
+	cfg.Budget = BudgetConfig{
+		DailyBytes: fc.Budget.DailyBytes,
+		Interval:   time.Duration(fc.Budget.Interval),
+		MinRate:    fc.Budget.MinRate,
+	}
+	return nil
+}
+
+// LayeredConfig is the result of merging configuration layers. Later layers override the
+// keys they set; objects are merged key by key, while arrays and scalars are replaced.
+type LayeredConfig struct {
+	// Sources lists the layers that were merged, in order.
+	Sources []string
+	merged  map[string]interface{}
+}
+
+// LoadLayeredConfig reads the base layer name+".json" from fsys and, if it exists, the
+// override layer name+"."+env+".json". If env is empty, the UNOLOGGER_ENV environment
+// variable is used. Use os.DirFS for a directory or an embed.FS for embedded files:
+//
+//	lc, err := unologger.LoadLayeredConfig(os.DirFS("config"), "logger", "prod")
+//	cfg, err := lc.Apply(unologger.Config{Stdout: os.Stdout})
+//	unologger.InitLoggerWithConfig(cfg)
+func LoadLayeredConfig(fsys fs.FS, name, env string) (*LayeredConfig, error) {
+	if env == "" {
+		env = os.Getenv(EnvVar)
+	}
+	files := []string{name + ".json"}
+	if env != "" {
+		files = append(files, name+"."+env+".json")
+	}
+	lc := &LayeredConfig{merged: map[string]interface{}{}}
+	for i, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			if i > 0 && errors.Is(err, fs.ErrNotExist) {
+				continue // The environment override is optional.
+			}
+			return nil, fmt.Errorf("unologger: read config layer: %w", err)
+		}
+		if err := lc.AddLayer(path.Base(file), data); err != nil {
+			return nil, err
+		}
+	}
+	return lc, nil
+}
+
+// MergeConfigLayers merges JSON layers given in memory, in order.
+func MergeConfigLayers(layers ...[]byte) (*LayeredConfig, error) {
+	lc := &LayeredConfig{merged: map[string]interface{}{}}
+	for i, data := range layers {
+		if err := lc.AddLayer(fmt.Sprintf("layer %d", i), data); err != nil {
+			return nil, err
+		}
+	}
+	return lc, nil
+}
+
+// AddLayer merges one more JSON layer on top of the current ones.
+func (lc *LayeredConfig) AddLayer(source string, data []byte) error {
+	var layer map[string]interface{}
+	if err := json.Unmarshal(data, &layer); err != nil {
+		return fmt.Errorf("unologger: config layer %s: %w", source, err)
+	}
+	if lc.merged == nil {
+		lc.merged = map[string]interface{}{}
+	}
+	mergeLayer(lc.merged, layer)
+	lc.Sources = append(lc.Sources, source)
+	return nil
+}
+
+// mergeLayer merges src into dst, recursing into objects present in both.
+func mergeLayer(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if sm, ok := v.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				mergeLayer(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// Apply returns cfg with the settings of the merged layers applied on top. Settings the
+// layers do not mention, and fields that cannot be set from files (writers, hooks,
+// formatters), keep their values from cfg. Unknown keys are reported as errors, which
+// catches typos in configuration files.
+func (lc *LayeredConfig) Apply(cfg Config) (Config, error) {
+	fc := fileConfigFrom(cfg)
+	data, err := json.Marshal(lc.merged)
+	if err != nil {
+		return cfg, fmt.Errorf("unologger: merge config layers: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return cfg, fmt.Errorf("unologger: apply config layers %v: %w", lc.Sources, err)
+	}
+	if err := fc.apply(&cfg); err != nil {
+		return cfg, err
+	}
+	cfg.ConfigSources = append([]string(nil), lc.Sources...)
+	return cfg, nil
+}
+
+// EffectiveConfig returns the file form of the settings currently in effect, including
+// changes made at runtime through the dynamic configuration API. Writers, hooks and
+// formatters are not represented. Regex masking rules are reported by pattern.
+func (l *Logger) EffectiveConfig() FileConfig {
+	dc := l.GetDynamicConfig()
+	l.locMu.RLock()
+	tz := l.loc.String()
+	l.locMu.RUnlock()
+
+	cfg := Config{
+		MinLevel:       dc.MinLevel,
+		Timezone:       tz,
+		JSON:           l.jsonFmtFlag.Load(),
+		Buffer:         l.BufferSize(),
+		Workers:        l.Workers(),
+		NonBlocking:    l.nonBlocking,
+		DropOldest:     l.dropOldest,
+		SingleWriter:   l.direct,
+		EnableOTel:     l.enableOTel.Load(),
+		Batch:          dc.Batch,
+		Retry:          dc.Retry,
+		Hook:           l.GetHookConfig(),
+		JSONFieldRules: dc.JSONFieldRules,
+		Quota:          l.GetQuota(),
+		Budget:         l.GetBudget(),
+	}
+	if len(dc.RegexRules) > 0 {
+		cfg.RegexPatternMap = make(map[string]string, len(dc.RegexRules))
+		for _, r := range dc.RegexRules {
+			cfg.RegexPatternMap[r.Pattern.String()] = r.Replacement
+		}
+	}
+	l.outputsMu.RLock()
+	if l.rotationSink != nil {
+		if lj, ok := l.rotationSink.Writer.(*lumberjack.Logger); ok {
+			cfg.Rotation = RotationConfig{
+				Enable:     true,
+				Filename:   lj.Filename,
+				MaxSizeMB:  lj.MaxSize,
+				MaxAge:     lj.MaxAge,
+				MaxBackups: lj.MaxBackups,
+				Compress:   lj.Compress,
+			}
+		}
+	}
+	l.outputsMu.RUnlock()
+	return fileConfigFrom(cfg)
+}
+
+// ConfigSources returns the configuration layers the logger was created from, in order,
+// or nil if it was not created from layered configuration.
+func (l *Logger) ConfigSources() []string {
+	return append([]string(nil), l.configSources...)
+}
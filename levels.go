@@ -0,0 +1,194 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the TRACE level and the custom levels. TRACE sits below DEBUG for the
+// very fine-grained diagnostics of libraries, and is disabled unless the minimum level is
+// lowered to it. Custom levels, such as AUDIT or NOTICE, carry their own name in the output
+// and can be selected by hooks, while behaving as a built-in level for everything that
+// depends on severity: the minimum level, sampling, routing to stderr and severity numbers.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TRACE level is for very fine-grained diagnostics, below DEBUG. It is disabled unless the
+// minimum level is TRACE, e.g. with SetMinLevel(TRACE) or -log-level trace.
+const TRACE Level = DEBUG - 1
+
+// numLevels is the number of built-in levels, from TRACE to FATAL.
+const numLevels = FATAL - TRACE + 1
+
+// levelOff is stored in place of a level threshold to disable it. It is below every level,
+// so that no level, TRACE included, can be mistaken for it.
+const levelOff = math.MinInt64
+
+// firstCustomLevel is the value of the first registered custom level.
+const firstCustomLevel Level = 16
+
+// customLevel is a registered custom level.
+type customLevel struct {
+	name string
+	base Level
+}
+
+// customLevels is the registry of the custom levels. Readers load the map without locking;
+// RegisterLevel replaces it under mu.
+var customLevels struct {
+	mu     sync.Mutex
+	levels atomic.Pointer[map[Level]customLevel]
+}
+
+// RegisterLevel defines a custom level named name, which behaves as base, and returns it.
+// The level is logged with Log and written with its own name, e.g. "AUDIT", while the
+// minimum level, sampling, routing to stderr, colors and severity numbers treat it as base:
+//
+//	var AUDIT = unologger.MustRegisterLevel("AUDIT", unologger.INFO)
+//
+//	lw.Log(AUDIT, "user %s deleted invoice %d", user, id)
+//
+// A hook can select the entries of the level with HookFilter.Levels. base must be a built-in
+// level from TRACE to ERROR: FATAL ends the process, which Log does not. Names are case
+// insensitive, and must not be empty or taken by another level. Custom levels are shared by
+// all the loggers of the process, so they are usually registered by package variables.
+func RegisterLevel(name string, base Level) (Level, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return 0, fmt.Errorf("unologger: invalid level name %q", name)
+	}
+	if base < TRACE || base > ERROR {
+		return 0, fmt.Errorf("unologger: level %s cannot be the base of a custom level", base)
+	}
+	reg := &customLevels
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, err := ParseLevel(name); err == nil {
+		return 0, fmt.Errorf("unologger: level %q already exists", name)
+	}
+	var old map[Level]customLevel
+	if m := reg.levels.Load(); m != nil {
+		old = *m
+	}
+	m := make(map[Level]customLevel, len(old)+1)
+	for lvl, c := range old {
+		m[lvl] = c
+	}
+	lvl := firstCustomLevel + Level(len(old))
+	m[lvl] = customLevel{name: name, base: base}
+	reg.levels.Store(&m)
+	return lvl, nil
+}
+
+// MustRegisterLevel is like RegisterLevel but panics if the level cannot be registered.
+func MustRegisterLevel(name string, base Level) Level {
+	lvl, err := RegisterLevel(name, base)
+	if err != nil {
+		panic(err)
+	}
+	return lvl
+}
+
+// lookupCustomLevel returns the custom level lvl, if registered.
+func lookupCustomLevel(lvl Level) (customLevel, bool) {
+	if lvl < firstCustomLevel {
+		return customLevel{}, false
+	}
+	m := customLevels.levels.Load()
+	if m == nil {
+		return customLevel{}, false
+	}
+	c, ok := (*m)[lvl]
+	return c, ok
+}
+
+// customLevelByName returns the custom level named name, in upper case, if registered.
+func customLevelByName(name string) (Level, bool) {
+	m := customLevels.levels.Load()
+	if m == nil {
+		return 0, false
+	}
+	for lvl, c := range *m {
+		if c.name == name {
+			return lvl, true
+		}
+	}
+	return 0, false
+}
+
+// Base returns the built-in level lvl behaves as: the base of a custom level, and lvl itself
+// for the built-in levels.
+func (lvl Level) Base() Level {
+	if c, ok := lookupCustomLevel(lvl); ok {
+		return c.base
+	}
+	return lvl
+}
+
+// IsCustom reports whether lvl is a level registered with RegisterLevel.
+func (lvl Level) IsCustom() bool {
+	_, ok := lookupCustomLevel(lvl)
+	return ok
+}
+
+// Log logs a message at level, which may be a custom level. A FATAL level exits like Fatal.
+func (l *Logger) Log(ctx context.Context, level Level, format string, args ...interface{}) {
+	if level == FATAL {
+		l.fatal(ctx, nil, format, args...)
+		return
+	}
+	l.log(ctx, level, format, args...)
+}
+
+// Trace logs a message at the TRACE level.
+func (l *Logger) Trace(ctx context.Context, format string, args ...interface{}) {
+	l.log(ctx, TRACE, format, args...)
+}
+
+// Log logs a formatted message at level, which may be a custom level, using the logger's
+// context. A FATAL level exits like Fatal.
+func (lw LoggerWithCtx) Log(level Level, format string, args ...interface{}) {
+	if level == FATAL {
+		lw.l.fatal(lw.ctx, lw.fields, format, args...)
+		return
+	}
+	lw.l.logFields(lw.ctx, level, lw.fields, format, args...)
+}
+
+// LogW logs a message with fields at level, which may be a custom level, using the logger's
+// context. A FATAL level exits like FatalW.
+func (lw LoggerWithCtx) LogW(level Level, msg string, fields Fields) {
+	if level == FATAL {
+		lw.l.fatal(lw.ctx, withFields(lw.fields, fields), msg, literalArgs...)
+		return
+	}
+	lw.l.logFields(lw.ctx, level, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// Trace logs a formatted message at TRACE level using the logger's context.
+func (lw LoggerWithCtx) Trace(format string, args ...interface{}) {
+	lw.l.logFields(lw.ctx, TRACE, lw.fields, format, args...)
+}
+
+// Trace logs a formatted message at TRACE level with the logger's fields.
+func (fl FieldLogger) Trace(ctx context.Context, format string, args ...interface{}) {
+	fl.l.logFields(ctx, TRACE, fl.fields, format, args...)
+}
+
+// Tracef logs a formatted message at TRACE level using the global logger.
+func Tracef(format string, args ...interface{}) {
+	lw := backgroundLogger()
+	lw.l.log(lw.ctx, TRACE, format, args...)
+}
+
+// Logf logs a formatted message at level, which may be a custom level, using the global
+// logger. A FATAL level exits like Fatalf.
+func Logf(level Level, format string, args ...interface{}) {
+	backgroundLogger().Log(level, format, args...)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements lifecycle events. Listeners are told when a logger starts, when its
+// configuration changes at runtime, when a sink is added or quarantined and when the logger
+// shuts down, so that platform teams can audit who changed logging behavior and when.
+
+package unologger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// LifecycleEventType identifies a lifecycle event.
+type LifecycleEventType string
+
+// Lifecycle event types.
+const (
+	// LifecycleStarted is emitted once the workers of a logger are running.
+	LifecycleStarted LifecycleEventType = "started"
+	// LifecycleConfigChanged is emitted after a setter of the dynamic configuration API
+	// (SetMinLevel, the masking rules, SetRetryPolicy, SetHooks, SetBatchConfig,
+	// SetJSONFormat, SetFormatter, SetTimezone, SetEnableOTEL, the writers) changed a
+	// setting; Setting names it, and Old, New and Actor describe the change as recorded by
+	// ConfigChanges.
+	LifecycleConfigChanged LifecycleEventType = "config-changed"
+	// LifecycleSinkAdded is emitted after AddExtraWriter, SetOutputs or SetRotation added a
+	// sink; Sink names it.
+	LifecycleSinkAdded LifecycleEventType = "sink-added"
+	// LifecycleSinkQuarantined is emitted when a sink has failed HealthConfig.SinkFailures
+	// times in a row; Sink names it and Err holds its last error. It is emitted again only
+	// after the sink has recovered and failed as many times again.
+	LifecycleSinkQuarantined LifecycleEventType = "sink-quarantined"
+	// LifecyclePaused is emitted once Pause has written the entries queued before it.
+	LifecyclePaused LifecycleEventType = "paused"
+	// LifecycleResumed is emitted once Resume has queued the entries held while paused.
+	LifecycleResumed LifecycleEventType = "resumed"
+	// LifecycleClosing is emitted when the shutdown of a logger starts.
+	LifecycleClosing LifecycleEventType = "closing"
+	// LifecycleClosed is emitted once the queue is drained and the sinks are closed.
+	LifecycleClosed LifecycleEventType = "closed"
+)
+
+// LifecycleEvent describes a lifecycle event of a logger.
+type LifecycleEvent struct {
+	Type    LifecycleEventType
+	Time    time.Time
+	Setting string // Setting changed by a LifecycleConfigChanged event, e.g. "min_level".
+	Old     string // Previous value of Setting, as recorded by ConfigChanges.
+	New     string // New value of Setting, as recorded by ConfigChanges.
+	Actor   string // Actor of the change of Setting, if set with Configure.
+	Sink    string // Sink of a LifecycleSinkAdded or LifecycleSinkQuarantined event.
+	Err     error  // Last error of the sink of a LifecycleSinkQuarantined event.
+}
+
+// LifecycleListener receives lifecycle events.
+type LifecycleListener func(LifecycleEvent)
+
+// lifecycleListeners holds the listeners of a logger.
+type lifecycleListeners struct {
+	mu     sync.Mutex
+	nextID uint64
+	fns    []lifecycleEntry
+}
+
+// lifecycleEntry is a registered listener and the ID used to remove it.
+type lifecycleEntry struct {
+	id uint64
+	fn LifecycleListener
+}
+
+// OnLifecycle registers fn to receive the lifecycle events of the logger and returns a
+// function that removes it. Listeners are called synchronously, in registration order, by
+// the goroutine that caused the event: the caller of a setter, once the setter released its
+// locks, the worker that wrote a failing sink, or the goroutine closing the logger. They
+// must return quickly and must not log synchronously to the same logger. A listener that
+// panics is reported on stderr and does not affect the logger.
+func (l *Logger) OnLifecycle(fn LifecycleListener) (remove func()) {
+	if fn == nil {
+		return func() {}
+	}
+	ll := &l.lifecycle
+	ll.mu.Lock()
+	ll.nextID++
+	id := ll.nextID
+	ll.fns = append(ll.fns, lifecycleEntry{id: id, fn: fn})
+	ll.mu.Unlock()
+	return func() {
+		ll.mu.Lock()
+		defer ll.mu.Unlock()
+		for i, e := range ll.fns {
+			if e.id == id {
+				// The slice is replaced rather than modified: emitters iterate a snapshot.
+				ll.fns = append(ll.fns[:i:i], ll.fns[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emitLifecycle sends ev to the listeners. Events are only emitted once the logger has
+// started, so that the setters run while a logger is being built stay silent.
+func (l *Logger) emitLifecycle(ev LifecycleEvent) {
+	if !l.started.Load() {
+		return
+	}
+	l.lifecycle.mu.Lock()
+	fns := l.lifecycle.fns
+	l.lifecycle.mu.Unlock()
+	if len(fns) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	for _, e := range fns {
+		callLifecycleListener(e.fn, ev)
+	}
+}
+
+// callLifecycleListener calls fn, recovering from a panic.
+func callLifecycleListener(fn LifecycleListener, ev LifecycleEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "unologger: lifecycle listener panic on %s: %v\n", ev.Type, r)
+		}
+	}()
+	fn(ev)
+}
+
+// configChanged records a change of setting by actor in the audit trail and emits a
+// LifecycleConfigChanged event for it. It must be called without holding the locks of the
+// logger; setters use changing to defer it past their locks.
+func (l *Logger) configChanged(actor, setting, old, new string) {
+	if !l.started.Load() {
+		return
+	}
+	c := l.audit.record(actor, setting, old, new)
+	l.emitLifecycle(LifecycleEvent{Type: LifecycleConfigChanged, Setting: setting, Old: old, New: new, Actor: c.Actor})
+}
+
+// sinkAdded emits a LifecycleSinkAdded event for sink.
+func (l *Logger) sinkAdded(sink string) {
+	l.emitLifecycle(LifecycleEvent{Type: LifecycleSinkAdded, Sink: sink})
+}
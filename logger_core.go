@@ -10,7 +10,6 @@ package unologger
 
 import (
 	"context"
-	"os"
 	"time"
 )
 
@@ -36,19 +35,36 @@ func (l *Logger) Error(ctx context.Context, format string, args ...interface{})
 }
 
 // Fatal logs a message at the FATAL level, attempts to flush all buffered logs,
-// and then terminates the application with a call to os.Exit(1).
+// and then terminates the application with a call to os.Exit(1). The message is
+// written synchronously to stderr and the rotation file before anything else, so it
+// is not lost if the asynchronous pipeline is stuck. See FatalConfig, SetExitFunc and
+// FatalCode to wait for every sink, replace os.Exit or choose the exit code.
 func (l *Logger) Fatal(ctx context.Context, format string, args ...interface{}) {
-	l.log(ctx, FATAL, format, args...)
-	// Attempt a graceful shutdown of this logger instance before exiting.
-	_ = CloseDetached(l, 2*time.Second)
-	os.Exit(1)
+	l.fatal(ctx, nil, format, args...)
+}
+
+// exitAfterFatal runs the exit hooks, then attempts a graceful shutdown of this logger
+// instance, and flushes its delegates, so that the FATAL entry and any buffered logs reach
+// their outputs, then calls the exit function with code. The delegates, such as the global
+// logger or the members of a Tee, are owned by others and are not closed: an exit
+// function that returns leaves them working. Each logger is closed or flushed within its
+// FatalConfig.Timeout.
+func (l *Logger) exitAfterFatal(code int) {
+	targets := l.delegateTargets()
+	exitFn, timeout := l.exitFunc(), l.GetFatalConfig().timeout()
+	l.runExitHooks(code, timeout)
+	_ = CloseDetached(l, timeout)
+	for _, d := range targets {
+		_ = d.Flush(d.GetFatalConfig().timeout())
+	}
+	exit(exitFn, code)
 }
 
 // WithContext returns a new LoggerWithCtx, which is a lightweight wrapper that
 // binds the logger to a specific context. This is useful for creating context-aware
 // loggers that can be passed through application layers.
 func (l *Logger) WithContext(ctx context.Context) LoggerWithCtx {
-	return LoggerWithCtx{l: l, ctx: ctx}
+	return LoggerWithCtx{l: l, ctx: orBackground(ctx)}
 }
 
 // GlobalLogger returns the shared global logger instance.
@@ -57,10 +73,7 @@ func (l *Logger) WithContext(ctx context.Context) LoggerWithCtx {
 // for "zero-configuration" logging. For custom configurations, call
 // InitLoggerWithConfig at application startup.
 func GlobalLogger() *Logger {
-	ensureInit()
-	globalMu.RLock()
-	defer globalMu.RUnlock()
-	return globalLogger
+	return loadGlobal()
 }
 
 // log is the central, internal logging method. It is responsible for:
@@ -69,9 +82,42 @@ func GlobalLogger() *Logger {
 //  3. Populating the logEntry with the current time, context, and message details.
 //  4. Passing the populated entry to the enqueue method for asynchronous processing.
 func (l *Logger) log(ctx context.Context, level Level, format string, args ...interface{}) {
-	// Atomically check if the log level is high enough. This is a fast path
-	// to discard logs without the overhead of creating a log entry.
-	if level < Level(l.minLevel.Load()) {
+	l.logFields(ctx, level, nil, format, args...)
+}
+
+// literalMsg is the type of the marker of literalArgs.
+type literalMsg struct{}
+
+// literalArgs is passed as the arguments of a log call by the W methods, whose message is
+// not a format string, through the delegation, synchronous and emergency paths. The
+// entry is then marked literal, and its message is written verbatim, so that "100%" stays
+// "100%", while the message of a printf-style call is always formatted, "100%%" included.
+var literalArgs = []interface{}{literalMsg{}}
+
+// splitLiteral returns the format arguments of args, and whether args is literalArgs.
+func splitLiteral(args []interface{}) ([]interface{}, bool) {
+	if len(args) == 1 && args[0] == (literalMsg{}) {
+		return nil, true
+	}
+	return args, false
+}
+
+// logFields is the variant of log that also carries structured fields supplied
+// directly at the call site. These fields are merged over the context attributes
+// when the entry is processed by a worker.
+func (l *Logger) logFields(ctx context.Context, level Level, fields Fields, format string, args ...interface{}) {
+	ctx = orBackground(ctx)
+	for _, d := range l.delegateTargets() {
+		d.logFields(ctx, level, fields, format, args...)
+	}
+	// Check if the log level is high enough, against the feature flags of the entry's
+	// module and tenant if a flag provider is set. This is a fast path to discard logs
+	// without the overhead of creating a log entry.
+	if !l.levelEnabled(ctx, level, true) {
+		return
+	}
+	// Trace-aware throttling and cost-aware sampling, both no-ops unless configured.
+	if l.traceThrottle(ctx, level) || l.sampledOut(level) || l.rateSampledOut(ctx, level, format) {
 		return
 	}
 
@@ -85,9 +131,13 @@ func (l *Logger) log(ctx context.Context, level Level, format string, args ...in
 	entry.ctx = ctx
 	entry.t = time.Now()
 	entry.tmpl = format
-	entry.args = args
-	// Note: entry.fields is not populated here. It's extracted from the context
-	// later in the pipeline during formatting.
+	entry.args, entry.literal = splitLiteral(args)
+	// Context attributes are extracted later in the pipeline; only call-site
+	// fields are stored on the entry itself.
+	entry.fields = fields
+	l.captureCaller(entry)
+	l.stampBackfill(entry)
+	l.stampModule(entry)
 
 	// Hand off the entry to the asynchronous processing pipeline.
 	l.enqueue(entry)
@@ -17,7 +17,7 @@ import (
 )
 
 // Level represents the severity of a log entry.
-// The zero value for Level is DEBUG.
+// The zero value for Level is DEBUG. See also TRACE and RegisterLevel.
 type Level int32
 
 // Log level constants.
@@ -34,9 +34,12 @@ const (
 	FATAL
 )
 
-// String returns the uppercase string representation of the log level.
+// String returns the uppercase string representation of the log level, which is the
+// registered name for a custom level.
 func (lvl Level) String() string {
 	switch lvl {
+	case TRACE:
+		return "TRACE"
 	case DEBUG:
 		return "DEBUG"
 	case INFO:
@@ -48,6 +51,9 @@ func (lvl Level) String() string {
 	case FATAL:
 		return "FATAL"
 	default:
+		if c, ok := lookupCustomLevel(lvl); ok {
+			return c.name
+		}
 		return "UNKNOWN"
 	}
 }
@@ -86,9 +92,9 @@ type HookConfig struct {
 	// Queue is the buffer size for the asynchronous hook event channel.
 	// Only applies if Async is true. Defaults to 1024.
 	Queue int
-	// Timeout is the maximum duration to wait for a single hook to execute.
-	// If a hook exceeds this timeout, it is abandoned, and an error is logged.
-	// If 0, there is no timeout. Defaults to 0.
+	// Timeout is the maximum duration a single hook may run. It is passed to each hook as
+	// a context deadline, and a hook that overruns it is recorded with ErrHookTimeout once
+	// it returns. If 0, there is no timeout. Defaults to 0.
 	Timeout time.Duration
 }
 
@@ -97,23 +103,55 @@ type HookConfig struct {
 type BatchConfig struct {
 	// Size is the maximum number of log entries to include in a single batch.
 	// When a batch reaches this size, it is flushed. Defaults to 1 (no batching).
+	// With a size of 1 the workers run in streaming mode: each entry is written as soon
+	// as it is received and no batch timer is used, so MaxWait has no effect.
 	Size int
 	// MaxWait is the maximum time to wait before flushing a batch, even if it's not full.
-	// This ensures logs are not held in memory for too long during periods of low activity.
-	// Defaults to 1 second.
+	// It is measured from the first entry of the batch and ensures logs are not held in
+	// memory for too long during periods of low activity. Defaults to 1 second.
 	MaxWait time.Duration
+	// FlushOnLevel, if true, makes an entry at or above FlushLevel flush its worker's
+	// batch immediately, so critical messages are not delayed by MaxWait.
+	FlushOnLevel bool
+	// FlushLevel is the minimum level that triggers an immediate flush when FlushOnLevel
+	// is set, e.g. ERROR.
+	FlushLevel Level
+}
+
+// AutoScaleConfig configures automatic scaling of the worker pool. The auto-scaler
+// periodically samples the fill ratio of the queues, the priority queue included, and adds or
+// removes one worker at a time.
+type AutoScaleConfig struct {
+	// Enable turns auto-scaling on.
+	Enable bool
+	// MinWorkers is the lower bound for the pool size. Defaults to Config.Workers.
+	MinWorkers int
+	// MaxWorkers is the upper bound for the pool size. Defaults to 4 × MinWorkers.
+	MaxWorkers int
+	// Interval is the time between samples. Defaults to 1 second.
+	Interval time.Duration
+	// HighWatermark is the queue fill ratio (0-1) above which a worker is added.
+	// Defaults to 0.75.
+	HighWatermark float64
+	// LowWatermark is the queue fill ratio (0-1) below which a worker is removed.
+	// Defaults to 0.10.
+	LowWatermark float64
 }
 
 // MaskRuleRegex defines a single regex-based masking rule.
 type MaskRuleRegex struct {
 	Pattern     *regexp.Regexp // The compiled regular expression to match.
 	Replacement string         // The string to replace matched content with.
+	Name        string         // Optional name, used by AddRegexRule and RemoveRegexRule.
+	Priority    int            // Rules with a higher priority are applied first; see MaskingRules.
 }
 
 // MaskFieldRule defines a rule for masking specific fields in structured (JSON) logs.
 type MaskFieldRule struct {
 	Keys        []string // The list of JSON field keys to mask (e.g., "password", "credit_card").
 	Replacement string   // The string that will replace the original field's value.
+	Name        string   // Optional name, used by AddFieldRule and RemoveFieldRule.
+	Priority    int      // Rules with a higher priority are matched first; see MaskingRules.
 }
 
 // RotationConfig configures log file rotation using the lumberjack library.
@@ -162,8 +200,20 @@ type Config struct {
 	// buffer to make room for the new one. If false, the new entry is dropped.
 	// This has no effect if NonBlocking is false.
 	DropOldest bool
+	// PriorityQueue optionally adds a second queue for severe entries, which the workers
+	// drain before the main queue. Disabled by default.
+	PriorityQueue PriorityQueueConfig
 	// Batch configures log batching. Defaults to disabled (size 1).
 	Batch BatchConfig
+	// AutoScale optionally grows and shrinks the worker pool based on queue depth.
+	// Disabled by default.
+	AutoScale AutoScaleConfig
+	// SingleWriter, if true, collapses the pipeline to a lock-protected direct write path:
+	// no channel, no worker goroutines and no batching. Each log call formats and writes
+	// its entry synchronously. This suits CLIs and short-lived tools where goroutine
+	// startup/shutdown overhead is undesirable. Buffer, Workers, NonBlocking, DropOldest
+	// and Batch are ignored in this mode. Async hooks still use their own worker pool.
+	SingleWriter bool
 	// Stdout is the writer for INFO and DEBUG logs. Defaults to os.Stdout.
 	Stdout io.Writer
 	// Stderr is the writer for WARN, ERROR, and FATAL logs. Defaults to os.Stderr.
@@ -176,6 +226,11 @@ type Config struct {
 	Retry RetryPolicy
 	// Hooks is a slice of functions to be executed for each log entry.
 	Hooks []HookFunc
+	// Hooks2 is a slice of context-aware hooks executed for each log entry after Hooks.
+	Hooks2 []HookFunc2
+	// FilteredHooks are executed after Hooks2 for the entries their filter selects. See
+	// AddHook.
+	FilteredHooks []FilteredHook
 	// Hook configures the hook execution system (async, timeouts, etc.).
 	Hook HookConfig
 	// RegexRules is a slice of pre-compiled regex masking rules.
@@ -187,8 +242,127 @@ type Config struct {
 	JSONFieldRules []MaskFieldRule
 	// Rotation configures log file rotation. Disabled by default.
 	Rotation RotationConfig
+	// Retention routes entries to dedicated sinks by retention class. Disabled by default.
+	Retention RetentionConfig
 	// EnableOTel, if true, enables automatic extraction of Trace and Span IDs from OpenTelemetry contexts.
 	EnableOTel bool
+	// SpanEvents, if true, adds every entry at or above SpanEventLevel to the active span as
+	// an OTel span event. See Logger.SetSpanEvents.
+	SpanEvents bool
+	// SpanEventLevel is the minimum level recorded as a span event when SpanEvents is set.
+	SpanEventLevel Level
+	// TraceAwareDebug, if true and EnableOTel is set, discards DEBUG entries of traces that
+	// are not sampled. See Logger.SetTraceAwareDebug.
+	TraceAwareDebug bool
+	// OnWriteError, if set, is called for every entry whose write to a sink failed after
+	// all retries. See Logger.OnWriteError.
+	OnWriteError WriteErrorHandler
+	// FailureHook, if set, receives every entry whose write to a sink failed after all
+	// retries, like a hook. See Logger.SetFailureHook.
+	FailureHook FailureHookFunc
+	// Delegate, if set, receives every entry logged through the logger, before the logger
+	// writes it to its own sinks. Stdout and Stderr then default to io.Discard, so that only
+	// the sinks configured explicitly are added to those of Delegate. See DelegateGlobal.
+	Delegate *Logger
+	// DelegateGlobal, if true and Delegate is nil, delegates to the global logger, resolved
+	// on every call so that delegation follows ReinitGlobalLogger. Stdout and Stderr default
+	// to io.Discard as with Delegate.
+	DelegateGlobal bool
+	// Quota sets optional daily byte quotas per module. Disabled by default.
+	Quota QuotaConfig
+	// Budget enables cost-aware sampling against a daily volume target. Disabled by default.
+	Budget BudgetConfig
+	// Sampling, if set, logs the first entries of each level and module per tick and then
+	// one in every so many. See SamplingConfig.
+	Sampling *SamplingConfig
+	// ReportCaller, if true, records the file, line and function of the code that logged
+	// each entry. See Logger.SetReportCaller.
+	ReportCaller bool
+	// ModuleFromCaller, if true, names the module of the entries whose context has none
+	// after the package of the caller. See Logger.SetModuleFromCaller.
+	ModuleFromCaller bool
+	// CallerModuleFunc returns the module of a package path for ModuleFromCaller. Defaults
+	// to PackageModule.
+	CallerModuleFunc func(pkgPath string) string
+	// CallerSkip is the number of wrapper frames skipped by ReportCaller.
+	CallerSkip int
+	// ErrorStacks captures the stack of the log calls made by the *Err methods, such as
+	// ErrorErr. See Logger.SetErrorStacks.
+	ErrorStacks bool
+	// Fatal sets how the FATAL calls end the process. See FatalConfig.
+	Fatal FatalConfig
+	// ExitFunc replaces os.Exit as the exit function of the FATAL calls. See
+	// Logger.SetExitFunc.
+	ExitFunc func(code int)
+	// ExitHooks are called with the exit code by the FATAL calls before the process exits.
+	// See Logger.OnExit.
+	ExitHooks []ExitHook
+	// LifecycleListeners receive the lifecycle events of the logger, starting with
+	// LifecycleStarted. See Logger.OnLifecycle.
+	LifecycleListeners []LifecycleListener
+	// KeyNormalization rewrites the keys of Fields/Attrs (prefix stripping, aliases,
+	// snake_case) before validation, hooks and formatting. Disabled by default.
+	KeyNormalization KeyNormalization
+	// ProfileLabels, if true, sets pprof labels (module, level, stage) on the worker
+	// goroutines while they process entries. See Logger.SetProfileLabels.
+	ProfileLabels bool
+	// Shredding, if set, encrypts identity fields with per-subject keys so that erasing a
+	// key erases the subject's data from existing logs. See ShreddingConfig.
+	Shredding *ShreddingConfig
+	// BlobRules replace oversized or binary field values with their length and SHA-256
+	// digest. See BlobRule.
+	BlobRules []BlobRule
+	// ClockJumpThreshold, if positive, detects wall-clock jumps of at least this size and
+	// annotates later entries. See Logger.SetClockJumpThreshold.
+	ClockJumpThreshold time.Duration
+	// EntryTTL drops, or tags as stale, the entries that waited in the queue longer than
+	// its MaxAge. See Logger.SetEntryTTL.
+	EntryTTL EntryTTLConfig
+	// IDBackfill gives a generated trace and flow ID to the entries whose context has none.
+	// See Logger.SetIDBackfill.
+	IDBackfill IDBackfillConfig
+	// Pause sets what the logger does with the entries logged while it is paused. See
+	// Logger.Pause.
+	Pause PauseConfig
+	// Timestamps adds the UTC offset to entries, or stamps them in UTC with a separate
+	// local-time field. See TimestampConfig.
+	Timestamps TimestampConfig
+	// Sequence stamps entries with sequence numbers per logger and per sink, so consumers
+	// can detect gaps. See SequenceConfig.
+	Sequence SequenceConfig
+	// SinkRates limits the entries per second written to the sinks with the given names,
+	// diverting the excess to local overflow files. See Logger.SetSinkRateLimit.
+	SinkRates map[string]SinkRateLimit
+	// Migration, if set, also writes every entry to an old and a new sink with their own
+	// formatters and compares them. See Logger.SetMigration.
+	Migration *MigrationConfig
+	// Shadow, if set, evaluates a proposed formatter, masking and sampling on a copy of the
+	// traffic written to a separate sink. See Logger.SetShadow.
+	Shadow *ShadowConfig
+	// OTLP, if set, also exports every entry as an OpenTelemetry log record to a
+	// collector. See OTLPConfig.
+	OTLP *OTLPConfig
+	// Syslog, if set, also sends every entry to a syslog daemon or server. See
+	// Logger.SetSyslog.
+	Syslog *SyslogConfig
+	// Signing, if set, signs the formatted output with Ed25519, per entry or per batch.
+	// See SigningConfig.
+	Signing *SigningConfig
+	// Flags, if its Provider is set, lets feature flags drive the level, sampling and debug
+	// windows per module and tenant. See Logger.SetFlags.
+	Flags FlagConfig
+	// Validation, if set, checks every entry against a schema before hooks and formatting.
+	// See ValidationSchema.
+	Validation *ValidationSchema
+	// Health sets the thresholds of Logger.Healthy. See HealthConfig.
+	Health HealthConfig
+	// CloseDiagnostics, if true, makes a timed-out Close or CloseDetached write a diagnostic
+	// dump to the process's stderr: the CloseReport, the queue fill level and the stacks of
+	// the logger's worker and hook goroutines. The stacks are also stored in CloseReport.Stacks.
+	CloseDiagnostics bool
+	// ConfigSources lists the configuration layers the settings were loaded from. It is set
+	// by LayeredConfig.Apply and reported by Logger.ConfigSources.
+	ConfigSources []string
 }
 
 // Fields is a map for adding structured, key-value data to a log entry.
@@ -197,15 +371,26 @@ type Fields map[string]interface{}
 // HookEvent contains all the data associated with a single log event,
 // passed to each hook function.
 type HookEvent struct {
-	Time     time.Time // The timestamp when the log event was created.
-	Level    Level     // The severity level of the log.
-	Module   string    // The module associated with the log via context.
-	Message  string    // The final, formatted log message.
-	TraceID  string    // OpenTelemetry Trace ID, if available.
-	FlowID   string    // Custom Flow ID, if available.
-	Attrs    Fields    // Key-value attributes from the context.
-	Fields   Fields    // Key-value fields passed directly to the log call.
-	JSONMode bool      // True if the logger is currently in JSON output mode.
+	Time     time.Time  // The timestamp when the log event was created.
+	Level    Level      // The severity level of the log.
+	Module   string     // The module associated with the log via context.
+	Message  string     // The final, formatted log message.
+	TraceID  string     // OpenTelemetry Trace ID, if available.
+	FlowID   string     // Custom Flow ID, if available.
+	Attrs    Fields     // Key-value attributes from the context, with their native types.
+	Fields   Fields     // Key-value fields passed directly to the log call.
+	JSONMode bool       // True if the logger is currently in JSON output mode.
+	Seq      uint64     // Sequence number of the entry, or 0 if sequence numbers are disabled.
+	Caller   CallerInfo // Code that logged the entry, if caller reporting is enabled.
+
+	// Error is the error logged by an *Err method, such as ErrorErr, or nil.
+	Error error
+	// ErrorChain is the message of Error followed by those of the errors it wraps, masked
+	// like Message. See ErrorChain.
+	ErrorChain []string
+	// Stack is the stack of the log call of an *Err method, if error stacks are enabled.
+	// See Logger.SetErrorStacks.
+	Stack string
 }
 
 // HookError stores detailed information about a hook execution that failed.
@@ -217,10 +402,23 @@ type HookError struct {
 	Err     error     // The error returned by the hook, or a timeout/panic error.
 }
 
+// WriteErrorHandler is called when a write to a sink fails after all retries. It receives
+// the sink name (e.g. "stdout", "stderr", the rotation file name or an extra writer name),
+// the error of the last attempt and the event of the affected entry. When a batch write
+// fails, the handler is called once per entry of the batch routed to that sink.
+type WriteErrorHandler func(sink string, err error, entry HookEvent)
+
 // HookFunc defines the signature for a function that can be used as a hook.
 // It receives a HookEvent and returns an error if it fails.
 type HookFunc func(e HookEvent) error
 
+// HookFunc2 is the context-aware hook signature. The context carries the values of
+// the context passed to the original log call (such as the active trace span) and the
+// configured hook timeout as its deadline; hooks should stop work and return once ctx
+// is done, because the logger does not abandon a running hook.
+// Use AdaptHook and LegacyHook to convert between HookFunc and HookFunc2.
+type HookFunc2 func(ctx context.Context, e HookEvent) error
+
 // --- Internal Types ---
 
 // ctxKey is a private string-based type used for context keys to avoid collisions.
@@ -238,11 +436,15 @@ var (
 	ctxFlowIDKey ctxKey = "unologger_flow_id"
 	// ctxFieldsKey is the context key for storing contextual attributes (Fields).
 	ctxFieldsKey ctxKey = "unologger_fields"
+	// ctxGroupKey is the context key for storing the path of the field groups ([]string).
+	ctxGroupKey ctxKey = "unologger_group"
 )
 
 // hookTask is an internal wrapper for passing a hook event to the async worker pool.
 type hookTask struct {
-	event HookEvent
+	ctx     context.Context // The context of the original log call.
+	event   HookEvent
+	failure *WriteFailure // If set, the task runs the failure hook instead of the hooks.
 }
 
 // writerSink is an internal struct that pairs an io.Writer with a name and an optional io.Closer.
@@ -250,73 +452,162 @@ type writerSink struct {
 	Name   string
 	Writer io.Writer
 	Closer io.Closer
+	temp   uint64 // Non-zero for a sink attached by WithTemporarySink.
 }
 
 // Logger is the central struct of the library, managing the entire logging pipeline.
 // It should be created via InitLoggerWithConfig or NewDetachedLogger.
 type Logger struct {
 	// --- Pipeline & Workers ---
-	ch          chan *logEntry // The central channel for incoming log entries.
-	workers     int            // Number of worker goroutines processing the channel.
-	wg          sync.WaitGroup // Waits for workers to finish during shutdown.
-	closed      atomicBool     // Indicates if the logger is shutting down.
-	nonBlocking bool           // If true, enqueue operations don't block when `ch` is full.
-	dropOldest  bool           // If true and non-blocking, drops the oldest entry from `ch`.
+	ch          chan *logEntry                 // The central channel for incoming log entries.
+	chLink      *queueLink                     // The link of `ch` in the chain of queues retired by SetBufferSize.
+	hi          chan *logEntry                 // The priority queue for severe entries, if enabled; never replaced.
+	hiLevel     Level                          // Lowest level queued in `hi`.
+	chMu        sync.RWMutex                   // Held for reading while sending on `ch`, for writing while closing it.
+	workers     int                            // Number of worker goroutines processing the channel.
+	workerCtls  []*workerCtl                   // Control channels of the running workers.
+	workersMu   sync.Mutex                     // Guards workers and workerCtls.
+	autoScale   AutoScaleConfig                // Normalized auto-scaling settings.
+	autoStop    chan struct{}                  // Closed to stop the auto-scaler goroutine.
+	wg          sync.WaitGroup                 // Waits for workers to finish during shutdown.
+	closed      atomicBool                     // Indicates if the logger is shutting down.
+	closing     chan struct{}                  // Closed when shutdown starts, releasing producers blocked on a full `ch`.
+	closeStage  atomicI64                      // Current closeStage while a shutdown is in progress.
+	closeDiag   bool                           // If true, a timed-out shutdown dumps diagnostics to stderr.
+	nonBlocking bool                           // If true, enqueue operations don't block when `ch` is full.
+	dropOldest  bool                           // If true and non-blocking, drops the oldest entry from `ch`.
+	direct      bool                           // If true, entries bypass `ch` and are written synchronously.
+	directMu    sync.Mutex                     // Serializes writes in direct (single-writer) mode.
+	emergencyMu sync.Mutex                     // Serializes synchronous writes on the emergency (FATAL/panic) path.
+	profLabels  atomicBool                     // If true, workers run under pprof labels of the entry being processed.
+	entryTTL    atomic.Pointer[EntryTTLConfig] // Maximum age of queued entries, if set.
+	idFill      atomic.Pointer[idBackfill]     // Back-fill of missing trace and flow IDs, if set.
+	paused      atomicBool                     // If true, new entries are held or dropped; see Pause.
+	pause       pauseState                     // Pause policy and the entries held while paused.
 
 	// --- Output & Formatting ---
-	stdOut       io.Writer      // Destination for non-error logs.
-	errOut       io.Writer      // Destination for ERROR and FATAL logs.
-	extraW       []writerSink   // Additional output destinations.
-	rotationSink *writerSink    // A special writer for log rotation.
-	outputsMu    sync.RWMutex   // Guards access to all output writers.
-	formatter    Formatter      // Formats a log entry into bytes.
-	loc          *time.Location // Timezone for timestamps.
-	locMu        sync.RWMutex   // Guards access to the timezone location.
-	jsonFmtFlag  atomicBool     // Atomic flag for runtime JSON format toggling.
-	formatterMu  sync.RWMutex   // Guards access to the formatter.
+	stdOut         io.Writer       // Destination for non-error logs.
+	errOut         io.Writer       // Destination for ERROR and FATAL logs.
+	extraW         []writerSink    // Additional output destinations.
+	rotationSink   *writerSink     // A special writer for log rotation.
+	outputsMu      sync.RWMutex    // Guards access to all output writers.
+	sharedStdout   bool            // stdOut belongs to the logger it was inherited from; never closed.
+	sharedStderr   bool            // errOut belongs to the logger it was inherited from; never closed.
+	delegates      []*Logger       // Loggers to which every entry is forwarded: Delegate, or the members of a Tee.
+	delegateGlobal bool            // If true and delegates is empty, entries are forwarded to the global logger.
+	self           []*Logger       // The logger alone, the targets of the loggers delegating to the global one.
+	formatter      Formatter       // Formats a log entry into bytes.
+	loc            *time.Location  // Timezone for timestamps.
+	locMu          sync.RWMutex    // Guards access to the timezone location.
+	timestamps     TimestampConfig // Timestamp options; guarded by locMu.
+	jsonFmtFlag    atomicBool      // Atomic flag for runtime JSON format toggling.
+	formatterMu    sync.RWMutex    // Guards access to the formatter.
+
+	writeErrFn atomic.Pointer[WriteErrorHandler] // Called when a write fails after all retries.
+	failureFn  atomic.Pointer[FailureHookFunc]   // Receives the entries whose write failed.
+
+	retentionSinks   map[string]writerSink // Sinks per retention class; fixed after creation.
+	retentionDefault string                // Retention class of untagged entries.
 
 	// --- Batching ---
 	batchSizeA atomicI64 // Atomic batch size for lock-free reads.
 	batchWaitA atomicI64 // Atomic batch wait duration (ns) for lock-free reads.
+	batchFlush atomicI64 // Level that flushes a batch immediately, or levelOff if disabled.
 
 	// --- Masking ---
 	regexRules     []MaskRuleRegex // Compiled regex rules for masking.
 	jsonFieldRules []MaskFieldRule // Rules for masking specific JSON fields.
 
 	// --- Hooks ---
-	hooks       []HookFunc     // The slice of registered hook functions.
-	hooksMu     sync.RWMutex   // Guards access to the hooks slice.
-	hookAsync   bool           // If true, hooks are processed asynchronously.
-	hookWorkers int            // Number of goroutines in the hook worker pool.
-	hookQueue   int            // Buffer size for the async hook channel.
-	hookTimeout time.Duration  // Timeout for a single hook execution.
-	hookQueueCh chan hookTask  // The channel for async hook processing.
-	hookWg      sync.WaitGroup // Waits for hook workers to finish during shutdown.
-	hookErrLog  []HookError    // A circular buffer of recent hook errors.
-	hookErrMu   sync.Mutex     // Guards access to hookErrLog.
-	hookErrMax  int            // Max size of the hookErrLog buffer.
+	hooks         []HookFunc     // The slice of registered hook functions.
+	hooks2        []HookFunc2    // The slice of registered context-aware hook functions.
+	filteredHooks []FilteredHook // Hooks registered with a filter; replaced, never modified.
+	hooksMu       sync.RWMutex   // Guards the hooks slice, hook settings and hookQueueCh.
+	hookAsync     bool           // If true, hooks are processed asynchronously.
+	hookWorkers   int            // Number of goroutines in the hook worker pool.
+	hookQueue     int            // Buffer size for the async hook channel.
+	hookTimeout   time.Duration  // Timeout for a single hook execution.
+	hookQueueCh   chan hookTask  // The channel for async hook processing; nil unless the runner is running.
+	hookState     atomicI64      // The hookRunnerState of the async runner.
+	hookRunMu     sync.Mutex     // Serializes hook runner start/stop transitions.
+	hookWg        sync.WaitGroup // Waits for hook workers to finish during shutdown.
+	hookErrLog    []HookError    // A circular buffer of recent hook errors.
+	hookErrMu     sync.Mutex     // Guards access to hookErrLog.
+	hookErrMax    int            // Max size of the hookErrLog buffer.
 
 	// --- Telemetry & Dynamic Config ---
-	enableOTel atomicBool    // Atomic flag to enable/disable OpenTelemetry integration.
-	minLevel   atomicLevel   // Atomic minimum log level.
-	dynConfig  DynamicConfig // Holds configuration that can be changed at runtime.
+	enableOTel   atomicBool    // Atomic flag to enable/disable OpenTelemetry integration.
+	traceDebug   atomicBool    // If true, DEBUG entries of unsampled traces are discarded.
+	spanEventLvl atomicI64     // Minimum level recorded as a span event, or levelOff if disabled.
+	minLevel     atomicLevel   // Atomic minimum log level.
+	dynConfig    DynamicConfig // Holds configuration that can be changed at runtime.
 
 	// --- Statistics ---
-	retryPolicy   RetryPolicy // The retry policy for failed writes.
-	writtenCount  atomicI64   // Total log entries successfully written.
-	droppedCount  atomicI64   // Total log entries dropped.
-	batchCount    atomicI64   // Total batches processed.
-	writeErrCount atomicI64   // Total errors encountered during writes.
-	hookErrCount  atomicI64   // Total errors encountered during hook execution.
-	writerErrs    sync.Map    // Stores error counts for specific writers.
+	retryPolicy    RetryPolicy // The retry policy for failed writes.
+	writtenCount   atomicI64   // Total log entries successfully written.
+	droppedCount   atomicI64   // Total log entries dropped.
+	batchCount     atomicI64   // Total batches processed.
+	writeErrCount  atomicI64   // Total errors encountered during writes.
+	hookErrCount   atomicI64   // Total errors encountered during hook execution.
+	afterClose     atomicI64   // Total log calls rejected because the logger was closed.
+	traceThrottled atomicI64   // Total DEBUG entries discarded because their trace was not sampled.
+	staleDropped   atomicI64   // Total entries dropped by the entry TTL.
+	staleTagged    atomicI64   // Total stale entries kept with the stale fields.
+	panics         atomicI64   // Total panics recovered by RecoverAndLog, PanicHandler, Go and GoErr.
+	writerErrs     sync.Map    // Stores a *writerHealth per writer name.
+	modules        sync.Map    // Stores a *moduleUsage per module name.
+
+	quota       atomic.Pointer[QuotaConfig]  // Daily byte quotas per module.
+	budgetCfg   atomic.Pointer[BudgetConfig] // Daily volume target for cost-aware sampling.
+	budget      budgetState                  // Measurements and sampling rates of the budget controller.
+	sampler     atomic.Pointer[samplerState] // Rate-based sampling, if enabled.
+	started     atomicBool                   // Set once the workers run; lifecycle events are emitted from then on.
+	lifecycle   lifecycleListeners           // Listeners of lifecycle events.
+	audit       configAudit                  // Recent changes of the dynamic configuration.
+	callerSkip  atomicI64                    // Caller skip depth plus one, or 0 if caller reporting is disabled.
+	errorStacks atomicBool                   // If true, the *Err methods capture the stack of the call.
+	levelCounts [numLevels]atomicI64         // Total log entries written per built-in level, from TRACE.
+	startedAt   atomicI64                    // Unix nanoseconds of the start of the logger.
+	fatalCfg    atomic.Pointer[FatalConfig]  // How the FATAL calls end the process, if set.
+	exitFn      atomic.Pointer[func(int)]    // Exit function of the FATAL calls, or nil for os.Exit.
+	exitHooks   exitHooks                    // Hooks run by the FATAL calls before the exit.
+	callerMod   atomic.Pointer[moduleFunc]   // Module of a package path, if the module is detected from the caller.
+
+	keyNorm         atomic.Pointer[keyNormalizer]           // Field key normalization, if enabled.
+	shredding       atomic.Pointer[ShreddingConfig]         // Crypto-shredding of identity fields, if enabled.
+	blobRules       atomic.Pointer[[]BlobRule]              // Blob summary rules, if any.
+	sinkRates       atomic.Pointer[map[string]*sinkLimiter] // Rate limits per sink name, if any.
+	sinkRatesMu     sync.Mutex                              // Serializes SetSinkRateLimit.
+	signing         atomic.Pointer[SigningConfig]           // Ed25519 signing of the output, if enabled.
+	clock           clockState                              // Wall-clock jump detection.
+	seqEntries      atomicBool                              // If true, entries get logger-wide sequence numbers.
+	seqSinks        atomicBool                              // If true, entries get per-sink sequence numbers.
+	seq             atomic.Uint64                           // Last logger-wide sequence number.
+	sinkSeqs        sync.Map                                // Stores an *atomic.Uint64 per sink name.
+	migration       atomic.Pointer[migrationState]          // Dual-write migration, if active.
+	shadow          atomic.Pointer[shadowState]             // Shadow configuration, if attached.
+	otlp            atomic.Pointer[otlpExporter]            // OTLP log exporter, if enabled.
+	syslog          atomic.Pointer[syslogSink]              // Syslog sink, if enabled.
+	defaults        atomic.Pointer[loggerDefaults]          // Default module and attributes, if set.
+	validation      atomic.Pointer[ValidationSchema]        // Schema checked by the validation stage, if any.
+	healthCfg       atomic.Pointer[HealthConfig]            // Thresholds of Healthy, if set.
+	validViolations atomicI64                               // Entries that violated the validation schema.
+	validFlagged    atomicI64                               // Nonconforming entries written with their violations.
+	validFixed      atomicI64                               // Nonconforming entries repaired by ValidationFix.
+	validDropped    atomicI64                               // Nonconforming entries discarded by ValidationDrop.
+
+	flags atomic.Pointer[flagState] // Feature flag provider and cached evaluations, if set.
+
+	configSources []string // Configuration layers the logger was created from.
 }
 
 // LoggerWithCtx is a lightweight wrapper that binds a *Logger instance to a context.Context.
 // This is the primary way to use the logger after initialization, as it allows for the
 // propagation of contextual metadata like module names and trace IDs.
 type LoggerWithCtx struct {
-	l   *Logger
-	ctx context.Context
+	l      *Logger
+	ctx    context.Context
+	fields Fields // Fields bound by With; never modified.
 }
 
 // logEntry is an internal representation of a single log event.
@@ -328,6 +619,176 @@ type logEntry struct {
 	tmpl   string
 	args   []any
 	fields Fields
+	group  []*logEntry   // Child entries emitted atomically by BufferedLogger.Commit.
+	seq    uint64        // Sequence number, if enabled; see stampSeq.
+	caller CallerInfo    // Code that logged the entry, if caller reporting is enabled.
+	stale  time.Duration // Age of the entry, if it was found stale and kept; see checkStale.
+	idFill string        // Back-filled trace and flow ID, if the context lacks one; see stampBackfill.
+	module string        // Module of the caller, if the context names none; see stampModule.
+
+	// literal marks the message of a W method, which is written verbatim rather than used
+	// as a format string; see literalArgs.
+	literal bool
+	// emergency marks an entry already written to stderr and the rotation file by the
+	// emergency path; the pipeline only runs hooks and writes the extra writers.
+	emergency bool
+	// held marks an entry held while the logger was paused, which Resume enqueues while
+	// the logger is still paused; see holdEntry.
+	held bool
+	// ack, if non-nil, receives the delivery result of a synchronous log call exactly once.
+	// It must be buffered so that the pipeline never blocks on a caller that gave up.
+	ack chan error
+	// barrier, if non-nil, marks an empty entry sent by flushQueued. It is closed once the
+	// batch holding the entry has been written, or when the entry is discarded.
+	barrier chan struct{}
+}
+
+// weight returns the number of log records represented by the entry, which is
+// the number of children for a group entry and one otherwise.
+func (e *logEntry) weight() int64 {
+	if e.group != nil {
+		return int64(len(e.group))
+	}
+	return 1
+}
+
+// batchOutput accumulates the formatted entries of a batch per destination, so that each
+// sink is written once per batch. It is reused through poolOutput.
+type batchOutput struct {
+	std   segments // Entries routed to stdout.
+	errb  segments // Entries routed to stderr.
+	rot   segments // Entries for the rotation file.
+	extra segments // Entries for the extra writers.
+
+	retention map[string]*segments // Entries for the retention sinks, by class.
+
+	migration      *migrationState // Migration the mig* entries were collected for, if any.
+	migOld, migNew segments        // Entries for the two sides of the migration.
+	shadowState    *shadowState    // Shadow the shadow entries were collected for, if any.
+	shadow         segments        // Entries for the shadow sink.
+	otlpExp        *otlpExporter   // OTLP exporter the otlp events were collected for, if any.
+	otlp           []HookEvent     // Events for the OTLP exporter.
+	syslogSink     *syslogSink     // Syslog sink the syslog messages were formatted for, if any.
+	syslog         segments        // Messages for the syslog sink.
+
+	time     timeSettings    // Timezone and timestamp options of the batch.
+	ttl      *EntryTTLConfig // Entry TTL of the batch, if enabled.
+	dequeued time.Time       // Time the batch was taken from the queue, if ttl is set.
+	acks     []pendingAck    // Synchronous calls waiting for the result of this batch.
+	idFill   *idBackfill     // ID back-fill of the batch, if enabled.
+	batchID  string          // Back-filled ID of the batch, once generated.
+}
+
+// pendingAck is the acknowledgement of a synchronous log call in a batch being written.
+type pendingAck struct {
+	ch        chan error
+	lvl       Level
+	emergency bool
+	retained  bool  // The entry was routed to a retention sink.
+	err       error // Set if the entry failed before reaching the writers.
+}
+
+// reset empties every destination so the batchOutput can be pooled.
+func (o *batchOutput) reset() {
+	o.std.reset()
+	o.errb.reset()
+	o.rot.reset()
+	o.extra.reset()
+	for _, s := range o.retention {
+		s.reset()
+	}
+	o.migOld.reset()
+	o.migNew.reset()
+	o.migration = nil
+	o.shadow.reset()
+	o.shadowState = nil
+	clear(o.otlp)
+	o.otlp = o.otlp[:0]
+	o.otlpExp = nil
+	o.syslog.reset()
+	o.syslogSink = nil
+	o.time = timeSettings{}
+	o.ttl = nil
+	o.idFill = nil
+	o.batchID = ""
+	clear(o.acks)
+	o.acks = o.acks[:0]
+}
+
+// segments is the list of formatted entries destined for one sink. Sinks that support
+// batch or vectored writes receive the entries as they are; the concatenation needed
+// by plain io.Writers is built lazily, at most once per batch.
+type segments struct {
+	parts   [][]byte
+	events  []HookEvent // Events of the parts; only kept while a write error handler is set.
+	flat    []byte
+	built   bool
+	sealed  bool // The batch signature stage has run (see sealSegments).
+	trailer bool // The last part is a batch signature trailer, not an entry.
+}
+
+// maxPooledOutput caps the buffer capacity kept by a pooled batchOutput, so that one
+// unusually large batch does not pin its memory for the lifetime of the process.
+const maxPooledOutput = 1 << 20
+
+// add appends one formatted entry, and its event if ev is non-nil.
+func (s *segments) add(b []byte, ev *HookEvent) {
+	s.parts = append(s.parts, b)
+	if ev != nil {
+		s.events = append(s.events, *ev)
+	}
+}
+
+// size returns the total number of bytes of all entries.
+func (s *segments) size() int {
+	if s.built {
+		return len(s.flat)
+	}
+	n := 0
+	for _, p := range s.parts {
+		n += len(p)
+	}
+	return n
+}
+
+// empty reports whether no entry was added.
+func (s *segments) empty() bool {
+	return len(s.parts) == 0
+}
+
+// bytes returns all entries concatenated into a single buffer.
+func (s *segments) bytes() []byte {
+	if !s.built {
+		for _, p := range s.parts {
+			s.flat = append(s.flat, p...)
+		}
+		s.built = true
+	}
+	return s.flat
+}
+
+// reset drops the entries, keeping the buffers' capacity unless the concatenation grew
+// beyond maxPooledOutput.
+func (s *segments) reset() {
+	clear(s.parts)
+	s.parts = s.parts[:0]
+	clear(s.events)
+	s.events = s.events[:0]
+	if cap(s.flat) > maxPooledOutput {
+		s.flat = nil
+	} else {
+		s.flat = s.flat[:0]
+	}
+	s.built = false
+	s.sealed, s.trailer = false, false
+}
+
+// workerCtl holds the control channels of one worker goroutine.
+type workerCtl struct {
+	quit  chan struct{}      // Closed to make the worker flush its batch and exit.
+	flush chan chan struct{} // Receives flush requests; the worker closes the ack channel once flushed.
+	done  chan struct{}      // Closed by the worker when it exits.
+	link  *queueLink         // The queue the worker starts from, taken when it is spawned.
 }
 
 // logBatch is an internal representation of a batch of log entries.
@@ -346,6 +807,8 @@ type DynamicConfig struct {
 	JSONFieldRules []MaskFieldRule
 	Retry          RetryPolicy
 	Hooks          []HookFunc
+	Hooks2         []HookFunc2
+	FilteredHooks  []FilteredHook
 	Batch          BatchConfig
 }
 
@@ -360,9 +823,10 @@ func (a *atomicLevel) Store(val int32) { atomic.StoreInt32(&a.v, val) }
 // atomicBool provides atomic operations for a boolean.
 type atomicBool struct{ v uint32 }
 
-func (a *atomicBool) Load() bool       { return atomic.LoadUint32(&a.v) != 0 }
-func (a *atomicBool) Store(val bool)   { atomic.StoreUint32(&a.v, b32(val)) }
-func (a *atomicBool) TrySetTrue() bool { return atomic.CompareAndSwapUint32(&a.v, 0, 1) }
+func (a *atomicBool) Load() bool         { return atomic.LoadUint32(&a.v) != 0 }
+func (a *atomicBool) Store(val bool)     { atomic.StoreUint32(&a.v, b32(val)) }
+func (a *atomicBool) TrySetTrue() bool   { return atomic.CompareAndSwapUint32(&a.v, 0, 1) }
+func (a *atomicBool) Swap(val bool) bool { return atomic.SwapUint32(&a.v, b32(val)) != 0 }
 
 // atomicI64 provides atomic operations for an int64.
 type atomicI64 struct{ v int64 }
@@ -390,6 +854,10 @@ var (
 	poolBatch = sync.Pool{
 		New: func() any { return &logBatch{items: make([]*logEntry, 0, 64)} },
 	}
+	// poolOutput reuses the per-destination buffers of a batch.
+	poolOutput = sync.Pool{
+		New: func() any { return &batchOutput{} },
+	}
 )
 
 const defaultHookErrMax = 1000
@@ -10,10 +10,14 @@ package unologger
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
+	"strings"
 )
 
 // applyMasking applies all configured masking rules to a log message string.
@@ -29,7 +33,12 @@ func (l *Logger) applyMasking(msg string, jsonMode bool) string {
 	regexRules := l.dynConfig.RegexRules
 	jsonFieldRules := l.dynConfig.JSONFieldRules
 	l.dynConfig.mu.RUnlock()
+	return maskWithRules(msg, jsonMode, regexRules, jsonFieldRules)
+}
 
+// maskWithRules applies the given masking rules to a message, in the order described by
+// applyMasking.
+func maskWithRules(msg string, jsonMode bool, regexRules []MaskRuleRegex, jsonFieldRules []MaskFieldRule) string {
 	if jsonMode {
 		// Attempt to mask JSON fields first.
 		if maskedJSON, ok := maskJSONFieldsWithRules(msg, jsonFieldRules); ok {
@@ -90,28 +99,78 @@ func maskJSONFieldsWithRules(s string, rules []MaskFieldRule) (string, bool) {
 	return string(out), true
 }
 
+// maxNestedJSONDepth bounds how many levels of JSON serialized inside JSON strings are
+// parsed and masked, e.g. {"body":"{\"password\":\"x\"}"} is one level.
+const maxNestedJSONDepth = 4
+
 // maskJSONValueWithRules recursively traverses a data structure (map or slice)
 // and applies masking rules. It takes a pointer to an interface{} to allow
 // in-place modification of the underlying data.
 func maskJSONValueWithRules(v *interface{}, rules []MaskFieldRule) {
+	maskJSONValueDepth(v, rules, 0)
+}
+
+// maskJSONValueDepth is maskJSONValueWithRules for a value found at the given level of
+// nested serialized JSON. It reports whether anything was masked.
+func maskJSONValueDepth(v *interface{}, rules []MaskFieldRule, depth int) bool {
+	masked := false
 	switch val := (*v).(type) {
 	case map[string]interface{}:
 		for k, subVal := range val {
 			if shouldMaskKeyWithRules(k, rules) {
 				val[k] = getMaskReplacementForKeyWithRules(k, rules)
-			} else {
+				masked = true
+			} else if maskJSONValueDepth(&subVal, rules, depth) {
 				// The value might be another map or slice, so recurse.
-				maskJSONValueWithRules(&subVal, rules)
 				val[k] = subVal
+				masked = true
 			}
 		}
 	case []interface{}:
 		for i, subVal := range val {
 			// Recurse into each element of the slice.
-			maskJSONValueWithRules(&subVal, rules)
-			val[i] = subVal
+			if maskJSONValueDepth(&subVal, rules, depth) {
+				val[i] = subVal
+				masked = true
+			}
+		}
+	case string:
+		// The string may itself hold serialized JSON, such as a request body.
+		if s, ok := maskNestedJSON(val, rules, depth+1); ok {
+			*v = s
+			masked = true
 		}
 	}
+	return masked
+}
+
+// maskNestedJSON masks s if it is a serialized JSON object or array, at the given level of
+// nesting. It returns the re-encoded string and true only if something was masked, so that
+// other strings keep their exact form.
+func maskNestedJSON(s string, rules []MaskFieldRule, depth int) (string, bool) {
+	if depth > maxNestedJSONDepth {
+		return s, false
+	}
+	t := strings.TrimSpace(s)
+	if len(t) < 2 || (t[0] != '{' && t[0] != '[') {
+		return s, false
+	}
+	var data interface{}
+	dec := json.NewDecoder(strings.NewReader(t))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil || dec.More() {
+		return s, false
+	}
+	if !maskJSONValueDepth(&data, rules, depth) {
+		return s, false
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return s, false
+	}
+	return string(bytes.TrimRight(buf.Bytes(), "\n")), true
 }
 
 // shouldMaskKeyWithRules checks if a given key matches any of the configured masking rules.
@@ -139,14 +198,18 @@ func getMaskReplacementForKeyWithRules(key string, rules []MaskFieldRule) string
 }
 
 // compileMaskRegexes is an internal helper that compiles a map of string patterns
-// into a slice of MaskRuleRegex. This is typically called once during logger initialization.
-// If a pattern is an invalid regex, an error is printed to os.Stderr and the pattern is skipped.
+// into a slice of MaskRuleRegex, in lexical order of the patterns. This is typically
+// called once during logger initialization. If a pattern is an invalid regex, an error is
+// printed to os.Stderr and the pattern is skipped.
 func compileMaskRegexes(patterns map[string]string) []MaskRuleRegex {
 	if len(patterns) == 0 {
 		return nil
 	}
 	rules := make([]MaskRuleRegex, 0, len(patterns))
-	for pat, repl := range patterns {
+	// Patterns are compiled in lexical order, so that the order in which overlapping
+	// patterns are applied does not depend on map iteration.
+	for _, pat := range slices.Sorted(maps.Keys(patterns)) {
+		repl := patterns[pat]
 		if re, err := regexp.Compile(pat); err == nil {
 			rules = append(rules, MaskRuleRegex{Pattern: re, Replacement: repl})
 		} else {
@@ -156,3 +219,62 @@ func compileMaskRegexes(patterns map[string]string) []MaskRuleRegex {
 	}
 	return rules
 }
+
+// MaskRuleInfo describes a masking rule in effect, for inspection.
+type MaskRuleInfo struct {
+	Kind        string   `json:"kind"` // "field" or "regex".
+	Name        string   `json:"name,omitempty"`
+	Priority    int      `json:"priority"`
+	Pattern     string   `json:"pattern,omitempty"` // Regex rules only.
+	Keys        []string `json:"keys,omitempty"`    // Field rules only.
+	Replacement string   `json:"replacement"`
+}
+
+// MaskingRules returns the masking rules in effect, in the order they are evaluated: the
+// field rules, which apply first to JSON messages, then the regex rules. Within each kind,
+// rules are sorted by decreasing Priority; rules of equal priority keep the order in which
+// they were configured, patterns from RegexPatternMap in lexical order.
+func (l *Logger) MaskingRules() []MaskRuleInfo {
+	l.dynConfig.mu.RLock()
+	regexRules := l.dynConfig.RegexRules
+	fieldRules := l.dynConfig.JSONFieldRules
+	l.dynConfig.mu.RUnlock()
+
+	infos := make([]MaskRuleInfo, 0, len(regexRules)+len(fieldRules))
+	for _, r := range fieldRules {
+		infos = append(infos, MaskRuleInfo{
+			Kind: "field", Name: r.Name, Priority: r.Priority,
+			Keys: append([]string(nil), r.Keys...), Replacement: r.Replacement,
+		})
+	}
+	for _, r := range regexRules {
+		info := MaskRuleInfo{Kind: "regex", Name: r.Name, Priority: r.Priority, Replacement: r.Replacement}
+		if r.Pattern != nil {
+			info.Pattern = r.Pattern.String()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// orderRegexRules returns a copy of rules sorted by decreasing priority, stable for equal
+// priorities.
+func orderRegexRules(rules []MaskRuleRegex) []MaskRuleRegex {
+	if rules == nil {
+		return nil
+	}
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b MaskRuleRegex) int { return cmp.Compare(b.Priority, a.Priority) })
+	return rules
+}
+
+// orderFieldRules returns a copy of rules sorted by decreasing priority, stable for equal
+// priorities.
+func orderFieldRules(rules []MaskFieldRule) []MaskFieldRule {
+	if rules == nil {
+		return nil
+	}
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b MaskFieldRule) int { return cmp.Compare(b.Priority, a.Priority) })
+	return rules
+}
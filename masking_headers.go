@@ -0,0 +1,116 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements masking for HTTP header blocks. Credentials travel in a handful of
+// headers whose values must never reach the logs, while their names are still useful to
+// tell, for example, an unauthenticated request from one with a rejected token.
+
+package unologger
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultSensitiveHeaders lists the headers whose values are redacted when no other list is
+// given.
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// defaultHeaderReplacement replaces the values of sensitive headers by default.
+const defaultHeaderReplacement = "[REDACTED]"
+
+// MaskHeaders returns a copy of h in which every value of the headers named in sensitive,
+// matched case-insensitively, is replaced by replacement. A nil sensitive list means
+// DefaultSensitiveHeaders and an empty replacement means "[REDACTED]". h is not modified.
+func MaskHeaders(h http.Header, sensitive []string, replacement string) http.Header {
+	sensitive, replacement = headerMaskDefaults(sensitive, replacement)
+	out := h.Clone()
+	for name, values := range out {
+		if isSensitiveHeader(name, sensitive) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = replacement
+			}
+			out[name] = masked
+		}
+	}
+	return out
+}
+
+// MaskHeaderDump masks a textual HTTP message, such as the output of httputil.DumpRequest
+// or httputil.DumpResponse: the values of the header lines named in sensitive are replaced
+// by replacement, keeping the names, the request or status line, the line endings and the
+// body unchanged. The defaults are those of MaskHeaders.
+func MaskHeaderDump(dump string, sensitive []string, replacement string) string {
+	sensitive, replacement = headerMaskDefaults(sensitive, replacement)
+	var b strings.Builder
+	rest := dump
+	for rest != "" {
+		line, eol := rest, ""
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			line, eol, rest = rest[:i], "\n", rest[i+1:]
+		} else {
+			rest = ""
+		}
+		if strings.HasSuffix(line, "\r") {
+			line, eol = line[:len(line)-1], "\r"+eol
+		}
+		if line == "" {
+			b.WriteString(eol)
+			b.WriteString(rest) // The body follows the blank line.
+			break
+		}
+		if name, _, ok := strings.Cut(line, ":"); ok && isHeaderName(name) && isSensitiveHeader(name, sensitive) {
+			line = name + ": " + replacement
+		}
+		b.WriteString(line)
+		b.WriteString(eol)
+	}
+	return b.String()
+}
+
+// headerMaskDefaults applies the defaults of MaskHeaders.
+func headerMaskDefaults(sensitive []string, replacement string) ([]string, string) {
+	if sensitive == nil {
+		sensitive = DefaultSensitiveHeaders
+	}
+	if replacement == "" {
+		replacement = defaultHeaderReplacement
+	}
+	return sensitive, replacement
+}
+
+// isSensitiveHeader reports whether name is in sensitive, ignoring case.
+func isSensitiveHeader(name string, sensitive []string) bool {
+	return slices.ContainsFunc(sensitive, func(s string) bool { return strings.EqualFold(s, name) })
+}
+
+// isHeaderName reports whether s is a valid header field name, which tells header lines
+// from the request line of a dump, e.g. "GET http://host:80/ HTTP/1.1".
+func isHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// headerBlock formats h, with the sensitive values redacted, as sorted "Name: value" lines.
+func (hc *HeaderCapture) headerBlock(h http.Header) string {
+	var b strings.Builder
+	_ = MaskHeaders(h, hc.Sensitive, hc.Replacement).Write(&b)
+	return strings.ReplaceAll(strings.TrimSuffix(b.String(), "\r\n"), "\r\n", "\n")
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements masking for payloads that are not JSON: XML documents, whose element
+// and attribute names are matched, and URL-encoded forms and query strings, whose parameter
+// names are matched. Both use the JSONFieldRules of the logger, so one set of names covers
+// every payload format, and both keep the rest of the payload byte for byte.
+
+package unologger
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// MaskPayload masks a payload according to its MIME type, with the logger's JSONFieldRules
+// followed by its regex rules: JSON types by field name, XML types ("application/xml",
+// "text/xml", "+xml") by element and attribute name, and "application/x-www-form-urlencoded"
+// by parameter name. Other types, and payloads that fail to parse, only get the regex rules.
+func (l *Logger) MaskPayload(payload, contentType string) string {
+	l.dynConfig.mu.RLock()
+	regexRules := l.dynConfig.RegexRules
+	fieldRules := l.dynConfig.JSONFieldRules
+	l.dynConfig.mu.RUnlock()
+
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return maskWithRules(payload, true, regexRules, fieldRules)
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		if s, ok := maskXMLWithRules(payload, fieldRules); ok {
+			payload = s
+		}
+	case mt == "application/x-www-form-urlencoded":
+		payload = maskFormWithRules(payload, fieldRules)
+	}
+	return maskRegexWithRules(payload, regexRules)
+}
+
+// MaskURL masks the query parameters of a URL, or of a bare query string, whose names match
+// the logger's JSONFieldRules. The rest of the URL is left unchanged.
+func (l *Logger) MaskURL(u string) string {
+	l.dynConfig.mu.RLock()
+	fieldRules := l.dynConfig.JSONFieldRules
+	l.dynConfig.mu.RUnlock()
+
+	q, frag := u, ""
+	if i := strings.IndexByte(q, '#'); i >= 0 {
+		q, frag = q[:i], q[i:]
+	}
+	prefix := ""
+	if i := strings.IndexByte(q, '?'); i >= 0 {
+		prefix, q = q[:i+1], q[i+1:]
+	} else if strings.Contains(q, "/") {
+		return u // A URL without a query.
+	}
+	return prefix + maskFormWithRules(q, fieldRules) + frag
+}
+
+// maskFormWithRules masks the values of the URL-encoded parameters of s whose decoded
+// names match rules. Parameters keep their order and encoding.
+func maskFormWithRules(s string, rules []MaskFieldRule) string {
+	if len(rules) == 0 || s == "" {
+		return s
+	}
+	parts := strings.Split(s, "&")
+	changed := false
+	for i, p := range parts {
+		name, _, _ := strings.Cut(p, "=")
+		if key, err := url.QueryUnescape(name); err == nil && shouldMaskKeyWithRules(key, rules) {
+			parts[i] = name + "=" + url.QueryEscape(getMaskReplacementForKeyWithRules(key, rules))
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	return strings.Join(parts, "&")
+}
+
+// maskXMLWithRules masks the content of the elements and the values of the attributes of
+// the XML document s whose local names match rules. The content of a masked element,
+// including its child elements, is replaced by the escaped replacement. It returns s and
+// false if s is not well-formed XML.
+func maskXMLWithRules(s string, rules []MaskFieldRule) (string, bool) {
+	if len(rules) == 0 {
+		return s, true
+	}
+	if t := strings.TrimSpace(s); t == "" || t[0] != '<' {
+		return s, false
+	}
+	d := xml.NewDecoder(strings.NewReader(s))
+	var out strings.Builder
+	last, depth, maskedAt := 0, 0, 0
+	for {
+		start := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return s, false
+		}
+		end := int(d.InputOffset())
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if maskedAt > 0 {
+				continue // Inside a masked element, whose content is dropped.
+			}
+			tag := s[start:end]
+			if masked := maskXMLAttrs(tag, t.Attr, rules); masked != tag {
+				out.WriteString(s[last:start])
+				out.WriteString(masked)
+				last = end
+			}
+			if shouldMaskKeyWithRules(t.Name.Local, rules) && !strings.HasSuffix(tag, "/>") {
+				out.WriteString(s[last:end])
+				out.WriteString(xmlEscape(getMaskReplacementForKeyWithRules(t.Name.Local, rules)))
+				last = end
+				maskedAt = depth
+			}
+		case xml.EndElement:
+			if maskedAt == depth {
+				last = start // Resume copying at the end tag.
+				maskedAt = 0
+			}
+			depth--
+		}
+	}
+	if depth != 0 {
+		return s, false
+	}
+	out.WriteString(s[last:])
+	return out.String(), true
+}
+
+// xmlAttrRe matches an attribute of a start tag. Its first group is the qualified name of
+// the attribute and its second group the quoted value.
+var xmlAttrRe = regexp.MustCompile(`\s([^\s=/>]+)\s*=\s*("[^"]*"|'[^']*')`)
+
+// maskXMLAttrs replaces, in the start tag, the values of the attributes whose local names
+// match rules.
+func maskXMLAttrs(tag string, attrs []xml.Attr, rules []MaskFieldRule) string {
+	if !slices.ContainsFunc(attrs, func(a xml.Attr) bool { return shouldMaskKeyWithRules(a.Name.Local, rules) }) {
+		return tag
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range xmlAttrRe.FindAllStringSubmatchIndex(tag, -1) {
+		name := tag[m[2]:m[3]]
+		if i := strings.LastIndexByte(name, ':'); i >= 0 {
+			name = name[i+1:]
+		}
+		if !shouldMaskKeyWithRules(name, rules) {
+			continue
+		}
+		b.WriteString(tag[last:m[4]])
+		b.WriteString(`"` + xmlEscape(getMaskReplacementForKeyWithRules(name, rules)) + `"`)
+		last = m[5]
+	}
+	b.WriteString(tag[last:])
+	return b.String()
+}
+
+// xmlEscape escapes s for use in XML character data and attribute values.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
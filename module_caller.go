@@ -0,0 +1,97 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the detection of the module from the caller. Entries logged with a
+// context that names no module, and loggers obtained from such a context with GetLogger,
+// take the module from the package of the calling code, e.g. "payment" for code in
+// "example.com/app/internal/payment", instead of "unknown".
+
+package unologger
+
+import (
+	"strings"
+)
+
+// moduleFunc returns the module of a package path.
+type moduleFunc func(pkgPath string) string
+
+// PackageModule returns the last element of a package path, e.g. "payment" for
+// "example.com/app/internal/payment". It is the default module function of
+// SetModuleFromCaller.
+func PackageModule(pkgPath string) string {
+	if i := strings.LastIndexByte(pkgPath, '/'); i >= 0 {
+		return pkgPath[i+1:]
+	}
+	return pkgPath
+}
+
+// SetModuleFromCaller enables or disables the detection of the module from the caller. When
+// enabled, an entry whose context has no module, and for which the logger has no default
+// module (see WithDefaults), gets the module returned by fn for the package path of the
+// code that logged it, as does a context completed by GetLogger. fn defaults to
+// PackageModule; a result of "" leaves the module unset. The frames skipped by
+// SetReportCaller, when caller reporting is enabled, are skipped here too, so that wrappers
+// do not name the module. Finding the caller walks the stack of every entry without a
+// module, which costs about a microsecond.
+func (l *Logger) SetModuleFromCaller(enabled bool, fn func(pkgPath string) string) {
+	if !enabled {
+		l.callerMod.Store(nil)
+		return
+	}
+	mod := moduleFunc(fn)
+	if mod == nil {
+		mod = PackageModule
+	}
+	l.callerMod.Store(&mod)
+}
+
+// ModuleFromCaller reports whether the module is detected from the caller.
+func (l *Logger) ModuleFromCaller() bool {
+	return l.callerMod.Load() != nil
+}
+
+// stampModule records in e the module of its caller, if the detection is enabled and
+// neither the context of e nor the defaults of the logger name a module. It must run on
+// the goroutine of the log call, after e.ctx and e.caller are set.
+func (l *Logger) stampModule(e *logEntry) {
+	if l.callerMod.Load() == nil {
+		return
+	}
+	if module, _ := e.ctx.Value(ctxModuleKey).(string); module != "" {
+		return
+	}
+	if d := l.defaults.Load(); d != nil && d.module != "" {
+		return
+	}
+	fn := e.caller.Function
+	if fn == "" {
+		fn = findCaller(l.moduleSkip()).Function
+	}
+	e.module = l.callerModule(fn)
+}
+
+// moduleSkip returns the number of wrapper frames skipped when finding the caller.
+func (l *Logger) moduleSkip() int {
+	return int(max(l.callerSkip.Load()-1, 0))
+}
+
+// callerModule returns the module of the qualified function name fn, or "" if the detection
+// is disabled.
+func (l *Logger) callerModule(fn string) string {
+	mod := l.callerMod.Load()
+	if mod == nil || fn == "" {
+		return ""
+	}
+	return (*mod)(funcPackage(fn))
+}
+
+// funcPackage returns the package path of a qualified function name, e.g.
+// "example.com/app/billing" for "example.com/app/billing.(*Service).Charge".
+func funcPackage(fn string) string {
+	slash := strings.LastIndexByte(fn, '/') + 1
+	if i := strings.IndexByte(fn[slash:], '.'); i >= 0 {
+		return fn[:slash+i]
+	}
+	return fn
+}
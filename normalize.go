@@ -0,0 +1,181 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements field key normalization. The keys of the merged Fields/Attrs of an
+// entry are rewritten before validation, hooks and formatting (reserved prefixes stripped,
+// aliases applied, snake_case enforced), so that inconsistent callers converge on a single
+// schema.
+
+package unologger
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// maxNormalizedKeys bounds the cache of normalized keys, protecting against callers that
+// generate unbounded key names.
+const maxNormalizedKeys = 4096
+
+// KeyNormalization configures how field keys are rewritten. The steps run in this order:
+// prefix stripping, alias lookup, snake_case conversion, and a second alias lookup on the
+// converted key. The zero value leaves keys unchanged.
+type KeyNormalization struct {
+	// SnakeCase converts keys to snake_case, e.g. "userId" and "User-ID" to "user_id".
+	SnakeCase bool
+	// Aliases maps keys to their canonical name, e.g. "uid" to "user_id".
+	Aliases map[string]string
+	// StripPrefixes lists prefixes removed from keys, e.g. "_" or "x-". Only the first
+	// matching prefix is removed, and a key is never stripped to an empty string.
+	StripPrefixes []string
+}
+
+// enabled reports whether the configuration rewrites any key.
+func (kn KeyNormalization) enabled() bool {
+	return kn.SnakeCase || len(kn.Aliases) > 0 || len(kn.StripPrefixes) > 0
+}
+
+// keyNormalizer applies a KeyNormalization and caches the normalized keys.
+type keyNormalizer struct {
+	cfg    KeyNormalization
+	cache  sync.Map // original key -> normalized key
+	cached atomic.Int64
+}
+
+// SetKeyNormalization replaces the key normalization settings at runtime.
+func (l *Logger) SetKeyNormalization(kn KeyNormalization) {
+	if !kn.enabled() {
+		l.keyNorm.Store(nil)
+		return
+	}
+	l.keyNorm.Store(&keyNormalizer{cfg: kn})
+}
+
+// GetKeyNormalization returns the key normalization settings currently in effect.
+func (l *Logger) GetKeyNormalization() KeyNormalization {
+	if n := l.keyNorm.Load(); n != nil {
+		return n.cfg
+	}
+	return KeyNormalization{}
+}
+
+// normalizeFields returns fields with normalized keys, including the keys of its groups
+// (see WithGroup). When two keys normalize to the same name, a key that is already
+// canonical wins over a rewritten one, and rewritten keys are otherwise applied in lexical
+// order of their original name, the first one winning. The input map is returned unchanged
+// if it has no group and no key needs rewriting.
+func (l *Logger) normalizeFields(fields Fields) Fields {
+	n := l.keyNorm.Load()
+	if n == nil || len(fields) == 0 {
+		return fields
+	}
+	return n.fields(fields)
+}
+
+// fields normalizes the keys of fields and of its groups. The groups are copied, since they
+// may be shared with a context.
+func (n *keyNormalizer) fields(fields Fields) Fields {
+	var renamed []string
+	hasGroup := false
+	for k, v := range fields {
+		if n.key(k) != k {
+			renamed = append(renamed, k)
+		}
+		if _, ok := v.(Fields); ok {
+			hasGroup = true
+		}
+	}
+	if len(renamed) == 0 && !hasGroup {
+		return fields
+	}
+	sort.Strings(renamed)
+	out := make(Fields, len(fields))
+	for k, v := range fields {
+		if n.key(k) == k {
+			out[k] = v
+		}
+	}
+	for _, k := range renamed {
+		nk := n.key(k)
+		if _, exists := out[nk]; !exists {
+			out[nk] = fields[k]
+		}
+	}
+	for k, v := range out {
+		if g, ok := v.(Fields); ok {
+			out[k] = n.fields(g)
+		}
+	}
+	return out
+}
+
+// key returns the normalized form of k.
+func (n *keyNormalizer) key(k string) string {
+	if v, ok := n.cache.Load(k); ok {
+		return v.(string)
+	}
+	nk := n.cfg.normalize(k)
+	if n.cached.Load() < maxNormalizedKeys {
+		if _, loaded := n.cache.LoadOrStore(k, nk); !loaded {
+			n.cached.Add(1)
+		}
+	}
+	return nk
+}
+
+// normalize applies the normalization steps to k.
+func (kn KeyNormalization) normalize(k string) string {
+	for _, p := range kn.StripPrefixes {
+		if p != "" && len(k) > len(p) && strings.HasPrefix(k, p) {
+			k = k[len(p):]
+			break
+		}
+	}
+	if a, ok := kn.Aliases[k]; ok {
+		return a
+	}
+	if kn.SnakeCase {
+		k = toSnakeCase(k)
+		if a, ok := kn.Aliases[k]; ok {
+			return a
+		}
+	}
+	return k
+}
+
+// toSnakeCase converts camelCase, PascalCase, kebab-case and space separated keys to
+// snake_case. Runs of capitals are treated as one word, so "HTTPStatus" becomes
+// "http_status" and "userID" becomes "user_id". Dots are kept.
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	sep := true // Suppresses separators at the start and after another separator.
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ' || r == '_':
+			if !sep {
+				b.WriteByte('_')
+				sep = true
+			}
+		case unicode.IsUpper(r):
+			if !sep && i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			sep = false
+		default:
+			b.WriteRune(r)
+			sep = r == '.'
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
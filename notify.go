@@ -0,0 +1,149 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements desktop notifications for development. While an application runs on
+// a developer's machine, ERROR and FATAL entries can raise a notification of the operating
+// system, so that failures during manual testing are noticed without watching a terminal.
+
+package unologger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DevNotifyEnvVar is the environment variable read by EnableDevNotifications. Any value
+// accepted by strconv.ParseBool as true, such as "1" or "true", enables the notifications.
+const DevNotifyEnvVar = "UNOLOGGER_DEV_NOTIFY"
+
+// DevNotifyHookName is the name under which EnableDevNotifications adds its hook.
+const DevNotifyHookName = "dev-notify"
+
+// ErrNotificationsUnsupported is returned by the hook of DesktopNotifyHook when the
+// platform has no supported notification command.
+var ErrNotificationsUnsupported = errors.New("unologger: desktop notifications are not supported on this platform")
+
+// Limits of desktop notifications.
+const (
+	// devNotifyInterval is the minimum time between two notifications. Entries in between
+	// are counted and reported by the next notification.
+	devNotifyInterval = 5 * time.Second
+	// devNotifyMaxLen bounds the length of the notification text, in runes.
+	devNotifyMaxLen = 200
+)
+
+// EnableDevNotifications adds DesktopNotifyHook, under DevNotifyHookName and for ERROR
+// and FATAL entries, if the UNOLOGGER_DEV_NOTIFY environment variable is set to true, and
+// reports whether it did. It is meant to be called unconditionally at startup: without the
+// variable, as in production, it does nothing.
+func (l *Logger) EnableDevNotifications() bool {
+	if on, _ := strconv.ParseBool(os.Getenv(DevNotifyEnvVar)); !on {
+		return false
+	}
+	l.AddHook(DesktopNotifyHook(), HookFilter{Name: DevNotifyHookName, MinLevel: ERROR})
+	return true
+}
+
+// DesktopNotifyHook returns a hook that raises a desktop notification for ERROR and FATAL
+// entries, with notify-send on Linux and the BSDs, osascript on macOS and PowerShell on
+// Windows. Notifications are at least 5 seconds apart; the entries in between are counted
+// in the next one. Other levels are ignored.
+func DesktopNotifyHook() HookFunc2 {
+	n := &desktopNotifier{send: sendDesktopNotification, interval: devNotifyInterval}
+	return n.hook
+}
+
+// desktopNotifier rate-limits the notifications of a hook.
+type desktopNotifier struct {
+	send     func(title, text string) error
+	interval time.Duration
+
+	mu         sync.Mutex
+	last       time.Time
+	suppressed int
+}
+
+// hook raises the notification of ev, unless the previous one is too recent.
+func (n *desktopNotifier) hook(_ context.Context, ev HookEvent) error {
+	if ev.Level.Base() < ERROR {
+		return nil
+	}
+	n.mu.Lock()
+	now := time.Now()
+	if !n.last.IsZero() && now.Sub(n.last) < n.interval {
+		n.suppressed++
+		n.mu.Unlock()
+		return nil
+	}
+	n.last = now
+	suppressed := n.suppressed
+	n.suppressed = 0
+	n.mu.Unlock()
+
+	title := ev.Level.String()
+	if ev.Module != "" {
+		title += " in " + ev.Module
+	}
+	text := ev.Message
+	if chain := eventErrorChain(ev); len(chain) > 0 {
+		text += ": " + chain[0]
+	}
+	if r := []rune(text); len(r) > devNotifyMaxLen {
+		text = string(r[:devNotifyMaxLen-1]) + "…"
+	}
+	if suppressed > 0 {
+		text += fmt.Sprintf(" (+%d more)", suppressed)
+	}
+	return n.send(title, text)
+}
+
+// sendDesktopNotification starts the notification command of the platform. It does not
+// wait for the command, which may outlive the hook timeout on Windows.
+func sendDesktopNotification(title, text string) error {
+	cmd := notifyCommand(title, text)
+	if cmd == nil {
+		return ErrNotificationsUnsupported
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unologger: desktop notification: %w", err)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// notifyCommand returns the command that shows a notification on this platform, or nil.
+// The title and text are passed as arguments or environment variables, never inside a
+// script, so that they need no quoting.
+func notifyCommand(title, text string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run", title, text)
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+			"Add-Type -AssemblyName System.Windows.Forms; "+
+				"$n = New-Object System.Windows.Forms.NotifyIcon; "+
+				"$n.Icon = [System.Drawing.SystemIcons]::Error; $n.Visible = $true; "+
+				"$n.ShowBalloonTip(5000, $env:UNOLOGGER_NOTIFY_TITLE, $env:UNOLOGGER_NOTIFY_TEXT, 'Error'); "+
+				"Start-Sleep -Seconds 6; $n.Dispose()")
+		cmd.Env = append(os.Environ(), "UNOLOGGER_NOTIFY_TITLE="+title, "UNOLOGGER_NOTIFY_TEXT="+text)
+		return cmd
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("notify-send"); err != nil {
+			return nil
+		}
+		return exec.Command("notify-send", "--urgency=critical", "--app-name=unologger", title, text)
+	default:
+		return nil
+	}
+}
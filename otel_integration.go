@@ -11,7 +11,10 @@ package unologger
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -64,7 +67,148 @@ func AttachOTelTrace(ctx context.Context) context.Context {
 
 	// Also attach the span ID as a field for more detailed correlation.
 	if sid := extractOTelSpanID(ctx); sid != "" {
-		ctx = WithAttrs(ctx, Fields{"span_id": sid})
+		ctx = WithAttrs(ctx, Fields{FieldSpanID: sid})
 	}
 	return ctx
 }
+
+// SetTraceAwareDebug enables or disables trace-aware throttling of DEBUG entries at runtime.
+// While enabled, and while OTel integration is enabled, a DEBUG entry whose context carries
+// a span context that is not sampled is discarded at the call site, so verbose logging
+// volume follows the trace sampling decision. DEBUG entries without a span context, and
+// entries at other levels, are not affected.
+func (l *Logger) SetTraceAwareDebug(enabled bool) {
+	l.traceDebug.Store(enabled)
+}
+
+// TraceThrottled returns the number of DEBUG entries discarded because their trace was
+// not sampled.
+func (l *Logger) TraceThrottled() int64 {
+	return l.traceThrottled.Load()
+}
+
+// traceThrottle reports whether an entry must be discarded because it is a DEBUG entry
+// of an unsampled trace, and counts it if so.
+func (l *Logger) traceThrottle(ctx context.Context, level Level) bool {
+	if level.Base() > DEBUG || ctx == nil || !l.traceDebug.Load() || !l.enableOTel.Load() {
+		return false
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || sc.IsSampled() {
+		return false
+	}
+	l.traceThrottled.Add(1)
+	return true
+}
+
+// SetSpanEvents enables or disables span events at runtime. While enabled, every entry at
+// or above minLevel whose context carries a recording span is added to that span as an
+// event named "log", with the level, module, message and fields as attributes, so tracing
+// UIs show the correlated log messages inline. An entry with an error-valued field is
+// recorded as an "exception" event carrying the attributes of ExceptionAttributes. The
+// event is added by the worker once the message has been formatted and masked; an entry
+// processed after its span has ended is not recorded.
+func (l *Logger) SetSpanEvents(enabled bool, minLevel Level) {
+	if enabled {
+		l.spanEventLvl.Store(int64(minLevel))
+	} else {
+		l.spanEventLvl.Store(levelOff)
+	}
+}
+
+// addSpanEvent records ev as an event on the span of ctx, if span events are enabled for
+// its level and the span is recording.
+func (l *Logger) addSpanEvent(ctx context.Context, ev HookEvent) {
+	if minLevel := l.spanEventLvl.Load(); minLevel == levelOff || int64(ev.Level.Base()) < minLevel {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, 3+len(ev.Fields))
+	attrs = append(attrs,
+		attribute.String("log.severity", ev.Level.String()),
+		attribute.String("log.message", ev.Message),
+	)
+	if ev.Module != "" {
+		attrs = append(attrs, attribute.String("log.module", ev.Module))
+	}
+	for k, v := range ev.Fields {
+		attrs = append(attrs, spanAttribute(k, v))
+	}
+	name := "log"
+	if err := eventError(ev); err != nil {
+		// Follow the semantic conventions so tracing backends render the entry as an exception.
+		name = "exception"
+		attrs = append(attrs, ExceptionAttributes(err, ev.Fields)...)
+	}
+	span.AddEvent(name, trace.WithTimestamp(ev.Time), trace.WithAttributes(attrs...))
+}
+
+// eventError returns the error carried by the fields of ev: the value of the "error" or
+// "err" field if it is an error, or otherwise the error-valued field with the smallest key.
+func eventError(ev HookEvent) error {
+	for _, k := range []string{"error", "err"} {
+		if err, ok := ev.Fields[k].(error); ok && err != nil {
+			return err
+		}
+	}
+	var key string
+	var found error
+	for k, v := range ev.Fields {
+		if err, ok := v.(error); ok && err != nil && (found == nil || k < key) {
+			key, found = k, err
+		}
+	}
+	return found
+}
+
+// ExceptionAttributes returns the OTel semantic convention attributes describing err:
+// exception.type (the Go type of err), exception.message and, when available,
+// exception.stacktrace. The stack trace is taken from a *PanicError, from a string "stack"
+// field (as set by LoggerWithCtx.Recover), or from the "%+v" formatting of errors that
+// implement fmt.Formatter and print a stack that way.
+func ExceptionAttributes(err error, fields Fields) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("exception.type", fmt.Sprintf("%T", err)),
+		attribute.String("exception.message", err.Error()),
+	}
+	var stack string
+	var pe *PanicError
+	switch {
+	case errors.As(err, &pe) && len(pe.Stack) > 0:
+		stack = string(pe.Stack)
+	case fields["stack"] != nil:
+		stack, _ = fields["stack"].(string)
+	default:
+		if _, ok := err.(fmt.Formatter); ok {
+			if s := fmt.Sprintf("%+v", err); s != err.Error() {
+				stack = s
+			}
+		}
+	}
+	if stack != "" {
+		attrs = append(attrs, attribute.String("exception.stacktrace", stack))
+	}
+	return attrs
+}
+
+// spanAttribute converts a field to a span attribute, keeping basic types and formatting
+// any other value with fmt.
+func spanAttribute(k string, v interface{}) attribute.KeyValue {
+	switch x := v.(type) {
+	case string:
+		return attribute.String(k, x)
+	case bool:
+		return attribute.Bool(k, x)
+	case int:
+		return attribute.Int(k, x)
+	case int64:
+		return attribute.Int64(k, x)
+	case float64:
+		return attribute.Float64(k, x)
+	default:
+		return attribute.String(k, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the OTLP log exporter. Every entry is also sent as an OpenTelemetry
+// log record to a collector, over OTLP/HTTP (protobuf or JSON) or OTLP/gRPC, with the trace
+// and span IDs of the entry, so logs are correlated with traces in the backend. The records
+// of a batch are exported in one request.
+
+package unologger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OTLPSinkName is the writer name under which the OTLP exporter appears in WriterStats.
+const OTLPSinkName = "otlp"
+
+// Defaults of OTLPConfig.
+const (
+	defaultOTLPTimeout  = 10 * time.Second
+	defaultOTLPMaxBatch = 512
+)
+
+// otlpGRPCPath is the gRPC method of the OTLP logs service.
+const otlpGRPCPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// OTLPProtocol selects the transport and encoding of the OTLP exporter.
+type OTLPProtocol int
+
+const (
+	// OTLPHTTPProtobuf sends protobuf-encoded requests over HTTP, the OTLP default.
+	OTLPHTTPProtobuf OTLPProtocol = iota
+	// OTLPHTTPJSON sends JSON-encoded requests over HTTP.
+	OTLPHTTPJSON
+	// OTLPGRPC calls the LogsService over gRPC.
+	OTLPGRPC
+)
+
+// OTLPConfig configures the OTLP log exporter.
+type OTLPConfig struct {
+	// Endpoint is the address of the collector. For HTTP, a URL whose path defaults to
+	// "/v1/logs", e.g. "http://localhost:4318". For gRPC, "host:port" or a URL, e.g.
+	// "localhost:4317". Required.
+	Endpoint string
+	// Protocol selects the transport and encoding. Defaults to OTLPHTTPProtobuf.
+	Protocol OTLPProtocol
+	// Headers are added to every request, e.g. an API key of a SaaS backend.
+	Headers map[string]string
+	// TLS configures the TLS client of https endpoints and of gRPC. Nil uses the
+	// system roots.
+	TLS *tls.Config
+	// Insecure makes gRPC use plaintext HTTP/2 instead of TLS. It is implied by an
+	// "http://" gRPC endpoint.
+	Insecure bool
+	// Timeout bounds each export request. Defaults to 10s.
+	Timeout time.Duration
+	// MaxBatch caps the records per export request; larger batches are split. Defaults
+	// to 512.
+	MaxBatch int
+	// Resource holds the resource attributes of the records, e.g. "service.name".
+	Resource Fields
+}
+
+// otlpExporter sends the records of a batch to the collector.
+type otlpExporter struct {
+	cfg    OTLPConfig
+	url    string
+	client *http.Client
+}
+
+// SetOTLP starts exporting every entry to an OpenTelemetry collector, or stops it if cfg is
+// nil. Records are exported by the workers, one request per batch and per MaxBatch records,
+// with the retry policy of the logger; failures are counted in WriterStats under "otlp" and
+// reported to the OnWriteError handler, but do not fail synchronous log calls.
+func (l *Logger) SetOTLP(cfg *OTLPConfig) error {
+	if cfg == nil {
+		if old := l.otlp.Swap(nil); old != nil {
+			old.client.CloseIdleConnections()
+		}
+		return nil
+	}
+	exp, err := newOTLPExporter(*cfg)
+	if err != nil {
+		return err
+	}
+	if old := l.otlp.Swap(exp); old != nil {
+		old.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// newOTLPExporter validates cfg, applies its defaults and builds the HTTP client.
+func newOTLPExporter(cfg OTLPConfig) (*otlpExporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("unologger: OTLP endpoint is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultOTLPTimeout
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = defaultOTLPMaxBatch
+	}
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		scheme := "https://"
+		if cfg.Protocol != OTLPGRPC || cfg.Insecure {
+			scheme = "http://"
+		}
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("unologger: invalid OTLP endpoint %q", cfg.Endpoint)
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = cfg.TLS
+	if cfg.Protocol == OTLPGRPC {
+		// gRPC requires HTTP/2, negotiated by TLS or spoken directly over plaintext.
+		tr.Protocols = new(http.Protocols)
+		if u.Scheme == "http" {
+			tr.Protocols.SetUnencryptedHTTP2(true)
+		} else {
+			tr.Protocols.SetHTTP2(true)
+		}
+		u.Path = otlpGRPCPath
+	} else if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	return &otlpExporter{
+		cfg:    cfg,
+		url:    u.String(),
+		client: &http.Client{Transport: tr, Timeout: cfg.Timeout},
+	}, nil
+}
+
+// collectOTLP keeps the event of an entry for the OTLP exporter, if one is set.
+func (l *Logger) collectOTLP(out *batchOutput, ev HookEvent) {
+	exp := l.otlp.Load()
+	if exp == nil {
+		return
+	}
+	if out.otlpExp != exp {
+		clear(out.otlp)
+		out.otlp = out.otlp[:0]
+		out.otlpExp = exp
+	}
+	out.otlp = append(out.otlp, ev)
+}
+
+// writeOTLP exports the events collected in out, in requests of at most MaxBatch records.
+func (l *Logger) writeOTLP(out *batchOutput) {
+	exp := out.otlpExp
+	if exp == nil {
+		return
+	}
+	for evs := out.otlp; len(evs) > 0; {
+		n := min(len(evs), exp.cfg.MaxBatch)
+		chunk := evs[:n]
+		evs = evs[n:]
+		body, err := exp.encode(chunk)
+		if err == nil {
+			err = l.retryWrite(OTLPSinkName, func() error { return exp.export(context.Background(), body) })
+		} else {
+			l.incWriterErr(OTLPSinkName, err)
+		}
+		if err != nil {
+			for _, ev := range chunk {
+				l.reportWriteError(OTLPSinkName, err, ev)
+			}
+			continue
+		}
+		l.writerSucceeded(OTLPSinkName, len(body), n)
+	}
+}
+
+// encode builds the body of an export request holding evs.
+func (exp *otlpExporter) encode(evs []HookEvent) ([]byte, error) {
+	switch exp.cfg.Protocol {
+	case OTLPHTTPJSON:
+		return otlpJSON(exp.cfg.Resource, evs)
+	case OTLPGRPC:
+		msg := otlpProtobuf(exp.cfg.Resource, evs)
+		// A gRPC message is framed by a compression flag and its big-endian length.
+		frame := make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+		return append(frame, msg...), nil
+	default:
+		return otlpProtobuf(exp.cfg.Resource, evs), nil
+	}
+}
+
+// export sends one request and checks the status of the HTTP or gRPC response.
+func (exp *otlpExporter) export(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, exp.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exp.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	switch exp.cfg.Protocol {
+	case OTLPHTTPJSON:
+		req.Header.Set("Content-Type", "application/json")
+	case OTLPGRPC:
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+	default:
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	}
+	for k, v := range exp.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := exp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The body must be read to the end for the gRPC trailers to be available.
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unologger: OTLP export failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if exp.cfg.Protocol == OTLPGRPC {
+		status, detail := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+		if status == "" {
+			// A trailers-only response carries the status in the headers.
+			status, detail = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+		}
+		if status != "0" {
+			return fmt.Errorf("unologger: OTLP export failed: grpc status %s: %s", status, detail)
+		}
+	}
+	return nil
+}
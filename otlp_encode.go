@@ -0,0 +1,417 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the encodings of OTLP export requests: the protobuf wire format of
+// ExportLogsServiceRequest, written by hand to avoid depending on generated code, and its
+// JSON mapping. Both are built from the same record representation.
+
+package unologger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpScopeName is the instrumentation scope of the exported records.
+const otlpScopeName = "github.com/phuonguno98/unologger"
+
+// otlpRecord is a log record, with attribute values reduced to the OTLP value types:
+// string, bool, int64, float64, []byte, []interface{} and []otlpKV.
+type otlpRecord struct {
+	time, observed uint64
+	severity       int
+	severityText   string
+	body           string
+	attrs          []otlpKV
+	traceID        []byte
+	spanID         []byte
+}
+
+// otlpKV is a key-value pair of attributes or of a map value.
+type otlpKV struct {
+	key   string
+	value interface{}
+}
+
+// otlpSeverity maps levels to OTLP severity numbers.
+func otlpSeverity(lvl Level) int {
+	switch lvl.Base() {
+	case TRACE:
+		return 1
+	case DEBUG:
+		return 5
+	case INFO:
+		return 9
+	case WARN:
+		return 13
+	case ERROR:
+		return 17
+	case FATAL:
+		return 21
+	default:
+		return 0
+	}
+}
+
+// newOTLPRecord converts an event. The trace ID, with the dashes of generated UUIDs
+// removed, and the span_id field become the trace context of the record when they are
+// valid hex IDs, and attributes otherwise. The module and flow ID become attributes, and
+// the caller the code.filepath, code.lineno and code.function attributes, and the error
+// the exception.message, exception.type and exception.stacktrace attributes.
+func newOTLPRecord(ev HookEvent, observed time.Time) otlpRecord {
+	r := otlpRecord{
+		observed:     uint64(observed.UnixNano()),
+		severity:     otlpSeverity(ev.Level),
+		severityText: ev.Level.String(),
+		body:         ev.Message,
+	}
+	if !ev.Time.IsZero() {
+		r.time = uint64(ev.Time.UnixNano())
+	}
+	fields := ev.Fields
+	if fields == nil {
+		fields = ev.Attrs
+	}
+	if ev.TraceID != "" {
+		if id, err := hex.DecodeString(strings.ReplaceAll(ev.TraceID, "-", "")); err == nil && len(id) == 16 {
+			r.traceID = id
+		} else {
+			r.attrs = append(r.attrs, otlpKV{"trace_id", ev.TraceID})
+		}
+	}
+	if s, ok := fields[FieldSpanID].(string); ok && r.traceID != nil {
+		if id, err := hex.DecodeString(s); err == nil && len(id) == 8 {
+			r.spanID = id
+		}
+	}
+	if ev.Module != "" {
+		r.attrs = append(r.attrs, otlpKV{"module", ev.Module})
+	}
+	if ev.FlowID != "" {
+		r.attrs = append(r.attrs, otlpKV{"flow_id", ev.FlowID})
+	}
+	if !ev.Caller.IsZero() {
+		r.attrs = append(r.attrs, otlpKV{"code.filepath", ev.Caller.File}, otlpKV{"code.lineno", int64(ev.Caller.Line)},
+			otlpKV{"code.function", ev.Caller.Function})
+	}
+	if chain := eventErrorChain(ev); len(chain) > 0 {
+		r.attrs = append(r.attrs, otlpKV{"exception.message", chain[0]})
+		if ev.Error != nil {
+			r.attrs = append(r.attrs, otlpKV{"exception.type", errorType(ev.Error)})
+		}
+		if ev.Stack != "" {
+			r.attrs = append(r.attrs, otlpKV{"exception.stacktrace", ev.Stack})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		if k == FieldSpanID && r.spanID != nil {
+			continue
+		}
+		r.attrs = append(r.attrs, otlpKV{k, otlpValue(fields[k], 0)})
+	}
+	return r
+}
+
+// otlpValue reduces v to an OTLP value type. Maps and slices are converted up to a small
+// depth; other values are formatted as strings.
+func otlpValue(v interface{}, depth int) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return val
+	case int:
+		return int64(val)
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case int64:
+		return val
+	case uint:
+		return otlpUint(uint64(val))
+	case uint8:
+		return int64(val)
+	case uint16:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		return otlpUint(val)
+	case float32:
+		return float64(val)
+	case float64:
+		return val
+	case []byte:
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case time.Duration:
+		return val.String()
+	case error:
+		return val.Error()
+	case fmt.Stringer:
+		return val.String()
+	}
+	if depth < 8 {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			out := make([]interface{}, rv.Len())
+			for i := range out {
+				out[i] = otlpValue(rv.Index(i).Interface(), depth+1)
+			}
+			return out
+		case reflect.Map:
+			if rv.Type().Key().Kind() == reflect.String {
+				kvs := make([]otlpKV, 0, rv.Len())
+				for _, k := range rv.MapKeys() {
+					kvs = append(kvs, otlpKV{k.String(), otlpValue(rv.MapIndex(k).Interface(), depth+1)})
+				}
+				slices.SortFunc(kvs, func(a, b otlpKV) int { return strings.Compare(a.key, b.key) })
+				return kvs
+			}
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// otlpUint converts an unsigned integer, as a string if it does not fit an int64.
+func otlpUint(u uint64) interface{} {
+	if u > math.MaxInt64 {
+		return strconv.FormatUint(u, 10)
+	}
+	return int64(u)
+}
+
+// otlpRecords converts the events of a request.
+func otlpRecords(evs []HookEvent) []otlpRecord {
+	now := time.Now()
+	records := make([]otlpRecord, len(evs))
+	for i, ev := range evs {
+		records[i] = newOTLPRecord(ev, now)
+	}
+	return records
+}
+
+// otlpResource returns the resource attributes, in lexical order.
+func otlpResource(resource Fields) []otlpKV {
+	kvs := make([]otlpKV, 0, len(resource))
+	for _, k := range slices.Sorted(maps.Keys(resource)) {
+		kvs = append(kvs, otlpKV{k, otlpValue(resource[k], 0)})
+	}
+	return kvs
+}
+
+// --- Protobuf ---
+
+// Protobuf wire types.
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+)
+
+// pbuf appends protobuf fields to a byte slice.
+type pbuf []byte
+
+func (b *pbuf) varint(v uint64) { *b = binary.AppendUvarint(*b, v) }
+
+func (b *pbuf) tag(field, wire int) { b.varint(uint64(field)<<3 | uint64(wire)) }
+
+func (b *pbuf) bytes(field int, p []byte) {
+	b.tag(field, pbBytes)
+	b.varint(uint64(len(p)))
+	*b = append(*b, p...)
+}
+
+func (b *pbuf) string(field int, s string) {
+	b.tag(field, pbBytes)
+	b.varint(uint64(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *pbuf) fixed64(field int, v uint64) {
+	b.tag(field, pbFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, v)
+}
+
+// message appends the embedded message written by fn.
+func (b *pbuf) message(field int, fn func(*pbuf)) {
+	var sub pbuf
+	fn(&sub)
+	b.bytes(field, sub)
+}
+
+// otlpProtobuf encodes an ExportLogsServiceRequest.
+func otlpProtobuf(resource Fields, evs []HookEvent) []byte {
+	var b pbuf
+	b.message(1, func(rl *pbuf) { // ResourceLogs
+		rl.message(1, func(res *pbuf) { // Resource
+			for _, kv := range otlpResource(resource) {
+				res.message(1, kv.protobuf)
+			}
+		})
+		rl.message(2, func(sl *pbuf) { // ScopeLogs
+			sl.message(1, func(scope *pbuf) { scope.string(1, otlpScopeName) })
+			for _, r := range otlpRecords(evs) {
+				sl.message(2, r.protobuf)
+			}
+		})
+	})
+	return b
+}
+
+// protobuf encodes a LogRecord.
+func (r otlpRecord) protobuf(b *pbuf) {
+	if r.time != 0 {
+		b.fixed64(1, r.time)
+	}
+	if r.severity != 0 {
+		b.tag(2, pbVarint)
+		b.varint(uint64(r.severity))
+	}
+	b.string(3, r.severityText)
+	b.message(5, func(v *pbuf) { pbAnyValue(v, r.body) })
+	for _, kv := range r.attrs {
+		b.message(6, kv.protobuf)
+	}
+	if r.traceID != nil {
+		b.bytes(9, r.traceID)
+	}
+	if r.spanID != nil {
+		b.bytes(10, r.spanID)
+	}
+	b.fixed64(11, r.observed)
+}
+
+// protobuf encodes a KeyValue.
+func (kv otlpKV) protobuf(b *pbuf) {
+	b.string(1, kv.key)
+	b.message(2, func(v *pbuf) { pbAnyValue(v, kv.value) })
+}
+
+// pbAnyValue encodes the fields of an AnyValue.
+func pbAnyValue(b *pbuf, v interface{}) {
+	switch val := v.(type) {
+	case string:
+		b.string(1, val)
+	case bool:
+		b.tag(2, pbVarint)
+		if val {
+			b.varint(1)
+		} else {
+			b.varint(0)
+		}
+	case int64:
+		b.tag(3, pbVarint)
+		b.varint(uint64(val))
+	case float64:
+		b.fixed64(4, math.Float64bits(val))
+	case []interface{}:
+		b.message(5, func(arr *pbuf) {
+			for _, e := range val {
+				arr.message(1, func(ev *pbuf) { pbAnyValue(ev, e) })
+			}
+		})
+	case []otlpKV:
+		b.message(6, func(list *pbuf) {
+			for _, kv := range val {
+				list.message(1, kv.protobuf)
+			}
+		})
+	case []byte:
+		b.bytes(7, val)
+	}
+}
+
+// --- JSON ---
+
+// otlpJSON encodes an ExportLogsServiceRequest with the OTLP JSON mapping: lowerCamelCase
+// names, 64-bit integers as strings and trace and span IDs as hex strings.
+func otlpJSON(resource Fields, evs []HookEvent) ([]byte, error) {
+	records := otlpRecords(evs)
+	logRecords := make([]interface{}, len(records))
+	for i, r := range records {
+		rec := map[string]interface{}{
+			"observedTimeUnixNano": strconv.FormatUint(r.observed, 10),
+			"severityText":         r.severityText,
+			"body":                 jsonAnyValue(r.body),
+			"attributes":           jsonKVs(r.attrs),
+		}
+		if r.time != 0 {
+			rec["timeUnixNano"] = strconv.FormatUint(r.time, 10)
+		}
+		if r.severity != 0 {
+			rec["severityNumber"] = r.severity
+		}
+		if r.traceID != nil {
+			rec["traceId"] = hex.EncodeToString(r.traceID)
+		}
+		if r.spanID != nil {
+			rec["spanId"] = hex.EncodeToString(r.spanID)
+		}
+		logRecords[i] = rec
+	}
+	return json.Marshal(map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": jsonKVs(otlpResource(resource))},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]interface{}{"name": otlpScopeName},
+				"logRecords": logRecords,
+			}},
+		}},
+	})
+}
+
+// jsonKVs maps key-value pairs to their JSON form.
+func jsonKVs(kvs []otlpKV) []interface{} {
+	out := make([]interface{}, len(kvs))
+	for i, kv := range kvs {
+		out[i] = map[string]interface{}{"key": kv.key, "value": jsonAnyValue(kv.value)}
+	}
+	return out
+}
+
+// jsonAnyValue maps a value to the JSON form of an AnyValue.
+func jsonAnyValue(v interface{}) map[string]interface{} {
+	switch val := v.(type) {
+	case bool:
+		return map[string]interface{}{"boolValue": val}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			// JSON numbers cannot represent them; the mapping uses these strings.
+			return map[string]interface{}{"doubleValue": strconv.FormatFloat(val, 'g', -1, 64)}
+		}
+		return map[string]interface{}{"doubleValue": val}
+	case []interface{}:
+		values := make([]interface{}, len(val))
+		for i, e := range val {
+			values[i] = jsonAnyValue(e)
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	case []otlpKV:
+		return map[string]interface{}{"kvlistValue": map[string]interface{}{"values": jsonKVs(val)}}
+	case []byte:
+		return map[string]interface{}{"bytesValue": val}
+	case string:
+		return map[string]interface{}{"stringValue": val}
+	}
+	return map[string]interface{}{"stringValue": fmt.Sprint(v)}
+}
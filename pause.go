@@ -0,0 +1,144 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements pausing. A paused logger keeps accepting log calls but leaves its
+// sinks alone, holding or dropping the entries until it is resumed, so that storage can be
+// maintained or the credentials of remote sinks rotated without closing the logger.
+
+package unologger
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrLoggerPaused is returned by the *Sync logging methods while the logger is paused.
+var ErrLoggerPaused = errors.New("unologger: logger is paused")
+
+// PausePolicy selects what a paused logger does with new entries.
+type PausePolicy int
+
+const (
+	// PauseBuffer holds the entries, up to PauseConfig.MaxBuffered, and writes them on
+	// Resume. Entries beyond the limit are dropped. This is the default.
+	PauseBuffer PausePolicy = iota
+	// PauseDrop drops the entries logged while the logger is paused.
+	PauseDrop
+)
+
+// PauseConfig configures what Pause does with the entries logged while paused.
+type PauseConfig struct {
+	Policy PausePolicy
+	// MaxBuffered bounds the entries held by PauseBuffer. Defaults to the capacity of the
+	// queue, or 1024 in single-writer mode.
+	MaxBuffered int
+}
+
+// defaultPauseBuffer is the default of PauseConfig.MaxBuffered in single-writer mode.
+const defaultPauseBuffer = 1024
+
+// pauseState holds the settings of pausing and the entries held while paused.
+type pauseState struct {
+	resume sync.Mutex // Serializes Resume calls.
+	mu     sync.Mutex
+	cfg    PauseConfig
+	held   []*logEntry
+}
+
+// SetPauseConfig sets what the logger does with the entries logged while paused. It takes
+// effect for the entries logged after the call.
+func (l *Logger) SetPauseConfig(cfg PauseConfig) {
+	l.pause.mu.Lock()
+	l.pause.cfg = cfg
+	l.pause.mu.Unlock()
+}
+
+// Pause stops writing entries until Resume is called, without closing the logger. It
+// returns once the entries queued before the call have been written, so that the sinks
+// are idle afterwards; entries logged while paused are held or dropped according to
+// PauseConfig, and synchronous calls fail with ErrLoggerPaused. FATAL entries and panics
+// recovered by Recover are still written to stderr and the rotation file by the emergency
+// path. Pause is idempotent. The delegates, such as the members of a Tee, are not paused.
+func (l *Logger) Pause() {
+	if !l.paused.TrySetTrue() {
+		return
+	}
+	// Wait for the producers that passed the paused check before it was set: they hold
+	// the queue lock, or the direct-write lock in single-writer mode, while enqueueing.
+	l.chMu.Lock()
+	l.chMu.Unlock() //nolint:staticcheck // Used as a barrier.
+	if l.direct {
+		l.directMu.Lock()
+		l.directMu.Unlock() //nolint:staticcheck // Used as a barrier.
+	} else {
+		l.flushQueued()
+	}
+	l.emitLifecycle(LifecycleEvent{Type: LifecyclePaused})
+}
+
+// Resume writes the entries held while paused, in the order they were logged, and
+// restarts normal operation. The logger stays paused until the held entries are queued,
+// so that the entries logged meanwhile are held too and written after them. With
+// NonBlocking, held entries that do not fit in the queue are dropped. Resume is a no-op
+// if the logger is not paused.
+func (l *Logger) Resume() {
+	p := &l.pause
+	p.resume.Lock()
+	defer p.resume.Unlock()
+	for {
+		p.mu.Lock()
+		if !l.paused.Load() {
+			p.mu.Unlock()
+			return
+		}
+		held := p.held
+		p.held = nil
+		if len(held) == 0 {
+			l.paused.Store(false)
+			p.mu.Unlock()
+			break
+		}
+		p.mu.Unlock()
+		for _, e := range held {
+			l.enqueue(e)
+		}
+	}
+	l.emitLifecycle(LifecycleEvent{Type: LifecycleResumed})
+}
+
+// Paused reports whether the logger is paused.
+func (l *Logger) Paused() bool {
+	return l.paused.Load()
+}
+
+// holdEntry applies the pause policy to an entry logged while paused. It reports false if
+// the logger was resumed in the meantime, in which case the entry must be enqueued.
+func (l *Logger) holdEntry(e *logEntry) bool {
+	if e.ack != nil {
+		e.settle(ErrLoggerPaused)
+		recycleEntry(e)
+		return true
+	}
+	p := &l.pause
+	p.mu.Lock()
+	if !l.paused.Load() {
+		p.mu.Unlock()
+		return false
+	}
+	limit := p.cfg.MaxBuffered
+	if limit <= 0 && l.direct {
+		limit = defaultPauseBuffer
+	} else if limit <= 0 {
+		limit = cap(l.ch) + cap(l.hi) // The caller holds chMu.
+	}
+	if p.cfg.Policy == PauseBuffer && len(p.held) < limit {
+		e.held = true
+		p.held = append(p.held, e)
+		p.mu.Unlock()
+		return true
+	}
+	p.mu.Unlock()
+	l.dropEntry(e)
+	return true
+}
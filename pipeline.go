@@ -19,11 +19,19 @@ import (
 //
 // Behavior paths:
 //
-//  1. If the logger is closed, the entry is immediately discarded and recycled.
+//  1. If the logger is closed, the entry is counted as logged-after-close and recycled.
+//     The closed check and the channel send happen under chMu's read lock, and
+//     closeLogger closes the channel under the write lock, so a send can never hit
+//     a closed channel. If the logger is paused, the entry is held or dropped by
+//     holdEntry, under the same lock.
 //
-//  2. If in blocking mode (`nonBlocking` is false), it will wait for space in the channel.
+//  2. If in single-writer mode, the entry is written synchronously by writeDirect.
 //
-//  3. If in non-blocking mode (`nonBlocking` is true):
+//  3. If in blocking mode (`nonBlocking` is false), it will wait for space in the channel.
+//     With a priority queue, the channel is the one of the entry's tier (see tierFor), and
+//     the steps below apply to that tier only.
+//
+//  4. If in non-blocking mode (`nonBlocking` is true):
 //     a. It first tries to send the entry.
 //
 //     b. If the channel is full and `dropOldest` is true, it attempts to remove the
@@ -32,14 +40,40 @@ import (
 //     c. If the channel is full and `dropOldest` is false (or if making space fails),
 //     the new entry is dropped.
 func (l *Logger) enqueue(e *logEntry) {
+	// Fast path: once closed, never touch the lock. This keeps producers from
+	// queueing behind a pending closeLogger write lock.
 	if l.closed.Load() {
-		recycleEntry(e)
+		l.rejectAfterClose(e)
+		return
+	}
+
+	l.stampSeq(e)
+	if l.direct {
+		l.writeDirect(e)
+		return
+	}
+
+	l.chMu.RLock()
+	defer l.chMu.RUnlock()
+	if l.closed.Load() {
+		l.rejectAfterClose(e)
+		return
+	}
+	if l.paused.Load() && e.barrier == nil && !e.held && l.holdEntry(e) {
 		return
 	}
+	q := l.tierFor(e)
 
-	if !l.nonBlocking {
-		// Blocking mode: wait for space.
-		l.ch <- e
+	if !l.nonBlocking || e.ack != nil || e.barrier != nil {
+		// Blocking mode: wait for space. Synchronous calls and barriers always wait,
+		// since their caller is blocked until the entry is written anyway. A producer
+		// holds the read lock while it waits, so it must give up once shutdown
+		// starts; otherwise closeLogger could never take the write lock.
+		select {
+		case q <- e:
+		case <-l.closing:
+			l.rejectAfterClose(e)
+		}
 		return
 	}
 
@@ -47,49 +81,95 @@ func (l *Logger) enqueue(e *logEntry) {
 	if l.dropOldest {
 		// Try to drop the oldest entry to make room.
 		select {
-		case l.ch <- e:
+		case q <- e:
 			// Enqueued successfully.
 		default:
 			// Channel is full, try to dequeue the oldest and enqueue the new one.
 			select {
-			case oldest := <-l.ch:
+			case oldest := <-q:
 				// Dropped the oldest entry.
-				l.droppedCount.Add(1)
-				recycleEntry(oldest)
+				l.dropEntry(oldest)
 				// Now try to enqueue the new entry again.
 				select {
-				case l.ch <- e:
+				case q <- e:
 					// Success.
 				default:
 					// Still full, drop the new entry.
-					l.droppedCount.Add(1)
-					recycleEntry(e)
+					l.dropEntry(e)
 				}
 			default:
 				// Channel is full and couldn't even drop an old one, so drop the new one.
-				l.droppedCount.Add(1)
-				recycleEntry(e)
+				l.dropEntry(e)
 			}
 		}
 	} else {
 		// Default non-blocking: drop the new entry if the queue is full.
 		select {
-		case l.ch <- e:
+		case q <- e:
 			// Enqueued successfully.
 		default:
 			// Channel is full, drop the current entry.
-			l.droppedCount.Add(1)
-			recycleEntry(e)
+			l.dropEntry(e)
 		}
 	}
 }
 
+// queue returns the current processing channel. The channel can be replaced at
+// runtime by SetBufferSize, so callers outside chMu must not cache l.ch.
+func (l *Logger) queue() chan *logEntry {
+	l.chMu.RLock()
+	defer l.chMu.RUnlock()
+	return l.ch
+}
+
+// queueLink returns the link of the current processing channel.
+func (l *Logger) queueLink() *queueLink {
+	l.chMu.RLock()
+	defer l.chMu.RUnlock()
+	return l.chLink
+}
+
+// rejectAfterClose counts and recycles an entry logged after the logger was closed.
+func (l *Logger) rejectAfterClose(e *logEntry) {
+	l.afterClose.Add(e.weight())
+	e.settle(ErrLoggerClosed)
+	recycleEntry(e)
+}
+
+// dropEntry counts and recycles an entry that could not be queued.
+func (l *Logger) dropEntry(e *logEntry) {
+	l.droppedCount.Add(e.weight())
+	e.settle(ErrEntryDropped)
+	recycleEntry(e)
+}
+
+// writeDirect processes a single entry on the caller's goroutine while holding directMu.
+// It is the whole pipeline in single-writer mode. The closed flag is re-checked under
+// the lock so that no write can race with closeLogger closing the writers.
+func (l *Logger) writeDirect(e *logEntry) {
+	l.directMu.Lock()
+	defer l.directMu.Unlock()
+	if l.closed.Load() {
+		l.rejectAfterClose(e)
+		return
+	}
+	if l.paused.Load() && !e.held && l.holdEntry(e) {
+		return
+	}
+	l.processBatch([]*logEntry{e})
+	l.batchCount.Add(1)
+}
+
 // workerLoop is the main loop for a single worker goroutine. It is responsible for
 // receiving log entries, collecting them into batches, and flushing them for processing.
-// Batching is triggered by two conditions: the batch reaching its maximum size, or a
-// timeout expiring.
-func (l *Logger) workerLoop() {
+// Batching is triggered by two conditions: the batch reaching its maximum size, or
+// MaxWait expiring after the first entry of a partial batch. An entry at or above the
+// configured flush level, or a request from FlushBatchNow, flushes the batch immediately.
+// Closing the worker's quit channel makes it flush its batch and exit, which is how
+// SetWorkers shrinks the pool.
+func (l *Logger) workerLoop(w *workerCtl) {
 	defer l.wg.Done()
+	defer close(w.done)
 
 	batch := poolBatch.Get().(*logBatch)
 	defer poolBatch.Put(batch) // Ensure batch is returned to the pool on exit.
@@ -111,128 +191,369 @@ func (l *Logger) workerLoop() {
 		}
 	}
 
-	// The timer triggers a flush when the MaxWait duration is reached.
-	wait := time.Duration(l.batchWaitA.Load())
-	if wait <= 0 {
-		wait = time.Second
-	}
-	timer := time.NewTimer(wait)
+	// The timer triggers a flush when the MaxWait duration is reached. It is armed only
+	// while a partial batch is pending: with a batch size of 1 every entry is flushed as
+	// soon as it arrives, so the worker streams entries without ever touching the timer.
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
 	defer timer.Stop()
+	var timerC <-chan time.Time // Nil while the timer is disarmed.
+
+	arm := func() {
+		if timerC != nil {
+			return
+		}
+		wait := time.Duration(l.batchWaitA.Load())
+		if wait <= 0 {
+			wait = time.Second
+		}
+		timer.Reset(wait)
+		timerC = timer.C
+	}
+	disarm := func() {
+		if timerC == nil {
+			return
+		}
+		// Since Go 1.23, Stop guarantees that no stale value is received afterwards.
+		timer.Stop()
+		timerC = nil
+	}
 
+	// add appends an entry to the batch and flushes it if the batch size limit is reached,
+	// if the entry is severe enough to skip the wait, or if a synchronous caller or
+	// flushQueued is waiting for it.
+	add := func(e *logEntry) {
+		batch.items = append(batch.items, e)
+		size := int(l.batchSizeA.Load())
+		if size <= 0 {
+			size = 1
+		}
+		flushLvl := l.batchFlush.Load()
+		if len(batch.items) >= size || (flushLvl != levelOff && int64(e.lvl.Base()) >= flushLvl) ||
+			e.ack != nil || e.barrier != nil {
+			flush()
+			disarm()
+		} else {
+			arm()
+		}
+	}
+
+	// The priority queue, if any, is drained before the main queue. It is nil when
+	// disabled or once closed, and a nil channel is never ready.
+	link, hi := w.link, l.hi
+	ch := link.ch
 	for {
+		if hi != nil {
+			select {
+			case e, ok := <-hi:
+				if ok {
+					add(e)
+					continue
+				}
+				hi = nil
+				if ch == nil {
+					flush()
+					return
+				}
+			default:
+			}
+		}
 		select {
-		case e, ok := <-l.ch:
+		case e, ok := <-hi:
+			if !ok {
+				hi = nil
+				if ch == nil {
+					flush()
+					return
+				}
+				continue
+			}
+			add(e)
+
+		case e, ok := <-ch:
 			if !ok {
-				// Channel closed, meaning the logger is shutting down.
-				// Flush any remaining entries and exit the worker.
+				// The channel was retired by SetBufferSize after being drained;
+				// continue with its replacement, which may be retired in turn.
+				l.chMu.RLock()
+				next := link.next
+				l.chMu.RUnlock()
+				if next != nil {
+					link, ch = next, next.ch
+					continue
+				}
+				// Channel closed, meaning the logger is shutting down. Once the
+				// priority queue is drained too, flush any remaining entries and exit.
+				if hi != nil {
+					ch = nil
+					continue
+				}
 				flush()
 				return
 			}
+			add(e)
 
-			batch.items = append(batch.items, e)
+		case ack := <-w.flush:
+			// FlushBatchNow was called.
+			flush()
+			close(ack)
+			disarm()
 
-			// Flush if the batch size limit is reached.
-			size := int(l.batchSizeA.Load())
-			if size <= 0 {
-				size = 1
-			}
-			if len(batch.items) >= size {
-				flush()
-				// It's crucial to stop and drain the timer before resetting it
-				// to prevent race conditions with the timer channel.
-				if !timer.Stop() {
-					select {
-					case <-timer.C: // Drain the channel.
-					default:
-					}
-				}
-				timer.Reset(wait)
-			}
+		case <-w.quit:
+			// The pool is shrinking; hand the remaining queue to the other workers.
+			flush()
+			return
 
-		case <-timer.C:
-			// Timer fired, flush the batch regardless of its size.
+		case <-timerC:
+			// MaxWait elapsed since the first entry of the batch; flush regardless of its size.
+			timerC = nil
 			flush()
-			// Reset the timer for the next interval.
-			wait = time.Duration(l.batchWaitA.Load())
-			if wait <= 0 {
-				wait = time.Second
-			}
-			timer.Reset(wait)
 		}
 	}
 }
 
 // processBatch orchestrates the processing of a slice of log entries.
-// For each entry, it formats the message, applies masking, triggers hooks,
-// formats the final output, and writes it to the configured destinations.
+// For each entry, it formats the message, applies masking, triggers hooks and
+// formats the final output. The formatted entries are coalesced per destination and
+// each sink receives the whole batch in a single call (see writeSegments), which keeps
+// the number of syscalls low for file and network sinks. Group entries produced by
+// BufferedLogger.Commit are expanded in order, so they stay contiguous as well.
 func (l *Logger) processBatch(entries []*logEntry) {
+	out := poolOutput.Get().(*batchOutput)
+	out.time = l.timeSettings() // Read once, so a runtime change never splits a batch.
+	if out.ttl = l.entryTTL.Load(); out.ttl != nil {
+		out.dequeued = time.Now()
+	}
+	out.idFill = l.idFill.Load()
+	var barriers []chan struct{}
 	for _, e := range entries {
-		l.writtenCount.Add(1)
-
-		l.locMu.RLock()
-		loc := l.loc
-		l.locMu.RUnlock()
-
-		// Extract metadata from the context.
-		module, _ := e.ctx.Value(ctxModuleKey).(string)
-		traceID, _ := e.ctx.Value(ctxTraceIDKey).(string)
-		flowID, _ := e.ctx.Value(ctxFlowIDKey).(string)
-		ctxFields, _ := e.ctx.Value(ctxFieldsKey).(Fields)
-
-		// Merge fields from context and the log call itself.
-		mergedFields := make(Fields, len(ctxFields)+len(e.fields))
-		for k, v := range ctxFields {
-			mergedFields[k] = v
-		}
-		for k, v := range e.fields {
-			mergedFields[k] = v
-		}
-
-		// Format the log message and apply masking.
-		msg := fmt.Sprintf(e.tmpl, e.args...)
-		jsonMode := l.jsonFmtFlag.Load()
-		msg = l.applyMasking(msg, jsonMode)
-
-		// Prepare and enqueue the event for the hook system.
-		hookEv := HookEvent{
-			Time:     e.t.In(loc),
-			Level:    e.lvl,
-			Module:   module,
-			Message:  msg,
-			TraceID:  traceID,
-			FlowID:   flowID,
-			Attrs:    mergedFields, // Attrs is now an alias for Fields.
-			Fields:   mergedFields,
-			JSONMode: jsonMode,
-		}
-		l.enqueueHook(hookEv)
-
-		// Format the final log line.
-		l.formatterMu.RLock()    // Acquire read lock
-		formatter := l.formatter // Get the current formatter
-		l.formatterMu.RUnlock()  // Release read lock
-
-		b, err := formatter.Format(hookEv)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "unologger: formatter error: %v\n", err)
-			l.writeErrCount.Add(1)
-			recycleEntry(e) // Recycle even on format error.
-			continue
-		}
-
-		// Write to configured outputs. WARN and above go to stderr per documentation.
-		isErrLevel := e.lvl >= WARN
-		l.writeToAll(b, isErrLevel)
+		if e.barrier != nil {
+			barriers = append(barriers, e.barrier)
+			e.barrier = nil
+		}
+		if e.group != nil {
+			for _, child := range e.group {
+				l.collect(out, child)
+			}
+		} else {
+			l.collect(out, e)
+		}
 		recycleEntry(e)
 	}
+	errs := l.writeLabeled(out)
+	for _, a := range out.acks {
+		err := a.err
+		if err == nil {
+			err = errs.forEntry(a)
+		}
+		a.ch <- err
+	}
+	for _, b := range barriers {
+		close(b)
+	}
+	out.reset()
+	poolOutput.Put(out)
+}
+
+// collectEntry formats a single entry and appends it to the buffers of its destinations,
+// unless it is stale and dropped by the entry TTL or its module is over its daily quota.
+// Entries of synchronous calls also register their acknowledgement channel.
+func (l *Logger) collectEntry(out *batchOutput, e *logEntry) {
+	if l.checkStale(out, e) {
+		return
+	}
+	l.backfillBatch(out, e)
+	ev, b, err := l.prepareEntry(e, out.time)
+	exempt := e.lvl.Base() >= ERROR || e.ack != nil
+	var ack *pendingAck
+	if e.ack != nil {
+		out.acks = append(out.acks, pendingAck{ch: e.ack, lvl: e.lvl, emergency: e.emergency, err: err})
+		ack = &out.acks[len(out.acks)-1]
+		e.ack = nil
+	}
+	if err != nil {
+		return
+	}
+	allow, notices := l.accountModule(ev, len(b), exempt)
+	for _, msg := range notices {
+		l.collectNotice(out, ev, msg)
+	}
+	if !allow {
+		return
+	}
+	l.publishEntry(e, ev)
+	l.recordBudget(ev.Time, e.lvl, len(b))
+	l.collectMigration(out, ev, b)
+	l.collectShadow(out, e, ev, b)
+	l.collectOTLP(out, ev)
+	// Events are only kept for the write error handler and the failure hook, which report
+	// them per entry.
+	var evp *HookEvent
+	if l.keepsEvents() {
+		evp = &ev
+	}
+	l.collectSyslog(out, ev, b, evp)
+	// Entries of a retention class with a sink go there instead of the storage sinks.
+	retained := false
+	if class := l.retentionClass(&ev); class != "" {
+		out.retained(class).add(b, evp)
+		retained = true
+		if ack != nil {
+			ack.retained = true
+		}
+	} else {
+		out.extra.add(b, evp)
+	}
+	if e.emergency {
+		// stderr and the rotation file were already written by the emergency path.
+		return
+	}
+	// WARN and above go to stderr per documentation.
+	if e.lvl.Base() >= WARN {
+		out.errb.add(b, evp)
+	} else {
+		out.std.add(b, evp)
+	}
+	if !retained {
+		out.rot.add(b, evp)
+	}
+}
+
+// prepareEntry turns a single log entry into its final formatted bytes. It merges
+// context metadata with call-site fields, applies masking, runs the validation stage and
+// runs the formatter. It returns the event together with the bytes, and an error if
+// validation dropped the entry or formatting failed.
+func (l *Logger) prepareEntry(e *logEntry, ts timeSettings) (HookEvent, []byte, error) {
+	ev := l.buildEvent(e, ts)
+	if err := l.validateEvent(&ev); err != nil {
+		return ev, nil, err
+	}
+	b, err := l.formatEvent(ev)
+	if err == nil {
+		b = l.signEntry(b)
+	}
+	return ev, b, err
+}
+
+// publishEntry counts an entry that passed the quota as written, records its span event
+// and dispatches it to the hook system. Entries suppressed by the quota skip all three.
+func (l *Logger) publishEntry(e *logEntry, ev HookEvent) {
+	l.writtenCount.Add(1)
+	l.countLevel(ev.Level)
+	l.addSpanEvent(e.ctx, ev)
+	l.enqueueHook(e.ctx, ev)
+}
+
+// buildEvent merges context metadata with call-site fields, formats the message and
+// applies masking, producing the event seen by hooks and formatters. The timestamp is
+// set according to ts.
+func (l *Logger) buildEvent(e *logEntry, ts timeSettings) HookEvent {
+	// Extract metadata from the context.
+	module, _ := e.ctx.Value(ctxModuleKey).(string)
+	traceID, _ := e.ctx.Value(ctxTraceIDKey).(string)
+	flowID, _ := e.ctx.Value(ctxFlowIDKey).(string)
+	ctxFields, _ := e.ctx.Value(ctxFieldsKey).(Fields)
+	var defAttrs Fields
+	if d := l.defaults.Load(); d != nil {
+		if module == "" {
+			module = d.module
+		}
+		defAttrs = d.attrs
+	}
+	if module == "" {
+		module = e.module
+	}
+
+	// Merge the default attributes, fields from context and the log call itself.
+	mergedFields := make(Fields, len(defAttrs)+len(ctxFields)+len(e.fields))
+	for k, v := range defAttrs {
+		mergedFields[k] = v
+	}
+	for k, v := range ctxFields {
+		mergedFields[k] = v
+	}
+	mergeAtGroup(mergedFields, fieldGroup(e.ctx), e.fields)
+	mergedFields = l.normalizeFields(mergedFields)
+	l.summarizeBlobs(mergedFields)
+	l.shredFields(mergedFields)
+	l.annotateClock(e.t, mergedFields)
+	annotateStale(e, mergedFields)
+	annotateBackfill(e, &traceID, &flowID, mergedFields)
+	t := ts.stamp(e.t, mergedFields)
+
+	// Format the log message and apply masking. The messages of the W methods are used
+	// verbatim so that literal messages containing '%' are not mangled.
+	msg := e.tmpl
+	if !e.literal {
+		msg = fmt.Sprintf(e.tmpl, e.args...)
+	}
+	jsonMode := l.jsonFmtFlag.Load()
+	msg = l.applyMasking(msg, jsonMode)
+
+	ev := HookEvent{
+		Time:     t,
+		Level:    e.lvl,
+		Module:   module,
+		Message:  msg,
+		TraceID:  traceID,
+		FlowID:   flowID,
+		Attrs:    mergedFields, // Attrs is now an alias for Fields.
+		Fields:   mergedFields,
+		JSONMode: jsonMode,
+		Seq:      e.seq,
+		Caller:   e.caller,
+	}
+	l.annotateError(e, &ev)
+	return ev
+}
+
+// formatEvent runs the current formatter on an event and returns the formatter's error,
+// if any, after reporting it on the process's stderr.
+func (l *Logger) formatEvent(ev HookEvent) ([]byte, error) {
+	l.formatterMu.RLock()    // Acquire read lock
+	formatter := l.formatter // Get the current formatter
+	l.formatterMu.RUnlock()  // Release read lock
+
+	b, err := formatter.Format(ev)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unologger: formatter error: %v\n", err)
+		l.writeErrCount.Add(1)
+		return nil, err
+	}
+	return b, nil
+}
+
+// settle delivers the result of a synchronous log call, if the entry belongs to one.
+func (e *logEntry) settle(err error) {
+	if e.ack != nil {
+		e.ack <- err
+		e.ack = nil
+	}
 }
 
 // recycleEntry resets a logEntry and returns it to the sync.Pool.
 // Nil-ing out pointers helps the GC by breaking references.
 func recycleEntry(e *logEntry) {
+	for i, child := range e.group {
+		recycleEntry(child)
+		e.group[i] = nil
+	}
+	e.group = nil
 	e.ctx = nil
 	e.args = nil
+	e.literal = false
 	e.tmpl = ""
 	e.fields = nil
+	e.emergency = false
+	e.held = false
+	e.ack = nil
+	e.seq = 0
+	e.caller = CallerInfo{}
+	e.stale = 0
+	e.idFill = ""
+	e.module = ""
+	if e.barrier != nil {
+		close(e.barrier)
+		e.barrier = nil
+	}
 	poolEntry.Put(e)
 }
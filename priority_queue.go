@@ -0,0 +1,53 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the optional two-tier priority queue. Severe entries get a queue of
+// their own, which the workers drain before the main queue, so that when the pipeline is
+// saturated by a burst of DEBUG and INFO entries, warnings and errors are still written
+// first and are not dropped for lack of room.
+
+package unologger
+
+// PriorityQueueConfig configures the priority queue.
+type PriorityQueueConfig struct {
+	// Enable adds the priority queue.
+	Enable bool
+	// Level is the lowest level queued with priority. Defaults to WARN; DEBUG, the zero
+	// value, is treated as unset.
+	Level Level
+	// Buffer is the capacity of the priority queue. Defaults to Config.Buffer.
+	Buffer int
+}
+
+// normalizePriorityQueue applies defaults to a PriorityQueueConfig.
+func normalizePriorityQueue(pq PriorityQueueConfig, buffer int) PriorityQueueConfig {
+	if !pq.Enable {
+		return pq
+	}
+	if pq.Level <= DEBUG {
+		pq.Level = WARN
+	}
+	if pq.Buffer <= 0 {
+		pq.Buffer = buffer
+	}
+	return pq
+}
+
+// tierFor returns the queue of e: the priority queue for entries at or above its level,
+// and the main queue otherwise. The caller must hold chMu for reading.
+//
+// Entries of different tiers are written out of order under load: a WARN entry may be
+// written before an INFO entry logged earlier. Entries of the same tier keep their order
+// as far as a single worker does.
+func (l *Logger) tierFor(e *logEntry) chan *logEntry {
+	if l.hi != nil && e.lvl.Base() >= l.hiLevel {
+		return l.hi
+	}
+	return l.ch
+}
+
+// queueLen returns the number of entries waiting in both queues.
+func (l *Logger) queueLen() int {
+	return len(l.queue()) + len(l.hi)
+}
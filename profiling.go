@@ -0,0 +1,60 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements optional pprof labels on the worker goroutines. While enabled, the
+// formatting of each entry runs under the labels of its module and level, and the writes
+// run under a stage label, so CPU profiles can attribute logging cost to the producing
+// modules (e.g. with `go tool pprof -tagfocus unologger_module=billing`).
+
+package unologger
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Profile label keys set on worker goroutines when profile labels are enabled.
+const (
+	// LabelModule is the pprof label holding the module of the entry being processed.
+	LabelModule = "unologger_module"
+	// LabelLevel is the pprof label holding the level of the entry being processed.
+	LabelLevel = "unologger_level"
+	// LabelStage is the pprof label holding the pipeline stage ("format" or "write").
+	LabelStage = "unologger_stage"
+)
+
+// SetProfileLabels enables or disables pprof labels on the worker goroutines at runtime.
+// Labels add a small allocation per entry, so they are disabled by default.
+func (l *Logger) SetProfileLabels(enabled bool) {
+	l.profLabels.Store(enabled)
+}
+
+// collect formats an entry into out, under its profile labels when they are enabled.
+func (l *Logger) collect(out *batchOutput, e *logEntry) {
+	// Entries are created with a non-nil context; this guards against custom paths that
+	// bypass the entry points.
+	e.ctx = orBackground(e.ctx)
+	if !l.profLabels.Load() {
+		l.collectEntry(out, e)
+		return
+	}
+	module, _ := e.ctx.Value(ctxModuleKey).(string)
+	labels := pprof.Labels(LabelStage, "format", LabelModule, module, LabelLevel, e.lvl.String())
+	pprof.Do(context.Background(), labels, func(context.Context) {
+		l.collectEntry(out, e)
+	})
+}
+
+// writeLabeled writes the buffers of a batch, under the write stage label when profile
+// labels are enabled.
+func (l *Logger) writeLabeled(out *batchOutput) routeErrors {
+	if !l.profLabels.Load() {
+		return l.writeRouted(out)
+	}
+	var errs routeErrors
+	pprof.Do(context.Background(), pprof.Labels(LabelStage, "write"), func(context.Context) {
+		errs = l.writeRouted(out)
+	})
+	return errs
+}
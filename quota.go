@@ -0,0 +1,228 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements per-module byte accounting and optional daily byte quotas. When a
+// module exceeds its quota, its entries below ERROR are no longer written; the logger emits
+// periodic summary lines instead, which keeps log-ingestion costs bounded without losing
+// track of what was suppressed.
+
+package unologger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultQuotaSummaryInterval is the default minimum time between two summary lines
+// of a module that is over its quota.
+const defaultQuotaSummaryInterval = time.Minute
+
+// QuotaConfig sets optional daily byte quotas per module. The bytes of an entry are the
+// length of its formatted output, counted once regardless of how many sinks receive it.
+// Days follow the logger's timezone. Entries at ERROR and above, and entries of the *Sync
+// methods, are always written but still count toward the quota.
+type QuotaConfig struct {
+	// Daily maps module names to their daily budget in bytes. Entries without a module
+	// are accounted under the empty name.
+	Daily map[string]int64
+	// Default is the daily budget of modules not listed in Daily. Zero means no quota.
+	Default int64
+	// SummaryInterval is the minimum time between two summary lines of a module that is
+	// over its quota. Summaries are emitted with the module's next entry, so the last one
+	// of a day comes with its first entry of the next day. Defaults to 1 minute.
+	SummaryInterval time.Duration
+}
+
+// limit returns the daily budget of module, or 0 if it has none.
+func (qc *QuotaConfig) limit(module string) int64 {
+	if qc == nil {
+		return 0
+	}
+	if n, ok := qc.Daily[module]; ok {
+		return n
+	}
+	return qc.Default
+}
+
+// ModuleStats describes the output volume of a single module.
+type ModuleStats struct {
+	BytesWritten    int64 `json:"bytes_written"`    // Total bytes of formatted output written.
+	EntriesWritten  int64 `json:"entries_written"`  // Total entries written.
+	BytesToday      int64 `json:"bytes_today"`      // Bytes written since the start of the current day.
+	Quota           int64 `json:"quota"`            // Daily budget in effect, or 0 if the module has none.
+	Suppressed      int64 `json:"suppressed"`       // Total entries suppressed because the module was over its quota.
+	SuppressedBytes int64 `json:"suppressed_bytes"` // Total bytes of the suppressed entries.
+}
+
+// moduleUsage holds the live counters behind ModuleStats for one module.
+type moduleUsage struct {
+	mu              sync.Mutex
+	day             int // Current day as yyyymmdd.
+	bytesToday      int64
+	bytes           int64
+	entries         int64
+	suppressed      int64
+	suppressedBytes int64
+
+	over         bool      // The quota has been exceeded today.
+	pending      int64     // Entries suppressed since the last summary.
+	pendingBytes int64     // Bytes suppressed since the last summary.
+	lastSummary  time.Time // Time of the last summary or quota notice.
+}
+
+// SetQuota replaces the quota settings at runtime. A module that is already over a quota
+// that is raised or removed resumes normal logging at the start of the next day.
+func (l *Logger) SetQuota(qc QuotaConfig) {
+	l.Configure("").SetQuota(qc)
+}
+
+// SetQuota is Logger.SetQuota on behalf of the actor of ed.
+func (ed ConfigEditor) SetQuota(qc QuotaConfig) {
+	c := ed.changing("quota")
+	defer c.commit()
+	if qc.SummaryInterval <= 0 {
+		qc.SummaryInterval = defaultQuotaSummaryInterval
+	}
+	var old QuotaConfig
+	if p := ed.l.quota.Swap(&qc); p != nil {
+		old = *p
+	}
+	c.old, c.new = fmt.Sprintf("%+v", old), fmt.Sprintf("%+v", qc)
+}
+
+// GetQuota returns the quota settings currently in effect.
+func (l *Logger) GetQuota() QuotaConfig {
+	if qc := l.quota.Load(); qc != nil {
+		return *qc
+	}
+	return QuotaConfig{}
+}
+
+// usage returns the usage record of the named module, creating it on first use.
+func (l *Logger) usage(module string) *moduleUsage {
+	if u, ok := l.modules.Load(module); ok {
+		return u.(*moduleUsage)
+	}
+	u, _ := l.modules.LoadOrStore(module, &moduleUsage{})
+	return u.(*moduleUsage)
+}
+
+// dayKey returns the calendar day of t as yyyymmdd.
+func dayKey(t time.Time) int {
+	y, m, d := t.Date()
+	return y*10000 + int(m)*100 + d
+}
+
+// accountModule records an entry of n formatted bytes for the module of ev and reports
+// whether it may be written. Exempt entries are always written. It also returns the
+// messages of the quota notices and summaries that must be emitted before the entry.
+func (l *Logger) accountModule(ev HookEvent, n int, exempt bool) (bool, []string) {
+	qc := l.quota.Load()
+	limit := qc.limit(ev.Module)
+	u := l.usage(ev.Module)
+	size := int64(n)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var notices []string
+	if day := dayKey(ev.Time); u.day != day {
+		if u.pending > 0 {
+			notices = append(notices, u.summaryLocked(ev.Module))
+		}
+		u.day = day
+		u.bytesToday = 0
+		u.over = false
+	}
+
+	if limit > 0 && !exempt && (u.over || u.bytesToday+size > limit) {
+		if !u.over {
+			u.over = true
+			u.lastSummary = ev.Time
+			notices = append(notices, fmt.Sprintf(
+				"unologger: module %q exceeded its daily log quota of %d bytes; entries below ERROR are summarized until the end of the day",
+				ev.Module, limit))
+		}
+		u.suppressed++
+		u.suppressedBytes += size
+		u.pending++
+		u.pendingBytes += size
+		if ev.Time.Sub(u.lastSummary) >= qc.SummaryInterval {
+			notices = append(notices, u.summaryLocked(ev.Module))
+			u.lastSummary = ev.Time
+		}
+		return false, notices
+	}
+
+	u.bytesToday += size
+	u.bytes += size
+	u.entries++
+	return true, notices
+}
+
+// summaryLocked returns the summary line of the entries suppressed since the last one
+// and resets the pending counters. The caller must hold u.mu.
+func (u *moduleUsage) summaryLocked(module string) string {
+	msg := fmt.Sprintf("unologger: module %q over its daily log quota: suppressed %d entries (%d bytes)",
+		module, u.pending, u.pendingBytes)
+	u.pending = 0
+	u.pendingBytes = 0
+	return msg
+}
+
+// collectNotice formats a quota notice as a WARN entry of the same module as ev and
+// appends it to the buffers of the stderr writer, the rotation file and the extra writers.
+// Notices bypass hooks and do not count toward the quota.
+func (l *Logger) collectNotice(out *batchOutput, ev HookEvent, msg string) {
+	b, err := l.formatEvent(HookEvent{
+		Time:     ev.Time,
+		Level:    WARN,
+		Module:   ev.Module,
+		Message:  msg,
+		JSONMode: ev.JSONMode,
+	})
+	if err != nil {
+		return
+	}
+	b = l.signEntry(b)
+	out.errb.add(b, nil)
+	out.rot.add(b, nil)
+	out.extra.add(b, nil)
+}
+
+// ModuleStats returns a snapshot of the output volume of every module that has logged,
+// keyed by module name. Entries without a module are reported under the empty name.
+func (l *Logger) ModuleStats() map[string]ModuleStats {
+	qc := l.quota.Load()
+	today := dayKey(l.now())
+	stats := make(map[string]ModuleStats)
+	l.modules.Range(func(key, value any) bool {
+		module := key.(string)
+		u := value.(*moduleUsage)
+		u.mu.Lock()
+		ms := ModuleStats{
+			BytesWritten:    u.bytes,
+			EntriesWritten:  u.entries,
+			Quota:           qc.limit(module),
+			Suppressed:      u.suppressed,
+			SuppressedBytes: u.suppressedBytes,
+		}
+		if u.day == today {
+			ms.BytesToday = u.bytesToday
+		}
+		u.mu.Unlock()
+		stats[module] = ms
+		return true
+	})
+	return stats
+}
+
+// now returns the current time in the logger's timezone.
+func (l *Logger) now() time.Time {
+	l.locMu.RLock()
+	loc := l.loc
+	l.locMu.RUnlock()
+	return time.Now().In(loc)
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements panic capture. RecoverAndLog is deferred at the top of a job or any
+// function that must not take the process down, and PanicHandler wraps HTTP handlers; both
+// log the panic with its stack and the logging metadata of the context, instead of each
+// application hand-rolling the same recover block.
+
+package unologger
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoverConfig configures RecoverAndLogWith.
+type RecoverConfig struct {
+	// Fatal logs the panic at FATAL level through the emergency path, which writes it
+	// synchronously even if the pipeline is wedged, instead of at ERROR level. The process
+	// does not exit: use Repanic to let the panic continue.
+	Fatal bool
+	// Repanic panics again with the recovered value once it is logged, so that the panic
+	// still reaches the handlers up the stack, or crashes the program.
+	Repanic bool
+	// AllGoroutines logs the stacks of all goroutines instead of the panicking one only.
+	AllGoroutines bool
+	// OnPanic, if set, is called with the recovered panic after it is logged and before
+	// Repanic, e.g. to reply with an error or mark a job as failed.
+	OnPanic func(ctx context.Context, p *PanicError)
+}
+
+// RecoverAndLog recovers a panic in progress, logs it at ERROR level with the stack trace
+// and the logger and metadata of ctx, and lets the function return normally. It must be
+// called directly by defer:
+//
+//	defer unologger.RecoverAndLog(ctx)
+//
+// Hooks receive the panic as a *PanicError in HookEvent.Error.
+func RecoverAndLog(ctx context.Context) {
+	if r := recover(); r != nil {
+		recovered(ctx, r, RecoverConfig{}, nil)
+	}
+}
+
+// RecoverAndLogWith is RecoverAndLog with options, e.g. to log at FATAL level and re-panic:
+//
+//	defer unologger.RecoverAndLogWith(ctx, unologger.RecoverConfig{Fatal: true, Repanic: true})
+//
+// Like RecoverAndLog, it must be called directly by defer.
+func RecoverAndLogWith(ctx context.Context, cfg RecoverConfig) {
+	if r := recover(); r != nil {
+		recovered(ctx, r, cfg, nil)
+	}
+}
+
+// Panics returns the number of panics recovered and logged with the logger by
+// RecoverAndLog, PanicHandler, Go and GoErr.
+func (l *Logger) Panics() int64 {
+	return l.panics.Load()
+}
+
+// PanicHandler returns a handler that recovers the panics of next. A panic is logged at
+// ERROR level with the stack trace, the method and path of the request and the logger,
+// module and flow ID of the request context, or the X-Request-ID header if the context has
+// no flow ID, and the client receives a 500 Internal Server Error unless the response was
+// already started. Wrap it inside HTTPMiddleware, so that the request entry is still logged
+// with the 500 status:
+//
+//	handler := l.HTTPMiddleware(unologger.HTTPConfig{})(unologger.PanicHandler(mux))
+//
+// http.ErrAbortHandler is not logged: it is re-panicked, as net/http expects.
+func PanicHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &panicResponseWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			ctx := r.Context()
+			if id, _ := ctx.Value(ctxFlowIDKey).(string); id == "" && r.Header.Get("X-Request-ID") != "" {
+				ctx = WithFlowID(ctx, r.Header.Get("X-Request-ID"))
+			}
+			recovered(ctx, v, RecoverConfig{}, Fields{
+				FieldHTTPMethod: r.Method,
+				FieldHTTPPath:   r.URL.Path,
+				FieldHTTPStatus: http.StatusInternalServerError,
+			})
+			if !pw.started {
+				http.Error(pw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(pw, r)
+	})
+}
+
+// panicResponseWriter records whether the response was started.
+type panicResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (pw *panicResponseWriter) WriteHeader(code int) {
+	pw.started = true
+	pw.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (pw *panicResponseWriter) Write(p []byte) (int, error) {
+	pw.started = true
+	return pw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (pw *panicResponseWriter) Flush() {
+	if f, ok := pw.ResponseWriter.(http.Flusher); ok {
+		pw.started = true
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (pw *panicResponseWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// recovered logs the recovered panic value r according to cfg, with fields.
+func recovered(ctx context.Context, r interface{}, cfg RecoverConfig, fields Fields) {
+	stack := debug.Stack()
+	if cfg.AllGoroutines {
+		stack = allStacks()
+	}
+	p := &PanicError{Value: r, Stack: stack}
+	lw := GetLogger(ctx)
+	lw.l.panics.Add(1)
+	lctx := context.WithValue(lw.ctx, ctxErrorKey, &errorInfo{err: p})
+	fields = withFields(lw.fields, MergeFields(fields, Fields{FieldStack: string(stack)}))
+	if cfg.Fatal {
+		lw.l.emergency(lctx, FATAL, false, fields, "panic: %v", r)
+	} else {
+		lw.l.logFields(lctx, ERROR, fields, "panic: %v", r)
+	}
+	if cfg.OnPanic != nil {
+		cfg.OnPanic(lw.ctx, p)
+	}
+	if cfg.Repanic {
+		panic(r)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements remote configuration. A ConfigSource delivers a JSON document from a
+// central store (an HTTP endpoint, Consul KV or etcd) and the logger applies the level,
+// masking and sampling settings it contains, so a whole fleet can be reconfigured without
+// a redeploy. The built-in sources only use the HTTP APIs of those stores.
+
+package unologger
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default timings of the built-in configuration sources.
+const (
+	defaultPollInterval  = 30 * time.Second
+	defaultRetryInterval = 5 * time.Second
+	defaultConsulWait    = 5 * time.Minute
+)
+
+// ErrConfigKeyNotFound is reported by a ConfigSource when the watched key does not exist.
+var ErrConfigKeyNotFound = errors.New("unologger: remote config key not found")
+
+// RemoteConfig is the document applied by ApplyRemoteConfig. Settings that are absent
+// from the document are left unchanged; an empty object or array clears the setting.
+type RemoteConfig struct {
+	// MinLevel is the minimum level, e.g. "DEBUG".
+	MinLevel *string `json:"min_level,omitempty"`
+	// RegexPatterns replaces the regex masking rules, including those set programmatically.
+	RegexPatterns map[string]string `json:"regex_patterns,omitempty"`
+	// JSONFieldRules replaces the JSON field masking rules.
+	JSONFieldRules []FileMaskFieldRule `json:"json_field_rules,omitempty"`
+	// Budget replaces the cost-aware sampling settings.
+	Budget *FileBudgetConfig `json:"budget,omitempty"`
+	// Quota replaces the per-module daily quotas.
+	Quota *FileQuotaConfig `json:"quota,omitempty"`
+}
+
+// ApplyRemoteConfig parses a RemoteConfig document and applies it. The document is
+// validated as a whole first: on error, none of its settings are applied.
+func (l *Logger) ApplyRemoteConfig(doc []byte) error {
+	return l.Configure("").ApplyRemoteConfig(doc)
+}
+
+// ApplyRemoteConfig is Logger.ApplyRemoteConfig on behalf of the actor of ed.
+func (ed ConfigEditor) ApplyRemoteConfig(doc []byte) error {
+	var rc RemoteConfig
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rc); err != nil {
+		return fmt.Errorf("unologger: invalid remote config: %w", err)
+	}
+
+	var minLevel Level
+	if rc.MinLevel != nil {
+		lvl, err := ParseLevel(*rc.MinLevel)
+		if err != nil {
+			return err
+		}
+		minLevel = lvl
+	}
+	var regexRules []MaskRuleRegex
+	for _, pat := range slices.Sorted(maps.Keys(rc.RegexPatterns)) {
+		repl := rc.RegexPatterns[pat]
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return fmt.Errorf("unologger: invalid masking pattern %q: %w", pat, err)
+		}
+		regexRules = append(regexRules, MaskRuleRegex{Pattern: re, Replacement: repl})
+	}
+
+	if rc.MinLevel != nil {
+		ed.SetMinLevel(minLevel)
+	}
+	if rc.RegexPatterns != nil {
+		ed.SetRegexRules(regexRules)
+	}
+	if rc.JSONFieldRules != nil {
+		rules := make([]MaskFieldRule, 0, len(rc.JSONFieldRules))
+		for _, r := range rc.JSONFieldRules {
+			rules = append(rules, MaskFieldRule(r))
+		}
+		ed.SetJSONFieldRules(rules)
+	}
+	if rc.Budget != nil {
+		ed.SetBudget(BudgetConfig{
+			DailyBytes: rc.Budget.DailyBytes,
+			Interval:   time.Duration(rc.Budget.Interval),
+			MinRate:    rc.Budget.MinRate,
+		})
+	}
+	if rc.Quota != nil {
+		ed.SetQuota(QuotaConfig{
+			Daily:           rc.Quota.Daily,
+			Default:         rc.Quota.Default,
+			SummaryInterval: time.Duration(rc.Quota.SummaryInterval),
+		})
+	}
+	return nil
+}
+
+// ConfigSource delivers a remote configuration document and its later versions.
+type ConfigSource interface {
+	// Watch calls fn with the current document and then with every new version of it,
+	// until ctx is done. Failures to reach the store are passed to fn as errors, and the
+	// source keeps retrying. Watch returns ctx.Err() when ctx is done.
+	Watch(ctx context.Context, fn func(doc []byte, err error)) error
+}
+
+// WatchConfig applies every document delivered by src until ctx is done. It blocks, so it
+// is usually started in its own goroutine:
+//
+//	go l.WatchConfig(ctx, &unologger.HTTPSource{URL: "https://config.internal/logging"}, nil)
+//
+// Errors of the source and invalid documents are passed to onError, if set; the last
+// valid configuration stays in effect. Changes are attributed to "remote-config" in
+// ConfigChanges.
+func (l *Logger) WatchConfig(ctx context.Context, src ConfigSource, onError func(error)) error {
+	return src.Watch(ctx, func(doc []byte, err error) {
+		if err == nil {
+			err = l.Configure("remote-config").ApplyRemoteConfig(doc)
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	})
+}
+
+// sleepCtx waits for d and reports whether ctx is still active.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// httpClient returns c, or http.DefaultClient if c is nil.
+func httpClient(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
+
+// HTTPSource polls an HTTP endpoint that serves the configuration document. It sends
+// If-None-Match when the server returns an ETag, and only delivers documents that changed.
+type HTTPSource struct {
+	URL      string
+	Header   http.Header   // Extra request headers, e.g. Authorization.
+	Interval time.Duration // Time between two polls. Defaults to 30 seconds.
+	Client   *http.Client  // Defaults to http.DefaultClient.
+}
+
+// Watch implements ConfigSource.
+func (s *HTTPSource) Watch(ctx context.Context, fn func(doc []byte, err error)) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var etag string
+	var last []byte
+	for {
+		doc, tag, err := s.fetch(ctx, etag)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			fn(nil, err)
+		case doc != nil && !bytes.Equal(doc, last):
+			etag, last = tag, doc
+			fn(doc, nil)
+		}
+		if !sleepCtx(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// fetch requests the document. It returns a nil document if it is unchanged.
+func (s *HTTPSource) fetch(ctx context.Context, etag string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := httpClient(s.Client).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusNotFound:
+		return nil, "", ErrConfigKeyNotFound
+	default:
+		return nil, "", fmt.Errorf("unologger: remote config %s: %s", s.URL, resp.Status)
+	}
+	doc, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return doc, resp.Header.Get("ETag"), nil
+}
+
+// ConsulSource watches a Consul KV key with blocking queries, so changes are delivered as
+// soon as they are written.
+type ConsulSource struct {
+	Address       string        // Consul HTTP address, e.g. "http://127.0.0.1:8500".
+	Key           string        // The KV key holding the document.
+	Token         string        // Optional ACL token.
+	Wait          time.Duration // Maximum duration of a blocking query. Defaults to 5 minutes.
+	RetryInterval time.Duration // Delay after a failed query. Defaults to 5 seconds.
+	Client        *http.Client  // Defaults to http.DefaultClient.
+}
+
+// Watch implements ConfigSource.
+func (s *ConsulSource) Watch(ctx context.Context, fn func(doc []byte, err error)) error {
+	wait := s.Wait
+	if wait <= 0 {
+		wait = defaultConsulWait
+	}
+	retry := s.RetryInterval
+	if retry <= 0 {
+		retry = defaultRetryInterval
+	}
+	var index uint64
+	var last []byte
+	for {
+		doc, next, err := s.query(ctx, index, wait)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fn(nil, err)
+			if !sleepCtx(ctx, retry) {
+				return ctx.Err()
+			}
+			continue
+		}
+		// The index must only increase; Consul documents resetting it otherwise.
+		if next < index {
+			next = 0
+		}
+		index = next
+		if !bytes.Equal(doc, last) {
+			last = doc
+			fn(doc, nil)
+		}
+	}
+}
+
+// query runs one blocking query and returns the raw value and the new Consul index.
+func (s *ConsulSource) query(ctx context.Context, index uint64, wait time.Duration) ([]byte, uint64, error) {
+	u := strings.TrimSuffix(s.Address, "/") + "/v1/kv/" + strings.TrimPrefix(s.Key, "/")
+	q := url.Values{"raw": {""}}
+	if index > 0 {
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", strconv.Itoa(int(wait/time.Second))+"s")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Consul-Token", s.Token)
+	}
+	resp, err := httpClient(s.Client).Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, 0, ErrConfigKeyNotFound
+	default:
+		return nil, 0, fmt.Errorf("unologger: consul key %s: %s", s.Key, resp.Status)
+	}
+	doc, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	return doc, next, nil
+}
+
+// EtcdSource polls a key through the JSON gateway of the etcd v3 API and delivers the
+// document whenever its revision changes.
+type EtcdSource struct {
+	Endpoint string        // etcd client URL, e.g. "http://127.0.0.1:2379".
+	Key      string        // The key holding the document.
+	Header   http.Header   // Extra request headers, e.g. the Authorization token.
+	Interval time.Duration // Time between two polls. Defaults to 30 seconds.
+	Client   *http.Client  // Defaults to http.DefaultClient.
+}
+
+// etcdRangeResponse is the part of the etcd v3 range response used by EtcdSource.
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Value       []byte `json:"value"` // Base64 in JSON, decoded by encoding/json.
+		ModRevision string `json:"mod_revision"`
+	} `json:"kvs"`
+}
+
+// Watch implements ConfigSource.
+func (s *EtcdSource) Watch(ctx context.Context, fn func(doc []byte, err error)) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var revision string
+	for {
+		doc, rev, err := s.get(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			fn(nil, err)
+		case rev != revision:
+			revision = rev
+			fn(doc, nil)
+		}
+		if !sleepCtx(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// get reads the key and returns its value and modification revision.
+func (s *EtcdSource) get(ctx context.Context) ([]byte, string, error) {
+	body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(s.Key))})
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/v3/kv/range"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient(s.Client).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unologger: etcd key %s: %s", s.Key, resp.Status)
+	}
+	var rr etcdRangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return nil, "", fmt.Errorf("unologger: etcd key %s: %w", s.Key, err)
+	}
+	if len(rr.Kvs) == 0 {
+		return nil, "", ErrConfigKeyNotFound
+	}
+	return rr.Kvs[0].Value, rr.Kvs[0].ModRevision, nil
+}
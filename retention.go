@@ -0,0 +1,101 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements retention classes. An entry is tagged with a class such as "30d"
+// through its context or a call-site field, and the entries of a class can be written to a
+// dedicated sink (a separate file or index), so retention policies can be enforced per
+// class by the storage behind each sink.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// RetentionKey is the field holding the retention class of an entry.
+const RetentionKey = "retention"
+
+// RetentionConfig routes entries to sinks by retention class.
+type RetentionConfig struct {
+	// Default is the class of entries that are not tagged. Empty means untagged entries
+	// keep no class.
+	Default string
+	// Sinks maps classes to their writers. The entries of a class with a sink are written
+	// to it instead of the rotation file and the extra writers; stdout and stderr still
+	// receive them. Writers implementing io.Closer are closed with the logger.
+	Sinks map[string]io.Writer
+	// Files maps classes to rotating files, like Sinks. A class present in both maps is
+	// written to its file.
+	Files map[string]RotationConfig
+}
+
+// WithRetention returns a new context whose entries are tagged with the retention class.
+// A RetentionKey call-site field overrides it for a single entry.
+func WithRetention(ctx context.Context, class string) context.Context {
+	return WithAttrs(ctx, Fields{RetentionKey: class})
+}
+
+// RetentionOf returns the retention class of an event, for hooks and custom sinks.
+func RetentionOf(ev HookEvent) string {
+	if v, ok := ev.Fields[RetentionKey]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// buildRetentionSinks opens the sinks of a RetentionConfig, keyed by class.
+func buildRetentionSinks(rc RetentionConfig) map[string]writerSink {
+	if len(rc.Sinks) == 0 && len(rc.Files) == 0 {
+		return nil
+	}
+	sinks := make(map[string]writerSink, len(rc.Sinks)+len(rc.Files))
+	for class, w := range rc.Sinks {
+		s := writerSink{Name: "retention:" + class, Writer: w}
+		if c, ok := w.(io.Closer); ok {
+			s.Closer = c
+		}
+		sinks[class] = s
+	}
+	for class, rot := range rc.Files {
+		rot.Enable = true
+		if w := initRotationWriter(rot); w != nil {
+			sinks[class] = writerSink{Name: "retention:" + class, Writer: w, Closer: w.(io.Closer)}
+		}
+	}
+	return sinks
+}
+
+// retentionClass returns the class of ev, or the default class if ev has none, provided
+// that the class has a sink. Otherwise it returns "".
+func (l *Logger) retentionClass(ev *HookEvent) string {
+	if l.retentionSinks == nil {
+		return ""
+	}
+	class := RetentionOf(*ev)
+	if class == "" {
+		class = l.retentionDefault
+	}
+	if _, ok := l.retentionSinks[class]; !ok {
+		return ""
+	}
+	return class
+}
+
+// retained returns the buffer of the entries of a retention class.
+func (o *batchOutput) retained(class string) *segments {
+	if o.retention == nil {
+		o.retention = make(map[string]*segments)
+	}
+	s, ok := o.retention[class]
+	if !ok {
+		s = &segments{}
+		o.retention[class] = s
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements rate-based sampling. Within each tick, the first entries of a level
+// and module are logged, and then only one in every so many, so that a hot loop repeating
+// the same INFO or DEBUG line does not flood the queue and the sinks. Entries are sampled
+// before they are enqueued, so discarded entries cost almost nothing.
+
+package unologger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultSamplingTick is the default period of the sampling counters.
+const defaultSamplingTick = time.Second
+
+// SamplingConfig configures rate-based sampling: per tick and per key, the first Initial
+// entries are logged, then every Thereafter-th entry. The key is the level and module of
+// the entry, and its message template with ByMessage.
+type SamplingConfig struct {
+	// Initial is the number of entries per key and tick logged before sampling starts.
+	// Zero disables sampling.
+	Initial int
+	// Thereafter logs one in every Thereafter entries after the first Initial of a tick.
+	// Zero or negative discards them all.
+	Thereafter int
+	// Tick is the period after which the counters restart. Defaults to 1 second.
+	Tick time.Duration
+	// MaxLevel is the highest level sampled; more severe entries are always logged.
+	// Defaults to INFO; DEBUG, the zero value, is treated as unset.
+	MaxLevel Level
+	// ByMessage adds the message template to the key, so that distinct messages of a
+	// module are sampled separately. Templates must then be constants, as usual for
+	// format strings, to keep the number of counters bounded.
+	ByMessage bool
+}
+
+// SamplingStats counts the entries seen by rate-based sampling since it was set.
+type SamplingStats struct {
+	Passed     int64            `json:"passed"`      // Entries logged.
+	SampledOut int64            `json:"sampled_out"` // Entries discarded.
+	ByModule   map[string]int64 `json:"by_module"`   // Entries discarded per module.
+}
+
+// samplerState holds the settings and counters of rate-based sampling.
+type samplerState struct {
+	cfg      SamplingConfig
+	tick     int64    // cfg.Tick in nanoseconds.
+	counters sync.Map // Stores a *sampleCounter per key.
+	passed   atomic.Int64
+	dropped  atomic.Int64
+	byModule sync.Map // Stores an *atomic.Int64 of discarded entries per module.
+}
+
+// sampleCounter counts the entries of one key in the current tick.
+type sampleCounter struct {
+	resetAt atomic.Int64 // Unix nanoseconds at which the tick ends.
+	n       atomic.Int64
+}
+
+// SetSampling replaces the rate-based sampling settings and resets their counters, or
+// disables sampling if cfg is nil or its Initial is zero. Sampling applies to asynchronous
+// and buffered log calls; synchronous calls and the emergency path are never sampled.
+func (l *Logger) SetSampling(cfg *SamplingConfig) {
+	l.Configure("").SetSampling(cfg)
+}
+
+// SetSampling is Logger.SetSampling on behalf of the actor of ed.
+func (ed ConfigEditor) SetSampling(cfg *SamplingConfig) {
+	c := ed.changing("sampling")
+	defer c.commit()
+	var s *samplerState
+	if cfg != nil && cfg.Initial > 0 {
+		sc := *cfg
+		if sc.Tick <= 0 {
+			sc.Tick = defaultSamplingTick
+		}
+		if sc.MaxLevel <= DEBUG {
+			sc.MaxLevel = INFO
+		}
+		s = &samplerState{cfg: sc, tick: int64(sc.Tick)}
+	}
+	c.old, c.new = describeSampling(ed.l.sampler.Swap(s)), describeSampling(s)
+}
+
+// describeSampling summarizes the sampling settings of s, or "off" if s is nil.
+func describeSampling(s *samplerState) string {
+	if s == nil {
+		return "off"
+	}
+	return fmt.Sprintf("%+v", s.cfg)
+}
+
+// GetSampling returns the rate-based sampling settings in effect, or nil if disabled.
+func (l *Logger) GetSampling() *SamplingConfig {
+	s := l.sampler.Load()
+	if s == nil {
+		return nil
+	}
+	c := s.cfg
+	return &c
+}
+
+// SamplingStats returns the counters of rate-based sampling.
+func (l *Logger) SamplingStats() SamplingStats {
+	s := l.sampler.Load()
+	if s == nil {
+		return SamplingStats{}
+	}
+	st := SamplingStats{
+		Passed:     s.passed.Load(),
+		SampledOut: s.dropped.Load(),
+		ByModule:   make(map[string]int64),
+	}
+	s.byModule.Range(func(key, value any) bool {
+		st.ByModule[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return st
+}
+
+// rateSampledOut reports whether an entry must be discarded by rate-based sampling, and
+// counts it.
+func (l *Logger) rateSampledOut(ctx context.Context, level Level, format string) bool {
+	s := l.sampler.Load()
+	if s == nil || level.Base() > s.cfg.MaxLevel {
+		return false
+	}
+	module, _ := ctx.Value(ctxModuleKey).(string)
+	if module == "" {
+		if d := l.defaults.Load(); d != nil {
+			module = d.module
+		}
+	}
+	key := strconv.Itoa(int(level)) + "\x00" + module
+	if s.cfg.ByMessage {
+		key += "\x00" + format
+	}
+	c, ok := s.counters.Load(key)
+	if !ok {
+		c, _ = s.counters.LoadOrStore(key, &sampleCounter{})
+	}
+	n := c.(*sampleCounter).inc(time.Now().UnixNano(), s.tick)
+	if n <= int64(s.cfg.Initial) ||
+		(s.cfg.Thereafter > 0 && (n-int64(s.cfg.Initial))%int64(s.cfg.Thereafter) == 0) {
+		s.passed.Add(1)
+		return false
+	}
+	s.dropped.Add(1)
+	m, ok := s.byModule.Load(module)
+	if !ok {
+		m, _ = s.byModule.LoadOrStore(module, new(atomic.Int64))
+	}
+	m.(*atomic.Int64).Add(1)
+	return true
+}
+
+// inc counts an entry at now and returns its rank in the current tick, starting a new
+// tick if the current one has ended.
+func (c *sampleCounter) inc(now, tick int64) int64 {
+	if end := c.resetAt.Load(); now >= end && c.resetAt.CompareAndSwap(end, now+tick) {
+		c.n.Store(1)
+		return 1
+	}
+	return c.n.Add(1)
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements log schema versioning for JSON output. A Schema is a named set of
+// formatter options (key names and time layout) registered under a version string. JSON
+// entries can carry their schema_version, so downstream parsers can negotiate the versions
+// they understand and migrate entries between versions while the schema evolves.
+
+package unologger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersionKey is the JSON key holding the schema version of an entry. It is the same
+// in every schema so that parsers can always find it.
+const SchemaVersionKey = "schema_version"
+
+// DefaultSchemaVersion is the version of the built-in schema used by JSONFormatter when
+// no schema is selected.
+const DefaultSchemaVersion = "1"
+
+// SchemaKeys names the JSON keys of the standard entry fields. An empty key uses the name
+// of the default schema, and "-" omits the field from the output.
+type SchemaKeys struct {
+	Time     string
+	Level    string
+	Module   string
+	TraceID  string
+	FlowID   string
+	Attrs    string
+	Message  string
+	Fields   string
+	Seq      string
+	Caller   string
+	Function string
+	Severity string
+	Error    string
+}
+
+// Schema is a versioned set of JSON formatter options.
+type Schema struct {
+	// Version identifies the schema and is written under SchemaVersionKey when the
+	// formatter's SchemaVersion is set.
+	Version string
+	// Keys names the JSON keys of the standard fields.
+	Keys SchemaKeys
+	// TimeFormat is the layout of the timestamp. Defaults to time.RFC3339.
+	TimeFormat string
+}
+
+// Errors returned by the schema registry.
+var (
+	// ErrUnknownSchema is returned when a schema version is not registered.
+	ErrUnknownSchema = errors.New("unologger: unknown schema version")
+	// ErrNoCommonSchema is returned by NegotiateSchema when none of the accepted versions
+	// is registered.
+	ErrNoCommonSchema = errors.New("unologger: no common schema version")
+)
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = map[string]Schema{
+		DefaultSchemaVersion: defaultSchema(),
+	}
+)
+
+// defaultSchema returns the built-in schema, version 1.
+func defaultSchema() Schema {
+	return Schema{
+		Version: DefaultSchemaVersion,
+		Keys: SchemaKeys{
+			Time:     "time",
+			Level:    "level",
+			Module:   "module",
+			TraceID:  "trace_id",
+			FlowID:   "flow_id",
+			Attrs:    "attrs",
+			Message:  "message",
+			Fields:   "fields",
+			Seq:      "seq",
+			Caller:   "caller",
+			Function: "function",
+			Severity: "severity",
+			Error:    "error",
+		},
+		TimeFormat: time.RFC3339,
+	}
+}
+
+// normalize fills the unset options of s from the default schema.
+func (s Schema) normalize() Schema {
+	def := defaultSchema()
+	pick := func(k *string, d string) {
+		if *k == "" {
+			*k = d
+		}
+	}
+	pick(&s.Keys.Time, def.Keys.Time)
+	pick(&s.Keys.Level, def.Keys.Level)
+	pick(&s.Keys.Module, def.Keys.Module)
+	pick(&s.Keys.TraceID, def.Keys.TraceID)
+	pick(&s.Keys.FlowID, def.Keys.FlowID)
+	pick(&s.Keys.Attrs, def.Keys.Attrs)
+	pick(&s.Keys.Message, def.Keys.Message)
+	pick(&s.Keys.Fields, def.Keys.Fields)
+	pick(&s.Keys.Seq, def.Keys.Seq)
+	pick(&s.Keys.Caller, def.Keys.Caller)
+	pick(&s.Keys.Function, def.Keys.Function)
+	pick(&s.Keys.Severity, def.Keys.Severity)
+	pick(&s.Keys.Error, def.Keys.Error)
+	pick(&s.TimeFormat, def.TimeFormat)
+	return s
+}
+
+// keyPairs returns the (from, to) key names of the standard fields of two schemas.
+func (s Schema) keyPairs(to Schema) [][2]string {
+	return [][2]string{
+		{s.Keys.Time, to.Keys.Time},
+		{s.Keys.Level, to.Keys.Level},
+		{s.Keys.Module, to.Keys.Module},
+		{s.Keys.TraceID, to.Keys.TraceID},
+		{s.Keys.FlowID, to.Keys.FlowID},
+		{s.Keys.Attrs, to.Keys.Attrs},
+		{s.Keys.Message, to.Keys.Message},
+		{s.Keys.Fields, to.Keys.Fields},
+		{s.Keys.Seq, to.Keys.Seq},
+		{s.Keys.Caller, to.Keys.Caller},
+		{s.Keys.Function, to.Keys.Function},
+		{s.Keys.Severity, to.Keys.Severity},
+		{s.Keys.Error, to.Keys.Error},
+	}
+}
+
+// RegisterSchema adds a schema to the registry, or replaces the schema registered under
+// the same version. The default schema version cannot be replaced.
+func RegisterSchema(s Schema) error {
+	if s.Version == "" {
+		return fmt.Errorf("unologger: schema version must not be empty")
+	}
+	if s.Version == DefaultSchemaVersion {
+		return fmt.Errorf("unologger: schema version %q is reserved", DefaultSchemaVersion)
+	}
+	schemasMu.Lock()
+	schemas[s.Version] = s.normalize()
+	schemasMu.Unlock()
+	return nil
+}
+
+// LookupSchema returns the schema registered under version.
+func LookupSchema(version string) (Schema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	s, ok := schemas[version]
+	return s, ok
+}
+
+// SchemaVersions returns the registered schema versions in lexical order.
+func SchemaVersions() []string {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+	out := make([]string, 0, len(schemas))
+	for v := range schemas {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NegotiateSchema returns the first of the accepted versions, in order of preference,
+// that is registered. It returns ErrNoCommonSchema if there is none.
+func NegotiateSchema(accepted ...string) (Schema, error) {
+	for _, v := range accepted {
+		if s, ok := LookupSchema(v); ok {
+			return s, nil
+		}
+	}
+	return Schema{}, fmt.Errorf("%w among %q", ErrNoCommonSchema, accepted)
+}
+
+// NewJSONFormatterForSchema returns a JSONFormatter using the schema negotiated from the
+// accepted versions, which writes the version in every entry. See NegotiateSchema.
+func NewJSONFormatterForSchema(accepted ...string) (*JSONFormatter, error) {
+	s, err := NegotiateSchema(accepted...)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONFormatter{Schema: s.Version, SchemaVersion: true}, nil
+}
+
+// MigrateJSON rewrites a JSON entry produced with any registered schema into the schema
+// registered under version: the standard keys are renamed, the timestamp is reformatted
+// and SchemaVersionKey is updated. Other keys are kept unchanged. Entries without
+// SchemaVersionKey are treated as the default schema. The keys of the result are written
+// in lexical order.
+func MigrateJSON(entry []byte, version string) ([]byte, error) {
+	to, ok := LookupSchema(version)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSchema, version)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(entry, &obj); err != nil {
+		return nil, fmt.Errorf("unologger: failed to decode log entry: %w", err)
+	}
+
+	fromVersion := DefaultSchemaVersion
+	if raw, ok := obj[SchemaVersionKey]; ok {
+		if err := json.Unmarshal(raw, &fromVersion); err != nil {
+			return nil, fmt.Errorf("unologger: invalid %s: %w", SchemaVersionKey, err)
+		}
+	}
+	from, ok := LookupSchema(fromVersion)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSchema, fromVersion)
+	}
+
+	// Reformat the timestamp before its key is renamed.
+	if raw, ok := obj[from.Keys.Time]; ok && from.TimeFormat != to.TimeFormat {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("unologger: invalid time: %w", err)
+		}
+		t, err := time.Parse(from.TimeFormat, s)
+		if err != nil {
+			return nil, fmt.Errorf("unologger: invalid time: %w", err)
+		}
+		obj[from.Keys.Time], _ = json.Marshal(t.Format(to.TimeFormat))
+	}
+
+	// Detach every standard field first, so that swapped key names cannot collide.
+	pairs := from.keyPairs(to)
+	values := make([]json.RawMessage, len(pairs))
+	for i, p := range pairs {
+		if p[0] == "-" {
+			continue
+		}
+		values[i] = obj[p[0]]
+		delete(obj, p[0])
+	}
+	for i, p := range pairs {
+		if values[i] != nil && p[1] != "-" {
+			obj[p[1]] = values[i]
+		}
+	}
+	obj[SchemaVersionKey], _ = json.Marshal(to.Version)
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return nil, fmt.Errorf("unologger: failed to encode log entry to JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the sink self-test. At startup, a readiness probe can check that
+// every configured sink accepts entries, instead of discovering a misconfigured file path
+// or an unreachable collector from the first failed writes.
+
+package unologger
+
+import (
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SelfTestMessage is the message of the probe entry written by SelfTest.
+const SelfTestMessage = "unologger: self-test probe"
+
+// HealthChecker is implemented by network sinks that can check their connectivity without
+// writing an entry, such as AckSink and AgentSink. SelfTest calls HealthCheck instead of
+// writing the probe entry to them.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// SinkCheck is the result of the self-test of one sink.
+type SinkCheck struct {
+	Sink    string        // Sink name, as reported by WriterStats.
+	OK      bool          // Whether the sink accepted the probe.
+	Err     error         // Why the check failed, if it did.
+	Latency time.Duration // Duration of the check.
+}
+
+// SelfTest checks every sink of the logger concurrently and returns one result per sink, in
+// the order stdout, stderr, rotation file, retention sinks, OTLP exporter, syslog and extra
+// writers. Sinks implementing HealthChecker, syslog included, are checked with it, the
+// OTLP exporter is sent an empty export request, and the other sinks are written a probe
+// entry at INFO level with the module "unologger", the message SelfTestMessage and the
+// field self_test=true.
+//
+// Probes bypass the queue, rate limits and retries and are not counted in WriterStats. A
+// check still running when ctx is done fails with the error of ctx.
+func (l *Logger) SelfTest(ctx context.Context) []SinkCheck {
+	type target struct {
+		name  string
+		check func(ctx context.Context) error
+	}
+	var targets []target
+	probe := l.selfTestProbe()
+	add := func(name string, w io.Writer) {
+		if w == nil {
+			return
+		}
+		targets = append(targets, target{name, func(ctx context.Context) error {
+			return probeSink(ctx, w, probe)
+		}})
+	}
+
+	l.outputsMu.RLock()
+	add("stdout", l.stdOut)
+	add("stderr", l.errOut)
+	if l.rotationSink != nil {
+		add(l.rotationSink.Name, l.rotationSink.Writer)
+	}
+	extras := slices.Clone(l.extraW)
+	l.outputsMu.RUnlock()
+	for _, class := range slices.Sorted(maps.Keys(l.retentionSinks)) {
+		sink := l.retentionSinks[class]
+		add(sink.Name, sink.Writer)
+	}
+	if exp := l.otlp.Load(); exp != nil {
+		targets = append(targets, target{OTLPSinkName, func(ctx context.Context) error {
+			body, err := exp.encode(nil)
+			if err != nil {
+				return err
+			}
+			return exp.export(ctx, body)
+		}})
+	}
+	if s := l.syslog.Load(); s != nil {
+		add(SyslogSinkName, s)
+	}
+	for _, sink := range extras {
+		add(sink.Name, sink.Writer)
+	}
+
+	checks := make([]SinkCheck, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := runCheck(ctx, t.check)
+			checks[i] = SinkCheck{Sink: t.name, OK: err == nil, Err: err, Latency: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// runCheck runs check, returning early with the error of ctx if ctx is done first. The
+// check is left to finish in the background then.
+func runCheck(ctx context.Context, check func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probeSink checks w with its HealthCheck method, or by writing the probe entry to it.
+func probeSink(ctx context.Context, w io.Writer, probe []byte) error {
+	switch sw := w.(type) {
+	case HealthChecker:
+		return sw.HealthCheck(ctx)
+	case BatchWriter:
+		return sw.WriteBatch([][]byte{probe})
+	default:
+		_, err := w.Write(probe)
+		return err
+	}
+}
+
+// selfTestProbe formats the probe entry in the current output format.
+func (l *Logger) selfTestProbe() []byte {
+	l.locMu.RLock()
+	loc := l.loc
+	l.locMu.RUnlock()
+	fields := Fields{"self_test": true}
+	b, err := l.formatEvent(HookEvent{
+		Time:     time.Now().In(loc),
+		Level:    INFO,
+		Module:   "unologger",
+		Message:  SelfTestMessage,
+		Fields:   fields,
+		Attrs:    fields,
+		JSONMode: l.jsonFmtFlag.Load(),
+	})
+	if err != nil {
+		b = []byte(SelfTestMessage + "\n")
+	}
+	return l.signEntry(b)
+}
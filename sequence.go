@@ -0,0 +1,165 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements sequence numbers. Entries are numbered when they are handed to the
+// pipeline, so entries dropped on a full queue leave gaps; each sink can also number the
+// entries it writes, so that a consumer of one sink detects entries lost in transport.
+
+package unologger
+
+import (
+	"bytes"
+	"strconv"
+	"sync/atomic"
+)
+
+// SinkSeqKey is the key of the per-sink sequence number.
+const SinkSeqKey = "sink_seq"
+
+// SequenceConfig selects which sequence numbers are stamped on entries.
+type SequenceConfig struct {
+	// Entries numbers every entry across the logger, starting at 1. The number is
+	// available to hooks and formatters as HookEvent.Seq and written as "seq".
+	Entries bool
+	// Sinks numbers the entries written to each sink separately, starting at 1, and
+	// writes the number at the start of the entry: a "sink_seq" key for JSON entries and
+	// a "sink_seq=N " prefix for text entries. Emergency entries written directly to
+	// stderr and the rotation file are not numbered.
+	Sinks bool
+}
+
+// SetSequence enables or disables sequence numbers at runtime. Counters continue from
+// their current values.
+func (l *Logger) SetSequence(sc SequenceConfig) {
+	l.seqEntries.Store(sc.Entries)
+	l.seqSinks.Store(sc.Sinks)
+}
+
+// stampSeq assigns the next sequence number to e, or to each entry of a group, unless it
+// already has one.
+func (l *Logger) stampSeq(e *logEntry) {
+	if !l.seqEntries.Load() {
+		return
+	}
+	for _, child := range e.group {
+		l.stampSeq(child)
+	}
+	if e.group == nil && e.seq == 0 {
+		e.seq = l.seq.Add(1)
+	}
+}
+
+// sinkSeq returns the sequence counter of the named sink.
+func (l *Logger) sinkSeq(name string) *atomic.Uint64 {
+	if c, ok := l.sinkSeqs.Load(name); ok {
+		return c.(*atomic.Uint64)
+	}
+	c, _ := l.sinkSeqs.LoadOrStore(name, new(atomic.Uint64))
+	return c.(*atomic.Uint64)
+}
+
+// stampSinkSeq returns a copy of s whose entries carry the next sequence numbers of the
+// named sink. A batch signature trailer is left unnumbered.
+func (l *Logger) stampSinkSeq(name string, s *segments) *segments {
+	n := len(s.parts)
+	if s.trailer {
+		n--
+	}
+	counter := l.sinkSeq(name)
+	first := counter.Add(uint64(n)) - uint64(n) + 1
+	out := &segments{parts: make([][]byte, len(s.parts)), events: s.events, trailer: s.trailer, sealed: true}
+	for i, p := range s.parts {
+		if i >= n {
+			out.parts[i] = p
+			continue
+		}
+		out.parts[i] = withSinkSeq(p, first+uint64(i))
+	}
+	return out
+}
+
+// withSinkSeq returns a copy of the entry b with the sink sequence number seq in front.
+func withSinkSeq(b []byte, seq uint64) []byte {
+	out := make([]byte, 0, len(b)+len(SinkSeqKey)+24)
+	if len(b) > 1 && b[0] == '{' {
+		out = append(out, `{"`+SinkSeqKey+`":`...)
+		out = strconv.AppendUint(out, seq, 10)
+		if b[1] != '}' {
+			out = append(out, ',')
+		}
+		return append(out, b[1:]...)
+	}
+	out = append(out, SinkSeqKey+"="...)
+	out = strconv.AppendUint(out, seq, 10)
+	out = append(out, ' ')
+	return append(out, b...)
+}
+
+// SinkSeqOf returns the sink sequence number of an entry read from a sink, and false if
+// the entry has none.
+func SinkSeqOf(line []byte) (uint64, bool) {
+	var rest []byte
+	switch {
+	case bytes.HasPrefix(line, []byte(`{"`+SinkSeqKey+`":`)):
+		rest = line[len(`{"`+SinkSeqKey+`":`):]
+	case bytes.HasPrefix(line, []byte(SinkSeqKey+"=")):
+		rest = line[len(SinkSeqKey+"="):]
+	default:
+		return 0, false
+	}
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	seq, err := strconv.ParseUint(string(rest[:end]), 10, 64)
+	return seq, err == nil
+}
+
+// stripSinkSeq returns the entry without its sink sequence number, as it was formatted.
+func stripSinkSeq(line []byte) []byte {
+	switch {
+	case bytes.HasPrefix(line, []byte(`{"`+SinkSeqKey+`":`)):
+		i := bytes.IndexAny(line, ",}")
+		if i < 0 {
+			return line
+		}
+		if line[i] == '}' {
+			return append([]byte{'{'}, line[i:]...)
+		}
+		return append([]byte{'{'}, line[i+1:]...)
+	case bytes.HasPrefix(line, []byte(SinkSeqKey+"=")):
+		if i := bytes.IndexByte(line, ' '); i >= 0 {
+			return line[i+1:]
+		}
+	}
+	return line
+}
+
+// SeqTracker detects gaps in the sequence numbers read from a log. It is not safe for
+// concurrent use.
+type SeqTracker struct {
+	last    uint64
+	started bool
+	// Missing is the total number of entries missing so far.
+	Missing uint64
+	// Restarts counts the sequence numbers that went backwards, which happens when the
+	// process restarts.
+	Restarts int
+}
+
+// Observe records the next sequence number and returns the number of entries missing
+// between it and the previous one.
+func (t *SeqTracker) Observe(seq uint64) uint64 {
+	defer func() { t.last, t.started = seq, true }()
+	switch {
+	case !t.started:
+		return 0
+	case seq <= t.last:
+		t.Restarts++
+		return 0
+	}
+	gap := seq - t.last - 1
+	t.Missing += gap
+	return gap
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements numeric severities. Many ingestion systems sort and alert on a
+// number rather than on the level name, so the formatters can write the syslog or the
+// OpenTelemetry severity number of an entry next to, or instead of, its level.
+
+package unologger
+
+// SeverityMode selects how a formatter writes the level of an entry.
+type SeverityMode int
+
+const (
+	// SeverityText writes the level name, e.g. "ERROR". This is the default.
+	SeverityText SeverityMode = iota
+	// SeverityTextAndNumber writes the level name and the severity number.
+	SeverityTextAndNumber
+	// SeverityNumber writes the severity number instead of the level name.
+	SeverityNumber
+)
+
+// SeverityScale selects the numbering of severity numbers.
+type SeverityScale int
+
+const (
+	// SeverityScaleOTel numbers severities as the OpenTelemetry log data model:
+	// TRACE 1, DEBUG 5, INFO 9, WARN 13, ERROR 17, FATAL 21. Higher is more severe. This is
+	// the default.
+	SeverityScaleOTel SeverityScale = iota
+	// SeverityScaleSyslog numbers severities as RFC 5424: TRACE and DEBUG 7, INFO 6,
+	// WARN 4, ERROR 3, FATAL 2. Lower is more severe.
+	SeverityScaleSyslog
+)
+
+// Severity returns the severity number of lvl on scale, that of its base level for a custom
+// level. Unknown levels are 0 on the OpenTelemetry scale, meaning unspecified, and 2
+// (critical) on the syslog scale.
+func (scale SeverityScale) Severity(lvl Level) int {
+	if scale == SeverityScaleSyslog {
+		return syslogSeverity(lvl)
+	}
+	return otlpSeverity(lvl)
+}
+
+// writesText reports whether the level name is written.
+func (m SeverityMode) writesText() bool { return m != SeverityNumber }
+
+// writesNumber reports whether the severity number is written.
+func (m SeverityMode) writesNumber() bool {
+	return m == SeverityTextAndNumber || m == SeverityNumber
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements shadow configurations. A proposed formatter, masking rules and
+// sampling rate are applied to a copy of the real traffic written to a separate sink, so
+// operators can inspect the result and compare volumes before promoting the configuration
+// through the debug endpoint or PromoteShadow.
+
+package unologger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+)
+
+// ErrNoShadow is returned by PromoteShadow when no shadow configuration is attached.
+var ErrNoShadow = errors.New("unologger: no shadow configuration")
+
+// ShadowConfig is a proposed configuration evaluated on real traffic. The shadow sees the
+// entries accepted by the logger's level, quotas and sampling.
+type ShadowConfig struct {
+	// Writer receives the shadow output. Required. It is owned by the caller.
+	Writer io.Writer
+	// Formatter formats the shadow entries. Nil uses the logger's formatter.
+	Formatter Formatter
+	// RegexRules and JSONFieldRules mask the messages of the shadow entries. A nil slice
+	// uses the logger's rules; an empty one disables that kind of masking.
+	RegexRules     []MaskRuleRegex
+	JSONFieldRules []MaskFieldRule
+	// SampleRate is the fraction of entries written to the shadow, in (0, 1]. Zero means 1.
+	// It lets the volume of a proposed sampling rate be evaluated; it is not promoted.
+	SampleRate float64
+}
+
+// ShadowStats compares the shadow output with the primary output of the same entries.
+type ShadowStats struct {
+	Seen         int64   `json:"seen"`          // Entries offered to the shadow.
+	SampledOut   int64   `json:"sampled_out"`   // Entries skipped by SampleRate.
+	Entries      int64   `json:"entries"`       // Shadow entries written.
+	Bytes        int64   `json:"bytes"`         // Shadow bytes written.
+	PrimaryBytes int64   `json:"primary_bytes"` // Primary bytes of the entries offered to the shadow.
+	FormatErrors int64   `json:"format_errors"` // Entries the shadow formatter rejected.
+	WriteErrors  int64   `json:"write_errors"`  // Shadow entries lost to failed writes.
+	VolumeRatio  float64 `json:"volume_ratio"`  // Bytes / PrimaryBytes, or 0.
+}
+
+// shadowState is an attached shadow configuration with its counters.
+type shadowState struct {
+	cfg                                 ShadowConfig
+	seen, sampledOut, entries, bytes    atomicI64
+	primaryBytes, formatErrs, writeErrs atomicI64
+}
+
+// SetShadow attaches a shadow configuration at runtime, replacing any previous one, or
+// detaches it if cfg is nil or has no writer.
+func (l *Logger) SetShadow(cfg *ShadowConfig) {
+	if cfg == nil || cfg.Writer == nil {
+		l.shadow.Store(nil)
+		return
+	}
+	l.shadow.Store(&shadowState{cfg: *cfg})
+}
+
+// ShadowStats returns the statistics of the attached shadow, and false if there is none.
+func (l *Logger) ShadowStats() (ShadowStats, bool) {
+	s := l.shadow.Load()
+	if s == nil {
+		return ShadowStats{}, false
+	}
+	st := ShadowStats{
+		Seen:         s.seen.Load(),
+		SampledOut:   s.sampledOut.Load(),
+		Entries:      s.entries.Load(),
+		Bytes:        s.bytes.Load(),
+		PrimaryBytes: s.primaryBytes.Load(),
+		FormatErrors: s.formatErrs.Load(),
+		WriteErrors:  s.writeErrs.Load(),
+	}
+	if st.PrimaryBytes > 0 {
+		st.VolumeRatio = float64(st.Bytes) / float64(st.PrimaryBytes)
+	}
+	return st, true
+}
+
+// PromoteShadow makes the formatter and masking rules of the shadow the logger's own and
+// detaches the shadow.
+func (l *Logger) PromoteShadow() error {
+	s := l.shadow.Swap(nil)
+	if s == nil {
+		return ErrNoShadow
+	}
+	if s.cfg.Formatter != nil {
+		l.SetFormatter(s.cfg.Formatter)
+	}
+	if s.cfg.RegexRules != nil {
+		l.SetRegexRules(s.cfg.RegexRules)
+	}
+	if s.cfg.JSONFieldRules != nil {
+		l.SetJSONFieldRules(s.cfg.JSONFieldRules)
+	}
+	return nil
+}
+
+// collectShadow formats e for the attached shadow, if any. b is the primary output of the
+// entry, whose event is ev.
+func (l *Logger) collectShadow(out *batchOutput, e *logEntry, ev HookEvent, b []byte) {
+	s := l.shadow.Load()
+	if s == nil {
+		return
+	}
+	if out.shadowState != s {
+		out.shadow.reset()
+		out.shadowState = s
+	}
+	s.seen.Add(1)
+	s.primaryBytes.Add(int64(len(b)))
+	if rate := s.cfg.SampleRate; rate > 0 && rate < 1 && rand.Float64() >= rate {
+		s.sampledOut.Add(1)
+		return
+	}
+
+	// The event carries the message as masked by the logger's rules; mask the original
+	// message with the shadow's rules instead.
+	msg := e.tmpl
+	if !e.literal {
+		msg = fmt.Sprintf(e.tmpl, e.args...)
+	}
+	regexRules, fieldRules := s.cfg.RegexRules, s.cfg.JSONFieldRules
+	if regexRules == nil || fieldRules == nil {
+		l.dynConfig.mu.RLock()
+		if regexRules == nil {
+			regexRules = l.dynConfig.RegexRules
+		}
+		if fieldRules == nil {
+			fieldRules = l.dynConfig.JSONFieldRules
+		}
+		l.dynConfig.mu.RUnlock()
+	}
+	ev.Message = maskWithRules(msg, ev.JSONMode, regexRules, fieldRules)
+
+	f := s.cfg.Formatter
+	if f == nil {
+		l.formatterMu.RLock()
+		f = l.formatter
+		l.formatterMu.RUnlock()
+	}
+	sb, err := f.Format(ev)
+	if err != nil {
+		s.formatErrs.Add(1)
+		return
+	}
+	out.shadow.add(sb, nil)
+}
+
+// writeShadow writes the entries collected for the shadow.
+func (l *Logger) writeShadow(out *batchOutput) {
+	s := out.shadowState
+	if s == nil || out.shadow.empty() {
+		return
+	}
+	n, size := int64(len(out.shadow.parts)), int64(out.shadow.size())
+	if err := l.writeSegments("shadow", s.cfg.Writer, &out.shadow); err != nil {
+		s.writeErrs.Add(n)
+		return
+	}
+	s.entries.Add(n)
+	s.bytes.Add(size)
+}
+
+// serveShadow serves the shadow routes of the debug endpoint: GET returns ShadowStats,
+// POST to ".../promote" promotes the shadow and DELETE detaches it.
+func (l *Logger) serveShadow(w http.ResponseWriter, r *http.Request, promote bool) {
+	switch {
+	case promote && r.Method == http.MethodPost:
+		if err := l.PromoteShadow(); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case !promote && r.Method == http.MethodDelete:
+		l.SetShadow(nil)
+		w.WriteHeader(http.StatusNoContent)
+	case !promote && r.Method == http.MethodGet:
+		st, ok := l.ShadowStats()
+		if !ok {
+			http.Error(w, ErrNoShadow.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(st)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements crypto-shredding of identity fields. The values of designated fields
+// are encrypted with a key per data subject before hooks and formatting; erasing the key of
+// a subject makes their values in every historical log unreadable, which serves deletion
+// requests without rewriting log storage.
+
+package unologger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// shredPrefix marks an encrypted field value: shred:v1:<key id>:<base64 nonce+ciphertext>.
+const shredPrefix = "shred:v1:"
+
+// ShredRedacted replaces identity fields of entries that have no subject, since they
+// cannot be encrypted with a subject key.
+const ShredRedacted = "[redacted]"
+
+// Errors returned by crypto-shredding.
+var (
+	// ErrSubjectErased is returned when the key of an encrypted value has been erased.
+	ErrSubjectErased = errors.New("unologger: subject key erased")
+	// ErrNotShredded is returned by RevealValue for values that are not encrypted.
+	ErrNotShredded = errors.New("unologger: value is not encrypted")
+)
+
+// KeyStore holds the encryption keys of data subjects. Implementations backed by a KMS or
+// a database make keys survive restarts; MemoryKeyStore keeps them in memory.
+type KeyStore interface {
+	// KeyFor returns the key ID and the 32-byte key of subject, creating them on first use.
+	KeyFor(subject string) (id string, key []byte, err error)
+	// Lookup returns the key with the given ID, or ErrSubjectErased if it was erased.
+	Lookup(id string) ([]byte, error)
+	// Erase deletes the key of subject. Values encrypted with it can no longer be read.
+	Erase(subject string) error
+}
+
+// ShreddingConfig configures crypto-shredding.
+type ShreddingConfig struct {
+	// Store holds the subject keys. Required.
+	Store KeyStore
+	// SubjectField is the field identifying the data subject, e.g. "user_id".
+	SubjectField string
+	// Fields lists the identity fields whose values are encrypted, e.g. "email", "name".
+	// It may include SubjectField itself. Entries without a subject get ShredRedacted
+	// for these fields.
+	Fields []string
+}
+
+// SetShredding enables crypto-shredding at runtime, or disables it if cfg is nil. Only
+// field values are encrypted: identity data must not be written into messages.
+func (l *Logger) SetShredding(cfg *ShreddingConfig) {
+	if cfg == nil || cfg.Store == nil || len(cfg.Fields) == 0 {
+		l.shredding.Store(nil)
+		return
+	}
+	c := *cfg
+	c.Fields = append([]string(nil), cfg.Fields...)
+	l.shredding.Store(&c)
+}
+
+// shredFields encrypts the identity fields of fields in place, including those nested in
+// groups (see WithGroup).
+func (l *Logger) shredFields(fields Fields) {
+	cfg := l.shredding.Load()
+	if cfg == nil || len(fields) == 0 {
+		return
+	}
+	shredGroup(cfg, fields, "")
+}
+
+// shredGroup encrypts the identity fields of a group and of its nested groups, which are
+// copied first since they may be shared with a context. The subject is the SubjectField of
+// the group, if set, and otherwise subject, that of the enclosing group.
+func shredGroup(cfg *ShreddingConfig, fields Fields, subject string) {
+	if v, ok := fields[cfg.SubjectField]; ok && v != nil {
+		subject = fmt.Sprint(v)
+	}
+	for k, v := range fields {
+		if g, ok := v.(Fields); ok {
+			g = maps.Clone(g)
+			fields[k] = g
+			shredGroup(cfg, g, subject)
+		}
+	}
+	for _, name := range cfg.Fields {
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if subject == "" {
+			fields[name] = ShredRedacted
+			continue
+		}
+		enc, err := ShredValue(cfg.Store, subject, name, fmt.Sprint(v))
+		if err != nil {
+			enc = ShredRedacted
+		}
+		fields[name] = enc
+	}
+}
+
+// ShredValue encrypts value with the key of subject using AES-256-GCM. The field name is
+// authenticated with the value, so an encrypted value cannot be moved to another field.
+func ShredValue(store KeyStore, subject, field, value string) (string, error) {
+	id, key, err := store.KeyFor(subject)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newShredCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(value)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(field))
+	return shredPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// RevealValue decrypts a value produced by crypto-shredding of the given field. It
+// returns ErrSubjectErased once the subject's key has been erased.
+func RevealValue(store KeyStore, field, value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, shredPrefix)
+	if !ok {
+		return "", ErrNotShredded
+	}
+	id, data, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", ErrNotShredded
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("unologger: malformed encrypted value: %w", err)
+	}
+	key, err := store.Lookup(id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newShredCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("unologger: malformed encrypted value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("unologger: decrypt %s: %w", field, err)
+	}
+	return string(plain), nil
+}
+
+// newShredCipher returns the AES-GCM cipher of a 32-byte key.
+func newShredCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("unologger: subject key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// MemoryKeyStore is an in-memory KeyStore. Its keys are lost when the process exits, which
+// makes it suitable for tests and for logs that do not outlive the process.
+type MemoryKeyStore struct {
+	mu       sync.Mutex
+	subjects map[string]string // subject -> key ID
+	keys     map[string][]byte // key ID -> key
+}
+
+// NewMemoryKeyStore returns an empty MemoryKeyStore.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{subjects: make(map[string]string), keys: make(map[string][]byte)}
+}
+
+// KeyFor implements KeyStore. Key IDs are random, so they do not reveal the subject.
+func (s *MemoryKeyStore) KeyFor(subject string) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.subjects[subject]; ok {
+		return id, s.keys[id], nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, err
+	}
+	id := newUUID()
+	s.subjects[subject] = id
+	s.keys[id] = key
+	return id, key, nil
+}
+
+// Lookup implements KeyStore.
+func (s *MemoryKeyStore) Lookup(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrSubjectErased
+	}
+	return key, nil
+}
+
+// Erase implements KeyStore. A later entry of the same subject gets a new key.
+func (s *MemoryKeyStore) Erase(subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.subjects[subject]; ok {
+		delete(s.keys, id)
+		delete(s.subjects, subject)
+	}
+	return nil
+}
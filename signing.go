@@ -0,0 +1,199 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements Ed25519 signing of the formatted output for audit-grade logs. Each
+// entry can carry its own signature, or each batch written to a sink can be sealed by a
+// trailer line signing the Merkle root of its entries, which is cheaper at high volume.
+// Verification helpers check both forms.
+
+package unologger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SigningMode selects what is signed.
+type SigningMode int
+
+const (
+	// SignEntries appends a signature to every entry: a `,"sig":"..."` field to JSON entries
+	// and a ` sig=...` trailer to text entries.
+	SignEntries SigningMode = iota
+	// SignBatches appends a trailer line to every batch written to a sink, signing the
+	// Merkle root of the entries of the batch. Emergency entries bypass batches and are
+	// not covered.
+	SignBatches
+)
+
+// ErrBadSignature is returned by the verification helpers when a signature does not match.
+var ErrBadSignature = errors.New("unologger: invalid log signature")
+
+// SigningConfig configures the signing of the formatted output.
+type SigningConfig struct {
+	// Key is the Ed25519 private key. Required.
+	Key ed25519.PrivateKey
+	// Mode selects per-entry signatures or per-batch Merkle roots.
+	Mode SigningMode
+	// KeyID, if set, is recorded in batch trailers to identify the verification key.
+	KeyID string
+}
+
+// BatchSeal is the trailer line written after each batch in SignBatches mode.
+type BatchSeal struct {
+	Root  string `json:"batch_merkle_root"` // Hex Merkle root of the entries.
+	Count int    `json:"batch_count"`       // Number of entries in the batch.
+	KeyID string `json:"batch_key_id,omitempty"`
+	Sig   string `json:"batch_sig"` // Base64 Ed25519 signature of the root and count.
+}
+
+// SetSigning enables signing at runtime, or disables it if cfg is nil.
+func (l *Logger) SetSigning(cfg *SigningConfig) {
+	if cfg == nil || len(cfg.Key) != ed25519.PrivateKeySize {
+		l.signing.Store(nil)
+		return
+	}
+	c := *cfg
+	l.signing.Store(&c)
+}
+
+// signEntry returns b with its signature appended, in SignEntries mode.
+func (l *Logger) signEntry(b []byte) []byte {
+	cfg := l.signing.Load()
+	if cfg == nil || cfg.Mode != SignEntries {
+		return b
+	}
+	body := bytes.TrimSuffix(b, []byte("\n"))
+	sig := base64.RawStdEncoding.EncodeToString(ed25519.Sign(cfg.Key, body))
+	out := make([]byte, 0, len(b)+len(sig)+10)
+	if isJSONObject(body) {
+		out = append(out, body[:len(body)-1]...)
+		out = append(out, `,"sig":"`...)
+		out = append(out, sig...)
+		out = append(out, `"}`...)
+	} else {
+		out = append(out, body...)
+		out = append(out, " sig="...)
+		out = append(out, sig...)
+	}
+	return append(out, '\n')
+}
+
+// sealSegments appends the batch trailer to s in SignBatches mode. It runs once per batch
+// and destination, before the first write.
+func (l *Logger) sealSegments(s *segments) {
+	s.sealed = true
+	cfg := l.signing.Load()
+	if cfg == nil || cfg.Mode != SignBatches || s.empty() {
+		return
+	}
+	root := merkleRoot(s.parts)
+	seal := BatchSeal{Root: hex.EncodeToString(root), Count: len(s.parts), KeyID: cfg.KeyID}
+	seal.Sig = base64.RawStdEncoding.EncodeToString(ed25519.Sign(cfg.Key, sealMessage(root, seal.Count)))
+	b, err := json.Marshal(seal)
+	if err != nil {
+		return
+	}
+	s.add(append(b, '\n'), nil)
+	s.trailer = true
+}
+
+// sealMessage returns the message signed by a batch trailer.
+func sealMessage(root []byte, count int) []byte {
+	return fmt.Appendf(append([]byte("unologger-batch:"), root...), ":%d", count)
+}
+
+// isJSONObject reports whether b looks like a single JSON object.
+func isJSONObject(b []byte) bool {
+	return len(b) >= 2 && b[0] == '{' && b[len(b)-1] == '}'
+}
+
+// merkleRoot returns the Merkle root of the entries, ignoring their trailing newlines and
+// sink sequence numbers. Leaves are SHA-256(0x00 || entry) and nodes SHA-256(0x01 || left
+// || right); an odd node is promoted to the next level unchanged.
+func merkleRoot(entries [][]byte) []byte {
+	level := make([][]byte, len(entries))
+	for i, e := range entries {
+		h := sha256.Sum256(append([]byte{0}, stripSinkSeq(bytes.TrimSuffix(e, []byte("\n")))...))
+		level[i] = h[:]
+	}
+	if len(level) == 0 {
+		h := sha256.Sum256(nil)
+		return h[:]
+	}
+	for len(level) > 1 {
+		next := level[:0:0]
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			buf := make([]byte, 0, 1+2*sha256.Size)
+			buf = append(append(append(buf, 1), level[i]...), level[i+1]...)
+			h := sha256.Sum256(buf)
+			next = append(next, h[:])
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// VerifyEntry checks the signature of an entry written in SignEntries mode. The trailing
+// newline is optional. Sink sequence numbers are added after signing and are not covered.
+func VerifyEntry(pub ed25519.PublicKey, line []byte) error {
+	line = stripSinkSeq(bytes.TrimSuffix(line, []byte("\n")))
+	var body, sig []byte
+	if isJSONObject(line) {
+		i := bytes.LastIndex(line, []byte(`,"sig":"`))
+		if i < 0 {
+			return ErrBadSignature
+		}
+		sig = line[i+len(`,"sig":"`) : len(line)-2]
+		body = append(append([]byte(nil), line[:i]...), '}')
+	} else {
+		i := bytes.LastIndex(line, []byte(" sig="))
+		if i < 0 {
+			return ErrBadSignature
+		}
+		sig, body = line[i+len(" sig="):], line[:i]
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(string(sig))
+	if err != nil || !ed25519.Verify(pub, body, raw) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// VerifyBatch checks the trailer of a batch written in SignBatches mode against the entries
+// that precede it, in order.
+func VerifyBatch(pub ed25519.PublicKey, entries [][]byte, trailer []byte) error {
+	var seal BatchSeal
+	if err := json.Unmarshal(trailer, &seal); err != nil || seal.Sig == "" {
+		return fmt.Errorf("unologger: not a batch trailer: %w", ErrBadSignature)
+	}
+	if seal.Count != len(entries) {
+		return fmt.Errorf("unologger: batch has %d entries, trailer covers %d: %w", len(entries), seal.Count, ErrBadSignature)
+	}
+	root := merkleRoot(entries)
+	if hex.EncodeToString(root) != seal.Root {
+		return ErrBadSignature
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(seal.Sig)
+	if err != nil || !ed25519.Verify(pub, sealMessage(root, seal.Count), raw) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// IsBatchSeal reports whether line is a batch trailer written in SignBatches mode, which
+// lets readers split a signed log into batches.
+func IsBatchSeal(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(line), []byte(`{"batch_merkle_root":`))
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements per-sink rate limits. A sink, typically a rate-limited SaaS ingestion
+// endpoint, receives at most a given number of entries per second; the excess is diverted
+// to a local overflow file instead of being rejected by the endpoint or lost, and the sink
+// receives a marker entry telling how many entries went to the file.
+
+package unologger
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// sinkRateMarkerInterval is the minimum interval between two marker entries of a sink.
+const sinkRateMarkerInterval = time.Second
+
+// SinkRateLimit caps the entries written to one sink.
+type SinkRateLimit struct {
+	// PerSecond is the sustained number of entries per second written to the sink.
+	// Zero or negative removes the limit.
+	PerSecond float64
+	// Burst is the number of entries the sink may receive at once after being idle.
+	// Defaults to PerSecond, and at least 1.
+	Burst int
+	// Overflow is the local file receiving the entries over the limit, rotated like the
+	// main log file; Enable is implied. Without a Filename the excess entries are dropped.
+	Overflow RotationConfig
+}
+
+// SinkRateStats counts the entries of a rate-limited sink since its limit was set.
+type SinkRateStats struct {
+	Passed   int64 `json:"passed"`   // Entries within the limit, handed to the sink.
+	Diverted int64 `json:"diverted"` // Entries over the limit written to the overflow file.
+	Dropped  int64 `json:"dropped"`  // Entries over the limit lost: no overflow file or a failed write.
+}
+
+// sinkLimiter is the token bucket and overflow file of one sink.
+type sinkLimiter struct {
+	cfg      SinkRateLimit
+	overflow io.Writer // Nil if the excess is dropped.
+
+	mu         sync.Mutex
+	refs       int  // Loggers using the limiter; a reinit shares it with the new global logger.
+	closed     bool // Set once the overflow file is closed; the limiter then lets every entry pass.
+	tokens     float64
+	last       time.Time // Time of the last refill.
+	lastMarker time.Time
+	unmarked   int64 // Entries over the limit since the last marker.
+	stats      SinkRateStats
+}
+
+// SetSinkRateLimit limits the entries written to the sink with the given name, as reported
+// by WriterStats ("stdout", "stderr", "rotation", an extra writer name, "retention:<class>"),
+// or removes its limit if lim is nil or has no rate. Setting a limit resets its statistics
+// and closes the overflow file of the previous limit.
+func (l *Logger) SetSinkRateLimit(sink string, lim *SinkRateLimit) {
+	l.sinkRatesMu.Lock()
+	defer l.sinkRatesMu.Unlock()
+	var next map[string]*sinkLimiter
+	var old *sinkLimiter
+	if cur := l.sinkRates.Load(); cur != nil {
+		next = maps.Clone(*cur)
+		old = next[sink]
+		delete(next, sink)
+	}
+	if lim != nil && lim.PerSecond > 0 {
+		if next == nil {
+			next = make(map[string]*sinkLimiter)
+		}
+		next[sink] = newSinkLimiter(*lim)
+	}
+	if len(next) == 0 {
+		l.sinkRates.Store(nil)
+	} else {
+		l.sinkRates.Store(&next)
+	}
+	// A write may still hold the old limiter; close takes its lock, so the write completes
+	// first and later ones see it closed.
+	if old != nil {
+		old.close()
+	}
+}
+
+// SinkRateStats returns the statistics of every rate-limited sink, keyed by sink name.
+func (l *Logger) SinkRateStats() map[string]SinkRateStats {
+	cur := l.sinkRates.Load()
+	if cur == nil {
+		return nil
+	}
+	stats := make(map[string]SinkRateStats, len(*cur))
+	for name, sl := range *cur {
+		sl.mu.Lock()
+		stats[name] = sl.stats
+		sl.mu.Unlock()
+	}
+	return stats
+}
+
+// newSinkLimiter creates the limiter of cfg, with a full bucket.
+func newSinkLimiter(cfg SinkRateLimit) *sinkLimiter {
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(int(cfg.PerSecond), 1)
+	}
+	cfg.Overflow.Enable = true
+	return &sinkLimiter{
+		cfg:      cfg,
+		overflow: initRotationWriter(cfg.Overflow),
+		refs:     1,
+		tokens:   float64(cfg.Burst),
+		last:     time.Now(),
+	}
+}
+
+// take refills the bucket and removes up to n tokens, returning how many were taken.
+func (sl *sinkLimiter) take(now time.Time, n int) int {
+	sl.tokens = min(sl.tokens+now.Sub(sl.last).Seconds()*sl.cfg.PerSecond, float64(sl.cfg.Burst))
+	sl.last = now
+	taken := min(n, int(sl.tokens))
+	sl.tokens -= float64(taken)
+	return taken
+}
+
+// share adds a logger using the limiter.
+func (sl *sinkLimiter) share() {
+	sl.mu.Lock()
+	sl.refs++
+	sl.mu.Unlock()
+}
+
+// close removes a logger using the limiter, and closes the overflow file once none is left.
+func (sl *sinkLimiter) close() {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.closed {
+		return
+	}
+	if sl.refs--; sl.refs > 0 {
+		return
+	}
+	sl.closed = true
+	if c, ok := sl.overflow.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+// shareSinkRates makes l use the rate limits of src, buckets and overflow files included,
+// in place of its own.
+func (l *Logger) shareSinkRates(src *Logger) {
+	l.closeSinkRates()
+	src.sinkRatesMu.Lock()
+	defer src.sinkRatesMu.Unlock()
+	cur := src.sinkRates.Load()
+	if cur != nil {
+		for _, sl := range *cur {
+			sl.share()
+		}
+	}
+	l.sinkRates.Store(cur)
+}
+
+// closeSinkRates closes the overflow files of every rate-limited sink.
+func (l *Logger) closeSinkRates() {
+	l.sinkRatesMu.Lock()
+	defer l.sinkRatesMu.Unlock()
+	if cur := l.sinkRates.Load(); cur != nil {
+		for _, sl := range *cur {
+			sl.close()
+		}
+	}
+}
+
+// limitSegments applies the rate limit of the named sink, if any, to the entries of s. It
+// returns s itself when every entry is within the limit. Otherwise it writes the excess to
+// the overflow file and returns the entries within the limit, followed by a marker entry
+// at most once per second. Batch seals are recomputed for both parts.
+func (l *Logger) limitSegments(name string, s *segments) *segments {
+	cur := l.sinkRates.Load()
+	if cur == nil {
+		return s
+	}
+	sl := (*cur)[name]
+	if sl == nil {
+		return s
+	}
+	n := len(s.parts)
+	if s.trailer {
+		n--
+	}
+	now := time.Now()
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.closed {
+		return s
+	}
+	k := sl.take(now, n)
+	sl.stats.Passed += int64(k)
+	if k == n {
+		return s
+	}
+
+	kept := &segments{parts: s.parts[:k:k]}
+	excess := &segments{parts: s.parts[k:n:n]}
+	if len(s.events) >= n {
+		kept.events = s.events[:k:k]
+		excess.events = s.events[k:n:n]
+	}
+	if sl.overflow != nil {
+		l.sealSegments(excess)
+		if _, err := sl.overflow.Write(excess.bytes()); err != nil {
+			l.incWriterErr(name+":overflow", err)
+			sl.stats.Dropped += int64(n - k)
+		} else {
+			sl.stats.Diverted += int64(n - k)
+		}
+	} else {
+		sl.stats.Dropped += int64(n - k)
+	}
+	sl.unmarked += int64(n - k)
+
+	if now.Sub(sl.lastMarker) >= sinkRateMarkerInterval {
+		if b, ok := l.sinkRateMarker(name, sl, now); ok {
+			kept.add(b, nil)
+			sl.lastMarker = now
+			sl.unmarked = 0
+		}
+	}
+	return kept
+}
+
+// sinkRateMarker formats the marker entry telling that entries of the sink went over its
+// limit since the previous marker.
+func (l *Logger) sinkRateMarker(name string, sl *sinkLimiter, now time.Time) ([]byte, bool) {
+	msg := fmt.Sprintf("unologger: sink %q over its rate limit of %g entries/s: %d entries dropped",
+		name, sl.cfg.PerSecond, sl.unmarked)
+	fields := Fields{"sink": name, "over_limit": sl.unmarked}
+	if sl.overflow != nil {
+		msg = fmt.Sprintf("unologger: sink %q over its rate limit of %g entries/s: %d entries diverted to %s",
+			name, sl.cfg.PerSecond, sl.unmarked, sl.cfg.Overflow.Filename)
+		fields["overflow_file"] = sl.cfg.Overflow.Filename
+	}
+	l.locMu.RLock()
+	loc := l.loc
+	l.locMu.RUnlock()
+	b, err := l.formatEvent(HookEvent{
+		Time:     now.In(loc),
+		Level:    WARN,
+		Module:   "unologger",
+		Message:  msg,
+		Fields:   fields,
+		Attrs:    fields,
+		JSONMode: l.jsonFmtFlag.Load(),
+	})
+	if err != nil {
+		return nil, false
+	}
+	return l.signEntry(b), true
+}
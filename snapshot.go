@@ -0,0 +1,91 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the effective-state snapshot. It extends the dynamic configuration
+// with the settings and sinks that surround it, so that tooling can dump everything a
+// running logger does without reaching into its internals.
+
+package unologger
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// SinkSnapshot describes a sink of a logger.
+type SinkSnapshot struct {
+	Name string // Name of the sink, as reported by WriterStats, e.g. "stdout" or "rotation".
+	// Type is the Go type of the writer, e.g. "*os.File", or the protocol of the built-in
+	// network sinks, "otlp" and "syslog".
+	Type        string
+	Temporary   bool        // Whether the sink was attached by WithTemporarySink.
+	Stats       WriterStats // Counters and last error of the sink.
+	Quarantined bool        // Whether the sink failed HealthConfig.SinkFailures times in a row.
+}
+
+// DynamicConfigSnapshot is the full effective state of a logger: a copy of its dynamic
+// configuration, the formatting settings, the hook execution settings and the sinks with
+// their health.
+type DynamicConfigSnapshot struct {
+	*DynamicConfig
+	Formatter  string // Go type of the formatter, e.g. "*unologger.JSONFormatter".
+	JSON       bool   // Whether the JSON format is enabled with SetJSONFormat.
+	Timezone   string // IANA name of the timezone of the timestamps.
+	EnableOTel bool   // Whether trace and span IDs are extracted from the context.
+	HookConfig HookConfig
+	Sinks      []SinkSnapshot // stdout, stderr, extra writers, rotation, retention, OTLP, syslog.
+}
+
+// GetDynamicConfigSnapshot returns the full effective state of the logger. Every part is
+// read under the lock that guards it, so the snapshot is safe to take while other
+// goroutines log and reconfigure the logger; parts changed during the call may however
+// reflect different moments. Like GetDynamicConfig, the result is a copy.
+func (l *Logger) GetDynamicConfigSnapshot() *DynamicConfigSnapshot {
+	s := &DynamicConfigSnapshot{
+		DynamicConfig: l.GetDynamicConfig(),
+		JSON:          l.jsonFmtFlag.Load(),
+		EnableOTel:    l.enableOTel.Load(),
+		HookConfig:    l.GetHookConfig(),
+	}
+	l.formatterMu.RLock()
+	s.Formatter = fmt.Sprintf("%T", l.formatter)
+	l.formatterMu.RUnlock()
+	l.locMu.RLock()
+	s.Timezone = l.loc.String()
+	l.locMu.RUnlock()
+
+	l.outputsMu.RLock()
+	s.Sinks = append(s.Sinks,
+		SinkSnapshot{Name: "stdout", Type: fmt.Sprintf("%T", l.stdOut)},
+		SinkSnapshot{Name: "stderr", Type: fmt.Sprintf("%T", l.errOut)})
+	for _, w := range l.extraW {
+		s.Sinks = append(s.Sinks, SinkSnapshot{Name: w.Name, Type: fmt.Sprintf("%T", w.Writer), Temporary: w.temp != 0})
+	}
+	if l.rotationSink != nil {
+		s.Sinks = append(s.Sinks, SinkSnapshot{Name: l.rotationSink.Name, Type: fmt.Sprintf("%T", l.rotationSink.Writer)})
+	}
+	for _, class := range slices.Sorted(maps.Keys(l.retentionSinks)) {
+		w := l.retentionSinks[class]
+		s.Sinks = append(s.Sinks, SinkSnapshot{Name: w.Name, Type: fmt.Sprintf("%T", w.Writer)})
+	}
+	l.outputsMu.RUnlock()
+	if l.otlp.Load() != nil {
+		s.Sinks = append(s.Sinks, SinkSnapshot{Name: OTLPSinkName, Type: "otlp"})
+	}
+	if l.syslog.Load() != nil {
+		s.Sinks = append(s.Sinks, SinkSnapshot{Name: SyslogSinkName, Type: "syslog"})
+	}
+
+	hc := defaultHealthConfig
+	if p := l.healthCfg.Load(); p != nil {
+		hc = *p
+	}
+	stats := l.WriterStats()
+	for i := range s.Sinks {
+		s.Sinks[i].Stats = stats[s.Sinks[i].Name]
+		s.Sinks[i].Quarantined = s.Sinks[i].Stats.ConsecutiveFailures >= hc.SinkFailures
+	}
+	return s
+}
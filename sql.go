@@ -0,0 +1,333 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements query logging for database code. Bind parameters are matched to the
+// columns they are compared with or inserted into, and the parameters of columns named by
+// JSONFieldRules (password, ssn, ...) are masked. An ellipsis mode logs only the types and
+// lengths of the parameters.
+
+package unologger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SQLArgMode selects how query parameters are logged.
+type SQLArgMode int
+
+const (
+	// SQLArgsFull logs parameter values, masking those of sensitive columns.
+	SQLArgsFull SQLArgMode = iota
+	// SQLArgsEllipsis logs only the type of each parameter, and the length of strings and
+	// byte slices, e.g. "string(12)".
+	SQLArgsEllipsis
+	// SQLArgsNone omits the parameters.
+	SQLArgsNone
+)
+
+// SQLConfig configures an SQLLogger.
+type SQLConfig struct {
+	// Module is the module of the query entries. Defaults to "sql".
+	Module string
+	// Args selects how parameters are logged. Defaults to SQLArgsFull.
+	Args SQLArgMode
+	// SlowThreshold, if positive, logs queries that take at least this long at WARN.
+	SlowThreshold time.Duration
+}
+
+// SQLLogger logs database queries. Call Log from a driver wrapper, an ORM callback or the
+// data access layer after each query:
+//
+//	sl := l.SQLLogger(unologger.SQLConfig{SlowThreshold: 200 * time.Millisecond})
+//	start := time.Now()
+//	rows, err := db.QueryContext(ctx, q, args...)
+//	sl.Log(ctx, q, args, time.Since(start), err)
+type SQLLogger struct {
+	l   *Logger
+	cfg SQLConfig
+}
+
+// SQLLogger returns an SQLLogger that logs with l.
+func (l *Logger) SQLLogger(cfg SQLConfig) *SQLLogger {
+	if cfg.Module == "" {
+		cfg.Module = "sql"
+	}
+	return &SQLLogger{l: l, cfg: cfg}
+}
+
+// Log logs a query at DEBUG, at WARN if it was slow, or at ERROR if it failed (an error
+// wrapping sql.ErrNoRows is not a failure). The entry carries the fields sql_query,
+// sql_args, duration_ms and error.
+func (s *SQLLogger) Log(ctx context.Context, query string, args []interface{}, elapsed time.Duration, err error) {
+	ctx = orBackground(ctx)
+	level := DEBUG
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		level = ERROR
+	case s.cfg.SlowThreshold > 0 && elapsed >= s.cfg.SlowThreshold:
+		level = WARN
+	}
+	ctx = context.WithValue(ctx, ctxModuleKey, s.cfg.Module)
+	if !s.l.levelEnabled(ctx, level, false) {
+		return
+	}
+	fields := Fields{
+		FieldSQLQuery:   query,
+		FieldDurationMS: elapsed.Milliseconds(),
+	}
+	if s.cfg.Args != SQLArgsNone && len(args) > 0 {
+		fields[FieldSQLArgs] = s.l.MaskSQLArgs(query, args, s.cfg.Args)
+	}
+	if err != nil {
+		fields[FieldError] = err.Error()
+	}
+	msg := "query"
+	if level == WARN {
+		msg = "slow query"
+	}
+	s.l.logFields(ctx, level, fields, msg, literalArgs...)
+}
+
+// MaskSQLArgs returns the loggable form of the parameters of query. A parameter is masked
+// with the replacement of the first JSONFieldRules rule naming its column, matched exactly
+// or in lower case. Columns are found for named parameters (sql.NamedArg, :name, @name),
+// for comparisons such as "password = ?" and for INSERT column lists; numbered placeholders
+// ($1, ?2) are supported.
+//
+// For a logger that forwards its entries, such as a Tee, the rules of every delegate are
+// applied in turn.
+func (l *Logger) MaskSQLArgs(query string, args []interface{}, mode SQLArgMode) []interface{} {
+	for _, d := range l.delegateTargets() {
+		args = d.MaskSQLArgs(query, args, mode)
+	}
+	l.dynConfig.mu.RLock()
+	rules := l.dynConfig.JSONFieldRules
+	l.dynConfig.mu.RUnlock()
+
+	columns := sqlParamColumns(query, len(args))
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		column := columns[i]
+		if na, ok := arg.(sql.NamedArg); ok {
+			column, arg = na.Name, na.Value
+		}
+		if column != "" {
+			key := column
+			if !shouldMaskKeyWithRules(key, rules) {
+				key = strings.ToLower(column)
+			}
+			if shouldMaskKeyWithRules(key, rules) {
+				out[i] = getMaskReplacementForKeyWithRules(key, rules)
+				continue
+			}
+		}
+		if mode == SQLArgsEllipsis {
+			out[i] = sqlArgShape(arg)
+		} else {
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+// sqlArgShape describes a parameter by its type and, for strings and byte slices, length.
+func sqlArgShape(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "string(" + strconv.Itoa(len(v)) + ")"
+	case []byte:
+		return "[]byte(" + strconv.Itoa(len(v)) + ")"
+	}
+	return reflect.TypeOf(arg).String()
+}
+
+// sqlToken is a lexical token of a query, as far as parameter matching needs it.
+type sqlToken struct {
+	text  string
+	param bool // A placeholder: ?, ?N, $N, :name or @name.
+}
+
+// sqlParamColumns returns the column of each of the n parameters of query, or "" where
+// it cannot be determined.
+func sqlParamColumns(query string, n int) []string {
+	columns := make([]string, n)
+	tokens := sqlTokens(query)
+	var insertCols []string // Column list of the INSERT statement, if any.
+	inValues := false       // Inside the VALUES clause of an INSERT.
+	depth, tuplePos := 0, 0 // Parenthesis depth and position inside a VALUES tuple.
+	seen := 0               // Positional placeholders seen so far.
+	for i, tok := range tokens {
+		if !tok.param {
+			switch {
+			case strings.EqualFold(tok.text, "INTO") && i+2 < len(tokens) && tokens[i+2].text == "(":
+				insertCols = sqlColumnList(tokens[i+3:])
+			case strings.EqualFold(tok.text, "VALUES"):
+				inValues, depth = true, 0
+			case !inValues:
+			case tok.text == "(":
+				if depth == 0 {
+					tuplePos = 0
+				}
+				depth++
+			case tok.text == ")":
+				depth--
+			case tok.text == ",":
+				if depth == 1 {
+					tuplePos++
+				}
+			case depth == 0:
+				inValues = false // The clause after VALUES, e.g. RETURNING.
+			}
+			continue
+		}
+
+		idx, name := sqlParamIndex(tok.text, &seen)
+		if idx < 0 || idx >= n {
+			continue
+		}
+		switch {
+		case name != "":
+			columns[idx] = name
+		case inValues && depth == 1 && tuplePos < len(insertCols):
+			columns[idx] = insertCols[tuplePos]
+		default:
+			columns[idx] = sqlComparedColumn(tokens[:i])
+		}
+	}
+	return columns
+}
+
+// sqlParamIndex returns the argument index and, for named placeholders, the name of a
+// placeholder token. seen counts the positional placeholders.
+func sqlParamIndex(tok string, seen *int) (int, string) {
+	switch tok[0] {
+	case ':', '@':
+		idx := *seen
+		*seen++
+		return idx, tok[1:]
+	}
+	if len(tok) > 1 {
+		if n, err := strconv.Atoi(tok[1:]); err == nil {
+			return n - 1, ""
+		}
+	}
+	idx := *seen
+	*seen++
+	return idx, ""
+}
+
+// sqlColumnList reads a parenthesized column list, starting after its "(".
+func sqlColumnList(tokens []sqlToken) []string {
+	var cols []string
+	for _, tok := range tokens {
+		switch tok.text {
+		case ")":
+			return cols
+		case ",":
+		default:
+			cols = append(cols, sqlColumnName(tok.text))
+		}
+	}
+	return nil
+}
+
+// sqlComparedColumn returns the column compared with a placeholder that follows tokens,
+// as in "password = ?", "t.ssn <> ?" or "email LIKE ?", or "" if there is none.
+func sqlComparedColumn(tokens []sqlToken) string {
+	i := len(tokens) - 1
+	for i >= 0 && (tokens[i].text == "(" || tokens[i].text == ",") {
+		i-- // Skip over IN ( ?, ? lists.
+		for i >= 0 && tokens[i].param {
+			i--
+		}
+	}
+	if i < 1 {
+		return ""
+	}
+	switch strings.ToUpper(tokens[i].text) {
+	case "=", "<>", "!=", "<", ">", "<=", ">=", "LIKE", "ILIKE", "IN":
+		if prev := tokens[i-1]; !prev.param {
+			return sqlColumnName(prev.text)
+		}
+	}
+	return ""
+}
+
+// sqlColumnName strips the table qualifier and quotes of a column reference.
+func sqlColumnName(ref string) string {
+	if i := strings.LastIndexByte(ref, '.'); i >= 0 {
+		ref = ref[i+1:]
+	}
+	return strings.Trim(ref, "`\"[]")
+}
+
+// sqlTokens splits query into identifiers, operators, punctuation and placeholders,
+// skipping string literals and comments.
+func sqlTokens(query string) []sqlToken {
+	var tokens []sqlToken
+	isIdent := func(r byte) bool {
+		return r == '_' || r == '.' || r == '"' || r == '`' || r == '[' || r == ']' ||
+			unicode.IsLetter(rune(r)) || unicode.IsDigit(rune(r)) || r >= 0x80
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i++
+			for i < len(query) && !(query[i] == '\'' && (i+1 >= len(query) || query[i+1] != '\'')) {
+				if query[i] == '\'' {
+					i++ // Escaped quote.
+				}
+				i++
+			}
+			i++
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '?' || c == '$' || ((c == ':' || c == '@') && i+1 < len(query) && isIdent(query[i+1]) && (i == 0 || query[i-1] != ':')):
+			j := i + 1
+			for j < len(query) && isIdent(query[j]) && query[j] != '.' {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: query[i:j], param: true})
+			i = j
+		case isIdent(c):
+			j := i + 1
+			for j < len(query) && isIdent(query[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: query[i:j]})
+			i = j
+		case c == '<' || c == '>' || c == '!' || c == '=':
+			j := i + 1
+			for j < len(query) && strings.IndexByte("<>=", query[j]) >= 0 {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: query[i:j]})
+			i = j
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		default:
+			tokens = append(tokens, sqlToken{text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
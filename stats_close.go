@@ -9,15 +9,20 @@
 package unologger
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Stats returns a snapshot of the current performance and error statistics for the global logger.
-// It is safe for concurrent use.
+// It is safe for concurrent use. Snapshot returns the same statistics, and more, as a struct.
 //
 // Returned values:
 //   - dropped: Total number of log entries dropped because the queue was full (in non-blocking mode).
@@ -47,11 +52,17 @@ func StatsDetached(l *Logger) (dropped, written, batches, writeErrs, hookErrs in
 		l.batchCount.Load(),
 		l.writeErrCount.Load(),
 		l.hookErrCount.Load(),
-		len(l.ch),
+		l.queueLen(),
 		l.getWriterErrorStats(),
 		l.GetHookErrors()
 }
 
+// LoggedAfterClose returns the number of log entries that were rejected because they
+// were logged after the logger had started shutting down.
+func (l *Logger) LoggedAfterClose() int64 {
+	return l.afterClose.Load()
+}
+
 // Close gracefully shuts down the global logger, ensuring all buffered logs are written.
 // It's crucial to call this at application exit to prevent log loss.
 //
@@ -81,23 +92,55 @@ func CloseDetached(l *Logger, timeout time.Duration) error {
 
 // closeLogger contains the core shutdown logic for any logger instance.
 func closeLogger(l *Logger, timeout time.Duration) error {
+	// Entries held by a paused logger are queued, so that they are written before the
+	// queue is drained.
+	l.Resume()
 	// Atomically set the `closed` flag. If it was already true, another goroutine
 	// is already handling the shutdown, so we can return.
 	if !l.closed.TrySetTrue() {
 		return nil
 	}
-
-	// Close the main channel. This signals the worker loops to stop accepting
-	// new entries and to exit once they have processed all remaining entries.
-	close(l.ch)
+	l.emitLifecycle(LifecycleEvent{Type: LifecycleClosing})
 
 	done := make(chan struct{})
 	go func() {
-		// Wait for all worker goroutines to finish their work.
+		// Close the main channel. This signals the worker loops to stop accepting
+		// new entries and to exit once they have processed all remaining entries.
+		// The write lock waits for producers that passed the closed check before it
+		// was set; it is taken here so that a producer blocked on a full queue cannot
+		// keep Close from honoring its timeout. Closing `closing` first releases such
+		// producers, whose entries are then counted as logged after close; without it,
+		// a worker that needs the read lock to find the queue could wait behind the
+		// pending write lock forever. In single-writer mode there is no channel.
+		l.closeStage.Store(int64(stageDrainingQueue))
+		l.stopAutoScaler()
+		if l.closing != nil {
+			close(l.closing)
+		}
+		l.chMu.Lock()
+		if l.ch != nil {
+			close(l.ch)
+		}
+		if l.hi != nil {
+			close(l.hi)
+		}
+		l.chMu.Unlock()
+		// Wait for all worker goroutines to finish their work. Taking workersMu first
+		// guarantees that SetWorkers observes the closed flag and spawns no new workers.
+		l.workersMu.Lock()
+		l.workersMu.Unlock() //nolint:staticcheck // Used as a barrier.
 		l.wg.Wait()
+		// Wait for an in-flight direct write to complete. Later direct writes
+		// observe the closed flag and are discarded.
+		l.directMu.Lock()
+		l.directMu.Unlock() //nolint:staticcheck // Used as a barrier.
 		// After workers are done, we can safely close the hooks and writers.
+		l.closeStage.Store(int64(stageStoppingHooks))
 		l.closeHookRunner()
+		l.closeStage.Store(int64(stageClosingWriters))
 		l.closeAllWriters()
+		l.closeStage.Store(int64(stageClosed))
+		l.emitLifecycle(LifecycleEvent{Type: LifecycleClosed})
 		close(done)
 	}()
 
@@ -115,57 +158,250 @@ func closeLogger(l *Logger, timeout time.Duration) error {
 		l.printFinalStats(os.Stderr)
 		return nil
 	case <-time.After(timeout):
-		// Timeout expired before shutdown could complete.
-		return fmt.Errorf("unologger: close timed out after %s", timeout)
-	}
-}
-
-// incWriterErr is a thread-safe method to increment the error count for a specific writer.
-func (l *Logger) incWriterErr(name string) {
-	// Use an atomic counter per writer to avoid lost updates under contention.
-	// Store *atomicI64 in the map and increment atomically.
-	if c, ok := l.writerErrs.Load(name); ok {
-		switch v := c.(type) {
-		case *atomicI64:
-			v.Add(1)
-			return
-		case int64:
-			// Backward compatibility in case an int64 was stored previously.
-			// Replace with an atomic counter initialized to v+1.
-			ai := &atomicI64{}
-			ai.Store(v + 1)
-			l.writerErrs.Store(name, ai)
-			return
+		// Timeout expired before shutdown could complete. Report what is still pending
+		// so operators can estimate how much was lost.
+		report := l.buildCloseReport(timeout)
+		if l.closeDiag {
+			report.Stacks = pipelineStacks()
+			l.writeCloseDiagnostics(os.Stderr, report)
 		}
+		return &CloseTimeoutError{Report: report}
+	}
+}
+
+// closeStage identifies the shutdown step a logger is currently executing.
+type closeStage int64
+
+const (
+	stageRunning closeStage = iota
+	stageDrainingQueue
+	stageStoppingHooks
+	stageClosingWriters
+	stageClosed
+)
+
+// String returns the stage name used in CloseReport.
+func (s closeStage) String() string {
+	switch s {
+	case stageDrainingQueue:
+		return "draining-queue"
+	case stageStoppingHooks:
+		return "stopping-hooks"
+	case stageClosingWriters:
+		return "closing-writers"
+	case stageClosed:
+		return "closed"
+	default:
+		return "running"
 	}
-	// Not present: create a new atomic counter starting at 1.
-	ai := &atomicI64{}
-	ai.Store(1)
-	if prev, loaded := l.writerErrs.LoadOrStore(name, ai); loaded {
-		// Another goroutine beat us; increment that one.
-		if p, ok := prev.(*atomicI64); ok {
-			p.Add(1)
-		} else if iv, ok := prev.(int64); ok {
-			tmp := &atomicI64{}
-			tmp.Store(iv + 1)
-			l.writerErrs.Store(name, tmp)
+}
+
+// ErrCloseTimeout is matched (via errors.Is) by the error returned from Close and
+// CloseDetached when shutdown does not complete within the timeout.
+var ErrCloseTimeout = errors.New("unologger: close timed out")
+
+// CloseReport describes the work that was still pending when a shutdown timed out.
+type CloseReport struct {
+	Timeout          time.Duration // The timeout that expired.
+	Stage            string        // The shutdown stage that was in progress, e.g. "draining-queue".
+	QueuedEntries    int           // Entries still waiting in the processing queue.
+	PendingHookTasks int           // Events still waiting in the async hook queue.
+	FailingSinks     []string      // Writers that have recorded at least one write or close error.
+	Stacks           string        // Pipeline goroutine stacks; only set when Config.CloseDiagnostics is enabled.
+}
+
+// CloseTimeoutError is returned by Close and CloseDetached when the shutdown does not
+// complete in time. It carries a CloseReport with the partial results.
+type CloseTimeoutError struct {
+	Report CloseReport
+}
+
+// Error returns a summary of the timeout including the pending work counts.
+func (e *CloseTimeoutError) Error() string {
+	r := e.Report
+	return fmt.Sprintf("unologger: close timed out after %s (stage=%s, queued=%d, pending_hooks=%d, failing_sinks=%v)",
+		r.Timeout, r.Stage, r.QueuedEntries, r.PendingHookTasks, r.FailingSinks)
+}
+
+// Unwrap returns ErrCloseTimeout so callers can use errors.Is.
+func (e *CloseTimeoutError) Unwrap() error {
+	return ErrCloseTimeout
+}
+
+// buildCloseReport snapshots the pending work of a logger whose shutdown timed out.
+func (l *Logger) buildCloseReport(timeout time.Duration) CloseReport {
+	r := CloseReport{
+		Timeout:       timeout,
+		Stage:         closeStage(l.closeStage.Load()).String(),
+		QueuedEntries: l.queueLen(),
+	}
+	l.hooksMu.RLock()
+	if q := l.hookQueueCh; q != nil {
+		r.PendingHookTasks = len(q)
+	}
+	l.hooksMu.RUnlock()
+	for name, count := range l.getWriterErrorStats() {
+		if count > 0 {
+			r.FailingSinks = append(r.FailingSinks, name)
 		}
 	}
+	sort.Strings(r.FailingSinks)
+	return r
+}
+
+// pipelineMarkers identify the goroutines that belong to a logger pipeline in a stack dump.
+var pipelineMarkers = []string{
+	"unologger.(*Logger).workerLoop",
+	"unologger.(*Logger).startHookRunnerLocked",
+	"unologger.(*Logger).runHooks",
+	"unologger.(*Logger).writeDirect",
+	"unologger.closeLogger",
+}
+
+// pipelineStacks returns the stacks of all goroutines currently running unologger worker,
+// hook-runner, direct-write or shutdown code. Goroutines of every Logger instance in the
+// process are included, since stack traces do not identify the receiver.
+func pipelineStacks() string {
+	var sb strings.Builder
+	for _, g := range strings.Split(string(allStacks()), "\n\n") {
+		for _, m := range pipelineMarkers {
+			if strings.Contains(g, m) {
+				sb.WriteString(g)
+				sb.WriteString("\n\n")
+				break
+			}
+		}
+	}
+	return sb.String()
+}
+
+// allStacks returns the stacks of all goroutines, as formatted by runtime.Stack.
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// writeCloseDiagnostics writes a human-readable summary of a timed-out shutdown to w.
+func (l *Logger) writeCloseDiagnostics(w io.Writer, r CloseReport) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "unologger: close timed out after %s at stage %s\n", r.Timeout, r.Stage)
+	fmt.Fprintf(&sb, "  queue: %d/%d entries, workers: %d\n", r.QueuedEntries, cap(l.queue())+cap(l.hi), l.Workers())
+	fmt.Fprintf(&sb, "  hook queue: %d pending\n", r.PendingHookTasks)
+	stats := l.getWriterErrorStats()
+	for _, name := range r.FailingSinks {
+		fmt.Fprintf(&sb, "  failing sink: %s (%d errors)\n", name, stats[name])
+	}
+	if r.Stacks != "" {
+		sb.WriteString("  goroutine stacks:\n\n")
+		sb.WriteString(r.Stacks)
+	}
+	_, _ = io.WriteString(w, sb.String())
+}
+
+// WriterStats describes the health of a single output writer. Write errors are counted
+// per attempt, so a write retried three times before failing counts three errors.
+type WriterStats struct {
+	Errors              int64     // Total failed write (and close) attempts.
+	ConsecutiveFailures int64     // Failed attempts since the last successful write.
+	LastError           error     // Error of the most recent failed attempt, if any.
+	LastErrorTime       time.Time // Time of the most recent failed attempt.
+	LastSuccess         time.Time // Time of the most recent successful write.
+	BytesWritten        int64     // Total bytes successfully written.
+	EntriesWritten      int64     // Total log entries successfully written.
+}
+
+// writerHealth holds the live counters behind WriterStats for one writer.
+type writerHealth struct {
+	errors      atomicI64
+	consecutive atomic.Int64
+	bytes       atomicI64
+	entries     atomicI64
+	lastOK      atomicI64 // Unix nanoseconds of the last successful write.
+
+	mu        sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+}
+
+// health returns the health record of the named writer, creating it on first use.
+func (l *Logger) health(name string) *writerHealth {
+	if h, ok := l.writerErrs.Load(name); ok {
+		return h.(*writerHealth)
+	}
+	h, _ := l.writerErrs.LoadOrStore(name, &writerHealth{})
+	return h.(*writerHealth)
+}
+
+// incWriterErr is a thread-safe method to record a failed write or close of a specific writer.
+func (l *Logger) incWriterErr(name string, err error) {
+	h := l.health(name)
+	h.errors.Add(1)
+	n := h.consecutive.Add(1)
+	h.mu.Lock()
+	h.lastErr = err
+	h.lastErrAt = time.Now()
+	h.mu.Unlock()
+	threshold := defaultHealthConfig.SinkFailures
+	if hc := l.healthCfg.Load(); hc != nil {
+		threshold = hc.SinkFailures
+	}
+	if n == threshold {
+		l.emitLifecycle(LifecycleEvent{Type: LifecycleSinkQuarantined, Sink: name, Err: err})
+	}
 }
 
-// getWriterErrorStats safely retrieves a snapshot of the writer error counts.
+// writerSucceeded records a successful write of n bytes holding the given number of entries.
+func (l *Logger) writerSucceeded(name string, n, entries int) {
+	h := l.health(name)
+	h.consecutive.Store(0)
+	h.bytes.Add(int64(n))
+	h.entries.Add(int64(entries))
+	h.lastOK.Store(time.Now().UnixNano())
+}
+
+// snapshot returns the current WriterStats of the record.
+func (h *writerHealth) snapshot() WriterStats {
+	ws := WriterStats{
+		Errors:              h.errors.Load(),
+		ConsecutiveFailures: h.consecutive.Load(),
+		BytesWritten:        h.bytes.Load(),
+		EntriesWritten:      h.entries.Load(),
+	}
+	if ns := h.lastOK.Load(); ns != 0 {
+		ws.LastSuccess = time.Unix(0, ns)
+	}
+	h.mu.Lock()
+	ws.LastError = h.lastErr
+	ws.LastErrorTime = h.lastErrAt
+	h.mu.Unlock()
+	return ws
+}
+
+// WriterStats returns a snapshot of the health of every writer that has been written to
+// or has failed, keyed by writer name ("stdout", "stderr", the rotation file name or the
+// name of an extra writer).
+func (l *Logger) WriterStats() map[string]WriterStats {
+	stats := make(map[string]WriterStats)
+	l.writerErrs.Range(func(key, value any) bool {
+		stats[key.(string)] = value.(*writerHealth).snapshot()
+		return true
+	})
+	return stats
+}
+
+// getWriterErrorStats safely retrieves a snapshot of the error counts of the writers
+// that have recorded at least one error.
 func (l *Logger) getWriterErrorStats() map[string]int64 {
 	stats := make(map[string]int64)
 	l.writerErrs.Range(func(key, value any) bool {
-		name := key.(string)
-		switch v := value.(type) {
-		case *atomicI64:
-			stats[name] = v.Load()
-		case int64:
-			// Backward compatibility: accept raw int64 values.
-			stats[name] = v
-		default:
-			// Unknown type; ignore.
+		if n := value.(*writerHealth).errors.Load(); n > 0 {
+			stats[key.(string)] = n
 		}
 		return true
 	})
@@ -203,16 +439,18 @@ func (l *Logger) closeAllWriters() {
 	l.outputsMu.Lock()
 	defer l.outputsMu.Unlock()
 
-	// Close standard output if it's a Closer (e.g., a file).
-	if closer, ok := l.stdOut.(io.Closer); ok {
+	// Close standard output if it's a Closer (e.g., a file). The process's own standard
+	// streams are never closed: the default configuration uses them, and a reinit closes
+	// the old global logger while the new one and the rest of the process still write there.
+	if closer, ok := l.stdOut.(io.Closer); ok && l.stdOut != io.Writer(os.Stdout) && !l.sharedStdout {
 		if err := closer.Close(); err != nil {
-			l.incWriterErr("stdout")
+			l.incWriterErr("stdout", err)
 		}
 	}
 	// Close standard error if it's a Closer.
-	if closer, ok := l.errOut.(io.Closer); ok {
+	if closer, ok := l.errOut.(io.Closer); ok && l.errOut != io.Writer(os.Stderr) && !l.sharedStderr {
 		if err := closer.Close(); err != nil {
-			l.incWriterErr("stderr")
+			l.incWriterErr("stderr", err)
 		}
 	}
 
@@ -220,17 +458,38 @@ func (l *Logger) closeAllWriters() {
 	for _, s := range l.extraW {
 		if s.Closer != nil {
 			if err := s.Closer.Close(); err != nil {
-				l.incWriterErr(s.Name)
+				l.incWriterErr(s.Name, err)
 			}
 		}
 	}
 	l.extraW = nil
 
+	// Close the retention sinks. The map itself is kept, since it is read without a lock.
+	for _, s := range l.retentionSinks {
+		if s.Closer != nil {
+			if err := s.Closer.Close(); err != nil {
+				l.incWriterErr(s.Name, err)
+			}
+		}
+	}
+
 	// Close the rotation writer.
 	if l.rotationSink != nil && l.rotationSink.Closer != nil {
 		if err := l.rotationSink.Closer.Close(); err != nil {
-			l.incWriterErr(l.rotationSink.Name)
+			l.incWriterErr(l.rotationSink.Name, err)
 		}
 	}
 	l.rotationSink = nil
+
+	// Close the overflow files of the rate-limited sinks and the connections of the
+	// OTLP exporter and the syslog sink.
+	l.closeSinkRates()
+	if exp := l.otlp.Load(); exp != nil {
+		exp.client.CloseIdleConnections()
+	}
+	if s := l.syslog.Load(); s != nil {
+		if err := s.Close(); err != nil {
+			l.incWriterErr(SyslogSinkName, err)
+		}
+	}
 }
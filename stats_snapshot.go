@@ -0,0 +1,108 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the typed statistics snapshot. Unlike the positional results of
+// Stats, StatsSnapshot is a struct that can grow new fields without breaking callers, and
+// gathers the counters, the per-level and per-writer statistics and the queue occupancy
+// of a logger in one call.
+
+package unologger
+
+import (
+	"time"
+)
+
+// StatsSnapshot is a point-in-time view of the statistics of a logger.
+type StatsSnapshot struct {
+	Time   time.Time     // When the snapshot was taken.
+	Uptime time.Duration // Time since the logger was started.
+
+	Written          int64 // Entries passed to the formatter.
+	Dropped          int64 // Entries dropped, e.g. because the queue was full.
+	Batches          int64 // Batches processed by the workers.
+	WriteErrors      int64 // Failed write attempts, over all writers.
+	HookErrors       int64 // Hook errors, timeouts and panics.
+	LoggedAfterClose int64 // Log calls rejected because the logger was closed.
+	Panics           int64 // Panics recovered by RecoverAndLog, PanicHandler, Go and GoErr.
+
+	// Levels counts the written entries per built-in level, TRACE included; the entries of
+	// a custom level are counted under its base level.
+	Levels map[Level]int64
+
+	QueueLen int // Entries waiting in the queue.
+	QueueCap int // Capacity of the queue, 0 in single-writer mode.
+	Workers  int // Running workers.
+
+	// Writers holds the health of every writer, keyed by name, with its error count and
+	// the times of its last error and last success. See WriterStats.
+	Writers map[string]WriterStats
+	// LastWriteError is the time of the most recent failed write attempt of any writer,
+	// or zero.
+	LastWriteError time.Time
+	// HookErrorLog holds the recent hook errors, oldest first.
+	HookErrorLog []HookError
+	// LastHookError is the time of the most recent hook error, or zero.
+	LastHookError time.Time
+	// Sampling holds the counters of rate-based sampling, zero if sampling is disabled.
+	// See SamplingStats.
+	Sampling SamplingStats
+}
+
+// Snapshot returns the statistics of the logger. It is safe for concurrent use; the
+// counters are read one after the other, so they may be slightly out of step with each
+// other while entries are being written.
+func (l *Logger) Snapshot() StatsSnapshot {
+	now := time.Now()
+	s := StatsSnapshot{
+		Time:             now,
+		Written:          l.writtenCount.Load(),
+		Dropped:          l.droppedCount.Load(),
+		Batches:          l.batchCount.Load(),
+		WriteErrors:      l.writeErrCount.Load(),
+		HookErrors:       l.hookErrCount.Load(),
+		LoggedAfterClose: l.afterClose.Load(),
+		Panics:           l.Panics(),
+		Levels:           make(map[Level]int64, len(l.levelCounts)),
+		QueueLen:         l.queueLen(),
+		Workers:          l.Workers(),
+		Writers:          l.WriterStats(),
+		HookErrorLog:     l.GetHookErrors(),
+		Sampling:         l.SamplingStats(),
+	}
+	if ns := l.startedAt.Load(); ns != 0 {
+		s.Uptime = now.Sub(time.Unix(0, ns))
+	}
+	if !l.direct {
+		s.QueueCap = cap(l.queue()) + cap(l.hi)
+	}
+	for i := range l.levelCounts {
+		s.Levels[TRACE+Level(i)] = l.levelCounts[i].Load()
+	}
+	for _, ws := range s.Writers {
+		if ws.LastErrorTime.After(s.LastWriteError) {
+			s.LastWriteError = ws.LastErrorTime
+		}
+	}
+	if n := len(s.HookErrorLog); n > 0 {
+		s.LastHookError = s.HookErrorLog[n-1].Time
+	}
+	return s
+}
+
+// Snapshot returns the statistics of the global logger, or a zero snapshot if it is not
+// initialized. See Logger.Snapshot.
+func Snapshot() StatsSnapshot {
+	l := GlobalLogger()
+	if l == nil {
+		return StatsSnapshot{}
+	}
+	return l.Snapshot()
+}
+
+// countLevel counts a written entry of level lvl, under its base level.
+func (l *Logger) countLevel(lvl Level) {
+	if i := lvl.Base() - TRACE; i >= 0 && int(i) < len(l.levelCounts) {
+		l.levelCounts[i].Add(1)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file defines StdLogger, a compatibility shim that exposes the method set of the
+// standard library's *log.Logger. Legacy code that calls Print, Printf, Fatal or Panic can be
+// redirected to unologger by swapping a single variable, without touching every call site.
+
+package unologger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// StdLogger implements the printing methods of the standard library's *log.Logger
+// (Print, Printf, Println, Fatal, Fatalf, Fatalln, Panic, Panicf, Panicln) on top of a
+// LoggerWithCtx. Print-style calls are logged at a configurable level; Fatal-style calls
+// are logged at FATAL and Panic-style calls at ERROR before panicking.
+//
+// StdLogger also implements io.Writer, so it can be passed to log.SetOutput or used as the
+// ErrorLog of an http.Server. It is safe for concurrent use.
+type StdLogger struct {
+	lw     LoggerWithCtx
+	level  Level
+	mu     sync.RWMutex
+	prefix string
+}
+
+// NewStdLogger creates a StdLogger that writes Print-style messages at the given level
+// through the provided LoggerWithCtx.
+// It panics if the provided logger is nil, mirroring NewAdapter.
+func NewStdLogger(lw LoggerWithCtx, level Level) *StdLogger {
+	if lw.l == nil {
+		panic("unologger: NewStdLogger received LoggerWithCtx with a nil *Logger")
+	}
+	return &StdLogger{lw: lw, level: level}
+}
+
+// Prefix returns the prefix prepended to every message.
+func (s *StdLogger) Prefix() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.prefix
+}
+
+// SetPrefix sets the prefix prepended to every message.
+func (s *StdLogger) SetPrefix(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefix = prefix
+}
+
+// message strips the trailing newline that stdlib-style callers typically add
+// and applies the prefix.
+func (s *StdLogger) message(msg string) string {
+	msg = strings.TrimSuffix(msg, "\n")
+	if p := s.Prefix(); p != "" {
+		msg = p + msg
+	}
+	return msg
+}
+
+// emit hands the literal message to the pipeline.
+func (s *StdLogger) emit(level Level, msg string) {
+	s.lw.l.logFields(s.lw.ctx, level, nil, s.message(msg), literalArgs...)
+}
+
+// fatal logs at FATAL level, flushes the logger, and exits like LoggerWithCtx.Fatal.
+func (s *StdLogger) fatal(msg string) {
+	s.lw.l.fatal(s.lw.ctx, nil, s.message(msg), literalArgs...)
+}
+
+// Print logs its arguments in the manner of fmt.Sprint.
+func (s *StdLogger) Print(v ...interface{}) { s.emit(s.level, fmt.Sprint(v...)) }
+
+// Printf logs its arguments in the manner of fmt.Sprintf.
+func (s *StdLogger) Printf(format string, v ...interface{}) {
+	s.emit(s.level, fmt.Sprintf(format, v...))
+}
+
+// Println logs its arguments in the manner of fmt.Sprintln.
+func (s *StdLogger) Println(v ...interface{}) { s.emit(s.level, fmt.Sprintln(v...)) }
+
+// Fatal is equivalent to Print followed by a flush and a call to os.Exit(1).
+func (s *StdLogger) Fatal(v ...interface{}) { s.fatal(fmt.Sprint(v...)) }
+
+// Fatalf is equivalent to Printf followed by a flush and a call to os.Exit(1).
+func (s *StdLogger) Fatalf(format string, v ...interface{}) { s.fatal(fmt.Sprintf(format, v...)) }
+
+// Fatalln is equivalent to Println followed by a flush and a call to os.Exit(1).
+func (s *StdLogger) Fatalln(v ...interface{}) { s.fatal(fmt.Sprintln(v...)) }
+
+// Panic is equivalent to Print at ERROR level followed by a call to panic().
+func (s *StdLogger) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	s.emit(ERROR, msg)
+	panic(msg)
+}
+
+// Panicf is equivalent to Printf at ERROR level followed by a call to panic().
+func (s *StdLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	s.emit(ERROR, msg)
+	panic(msg)
+}
+
+// Panicln is equivalent to Println at ERROR level followed by a call to panic().
+func (s *StdLogger) Panicln(v ...interface{}) {
+	msg := fmt.Sprintln(v...)
+	s.emit(ERROR, msg)
+	panic(msg)
+}
+
+// Write implements io.Writer. Each call is logged as a single message at the
+// configured level, which makes StdLogger usable as the output of a *log.Logger.
+func (s *StdLogger) Write(p []byte) (int, error) {
+	s.emit(s.level, string(p))
+	return len(p), nil
+}
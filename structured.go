@@ -0,0 +1,170 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the field-first logging API. The *W methods take a literal message
+// and structured fields at the call site, and With binds fields to a logger value for a
+// series of calls, so data does not have to be formatted into the message or stored in the
+// context to be logged as fields.
+
+package unologger
+
+import "context"
+
+// DebugW logs a literal message with structured fields at DEBUG level. The message is not
+// treated as a format string. The fields are merged over the context attributes.
+func (l *Logger) DebugW(ctx context.Context, msg string, fields Fields) {
+	l.logFields(ctx, DEBUG, fields, msg, literalArgs...)
+}
+
+// InfoW logs a literal message with structured fields at INFO level. See DebugW.
+func (l *Logger) InfoW(ctx context.Context, msg string, fields Fields) {
+	l.logFields(ctx, INFO, fields, msg, literalArgs...)
+}
+
+// WarnW logs a literal message with structured fields at WARN level. See DebugW.
+func (l *Logger) WarnW(ctx context.Context, msg string, fields Fields) {
+	l.logFields(ctx, WARN, fields, msg, literalArgs...)
+}
+
+// ErrorW logs a literal message with structured fields at ERROR level. See DebugW.
+func (l *Logger) ErrorW(ctx context.Context, msg string, fields Fields) {
+	l.logFields(ctx, ERROR, fields, msg, literalArgs...)
+}
+
+// FatalW logs a literal message with structured fields at FATAL level, then flushes the
+// logger and exits like Fatal.
+func (l *Logger) FatalW(ctx context.Context, msg string, fields Fields) {
+	l.fatal(ctx, fields, msg, literalArgs...)
+}
+
+// FieldLogger is a Logger with structured fields bound to it by With. Its fields are
+// added to every entry it logs, after the context attributes and before the fields of
+// the call itself, later ones overriding earlier ones. It is an immutable value, safe
+// for concurrent use.
+type FieldLogger struct {
+	l      *Logger
+	fields Fields
+}
+
+// With returns a FieldLogger that adds fields to every entry:
+//
+//	log := l.With(unologger.Fields{"order_id": id})
+//	log.Info(ctx, "payment accepted")
+//
+// The fields are copied, so later changes to the map do not affect the logger.
+func (l *Logger) With(fields Fields) FieldLogger {
+	return FieldLogger{l: l, fields: MergeFields(fields)}
+}
+
+// With returns a FieldLogger with fields added to those of fl.
+func (fl FieldLogger) With(fields Fields) FieldLogger {
+	return FieldLogger{l: fl.l, fields: MergeFields(fl.fields, fields)}
+}
+
+// WithContext binds the FieldLogger to ctx, keeping its fields.
+func (fl FieldLogger) WithContext(ctx context.Context) LoggerWithCtx {
+	return LoggerWithCtx{l: fl.l, ctx: orBackground(ctx), fields: fl.fields}
+}
+
+// Debug logs a formatted message at DEBUG level with the bound fields.
+func (fl FieldLogger) Debug(ctx context.Context, format string, args ...interface{}) {
+	fl.l.logFields(ctx, DEBUG, fl.fields, format, args...)
+}
+
+// Info logs a formatted message at INFO level with the bound fields.
+func (fl FieldLogger) Info(ctx context.Context, format string, args ...interface{}) {
+	fl.l.logFields(ctx, INFO, fl.fields, format, args...)
+}
+
+// Warn logs a formatted message at WARN level with the bound fields.
+func (fl FieldLogger) Warn(ctx context.Context, format string, args ...interface{}) {
+	fl.l.logFields(ctx, WARN, fl.fields, format, args...)
+}
+
+// Error logs a formatted message at ERROR level with the bound fields.
+func (fl FieldLogger) Error(ctx context.Context, format string, args ...interface{}) {
+	fl.l.logFields(ctx, ERROR, fl.fields, format, args...)
+}
+
+// Fatal logs a formatted message at FATAL level with the bound fields, then flushes the
+// logger and exits like Logger.Fatal.
+func (fl FieldLogger) Fatal(ctx context.Context, format string, args ...interface{}) {
+	fl.l.fatal(ctx, fl.fields, format, args...)
+}
+
+// DebugW logs a literal message at DEBUG level with the bound fields and fields.
+func (fl FieldLogger) DebugW(ctx context.Context, msg string, fields Fields) {
+	fl.l.logFields(ctx, DEBUG, withFields(fl.fields, fields), msg, literalArgs...)
+}
+
+// InfoW logs a literal message at INFO level with the bound fields and fields.
+func (fl FieldLogger) InfoW(ctx context.Context, msg string, fields Fields) {
+	fl.l.logFields(ctx, INFO, withFields(fl.fields, fields), msg, literalArgs...)
+}
+
+// WarnW logs a literal message at WARN level with the bound fields and fields.
+func (fl FieldLogger) WarnW(ctx context.Context, msg string, fields Fields) {
+	fl.l.logFields(ctx, WARN, withFields(fl.fields, fields), msg, literalArgs...)
+}
+
+// ErrorW logs a literal message at ERROR level with the bound fields and fields.
+func (fl FieldLogger) ErrorW(ctx context.Context, msg string, fields Fields) {
+	fl.l.logFields(ctx, ERROR, withFields(fl.fields, fields), msg, literalArgs...)
+}
+
+// FatalW logs a literal message at FATAL level with the bound fields and fields, then
+// flushes the logger and exits like Logger.Fatal.
+func (fl FieldLogger) FatalW(ctx context.Context, msg string, fields Fields) {
+	fl.l.fatal(ctx, withFields(fl.fields, fields), msg, literalArgs...)
+}
+
+// With returns a copy of lw that adds fields to every entry, after the context attributes.
+// Unlike WithAttrs, the fields are not stored in the context, so they do not propagate to
+// the loggers later derived from Context().
+func (lw LoggerWithCtx) With(fields Fields) LoggerWithCtx {
+	lw.fields = MergeFields(lw.fields, fields)
+	return lw
+}
+
+// DebugW logs a literal message with structured fields at DEBUG level using the logger's
+// context. The message is not treated as a format string.
+func (lw LoggerWithCtx) DebugW(msg string, fields Fields) {
+	lw.l.logFields(lw.ctx, DEBUG, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// InfoW logs a literal message with structured fields at INFO level using the logger's
+// context.
+func (lw LoggerWithCtx) InfoW(msg string, fields Fields) {
+	lw.l.logFields(lw.ctx, INFO, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// WarnW logs a literal message with structured fields at WARN level using the logger's
+// context.
+func (lw LoggerWithCtx) WarnW(msg string, fields Fields) {
+	lw.l.logFields(lw.ctx, WARN, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// ErrorW logs a literal message with structured fields at ERROR level using the logger's
+// context.
+func (lw LoggerWithCtx) ErrorW(msg string, fields Fields) {
+	lw.l.logFields(lw.ctx, ERROR, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// FatalW logs a literal message with structured fields at FATAL level using the logger's
+// context, then flushes the logger and exits like Logger.Fatal.
+func (lw LoggerWithCtx) FatalW(msg string, fields Fields) {
+	lw.l.fatal(lw.ctx, withFields(lw.fields, fields), msg, literalArgs...)
+}
+
+// withFields returns the bound fields base with the call-site fields over them, without
+// copying when either is empty. The result must not be modified.
+func withFields(base, fields Fields) Fields {
+	switch {
+	case len(fields) == 0:
+		return base
+	case len(base) == 0:
+		return fields
+	}
+	return MergeFields(base, fields)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements synchronous logging calls with delivery acknowledgements. The *Sync
+// variants go through the regular pipeline (batching, masking, hooks) but block until the
+// entry has been written to every sink, and report the write errors to the caller. They are
+// meant for audit-critical statements that must not be fire-and-forget.
+
+package unologger
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEntryDropped is returned by the *Sync logging methods when the entry was discarded
+// before reaching the writers, for example when the DropOldest policy evicted it.
+var ErrEntryDropped = errors.New("unologger: log entry dropped")
+
+// logSync enqueues an entry and waits for its delivery result. Entries below the minimum
+// level are not an error and return nil immediately. Synchronous entries always wait for
+// queue space, even in non-blocking mode. If ctx is done before the entry is written, the
+// context error is returned; the entry itself is still written.
+func (l *Logger) logSync(ctx context.Context, level Level, fields Fields, format string, args ...interface{}) error {
+	ctx = orBackground(ctx)
+	targets := l.delegateTargets()
+	if len(targets) == 0 {
+		return l.logSyncOwn(ctx, level, fields, format, args...)
+	}
+	errs := make([]error, 0, len(targets)+1)
+	for _, d := range targets {
+		errs = append(errs, d.logSync(ctx, level, fields, format, args...))
+	}
+	return errors.Join(append(errs, l.logSyncOwn(ctx, level, fields, format, args...))...)
+}
+
+// logSyncOwn is logSync for the pipeline of l itself, without its delegates.
+func (l *Logger) logSyncOwn(ctx context.Context, level Level, fields Fields, format string, args ...interface{}) error {
+	if !l.levelEnabled(ctx, level, false) {
+		return nil
+	}
+	if l.enableOTel.Load() {
+		ctx = AttachOTelTrace(ctx)
+	}
+	ack := make(chan error, 1)
+	entry := poolEntry.Get().(*logEntry)
+	entry.lvl = level
+	entry.ctx = ctx
+	entry.t = time.Now()
+	entry.tmpl = format
+	entry.args, entry.literal = splitLiteral(args)
+	entry.fields = fields
+	entry.ack = ack
+	l.captureCaller(entry)
+	l.stampBackfill(entry)
+	l.stampModule(entry)
+
+	l.enqueue(entry)
+	select {
+	case err := <-ack:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DebugSync logs a message at DEBUG level and waits until it is written to every sink.
+// It returns the joined write errors of the sinks the entry was routed to, ErrLoggerClosed
+// if the logger is closed, or ErrEntryDropped if the entry was discarded.
+func (l *Logger) DebugSync(ctx context.Context, format string, args ...interface{}) error {
+	return l.logSync(ctx, DEBUG, nil, format, args...)
+}
+
+// InfoSync logs a message at INFO level and waits until it is written. See DebugSync.
+func (l *Logger) InfoSync(ctx context.Context, format string, args ...interface{}) error {
+	return l.logSync(ctx, INFO, nil, format, args...)
+}
+
+// WarnSync logs a message at WARN level and waits until it is written. See DebugSync.
+func (l *Logger) WarnSync(ctx context.Context, format string, args ...interface{}) error {
+	return l.logSync(ctx, WARN, nil, format, args...)
+}
+
+// ErrorSync logs a message at ERROR level and waits until it is written. See DebugSync.
+func (l *Logger) ErrorSync(ctx context.Context, format string, args ...interface{}) error {
+	return l.logSync(ctx, ERROR, nil, format, args...)
+}
+
+// DebugSync logs a message at DEBUG level using the logger's context and waits until it
+// is written. See Logger.DebugSync.
+func (lw LoggerWithCtx) DebugSync(format string, args ...interface{}) error {
+	return lw.l.logSync(lw.ctx, DEBUG, lw.fields, format, args...)
+}
+
+// InfoSync logs a message at INFO level using the logger's context and waits until it
+// is written. See Logger.DebugSync.
+func (lw LoggerWithCtx) InfoSync(format string, args ...interface{}) error {
+	return lw.l.logSync(lw.ctx, INFO, lw.fields, format, args...)
+}
+
+// WarnSync logs a message at WARN level using the logger's context and waits until it
+// is written. See Logger.DebugSync.
+func (lw LoggerWithCtx) WarnSync(format string, args ...interface{}) error {
+	return lw.l.logSync(lw.ctx, WARN, lw.fields, format, args...)
+}
+
+// ErrorSync logs a message at ERROR level using the logger's context and waits until it
+// is written. See Logger.DebugSync.
+func (lw LoggerWithCtx) ErrorSync(format string, args ...interface{}) error {
+	return lw.l.logSync(lw.ctx, ERROR, lw.fields, format, args...)
+}
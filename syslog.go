@@ -0,0 +1,358 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the syslog sink. Every entry is also sent to a syslog daemon or
+// collector, local or remote, as an RFC 5424 or RFC 3164 message whose severity is derived
+// from the level of the entry, for environments where syslog is the required delivery path.
+
+package unologger
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogSinkName is the writer name under which the syslog sink appears in WriterStats.
+const SyslogSinkName = "syslog"
+
+// defaultSyslogTimeout bounds the connection and each write of the syslog sink.
+const defaultSyslogTimeout = 5 * time.Second
+
+// syslogSockets are the local syslog sockets tried when SyslogConfig.Network is empty.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogFormat selects the message format of the syslog sink.
+type SyslogFormat int
+
+const (
+	// SyslogRFC5424 formats messages per RFC 5424, with an RFC 3339 timestamp.
+	SyslogRFC5424 SyslogFormat = iota
+	// SyslogRFC3164 formats messages in the legacy BSD format of RFC 3164.
+	SyslogRFC3164
+)
+
+// SyslogFacility is the facility of syslog messages.
+type SyslogFacility int
+
+// Syslog facilities, per RFC 5424.
+const (
+	SyslogKern SyslogFacility = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLPR
+	SyslogNews
+	SyslogUUCP
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFTP
+	_
+	_
+	_
+	_
+	SyslogLocal0
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// SyslogConfig configures the syslog sink.
+type SyslogConfig struct {
+	// Network is "udp", "tcp", "unix" or "unixgram". Empty connects to the local syslog
+	// daemon through its usual socket, ignoring Address.
+	Network string
+	// Address is the address of the syslog server, e.g. "logs.example.com:514", or the
+	// path of a Unix socket.
+	Address string
+	// TLS, if set, secures "tcp" connections (RFC 5425).
+	TLS *tls.Config
+	// Facility of the messages. Defaults to SyslogUser; SyslogKern, the zero value, is
+	// treated as unset, since it is reserved for the kernel.
+	Facility SyslogFacility
+	// Tag is the application name of the messages. Defaults to the program name.
+	Tag string
+	// Hostname is the host name of the messages. Defaults to os.Hostname.
+	Hostname string
+	// Format selects RFC 5424 (the default) or RFC 3164 messages.
+	Format SyslogFormat
+	// Timeout bounds the connection and each write. Defaults to 5s.
+	Timeout time.Duration
+}
+
+// syslogSink formats and sends the messages of the syslog sink. Stream connections use
+// octet-counting framing (RFC 6587) for RFC 5424 and newline framing for RFC 3164;
+// datagram connections carry one message per datagram. The connection is opened lazily
+// and reopened on the next write after a failure.
+type syslogSink struct {
+	cfg  SyslogConfig
+	host string
+	tag  string
+	pid  string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	stream bool
+	closed bool
+}
+
+// SetSyslog starts sending every entry to syslog, or stops it if cfg is nil. Messages are
+// sent by the workers, once per batch, with the retry policy of the logger; failures are
+// counted in WriterStats under "syslog" and reported to the OnWriteError handler, but do
+// not fail synchronous log calls. The connection is not opened until the first batch.
+//
+// Levels map to syslog severities as DEBUG to debug (7), INFO to informational (6), WARN
+// to warning (4), ERROR to error (3) and FATAL to critical (2). The body of a message is
+// the entry in the output format of the logger.
+func (l *Logger) SetSyslog(cfg *SyslogConfig) error {
+	if cfg == nil {
+		if old := l.syslog.Swap(nil); old != nil {
+			_ = old.Close()
+		}
+		return nil
+	}
+	s, err := newSyslogSink(*cfg)
+	if err != nil {
+		return err
+	}
+	if old := l.syslog.Swap(s); old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// newSyslogSink validates cfg and applies its defaults.
+func newSyslogSink(cfg SyslogConfig) (*syslogSink, error) {
+	switch cfg.Network {
+	case "":
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+		if cfg.Address == "" {
+			return nil, errors.New("unologger: syslog address is required")
+		}
+	default:
+		return nil, fmt.Errorf("unologger: unsupported syslog network %q", cfg.Network)
+	}
+	if cfg.Facility <= SyslogKern || cfg.Facility > SyslogLocal7 {
+		cfg.Facility = SyslogUser
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSyslogTimeout
+	}
+	s := &syslogSink{cfg: cfg, host: cfg.Hostname, tag: cfg.Tag, pid: strconv.Itoa(os.Getpid())}
+	if s.host == "" {
+		s.host, _ = os.Hostname()
+	}
+	if s.tag == "" {
+		s.tag = filepath.Base(os.Args[0])
+	}
+	s.host = syslogToken(s.host, 255)
+	s.tag = syslogToken(s.tag, 48)
+	return s, nil
+}
+
+// syslogSeverity maps levels to syslog severities.
+func syslogSeverity(lvl Level) int {
+	switch lvl.Base() {
+	case TRACE, DEBUG:
+		return 7
+	case INFO:
+		return 6
+	case WARN:
+		return 4
+	case ERROR:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// syslogToken makes s a valid header field: printable US-ASCII without spaces, at most
+// n characters, and "-" if empty.
+func syslogToken(s string, n int) string {
+	b := make([]byte, 0, min(len(s), n))
+	for i := 0; i < len(s) && len(b) < n; i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+// message formats the syslog message of an entry: the header built from ev and the
+// formatted entry b, without its trailing newline, as the body.
+func (s *syslogSink) message(ev HookEvent, b []byte) []byte {
+	pri := int(s.cfg.Facility)*8 + syslogSeverity(ev.Level)
+	for len(b) > 0 && (b[len(b)-1] == '\n' || b[len(b)-1] == '\r') {
+		b = b[:len(b)-1]
+	}
+	t := ev.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	msg := make([]byte, 0, len(b)+len(s.host)+len(s.tag)+64)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(pri), 10)
+	msg = append(msg, '>')
+	if s.cfg.Format == SyslogRFC3164 {
+		msg = t.AppendFormat(msg, time.Stamp)
+		msg = fmt.Appendf(msg, " %s %s[%s]: ", s.host, s.tag, s.pid)
+	} else {
+		msg = append(msg, "1 "...)
+		msg = t.AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+		msg = fmt.Appendf(msg, " %s %s %s %s - ", s.host, s.tag, s.pid, syslogToken(ev.Module, 32))
+	}
+	return append(msg, b...)
+}
+
+// connectLocked opens the connection if there is none. s.mu must be held.
+func (s *syslogSink) connectLocked() error {
+	if s.conn != nil {
+		return nil
+	}
+	d := net.Dialer{Timeout: s.cfg.Timeout}
+	network := s.cfg.Network
+	var conn net.Conn
+	var err error
+	switch {
+	case network == "":
+	local:
+		for _, path := range syslogSockets {
+			for _, network = range []string{"unixgram", "unix"} {
+				if conn, err = d.Dial(network, path); err == nil {
+					break local
+				}
+			}
+		}
+	case s.cfg.TLS != nil && (s.cfg.Network == "tcp" || s.cfg.Network == "tcp4" || s.cfg.Network == "tcp6"):
+		conn, err = tls.DialWithDialer(&d, s.cfg.Network, s.cfg.Address, s.cfg.TLS)
+	default:
+		conn, err = d.Dial(s.cfg.Network, s.cfg.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("unologger: connect to syslog: %w", err)
+	}
+	s.conn = conn
+	s.stream = !strings.HasPrefix(network, "udp") && network != "unixgram"
+	return nil
+}
+
+// Write implements io.Writer by sending p as one message.
+func (s *syslogSink) Write(p []byte) (int, error) {
+	if err := s.WriteBatch([][]byte{p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBatch sends messages, in order. On failure the connection is closed, so that the
+// next attempt reconnects; messages sent before the failure are not resent by the sink.
+func (s *syslogSink) WriteBatch(msgs [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	if err := s.connectLocked(); err != nil {
+		return err
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+	var err error
+	if s.stream {
+		var buf []byte
+		for _, m := range msgs {
+			if s.cfg.Format == SyslogRFC3164 {
+				buf = append(append(buf, m...), '\n')
+			} else {
+				buf = append(strconv.AppendInt(buf, int64(len(m)), 10), ' ')
+				buf = append(buf, m...)
+			}
+		}
+		_, err = s.conn.Write(buf)
+	} else {
+		for _, m := range msgs {
+			if _, err = s.conn.Write(m); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// HealthCheck implements HealthChecker by connecting to syslog if the sink is not
+// connected. Datagram sockets report an unreachable daemon only on write, if at all.
+func (s *syslogSink) HealthCheck(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	return s.connectLocked()
+}
+
+// Close closes the connection. Later writes fail.
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// collectSyslog formats the syslog message of an entry, if a syslog sink is set.
+func (l *Logger) collectSyslog(out *batchOutput, ev HookEvent, b []byte, evp *HookEvent) {
+	s := l.syslog.Load()
+	if s == nil {
+		return
+	}
+	if out.syslogSink != s {
+		out.syslog.reset()
+		out.syslogSink = s
+	}
+	out.syslog.add(s.message(ev, b), evp)
+}
+
+// writeSyslog sends the messages collected in out. The messages bypass sequence stamps,
+// batch seals and rate limits, which would not fit the syslog framing.
+func (l *Logger) writeSyslog(out *batchOutput) {
+	s := out.syslogSink
+	if s == nil || out.syslog.empty() {
+		return
+	}
+	parts := out.syslog.parts
+	if err := l.retryWrite(SyslogSinkName, func() error { return s.WriteBatch(parts) }); err != nil {
+		for _, ev := range out.syslog.events {
+			l.reportWriteError(SyslogSinkName, err, ev)
+		}
+		return
+	}
+	l.writerSucceeded(SyslogSinkName, out.syslog.size(), len(parts))
+}
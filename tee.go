@@ -0,0 +1,32 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements Tee, a Logger that fans entries out to several independent loggers,
+// for example when a component must log to both an application pipeline and a compliance
+// pipeline with different levels, masking and sinks.
+
+package unologger
+
+import (
+	"io"
+	"slices"
+)
+
+// Tee returns a detached Logger that forwards every entry to each of loggers, as to the
+// Delegate of Config. Each logger applies its own level, feature flags, sampling, masking,
+// hooks and sinks, so an entry can be written by some of them and filtered out by others.
+// Nil loggers are ignored.
+//
+// The tee is a logger of its own, whose sinks default to io.Discard: its configuration
+// methods, statistics, exit function and exit hooks are those of the tee, and sinks added
+// to it, such as extra writers, receive the entries that pass its own level. The members
+// must be configured directly. Closing the tee stops the fan-out but does not close the
+// members, which remain owned by the caller; Flush and Healthy cover the members too, and a
+// FATAL entry flushes every member before exiting.
+func Tee(loggers ...*Logger) *Logger {
+	l := newLoggerFromConfig(Config{Stdout: io.Discard, Stderr: io.Discard})
+	l.delegates = slices.DeleteFunc(slices.Clone(loggers), func(m *Logger) bool { return m == nil })
+	l.start()
+	return l
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements scoped temporary sinks. A writer is attached for the duration of a
+// callback, for example to capture the logs of a single admin-triggered job into a buffer
+// the operator can download, and detached once everything logged meanwhile is written.
+
+package unologger
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// TempSinkName is the writer name under which temporary sinks appear in WriterStats.
+const TempSinkName = "temporary"
+
+// tempSinkIDs numbers the temporary sinks, so that concurrent ones are detached exactly.
+var tempSinkIDs atomic.Uint64
+
+// WithTemporarySink attaches w as an extra writer, runs fn, and detaches w once every entry
+// logged before fn returned has been written, even if fn panics. Entries still queued when
+// it is called are written first, so that w only receives the entries logged while fn runs,
+// by any goroutine. w is owned by the caller and is not closed.
+func (l *Logger) WithTemporarySink(w io.Writer, fn func()) {
+	if w == nil {
+		fn()
+		return
+	}
+	id := tempSinkIDs.Add(1)
+	l.flushQueued()
+	l.outputsMu.Lock()
+	l.extraW = append(l.extraW, writerSink{Name: TempSinkName, Writer: w, temp: id})
+	l.outputsMu.Unlock()
+	defer func() {
+		l.flushQueued()
+		l.removeTempSink(id)
+	}()
+	fn()
+}
+
+// removeTempSink detaches the temporary sink with the given id.
+func (l *Logger) removeTempSink(id uint64) {
+	l.outputsMu.Lock()
+	defer l.outputsMu.Unlock()
+	for i, s := range l.extraW {
+		if s.temp == id {
+			l.extraW = append(l.extraW[:i], l.extraW[i+1:]...)
+			return
+		}
+	}
+}
+
+// flushQueued returns once every entry enqueued before the call has been written. It sends
+// a barrier entry through the queue: since the queue is FIFO, every earlier entry has been
+// taken by a worker once the batch holding the barrier is written, and FlushBatchNow then
+// makes the other workers write their partial batches. With a priority queue, a barrier
+// is sent through each tier. It returns immediately on a closed logger.
+func (l *Logger) flushQueued() {
+	levels := []Level{DEBUG}
+	if l.hi != nil {
+		levels = append(levels, l.hiLevel)
+	}
+	barriers := make([]chan struct{}, len(levels))
+	for i, lvl := range levels {
+		barriers[i] = make(chan struct{})
+		e := poolEntry.Get().(*logEntry)
+		e.lvl = lvl
+		e.t = time.Now()
+		e.group = []*logEntry{}
+		e.barrier = barriers[i]
+		l.enqueue(e)
+	}
+	for _, b := range barriers {
+		<-b
+	}
+	l.FlushBatchNow()
+}
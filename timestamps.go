@@ -0,0 +1,86 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the timestamp options. Entries can carry the UTC offset of their
+// timestamp, or be stamped in UTC with the local time kept in a separate field, and the
+// timezone is read once per batch, so that a runtime change never splits a batch between
+// two timezones.
+
+package unologger
+
+import (
+	"fmt"
+	"time"
+)
+
+// Fields added to entries by the timestamp options.
+const (
+	// TZOffsetKey is the UTC offset of the local time of the entry, e.g. "+07:00".
+	TZOffsetKey = "tz_offset"
+	// LocalTimeKey is the time of the entry in the configured timezone, in RFC 3339, added
+	// when timestamps are written in UTC.
+	LocalTimeKey = "local_time"
+)
+
+// TimestampConfig configures the timestamps of entries.
+type TimestampConfig struct {
+	// TZOffset adds the UTC offset of the time of the entry in the configured timezone
+	// under TZOffsetKey.
+	TZOffset bool
+	// UTC writes the timestamp of every entry in UTC, whatever the timezone, and adds the
+	// time in the configured timezone under LocalTimeKey for operators reading the logs.
+	UTC bool
+}
+
+// timeSettings are the timezone and timestamp options applied to a batch.
+type timeSettings struct {
+	loc *time.Location
+	cfg TimestampConfig
+}
+
+// SetTimestampConfig replaces the timestamp options. Like the timezone, they are read once
+// per batch: the entries of a batch are all stamped with the same settings.
+func (l *Logger) SetTimestampConfig(tc TimestampConfig) {
+	l.Configure("").SetTimestampConfig(tc)
+}
+
+// SetTimestampConfig is Logger.SetTimestampConfig on behalf of the actor of ed.
+func (ed ConfigEditor) SetTimestampConfig(tc TimestampConfig) {
+	l := ed.l
+	l.locMu.Lock()
+	old := l.timestamps
+	l.timestamps = tc
+	l.locMu.Unlock()
+	if old != tc {
+		ed.configChanged("timestamps", fmt.Sprintf("%+v", old), fmt.Sprintf("%+v", tc))
+	}
+}
+
+// GetTimestampConfig returns the timestamp options in effect.
+func (l *Logger) GetTimestampConfig() TimestampConfig {
+	l.locMu.RLock()
+	defer l.locMu.RUnlock()
+	return l.timestamps
+}
+
+// timeSettings returns the timezone and the timestamp options, read together.
+func (l *Logger) timeSettings() timeSettings {
+	l.locMu.RLock()
+	defer l.locMu.RUnlock()
+	return timeSettings{loc: l.loc, cfg: l.timestamps}
+}
+
+// stamp returns the timestamp of an entry taken at t, and adds the timestamp fields to
+// fields.
+func (ts timeSettings) stamp(t time.Time, fields Fields) time.Time {
+	local := t.In(ts.loc)
+	if ts.cfg.TZOffset {
+		fields[TZOffsetKey] = local.Format("-07:00")
+	}
+	if !ts.cfg.UTC {
+		return local
+	}
+	fields[LocalTimeKey] = local.Format(time.RFC3339)
+	return t.UTC()
+}
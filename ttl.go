@@ -0,0 +1,90 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the entry TTL. When the pipeline stalls, entries wait in the queue;
+// once it recovers, those older than the TTL are dropped or tagged as stale, so that a burst
+// of minutes-old logs does not show up as current events in incident timelines.
+
+package unologger
+
+import (
+	"time"
+)
+
+// Fields added to the stale entries kept by EntryTTLConfig.Tag.
+const (
+	// StaleKey is set to true on an entry that waited in the queue longer than the TTL.
+	StaleKey = "stale"
+	// StaleAgeKey is the time the entry waited before being processed, in milliseconds.
+	StaleAgeKey = "stale_age_ms"
+)
+
+// EntryTTLConfig sets the maximum age of queued entries.
+type EntryTTLConfig struct {
+	// MaxAge is the time after the log call beyond which an entry taken from the queue is
+	// stale. Zero disables the TTL.
+	MaxAge time.Duration
+	// Tag keeps stale entries, with the StaleKey and StaleAgeKey fields, instead of
+	// dropping them.
+	Tag bool
+}
+
+// StaleStats counts the entries found stale when taken from the queue.
+type StaleStats struct {
+	Dropped int64 `json:"dropped"` // Stale entries dropped.
+	Tagged  int64 `json:"tagged"`  // Stale entries written with the stale fields.
+}
+
+// SetEntryTTL sets the maximum age of queued entries, or disables it if cfg.MaxAge is not
+// positive. The age of an entry is checked when a worker takes its batch from the queue.
+// Synchronous calls are never dropped, since their caller waits for the result; they are
+// tagged instead, as are the entries of the emergency path.
+func (l *Logger) SetEntryTTL(cfg EntryTTLConfig) {
+	if cfg.MaxAge <= 0 {
+		l.entryTTL.Store(nil)
+		return
+	}
+	l.entryTTL.Store(&cfg)
+}
+
+// GetEntryTTL returns the entry TTL in effect; MaxAge is zero if it is disabled.
+func (l *Logger) GetEntryTTL() EntryTTLConfig {
+	if c := l.entryTTL.Load(); c != nil {
+		return *c
+	}
+	return EntryTTLConfig{}
+}
+
+// StaleStats returns the counters of the entry TTL.
+func (l *Logger) StaleStats() StaleStats {
+	return StaleStats{Dropped: l.staleDropped.Load(), Tagged: l.staleTagged.Load()}
+}
+
+// checkStale applies the entry TTL of the batch to e. It reports whether e must be dropped,
+// and otherwise records its age in e.stale if it is stale.
+func (l *Logger) checkStale(out *batchOutput, e *logEntry) bool {
+	ttl := out.ttl
+	if ttl == nil {
+		return false
+	}
+	age := out.dequeued.Sub(e.t)
+	if age <= ttl.MaxAge {
+		return false
+	}
+	if !ttl.Tag && e.ack == nil && !e.emergency {
+		l.staleDropped.Add(1)
+		return true
+	}
+	l.staleTagged.Add(1)
+	e.stale = age
+	return false
+}
+
+// annotateStale adds the stale fields to fields if the entry was found stale.
+func annotateStale(e *logEntry, fields Fields) {
+	if e.stale > 0 {
+		fields[StaleKey] = true
+		fields[StaleAgeKey] = e.stale.Milliseconds()
+	}
+}
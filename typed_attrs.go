@@ -0,0 +1,47 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the typed variants of WithAttrs. The attributes they add keep their
+// native type up to the output, so that the JSON formatter writes numbers and booleans, not
+// strings, and downstream aggregations can sum or compare them without parsing.
+
+package unologger
+
+import (
+	"context"
+	"math"
+	"strconv"
+)
+
+// WithString returns a context with the string attribute key, like WithAttrs.
+func WithString(ctx context.Context, key, v string) context.Context {
+	return WithAttrs(ctx, Fields{key: v})
+}
+
+// WithBool returns a context with the boolean attribute key, like WithAttrs. It is written
+// as a JSON true or false.
+func WithBool(ctx context.Context, key string, v bool) context.Context {
+	return WithAttrs(ctx, Fields{key: v})
+}
+
+// WithInt returns a context with the integer attribute key, like WithAttrs. It is written
+// as a JSON number, and hooks see an int64.
+func WithInt(ctx context.Context, key string, v int64) context.Context {
+	return WithAttrs(ctx, Fields{key: v})
+}
+
+// WithFloat returns a context with the floating-point attribute key, like WithAttrs. It is
+// written as a JSON number, and hooks see a float64. JSON has no NaN or infinities, so
+// those are written as the strings "NaN", "+Inf" and "-Inf" rather than failing the entry.
+func WithFloat(ctx context.Context, key string, v float64) context.Context {
+	return WithAttrs(ctx, Fields{key: floatValue(v)})
+}
+
+// floatValue returns v, or its name if JSON cannot represent it.
+func floatValue(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements unit-safe field helpers. Durations, sizes and percentages are always
+// written as plain numbers under a key carrying the unit, instead of strings such as "1.2s"
+// or "1200ms" that dashboards would have to parse.
+
+package unologger
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Unit suffixes appended to the keys of the unit-safe field helpers.
+const (
+	suffixMS      = "_ms"
+	suffixBytes   = "_bytes"
+	suffixPercent = "_pct"
+)
+
+// DurationMS returns a field holding d in milliseconds, as a float64 with microsecond
+// precision, under key with the "_ms" suffix: DurationMS("latency", 1200*time.Millisecond)
+// gives {"latency_ms": 1200}.
+func DurationMS(key string, d time.Duration) Fields {
+	return Fields{unitKey(key, suffixMS): float64(d.Microseconds()) / 1000}
+}
+
+// Bytes returns a field holding the size n under key with the "_bytes" suffix:
+// Bytes("body", 512) gives {"body_bytes": 512}.
+func Bytes(key string, n int64) Fields {
+	return Fields{unitKey(key, suffixBytes): n}
+}
+
+// Percent returns a field holding part/total as a percentage rounded to two decimals, under
+// key with the "_pct" suffix: Percent("cache_hit", 1, 3) gives {"cache_hit_pct": 33.33}.
+// A zero total gives 0.
+func Percent(key string, part, total float64) Fields {
+	var pct float64
+	if total != 0 {
+		pct = math.Round(part/total*10000) / 100
+	}
+	return Fields{unitKey(key, suffixPercent): pct}
+}
+
+// MergeFields returns a new Fields holding the fields of fs, later ones overriding earlier
+// ones, so several helpers can be passed to WithAttrs at once.
+func MergeFields(fs ...Fields) Fields {
+	n := 0
+	for _, f := range fs {
+		n += len(f)
+	}
+	out := make(Fields, n)
+	for _, f := range fs {
+		for k, v := range f {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// unitKey appends suffix to key unless key already ends with it.
+func unitKey(key, suffix string) string {
+	if strings.HasSuffix(key, suffix) {
+		return key
+	}
+	return key + suffix
+}
@@ -3,17 +3,42 @@
 package unologger
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // blockingWriter is a test helper writer that blocks writes until unblocked.
@@ -43,6 +68,67 @@ func (w *blockingWriter) unblock() {
 	}
 }
 
+// syncBuffer is a bytes.Buffer that can be written by workers while a test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+// countingWriter is a syncBuffer that also counts Write calls.
+type countingWriter struct {
+	syncBuffer
+	writes atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.syncBuffer.Write(p)
+}
+
+// failingWriter rejects every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("sink unavailable") }
+
+// batchRecorder implements BatchWriter and records the size of every batch.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *batchRecorder) Write(p []byte) (int, error) {
+	return len(p), r.WriteBatch([][]byte{p})
+}
+
+func (r *batchRecorder) WriteBatch(entries [][]byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batch := make([]string, len(entries))
+	for i, e := range entries {
+		batch[i] = string(e)
+	}
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
 func (w *blockingWriter) String() string {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -210,6 +296,4241 @@ func TestSetRotationCreatesSink(t *testing.T) {
 	require.NotNil(t, l.rotationSink)
 }
 
+func TestGlobalConvenienceFunctions(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := Config{MinLevel: DEBUG, Timezone: "UTC", JSON: true, Buffer: 16, Workers: 1, Stdout: out, Stderr: out}
+	l, err := ReinitGlobalLogger(cfg, 2*time.Second)
+	require.NoError(t, err)
+
+	Infof("progress %d%%", 50)
+	InfoW("literal 100%", Fields{"user_id": "u1"})
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	s := out.String()
+	require.Contains(t, s, `"message":"progress 50%"`)
+	require.Contains(t, s, `"message":"literal 100%"`)
+	require.Contains(t, s, `"user_id":"u1"`)
+}
+
+func TestStdLoggerShim(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: out, Stderr: out}
+	l := NewDetachedLogger(cfg)
+
+	std := NewStdLogger(l.WithContext(context.Background()), INFO)
+	std.SetPrefix("legacy: ")
+	std.Println("hello", "world")
+	require.PanicsWithValue(t, "boom", func() { std.Panic("boom") })
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	s := out.String()
+	require.Contains(t, s, "[INFO] () legacy: hello world\n")
+	require.Contains(t, s, "[ERROR] () legacy: boom")
+}
+
+func TestBufferedLoggerCommit(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 2, Stdout: out, Stderr: out}
+	l := NewDetachedLogger(cfg)
+
+	buf := l.WithContext(context.Background()).Buffered()
+	buf.Info("report line 1")
+	buf.Debug("filtered by level")
+	buf.Info("report line 2")
+	require.Equal(t, 2, buf.Len())
+	buf.Commit()
+	require.Equal(t, 0, buf.Len())
+
+	discarded := l.Buffered(context.Background())
+	discarded.Info("never written")
+	discarded.Discard()
+	discarded.Commit()
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	s := out.String()
+	require.Regexp(t, `report line 1\n[^\n]*report line 2\n`, s)
+	require.NotContains(t, s, "filtered by level")
+	require.NotContains(t, s, "never written")
+	_, written, _, _, _, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(2), written)
+}
+
+func TestSingleWriterModeWritesSynchronously(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", SingleWriter: true, Stdout: out, Stderr: out}
+	l := NewDetachedLogger(cfg)
+
+	l.WithContext(context.Background()).Info("direct write")
+	// No flush is needed: the entry is written before the call returns.
+	require.Contains(t, out.String(), "direct write")
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	l.WithContext(context.Background()).Info("after close")
+	require.NotContains(t, out.String(), "after close")
+}
+
+func TestCloseTimeoutReturnsReport(t *testing.T) {
+	bw := newBlockingWriter()
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: bw, Stderr: bw}
+	l := NewDetachedLogger(cfg)
+
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 5; i++ {
+		lw.Info("pending %d", i)
+	}
+	err := CloseDetached(l, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrCloseTimeout)
+
+	var cte *CloseTimeoutError
+	require.ErrorAs(t, err, &cte)
+	require.Equal(t, "draining-queue", cte.Report.Stage)
+	require.Greater(t, cte.Report.QueuedEntries, 0)
+	bw.unblock()
+}
+
+func TestCloseDiagnosticsCapturesPipelineStacks(t *testing.T) {
+	bw := newBlockingWriter()
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: bw, Stderr: bw, CloseDiagnostics: true}
+	l := NewDetachedLogger(cfg)
+	l.WithContext(context.Background()).Info("stuck")
+
+	var cte *CloseTimeoutError
+	require.ErrorAs(t, CloseDetached(l, 50*time.Millisecond), &cte)
+	require.Contains(t, cte.Report.Stacks, "workerLoop")
+
+	var out bytes.Buffer
+	l.writeCloseDiagnostics(&out, cte.Report)
+	require.Contains(t, out.String(), "at stage draining-queue")
+	require.Contains(t, out.String(), "goroutine stacks:")
+	bw.unblock()
+}
+
+func TestLogAfterCloseIsCountedWithoutPanic(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 4, Workers: 2, Stdout: io.Discard, Stderr: io.Discard}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				lw.Info("racing %d", j)
+			}
+		}()
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	wg.Wait()
+
+	lw.Info("definitely after close")
+	require.GreaterOrEqual(t, l.LoggedAfterClose(), int64(1))
+}
+
+func TestSetHookConfigAtRuntime(t *testing.T) {
+	var mu sync.Mutex
+	seen := 0
+	hook := func(_ HookEvent) error {
+		mu.Lock()
+		seen++
+		mu.Unlock()
+		return nil
+	}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 2, Stdout: io.Discard, Stderr: io.Discard,
+		Hooks: []HookFunc{hook}}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			lw.Info("event %d", i)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		l.SetHookConfig(HookConfig{Async: i%2 == 0, Workers: 2, Queue: 512})
+	}
+	<-done
+	require.False(t, l.GetHookConfig().Async)
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	mu.Lock()
+	defer mu.Unlock()
+	_, _, _, _, hookErrs, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(300), int64(seen)+hookErrs)
+}
+
+func TestContextHookTimeoutIsCooperative(t *testing.T) {
+	cooperative := func(ctx context.Context, _ HookEvent) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		Hooks2: []HookFunc2{cooperative}, Hook: HookConfig{Timeout: 20 * time.Millisecond}}
+	l := NewDetachedLogger(cfg)
+
+	l.WithContext(context.Background()).Info("trigger")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	_, _, _, _, hookErrs, _, _, hlog := StatsDetached(l)
+	require.Equal(t, int64(1), hookErrs)
+	require.ErrorIs(t, hlog[0].Err, ErrHookTimeout)
+}
+
+func TestHookFunc2ReceivesEntryContext(t *testing.T) {
+	type key struct{}
+	got := make(chan any, 1)
+	hook := func(ctx context.Context, _ HookEvent) error {
+		got <- ctx.Value(key{})
+		return ctx.Err()
+	}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard}
+	l := NewDetachedLogger(cfg)
+	l.SetHooks2([]HookFunc2{hook})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "request-42"))
+	cancel()
+	l.Info(ctx, "cancelled request")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Equal(t, "request-42", <-got)
+	_, _, _, _, hookErrs, _, _, _ := StatsDetached(l)
+	require.Zero(t, hookErrs)
+}
+
+func TestSetWorkersGrowAndShrink(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 1, Stdout: io.Discard, Stderr: io.Discard}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	require.Equal(t, 4, l.SetWorkers(4))
+	for i := 0; i < 100; i++ {
+		lw.Info("entry %d", i)
+	}
+	require.Equal(t, 1, l.SetWorkers(0))
+	for i := 0; i < 100; i++ {
+		lw.Info("entry %d", i)
+	}
+	require.Equal(t, 1, l.Workers())
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.Zero(t, l.SetWorkers(3))
+	_, written, _, _, _, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(200), written)
+}
+
+func TestSetBufferSizeDrainsOldQueue(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 8, Workers: 2, Stdout: io.Discard, Stderr: io.Discard}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			lw.Info("entry %d", i)
+		}
+	}()
+	for _, n := range []int{2, 256, 16} {
+		require.NoError(t, l.SetBufferSize(n))
+	}
+	<-done
+	require.Equal(t, 16, l.BufferSize())
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.ErrorIs(t, l.SetBufferSize(32), ErrLoggerClosed)
+	_, written, _, _, _, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(500), written)
+}
+
+func TestSetBufferSizeBackToBackKeepsEveryRetiredQueue(t *testing.T) {
+	bw := newBlockingWriter()
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", Buffer: 4, Workers: 1,
+		Batch: BatchConfig{Size: 1}, Stdout: bw, Stderr: bw,
+	}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	// The worker blocks on a0 while each resize retires a queue still holding an entry.
+	lw.Info("a0")
+	lw.Info("a1")
+	require.NoError(t, l.SetBufferSize(8))
+	lw.Info("b1")
+	require.NoError(t, l.SetBufferSize(16))
+	lw.Info("c1")
+	bw.unblock()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	out := bw.buf.String()
+	last := -1
+	for _, msg := range []string{"a0", "a1", "b1", "c1"} {
+		i := strings.Index(out, msg)
+		require.Greater(t, i, last, "%s missing or out of order in %q", msg, out)
+		last = i
+	}
+	_, written, _, _, _, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(4), written)
+}
+
+func TestReinitGlobalLoggerCarriesRuntimeState(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard}
+	old, err := ReinitGlobalLogger(cfg, 2*time.Second)
+	require.NoError(t, err)
+
+	sink := &bytes.Buffer{}
+	old.AddExtraWriter("audit", sink)
+	old.SetMinLevel(WARN)
+	GetLogger(context.Background()).Warn("before reinit")
+
+	l, err := ReinitGlobalLoggerWithOptions(cfg, 2*time.Second, ReinitOptions{
+		CarryStats: true, CarryWriters: true, CarryDynamic: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WARN, l.GetDynamicConfig().MinLevel)
+
+	GetLogger(context.Background()).Info("filtered by carried level")
+	GetLogger(context.Background()).Warn("after reinit")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Contains(t, sink.String(), "before reinit")
+	require.Contains(t, sink.String(), "after reinit")
+	require.NotContains(t, sink.String(), "filtered by carried level")
+	_, written, _, _, _, _, _, _ := StatsDetached(l)
+	require.Equal(t, int64(2), written)
+}
+
+func TestGlobalLoggerSwapDuringLogging(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 1, Stdout: io.Discard, Stderr: io.Discard}
+	_, err := ReinitGlobalLogger(cfg, 2*time.Second)
+	require.NoError(t, err)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					Infof("tick")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		_, err := ReinitGlobalLogger(cfg, 2*time.Second)
+		require.NoError(t, err)
+	}
+	close(stop)
+	wg.Wait()
+
+	l := GlobalLogger()
+	require.NotNil(t, l)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestEmergencyPathBypassesWedgedWorkers(t *testing.T) {
+	gate := newBlockingWriter()
+	stderr := &bytes.Buffer{}
+	extra := &bytes.Buffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: gate, Stderr: stderr}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("extra", extra)
+	lw := l.WithContext(context.Background())
+
+	lw.Info("wedges the only worker")
+	time.Sleep(50 * time.Millisecond)
+	l.emergency(lw.ctx, FATAL, false, nil, "last words %d", 1)
+	require.Contains(t, stderr.String(), "[FATAL] () last words 1")
+
+	require.PanicsWithValue(t, "boom", func() {
+		defer lw.Recover()
+		panic("boom")
+	})
+	require.Contains(t, stderr.String(), "panic: boom")
+
+	gate.unblock()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.Contains(t, extra.String(), "last words 1")
+	require.Equal(t, 1, bytes.Count(stderr.Bytes(), []byte("last words 1")))
+}
+
+func TestFlushOnLevelAndFlushBatchNow(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 2, Stdout: out, Stderr: out,
+		Batch: BatchConfig{Size: 100, MaxWait: time.Hour, FlushOnLevel: true, FlushLevel: ERROR},
+	}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	lw.Info("held in batch")
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, out.String())
+
+	lw.Error("flushes immediately")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "flushes immediately")
+	}, time.Second, 5*time.Millisecond)
+
+	lw.Warn("below flush level")
+	time.Sleep(50 * time.Millisecond)
+	l.FlushBatchNow()
+	require.Contains(t, out.String(), "held in batch")
+	require.Contains(t, out.String(), "below flush level")
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	l.FlushBatchNow() // No-op once closed.
+}
+
+func TestBatchIsWrittenWithOneWritePerSink(t *testing.T) {
+	out := &countingWriter{}
+	extra := &countingWriter{}
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 1, Stdout: out, Stderr: io.Discard,
+		Batch: BatchConfig{Size: 10, MaxWait: time.Hour},
+	}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("extra", extra)
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 10; i++ {
+		lw.Info("line %d", i)
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Equal(t, int64(1), out.writes.Load())
+	require.Equal(t, int64(1), extra.writes.Load())
+	require.Equal(t, 10, strings.Count(out.String(), "\n"))
+	require.Equal(t, out.String(), extra.String())
+}
+
+func TestBatchWriterAndNetConnSinks(t *testing.T) {
+	rec := &batchRecorder{}
+	client, server := net.Pipe()
+	received := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(server)
+		received <- string(b)
+	}()
+
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 1, Stdout: rec, Stderr: io.Discard,
+		Writers: []io.Writer{client}, WriterNames: []string{"conn"},
+		Batch: BatchConfig{Size: 3, MaxWait: time.Hour},
+	}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 3; i++ {
+		lw.Info("segment %d", i)
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Len(t, rec.batches, 1)
+	require.Len(t, rec.batches[0], 3)
+	require.Contains(t, rec.batches[0][2], "segment 2")
+	require.Equal(t, strings.Join(rec.batches[0], ""), <-received)
+}
+
+func TestStreamingModeAndMaxWaitFlush(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: out, Stderr: out,
+		Batch: BatchConfig{Size: 1, MaxWait: 0}}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	lw.Info("streamed")
+	require.Eventually(t, func() bool { return strings.Contains(out.String(), "streamed") },
+		200*time.Millisecond, time.Millisecond)
+
+	// A partial batch is flushed once MaxWait has elapsed since its first entry.
+	l.SetBatchConfig(BatchConfig{Size: 10, MaxWait: 30 * time.Millisecond})
+	lw.Info("partial batch")
+	require.Eventually(t, func() bool { return strings.Contains(out.String(), "partial batch") },
+		time.Second, 5*time.Millisecond)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestSyncLoggingReportsDelivery(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: out, Stderr: out,
+		Batch: BatchConfig{Size: 100, MaxWait: time.Hour}}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+
+	require.NoError(t, lw.InfoSync("audit %d", 1))
+	require.Contains(t, out.String(), "audit 1")
+	require.NoError(t, lw.DebugSync("below min level"))
+
+	l.AddExtraWriter("broken", failingWriter{})
+	err := lw.WarnSync("audit %d", 2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "write to broken")
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.ErrorIs(t, lw.ErrorSync("after close"), ErrLoggerClosed)
+}
+
+func TestOnWriteErrorReportsEachFailedEntry(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		Batch: BatchConfig{Size: 2, MaxWait: time.Hour},
+		OnWriteError: func(sink string, err error, entry HookEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, sink+": "+entry.Message+": "+err.Error())
+		}}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("broken", failingWriter{})
+	lw := l.WithContext(context.Background())
+	lw.Info("first")
+	require.Error(t, lw.WarnSync("second"))
+
+	l.OnWriteError(nil)
+	lw.Info("not reported")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{
+		"broken: first: unologger: write to broken: sink unavailable",
+		"broken: second: unologger: write to broken: sink unavailable",
+	}, got)
+}
+
+func TestWriterStatsTrackHealthPerSink(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: out, Stderr: out}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("broken", failingWriter{})
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 3; i++ {
+		require.Error(t, lw.InfoSync("entry %d", i))
+	}
+
+	ws := l.WriterStats()
+	require.Equal(t, int64(3), ws["stdout"].EntriesWritten)
+	require.Equal(t, int64(len(out.String())), ws["stdout"].BytesWritten)
+	require.False(t, ws["stdout"].LastSuccess.IsZero())
+	require.Zero(t, ws["stdout"].Errors)
+
+	require.Equal(t, int64(3), ws["broken"].Errors)
+	require.Equal(t, int64(3), ws["broken"].ConsecutiveFailures)
+	require.EqualError(t, ws["broken"].LastError, "sink unavailable")
+	require.Zero(t, ws["broken"].EntriesWritten)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestModuleQuotaSummarizesExcessEntries(t *testing.T) {
+	out := &syncBuffer{}
+	var hooked atomic.Int64
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", SingleWriter: true, Stdout: out, Stderr: out,
+		Quota: QuotaConfig{Daily: map[string]int64{"billing": 300}, SummaryInterval: time.Hour},
+		Hooks: []HookFunc{func(HookEvent) error { hooked.Add(1); return nil }},
+	}
+	l := NewDetachedLogger(cfg)
+	lw := WithModule(WithLogger(context.Background(), l), "billing")
+	for i := 0; i < 10; i++ {
+		lw.Info("entry %d", i)
+	}
+	lw.Error("still written")
+	l.WithContext(context.Background()).Info("no module")
+
+	require.Contains(t, out.String(), `module "billing" exceeded its daily log quota of 300 bytes`)
+	require.Contains(t, out.String(), "still written")
+	require.Contains(t, out.String(), "no module")
+	ms := l.ModuleStats()
+	billing := ms["billing"]
+	require.Equal(t, int64(300), billing.Quota)
+	require.Positive(t, billing.Suppressed)
+	require.Equal(t, int64(11), billing.EntriesWritten+billing.Suppressed)
+	require.Equal(t, billing.BytesWritten, billing.BytesToday)
+	require.Equal(t, int64(1), ms[""].EntriesWritten)
+	require.Zero(t, ms[""].Suppressed)
+	// Suppressed entries are neither counted as written nor passed to the hooks.
+	require.Equal(t, billing.EntriesWritten+1, l.writtenCount.Load())
+
+	// A new day emits the summary of the previous one and lifts the suppression.
+	u := l.usage("billing")
+	u.mu.Lock()
+	u.day--
+	u.mu.Unlock()
+	lw.Info("next day")
+	require.Contains(t, out.String(), `module "billing" over its daily log quota: suppressed`)
+	require.Contains(t, out.String(), "next day")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.Equal(t, billing.EntriesWritten+2, hooked.Load())
+}
+
+func TestBudgetSamplesLowLevelsFirst(t *testing.T) {
+	out := &countingWriter{}
+	cfg := Config{
+		MinLevel: DEBUG, Timezone: "UTC", SingleWriter: true, Stdout: out, Stderr: out,
+		Budget: BudgetConfig{DailyBytes: 1, Interval: 10 * time.Millisecond, MinRate: 0.1},
+	}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+	lw.Debug("warm up")
+	time.Sleep(20 * time.Millisecond)
+	lw.Debug("adjust")
+
+	bs := l.BudgetStats()
+	require.Equal(t, int64(1), bs.Target)
+	require.InDelta(t, 0.1, bs.Rates[DEBUG], 1e-9)
+	require.Equal(t, 1.0, bs.Rates[ERROR])
+	require.Positive(t, bs.BytesToday)
+
+	before := out.writes.Load()
+	for i := 0; i < 1000; i++ {
+		lw.Debug("sampled %d", i)
+		lw.Error("kept %d", i)
+	}
+	written := out.writes.Load() - before
+	require.GreaterOrEqual(t, written, int64(1000))
+	require.Less(t, written, int64(1300))
+	require.Equal(t, int64(2000)-written, l.BudgetStats().Sampled)
+
+	// Disabling the budget stops sampling immediately.
+	l.SetBudget(BudgetConfig{})
+	require.Equal(t, 1.0, l.BudgetStats().Rates[DEBUG])
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestSchemaVersioningAndMigration(t *testing.T) {
+	require.NoError(t, RegisterSchema(Schema{
+		Version:    "2",
+		Keys:       SchemaKeys{Time: "@timestamp", Message: "msg", FlowID: "-"},
+		TimeFormat: time.RFC3339Nano,
+	}))
+	require.Error(t, RegisterSchema(Schema{Version: DefaultSchemaVersion}))
+	require.Contains(t, SchemaVersions(), "2")
+
+	ev := HookEvent{
+		Time: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), Level: INFO, Module: "api",
+		Message: "a < b", FlowID: "f1", Fields: Fields{"k": 1},
+	}
+	b, err := (&JSONFormatter{}).Format(ev)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(b), `{"time":"2025-01-02T03:04:05Z","level":"INFO","module":"api","flow_id":"f1","message":"a < b"`))
+	b, err = (&JSONFormatter{SchemaVersion: true}).Format(ev)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(b), `{"schema_version":"1","time":"2025-01-02T03:04:05Z",`))
+
+	f, err := NewJSONFormatterForSchema("3", "2", "1")
+	require.NoError(t, err)
+	require.Equal(t, "2", f.Schema)
+	b2, err := f.Format(ev)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(b2, &m))
+	require.Equal(t, "2", m[SchemaVersionKey])
+	require.Equal(t, "2025-01-02T03:04:05.000000006Z", m["@timestamp"])
+	require.Equal(t, "a < b", m["msg"])
+	require.NotContains(t, m, "flow_id")
+
+	_, err = NegotiateSchema("9")
+	require.ErrorIs(t, err, ErrNoCommonSchema)
+	_, err = (&JSONFormatter{Schema: "9"}).Format(ev)
+	require.ErrorIs(t, err, ErrUnknownSchema)
+
+	// Migrating back renames the keys and reformats the timestamp.
+	back, err := MigrateJSON(b2, DefaultSchemaVersion)
+	require.NoError(t, err)
+	m = nil
+	require.NoError(t, json.Unmarshal(back, &m))
+	require.Equal(t, "1", m[SchemaVersionKey])
+	require.Equal(t, "2025-01-02T03:04:05Z", m["time"])
+	require.Equal(t, "a < b", m["message"])
+	require.Equal(t, map[string]interface{}{"k": float64(1)}, m["fields"])
+}
+
+func TestValidationFlagsFixesAndDrops(t *testing.T) {
+	out := &syncBuffer{}
+	schema := &ValidationSchema{
+		Required: []string{"request_id"},
+		Types:    map[string]FieldType{"status": FieldInt},
+		Modules:  []string{"api"},
+	}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", JSON: true, SingleWriter: true, Stdout: out, Stderr: out, Validation: schema}
+	l := NewDetachedLogger(cfg)
+	ctx := WithLogger(context.Background(), l)
+	api := WithModule(ctx, "api")
+
+	api.WithAttrs(Fields{"request_id": "r1", "status": 200}).Info("ok")
+	require.NotContains(t, out.String(), "schema_violations")
+	api.WithAttrs(Fields{"status": "oops"}).Info("flagged")
+	require.Contains(t, out.String(), `"schema_violations":["missing required field \"request_id\"","field \"status\" is string, want int"]`)
+
+	out.Reset()
+	var seen []error
+	l.SetValidation(&ValidationSchema{
+		Required:    schema.Required,
+		Types:       schema.Types,
+		Modules:     schema.Modules,
+		Action:      ValidationFix,
+		Defaults:    Fields{"request_id": "none"},
+		OnViolation: func(_ HookEvent, err error) { seen = append(seen, err) },
+	})
+	WithModule(ctx, "billing").WithAttrs(Fields{"status": "404"}).Info("fixed")
+	require.Contains(t, out.String(), `"module":"unknown"`)
+	require.Contains(t, out.String(), `"request_id":"none","status":404`)
+	require.NotContains(t, out.String(), "schema_violations")
+	require.Len(t, seen, 1)
+
+	out.Reset()
+	l.SetValidation(&ValidationSchema{Required: []string{"request_id"}, Action: ValidationDrop})
+	require.ErrorIs(t, api.InfoSync("dropped"), ErrEntryInvalid)
+	require.Empty(t, out.String())
+
+	require.Equal(t, ValidationStats{Violations: 3, Flagged: 1, Fixed: 1, Dropped: 1}, l.ValidationStats())
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestKeyNormalization(t *testing.T) {
+	for in, want := range map[string]string{
+		"userId": "user_id", "UserID": "user_id", "HTTPStatus": "http_status",
+		"user-name": "user_name", "already_snake": "already_snake", "http.statusCode": "http.status_code",
+	} {
+		require.Equal(t, want, toSnakeCase(in), in)
+	}
+
+	out := &syncBuffer{}
+	cfg := Config{
+		MinLevel: INFO, Timezone: "UTC", JSON: true, SingleWriter: true, Stdout: out, Stderr: out,
+		KeyNormalization: KeyNormalization{
+			SnakeCase:     true,
+			Aliases:       map[string]string{"uid": "user_id"},
+			StripPrefixes: []string{"x_"},
+		},
+	}
+	l := NewDetachedLogger(cfg)
+	l.WithContext(context.Background()).WithAttrs(Fields{"uid": 1, "x_RequestId": "r1", "orderID": 7}).Info("normalized")
+	require.Contains(t, out.String(), `"fields":{"order_id":7,"request_id":"r1","user_id":1}`)
+
+	// A canonical key wins over an alias that normalizes to the same name.
+	out.Reset()
+	l.WithContext(context.Background()).WithAttrs(Fields{"user_id": 1, "userId": 2}).Info("collision")
+	require.Contains(t, out.String(), `"fields":{"user_id":1}`)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestGoroutineHelpersPropagateMetadataAndLogPanics(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", SingleWriter: true, Stdout: out, Stderr: out}
+	l := NewDetachedLogger(cfg)
+	parent, cancel := context.WithCancel(WithFlowID(WithModule(WithLogger(context.Background(), l), "jobs").Context(), "flow-42"))
+
+	detached := Detach(parent)
+	cancel()
+	require.NoError(t, detached.Err())
+	require.Equal(t, "flow-42", detached.Value(ctxFlowIDKey))
+	require.Equal(t, l, detached.Value(ctxLoggerKey))
+
+	done := make(chan struct{})
+	Go(detached, func(ctx context.Context) {
+		defer close(done)
+		GetLogger(ctx).Info("child ran")
+	})
+	<-done
+	require.Contains(t, out.String(), "(jobs) flow=flow-42")
+	require.Contains(t, out.String(), "child ran")
+
+	err := GoErr(detached, func(context.Context) error { panic("boom") })()
+	var pe *PanicError
+	require.ErrorAs(t, err, &pe)
+	require.Equal(t, "boom", pe.Value)
+	require.Contains(t, out.String(), "[ERROR] (jobs) flow=flow-42")
+	require.Contains(t, out.String(), "panic in goroutine: boom")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestDebugHandlerServesSnapshot(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 8, Workers: 2, Stdout: out, Stderr: out, ProfileLabels: true}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("broken", failingWriter{})
+	require.Error(t, WithModule(WithLogger(context.Background(), l), "billing").InfoSync("labeled"))
+
+	srv := httptest.NewServer(http.StripPrefix("/debug/unologger", l.DebugHandler()))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/debug/unologger/")
+	require.NoError(t, err)
+	var snap map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&snap))
+	_ = resp.Body.Close()
+	require.Equal(t, float64(2), snap["workers"])
+	require.Equal(t, float64(8), snap["queue_cap"])
+	require.Equal(t, true, snap["profile_labels"])
+	require.Equal(t, "sink unavailable", snap["writers"].(map[string]interface{})["broken"].(map[string]interface{})["last_error"])
+	require.Contains(t, snap["modules"], "billing")
+
+	resp, err = http.Get(srv.URL + "/debug/unologger/profile?seconds=x")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestTraceAwareDebugFollowsSamplingDecision(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: DEBUG, Timezone: "UTC", SingleWriter: true, Stdout: out, Stderr: out, EnableOTel: true, TraceAwareDebug: true}
+	l := NewDetachedLogger(cfg)
+	span := func(flags trace.TraceFlags) context.Context {
+		return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: flags,
+		}))
+	}
+
+	l.Debug(span(trace.FlagsSampled), "sampled debug")
+	l.Debug(span(0), "unsampled debug")
+	l.Info(span(0), "unsampled info")
+	l.Debug(context.Background(), "no trace debug")
+	require.Contains(t, out.String(), "sampled debug")
+	require.NotContains(t, out.String(), "unsampled debug")
+	require.Contains(t, out.String(), "unsampled info")
+	require.Contains(t, out.String(), "no trace debug")
+	require.Equal(t, int64(1), l.TraceThrottled())
+
+	l.SetTraceAwareDebug(false)
+	l.Debug(span(0), "unthrottled debug")
+	require.Contains(t, out.String(), "unthrottled debug")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+// recordingSpan is a span that records the events added to it.
+type recordingSpan struct {
+	noop.Span
+	mu     sync.Mutex
+	events []string
+	attrs  []attribute.KeyValue
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, name)
+	s.attrs = append(s.attrs, cfg.Attributes()...)
+}
+
+func TestSpanEventsRecordEntriesOnActiveSpan(t *testing.T) {
+	cfg := Config{
+		MinLevel: DEBUG, Timezone: "UTC", SingleWriter: true, Stdout: io.Discard, Stderr: io.Discard,
+		SpanEvents: true, SpanEventLevel: INFO,
+		RegexPatternMap: map[string]string{`secret-\w+`: "****"},
+	}
+	l := NewDetachedLogger(cfg)
+	span := &recordingSpan{}
+	ctx := trace.ContextWithSpan(WithModule(WithLogger(context.Background(), l), "api").Context(), span)
+
+	GetLogger(ctx).Debug("below level")
+	GetLogger(ctx).WithAttrs(Fields{"status": 500}).Error("failed with secret-abc")
+	require.Equal(t, []string{"log"}, span.events)
+	require.Contains(t, span.attrs, attribute.String("log.severity", "ERROR"))
+	require.Contains(t, span.attrs, attribute.String("log.message", "failed with ****"))
+	require.Contains(t, span.attrs, attribute.String("log.module", "api"))
+	require.Contains(t, span.attrs, attribute.Int("status", 500))
+
+	l.SetSpanEvents(false, INFO)
+	GetLogger(ctx).Error("not recorded")
+	require.Len(t, span.events, 1)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestSpanEventsMapErrorsToExceptionConventions(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", SingleWriter: true, Stdout: io.Discard, Stderr: io.Discard, SpanEvents: true, SpanEventLevel: ERROR}
+	l := NewDetachedLogger(cfg)
+	span := &recordingSpan{}
+	ctx := trace.ContextWithSpan(WithLogger(context.Background(), l), span)
+
+	cause := fmt.Errorf("query: %w", os.ErrDeadlineExceeded)
+	GetLogger(ctx).WithAttrs(Fields{"err": cause}).Error("db failed")
+	require.Equal(t, []string{"exception"}, span.events)
+	require.Contains(t, span.attrs, attribute.String("exception.type", "*fmt.wrapError"))
+	require.Contains(t, span.attrs, attribute.String("exception.message", "query: i/o timeout"))
+
+	attrs := ExceptionAttributes(&PanicError{Value: "boom", Stack: []byte("goroutine 1")}, nil)
+	require.Contains(t, attrs, attribute.String("exception.message", "panic: boom"))
+	require.Contains(t, attrs, attribute.String("exception.stacktrace", "goroutine 1"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestLayeredConfig(t *testing.T) {
+	fsys := fstest.MapFS{
+		"logger.json": {Data: []byte(`{
+			"min_level": "info",
+			"json": true,
+			"batch": {"size": 8, "max_wait": "200ms"},
+			"quota": {"daily": {"billing": 1000, "auth": 500}}
+		}`)},
+		"logger.prod.json": {Data: []byte(`{
+			"min_level": "WARN",
+			"batch": {"size": 64},
+			"quota": {"daily": {"billing": 5000}}
+		}`)},
+	}
+
+	lc, err := LoadLayeredConfig(fsys, "logger", "prod")
+	require.NoError(t, err)
+	require.Equal(t, []string{"logger.json", "logger.prod.json"}, lc.Sources)
+
+	buf := &syncBuffer{}
+	cfg, err := lc.Apply(Config{Stdout: buf, Stderr: buf, Workers: 2})
+	require.NoError(t, err)
+	require.Equal(t, WARN, cfg.MinLevel)
+	require.True(t, cfg.JSON)
+	require.Equal(t, 2, cfg.Workers, "settings absent from the layers keep their programmatic value")
+	require.Equal(t, 64, cfg.Batch.Size)
+	require.Equal(t, 200*time.Millisecond, cfg.Batch.MaxWait, "objects are merged key by key")
+	require.Equal(t, map[string]int64{"billing": 5000, "auth": 500}, cfg.Quota.Daily)
+	require.Equal(t, buf, cfg.Stdout)
+
+	l := NewDetachedLogger(cfg)
+	defer func() { _ = CloseDetached(l, 2*time.Second) }()
+	require.Equal(t, lc.Sources, l.ConfigSources())
+
+	l.SetMinLevel(ERROR)
+	eff := l.EffectiveConfig()
+	require.Equal(t, "ERROR", eff.MinLevel, "the effective config reflects runtime changes")
+	require.Equal(t, 64, eff.Batch.Size)
+	require.Equal(t, Duration(200*time.Millisecond), eff.Batch.MaxWait)
+	require.Equal(t, int64(5000), eff.Quota.Daily["billing"])
+
+	b, err := json.Marshal(eff)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"max_wait":"200ms"`)
+
+	// The environment override is optional; the base layer is not.
+	lc, err = LoadLayeredConfig(fsys, "logger", "dev")
+	require.NoError(t, err)
+	require.Equal(t, []string{"logger.json"}, lc.Sources)
+	_, err = LoadLayeredConfig(fsys, "missing", "prod")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// Typos and invalid values are reported.
+	lc, err = MergeConfigLayers([]byte(`{"min_levle": "INFO"}`))
+	require.NoError(t, err)
+	_, err = lc.Apply(Config{})
+	require.Error(t, err)
+	lc, err = MergeConfigLayers([]byte(`{"min_level": "LOUD"}`))
+	require.NoError(t, err)
+	_, err = lc.Apply(Config{})
+	require.Error(t, err)
+}
+
+func TestRemoteConfig(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{MinLevel: INFO, Stdout: buf, Stderr: buf})
+	defer func() { _ = CloseDetached(l, 2*time.Second) }()
+
+	require.NoError(t, l.ApplyRemoteConfig([]byte(`{"min_level": "debug", "regex_patterns": {"secret-[0-9]+": "***"}}`)))
+	require.Equal(t, DEBUG, l.GetDynamicConfig().MinLevel)
+	require.Len(t, l.GetDynamicConfig().RegexRules, 1)
+
+	// An invalid document is rejected as a whole.
+	require.Error(t, l.ApplyRemoteConfig([]byte(`{"min_level": "WARN", "regex_patterns": {"(": "x"}}`)))
+	require.Equal(t, DEBUG, l.GetDynamicConfig().MinLevel)
+	require.Error(t, l.ApplyRemoteConfig([]byte(`{"level": "WARN"}`)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// HTTP polling honours ETags and only delivers changed documents.
+	var polls, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"min_level": "ERROR"}`))
+	}))
+	defer srv.Close()
+	hctx, hcancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- l.WatchConfig(hctx, &HTTPSource{URL: srv.URL, Interval: 5 * time.Millisecond}, nil) }()
+	require.Eventually(t, func() bool { return notModified.Load() >= 2 }, 3*time.Second, 5*time.Millisecond)
+	hcancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, ERROR, l.GetDynamicConfig().MinLevel)
+
+	// Consul blocking queries pass the last index.
+	var indexes []string
+	var mu sync.Mutex
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/kv/logging/app", r.URL.Path)
+		require.Equal(t, "tok", r.Header.Get("X-Consul-Token"))
+		mu.Lock()
+		indexes = append(indexes, r.URL.Query().Get("index"))
+		n := len(indexes)
+		mu.Unlock()
+		if n > 2 {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", strconv.Itoa(10*n))
+		_, _ = fmt.Fprintf(w, `{"min_level": "%s"}`, []string{"INFO", "WARN"}[n-1])
+	}))
+	defer consul.Close()
+	var errs []error
+	cctx, ccancel := context.WithCancel(ctx)
+	go func() {
+		done <- l.WatchConfig(cctx, &ConsulSource{Address: consul.URL, Key: "logging/app", Token: "tok"}, func(err error) { errs = append(errs, err) })
+	}()
+	require.Eventually(t, func() bool { return l.GetDynamicConfig().MinLevel == WARN }, 3*time.Second, 5*time.Millisecond)
+	ccancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	mu.Lock()
+	require.Equal(t, []string{"", "10", "20"}, indexes)
+	mu.Unlock()
+	require.Empty(t, errs)
+
+	// etcd values are read through the v3 JSON gateway.
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req["key"] != base64.StdEncoding.EncodeToString([]byte("/logging/app")) {
+			_, _ = w.Write([]byte(`{"header": {}}`))
+			return
+		}
+		value := base64.StdEncoding.EncodeToString([]byte(`{"json_field_rules": [{"keys": ["ssn"], "replacement": "[x]"}]}`))
+		_, _ = fmt.Fprintf(w, `{"kvs": [{"value": %q, "mod_revision": "7"}]}`, value)
+	}))
+	defer etcd.Close()
+	ectx, ecancel := context.WithCancel(ctx)
+	go func() {
+		done <- l.WatchConfig(ectx, &EtcdSource{Endpoint: etcd.URL, Key: "/logging/app", Interval: 5 * time.Millisecond}, nil)
+	}()
+	require.Eventually(t, func() bool { return len(l.GetDynamicConfig().JSONFieldRules) == 1 }, 3*time.Second, 5*time.Millisecond)
+	ecancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	missing := make(chan error, 1)
+	ectx, ecancel = context.WithCancel(ctx)
+	defer ecancel()
+	go func() {
+		_ = l.WatchConfig(ectx, &EtcdSource{Endpoint: etcd.URL, Key: "/other", Interval: time.Hour}, func(err error) { missing <- err })
+	}()
+	require.ErrorIs(t, <-missing, ErrConfigKeyNotFound)
+}
+
+// staticFlags is a test FlagProvider serving flags per tenant, or per module when the
+// tenant has none.
+type staticFlags struct {
+	mu    sync.Mutex
+	flags map[string]map[string]string // tenant or module -> flag -> value
+	evals int
+}
+
+func (p *staticFlags) lookup(key string, target FlagTarget) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.evals++
+	for _, k := range []string{target.Tenant, target.Module} {
+		if v, ok := p.flags[k][key]; ok && k != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (p *staticFlags) StringFlag(_ context.Context, key, def string, target FlagTarget) string {
+	if v, ok := p.lookup(key, target); ok {
+		return v
+	}
+	return def
+}
+
+func (p *staticFlags) FloatFlag(_ context.Context, key string, def float64, target FlagTarget) float64 {
+	if v, ok := p.lookup(key, target); ok {
+		var f float64
+		_, _ = fmt.Sscan(v, &f)
+		return f
+	}
+	return def
+}
+
+func TestFeatureFlags(t *testing.T) {
+	buf := &syncBuffer{}
+	flags := &staticFlags{flags: map[string]map[string]string{
+		"billing": {FlagLevel: "DEBUG"},
+		"noisy":   {FlagSampleRate: "0"},
+		"acme":    {FlagDebugUntil: time.Now().Add(time.Hour).Format(time.RFC3339)},
+		"expired": {FlagDebugUntil: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		"quiet":   {FlagLevel: "ERROR"},
+	}}
+	l := NewDetachedLogger(Config{MinLevel: INFO, Stdout: buf, Stderr: buf, Flags: FlagConfig{Provider: flags}})
+
+	ctx := context.Background()
+	lw := func(module string, attrs Fields) LoggerWithCtx {
+		c := ctx
+		if attrs != nil {
+			c = WithAttrs(c, attrs)
+		}
+		return LoggerWithCtx{l: l, ctx: context.WithValue(c, ctxModuleKey, module)}
+	}
+	lw("billing", nil).Debug("billing debug")
+	lw("other", nil).Debug("other debug")
+	lw("noisy", nil).Info("noisy info")
+	lw("noisy", nil).Error("noisy error")
+	lw("other", Fields{"tenant": "acme"}).Debug("acme debug")
+	lw("other", Fields{"tenant": "expired"}).Debug("expired debug")
+	lw("quiet", nil).Warn("quiet warn")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	out := buf.String()
+	require.Contains(t, out, "billing debug", "the level flag lowers the level of a module")
+	require.NotContains(t, out, "other debug")
+	require.NotContains(t, out, "noisy info", "the sample rate flag discards entries below ERROR")
+	require.Contains(t, out, "noisy error")
+	require.Contains(t, out, "acme debug", "an open debug window enables DEBUG for a tenant")
+	require.NotContains(t, out, "expired debug")
+	require.NotContains(t, out, "quiet warn", "the level flag raises the level of a module")
+
+	// Evaluations are cached per target.
+	l2 := NewDetachedLogger(Config{MinLevel: INFO, Stdout: io.Discard, Stderr: io.Discard})
+	defer func() { _ = CloseDetached(l2, 2*time.Second) }()
+	flags.evals = 0
+	l2.SetFlags(FlagConfig{Provider: flags})
+	for i := 0; i < 10; i++ {
+		LoggerWithCtx{l: l2, ctx: context.WithValue(ctx, ctxModuleKey, "billing")}.Debug("x")
+	}
+	require.Equal(t, 3, flags.evals)
+
+	// A full cache is pruned rather than refusing new targets: expired decisions go first,
+	// then those that expire first.
+	fs := l2.flags.Load()
+	now := time.Now()
+	for i := 0; i < maxFlagTargets-1; i++ {
+		fs.decision(WithAttrs(ctx, Fields{"tenant": i}), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	require.Equal(t, int64(maxFlagTargets), fs.cached.Load())
+	fs.decision(WithAttrs(ctx, Fields{"tenant": "new"}), now.Add(defaultFlagTTL))
+	require.Equal(t, int64(maxFlagTargets*3/4), fs.cached.Load())
+	_, ok := fs.cache.Load(FlagTarget{Tenant: "new"})
+	require.True(t, ok)
+	_, ok = fs.cache.Load(FlagTarget{Tenant: fmt.Sprint(maxFlagTargets - 2)})
+	require.True(t, ok)
+	_, ok = fs.cache.Load(FlagTarget{Tenant: "0"})
+	require.False(t, ok)
+
+	var of OpenFeatureFlags
+	of.StringValue = func(_ context.Context, flag, def, key string, attrs map[string]interface{}) (string, error) {
+		require.Equal(t, "acme", key)
+		require.Equal(t, "billing", attrs["module"])
+		if flag == FlagLevel {
+			return "WARN", nil
+		}
+		return def, errors.New("flag not found")
+	}
+	require.Equal(t, "WARN", of.StringFlag(ctx, FlagLevel, "", FlagTarget{Module: "billing", Tenant: "acme"}))
+	require.Equal(t, "", of.StringFlag(ctx, FlagDebugUntil, "", FlagTarget{Module: "billing", Tenant: "acme"}))
+	require.Equal(t, 0.5, of.FloatFlag(ctx, FlagSampleRate, 0.5, FlagTarget{}))
+}
+
+func TestHTTPMiddlewareBodyCapture(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:        INFO,
+		JSON:            true,
+		Stdout:          buf,
+		Stderr:          buf,
+		JSONFieldRules:  []MaskFieldRule{{Keys: []string{"password"}, Replacement: "***"}},
+		RegexPatternMap: map[string]string{`tok_[a-z0-9]+`: "tok_***"},
+	})
+
+	h := l.HTTPMiddleware(HTTPConfig{
+		Body: BodyCapture{Request: true, Response: true, MaxBytes: 64},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mod, _ := r.Context().Value(ctxModuleKey).(string)
+		require.Equal(t, "http", mod)
+		_, _ = io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"token":"tok_abc123"}`))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/big":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(strings.Repeat("x", 200)))
+		}
+	}))
+
+	do := func(path, contentType, body string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Request-ID", "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	do("/login", "application/json; charset=utf-8", `{"user":"bob","password":"hunter2"}`)
+	do("/image", "application/octet-stream", "binary")
+	do("/big", "text/plain", "hello")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		entries = append(entries, m)
+	}
+	require.Len(t, entries, 3)
+	byPath := map[string]map[string]interface{}{}
+	for _, e := range entries {
+		f := e["fields"].(map[string]interface{})
+		byPath[f["http_path"].(string)] = e
+	}
+
+	login := byPath["/login"]["fields"].(map[string]interface{})
+	require.Equal(t, "req-1", byPath["/login"]["flow_id"])
+	require.Equal(t, float64(200), login["http_status"])
+	require.Contains(t, login["request_body"], `"password":"***"`)
+	require.NotContains(t, login["request_body"], "hunter2")
+	require.Equal(t, `{"token":"tok_***"}`, login["response_body"])
+
+	image := byPath["/image"]["fields"].(map[string]interface{})
+	require.NotContains(t, image, "request_body", "content types outside the allowlist are not captured")
+	require.NotContains(t, image, "response_body")
+
+	big := byPath["/big"]
+	require.Equal(t, "ERROR", big["level"])
+	bf := big["fields"].(map[string]interface{})
+	require.Equal(t, "hello", bf["request_body"])
+	require.Len(t, bf["response_body"], 64)
+	require.Equal(t, true, bf["response_body_truncated"])
+	require.Equal(t, float64(200), bf["http_bytes"])
+}
+
+func TestSQLArgMasking(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:       DEBUG,
+		JSON:           true,
+		Stdout:         buf,
+		Stderr:         buf,
+		JSONFieldRules: []MaskFieldRule{{Keys: []string{"password", "ssn"}, Replacement: "[masked]"}},
+	})
+
+	cases := []struct {
+		query string
+		args  []interface{}
+		want  []interface{}
+	}{
+		{"SELECT id FROM users WHERE email = ? AND u.Password = ?", []interface{}{"a@b.c", "pw"}, []interface{}{"a@b.c", "[masked]"}},
+		{"INSERT INTO users (name, ssn, age) VALUES (?, ?, ?), (?, ?, ?)", []interface{}{"x", "123", 1, "y", "456", 2}, []interface{}{"x", "[masked]", 1, "y", "[masked]", 2}},
+		{`UPDATE users SET "password" = $2, note = 'a = ?' WHERE id = $1`, []interface{}{7, "pw"}, []interface{}{7, "[masked]"}},
+		{"SELECT * FROM t WHERE ssn IN (?, ?) -- password = ?", []interface{}{"1", "2"}, []interface{}{"[masked]", "[masked]"}},
+		{"UPDATE users SET password = :pw WHERE id = :id", []interface{}{sql.Named("password", "pw"), sql.Named("id", 3)}, []interface{}{"[masked]", 3}},
+		{"SELECT ?::text, x FROM t WHERE y = ?", []interface{}{"a", "b"}, []interface{}{"a", "b"}},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, l.MaskSQLArgs(c.query, c.args, SQLArgsFull), c.query)
+	}
+	require.Equal(t, []interface{}{"string(5)", "[masked]", "int", "[]byte(3)", "nil"},
+		l.MaskSQLArgs("INSERT INTO u (a, ssn, b, c, d) VALUES (?, ?, ?, ?, ?)",
+			[]interface{}{"hello", "123", 4, []byte("abc"), nil}, SQLArgsEllipsis))
+
+	sl := l.SQLLogger(SQLConfig{SlowThreshold: 100 * time.Millisecond, Args: SQLArgsEllipsis})
+	ctx := context.Background()
+	sl.Log(ctx, "SELECT 1 FROM users WHERE password = ?", []interface{}{"pw"}, time.Millisecond, nil)
+	sl.Log(ctx, "SELECT 2", nil, time.Second, nil)
+	sl.Log(ctx, "SELECT 3", nil, time.Millisecond, errors.New("boom"))
+	sl.Log(ctx, "SELECT 4", nil, time.Millisecond, sql.ErrNoRows)
+	sl.Log(ctx, "SELECT 5", nil, time.Millisecond, fmt.Errorf("load user: %w", sql.ErrNoRows))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	out := buf.String()
+	require.NotContains(t, out, `"pw"`)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 5)
+	levels := map[string]string{}
+	for _, line := range lines {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		require.Equal(t, "sql", m["module"])
+		levels[m["fields"].(map[string]interface{})["sql_query"].(string)] = m["level"].(string)
+	}
+	require.Equal(t, map[string]string{
+		"SELECT 1 FROM users WHERE password = ?": "DEBUG",
+		"SELECT 2":                               "WARN",
+		"SELECT 3":                               "ERROR",
+		"SELECT 4":                               "DEBUG",
+		"SELECT 5":                               "DEBUG",
+	}, levels)
+}
+
+func TestAnonymization(t *testing.T) {
+	var p AnonymizationPolicy
+	require.Equal(t, "203.0.113.0", p.AnonymizeIP("203.0.113.77"))
+	require.Equal(t, "203.0.113.0", p.AnonymizeIP("203.0.113.77:5123"))
+	require.Equal(t, "203.0.113.0", p.AnonymizeIP("::ffff:203.0.113.77"))
+	require.Equal(t, "2001:db8:85a3::", p.AnonymizeIP("[2001:db8:85a3:8d3:1319:8a2e:370:7348]:443"))
+	require.Equal(t, "", p.AnonymizeIP("not-an-ip"))
+	require.Equal(t, "203.0.0.0", AnonymizationPolicy{IPv4Bits: 16}.AnonymizeIP("203.0.113.77"))
+	require.Equal(t, "203.0.113.77", AnonymizationPolicy{IPv4Bits: 32}.AnonymizeIP("203.0.113.77"))
+	require.Equal(t, "", AnonymizationPolicy{IPv4Bits: -1}.AnonymizeIP("203.0.113.77"))
+
+	ua := "Mozilla/5.0 (X11; Linux x86_64)"
+	h := p.AnonymizeUserAgent(ua)
+	require.Len(t, h, 16)
+	require.Equal(t, h, p.AnonymizeUserAgent(ua))
+	require.NotEqual(t, h, AnonymizationPolicy{Salt: "s"}.AnonymizeUserAgent(ua))
+	require.Equal(t, ua, AnonymizationPolicy{UserAgent: UserAgentKeep}.AnonymizeUserAgent(ua))
+	require.Equal(t, "", AnonymizationPolicy{UserAgent: UserAgentDrop}.AnonymizeUserAgent(ua))
+
+	SetRegionPolicy("US", AnonymizationPolicy{IPv4Bits: 32, UserAgent: UserAgentKeep})
+	defer SetRegionPolicy("us", DefaultAnonymizationPolicy)
+	t.Setenv(RegionEnvVar, "us")
+	require.Equal(t, "203.0.113.77", PolicyFromEnv().AnonymizeIP("203.0.113.77"))
+	require.Equal(t, "203.0.113.0", RegionPolicy("eu").AnonymizeIP("203.0.113.77"))
+
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{MinLevel: INFO, JSON: true, Stdout: buf, Stderr: buf})
+	eu := RegionPolicy("eu")
+	eu.TrustProxy = true
+	h2 := l.HTTPMiddleware(HTTPConfig{Client: &eu})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		GetLogger(r.Context()).Info("handled")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "198.51.100.23, 10.0.0.1")
+	req.Header.Set("User-Agent", ua)
+	h2.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	out := buf.String()
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		require.Contains(t, line, `"client_ip":"198.51.100.0"`, "handler entries and the request entry carry the client fields")
+	}
+	require.Contains(t, out, `"user_agent":"`+h+`"`)
+	require.NotContains(t, out, "198.51.100.23")
+	require.NotContains(t, out, "Mozilla")
+}
+
+func TestRetentionClasses(t *testing.T) {
+	std, extra, short, long := &syncBuffer{}, &syncBuffer{}, &syncBuffer{}, &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		Stdout:   std,
+		Stderr:   std,
+		Writers:  []io.Writer{extra},
+		Retention: RetentionConfig{
+			Default: "7d",
+			Sinks:   map[string]io.Writer{"7d": short, "1y": long},
+		},
+	})
+	ctx := WithRetention(context.Background(), "1y")
+	lw := LoggerWithCtx{l: l, ctx: ctx}
+	lw.Info("audit entry")
+	LoggerWithCtx{l: l, ctx: context.Background()}.Info("plain entry")
+	l.logFields(ctx, INFO, Fields{RetentionKey: "90d"}, "unknown class")
+	require.NoError(t, lw.InfoSync("synced audit"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Contains(t, long.String(), "audit entry")
+	require.Contains(t, long.String(), "synced audit")
+	require.Contains(t, long.String(), "retention:1y")
+	require.NotContains(t, long.String(), "plain entry")
+	require.Contains(t, short.String(), "plain entry", "untagged entries get the default class")
+	require.NotContains(t, extra.String(), "audit entry", "retained entries bypass the extra writers")
+	require.Contains(t, extra.String(), "unknown class", "classes without a sink use the regular sinks")
+	for _, msg := range []string{"audit entry", "plain entry", "unknown class"} {
+		require.Contains(t, std.String(), msg)
+	}
+	require.Equal(t, "30d", RetentionOf(HookEvent{Fields: Fields{RetentionKey: "30d"}}))
+}
+
+func TestCryptoShredding(t *testing.T) {
+	store := NewMemoryKeyStore()
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:  INFO,
+		JSON:      true,
+		Stdout:    buf,
+		Stderr:    buf,
+		Shredding: &ShreddingConfig{Store: store, SubjectField: "user_id", Fields: []string{"user_id", "email"}},
+	})
+	lw := LoggerWithCtx{l: l, ctx: WithAttrs(context.Background(), Fields{"user_id": "u-42"})}
+	lw.WithAttrs(Fields{"email": "bob@example.com", "plan": "pro"}).Info("signed up")
+	LoggerWithCtx{l: l, ctx: WithAttrs(context.Background(), Fields{"email": "anon@example.com"})}.Info("no subject")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	out := buf.String()
+	require.NotContains(t, out, "bob@example.com")
+	require.NotContains(t, out, "u-42")
+	require.NotContains(t, out, "anon@example.com")
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &m))
+	fields := m["fields"].(map[string]interface{})
+	require.Equal(t, "pro", fields["plan"])
+	email := fields["email"].(string)
+	require.True(t, strings.HasPrefix(email, "shred:v1:"))
+
+	plain, err := RevealValue(store, "email", email)
+	require.NoError(t, err)
+	require.Equal(t, "bob@example.com", plain)
+	_, err = RevealValue(store, "user_id", email)
+	require.Error(t, err, "a value cannot be decrypted as another field")
+	_, err = RevealValue(store, "plan", "pro")
+	require.ErrorIs(t, err, ErrNotShredded)
+
+	require.NoError(t, store.Erase("u-42"))
+	_, err = RevealValue(store, "email", email)
+	require.ErrorIs(t, err, ErrSubjectErased)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &m))
+	require.Equal(t, ShredRedacted, m["fields"].(map[string]interface{})["email"])
+}
+
+func TestLogSigning(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	for _, jsonMode := range []bool{true, false} {
+		buf := &syncBuffer{}
+		l := NewDetachedLogger(Config{
+			MinLevel: INFO,
+			JSON:     jsonMode,
+			Stdout:   buf,
+			Stderr:   buf,
+			Signing:  &SigningConfig{Key: priv},
+		})
+		lw := l.WithContext(context.Background())
+		lw.Info("first")
+		lw.Warn("second %d", 2)
+		require.NoError(t, CloseDetached(l, 2*time.Second))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			require.NoError(t, VerifyEntry(pub, []byte(line)), line)
+		}
+		tampered := strings.Replace(lines[0], "first", "frist", 1)
+		require.ErrorIs(t, VerifyEntry(pub, []byte(tampered)), ErrBadSignature)
+		if jsonMode {
+			var m map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &m), "signed entries stay valid JSON")
+		}
+	}
+
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		JSON:     true,
+		Workers:  1,
+		Batch:    BatchConfig{Size: 4, MaxWait: 50 * time.Millisecond},
+		Stdout:   buf,
+		Stderr:   buf,
+		Signing:  &SigningConfig{Key: priv, Mode: SignBatches, KeyID: "k1"},
+	})
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 10; i++ {
+		lw.Info("entry %d", i)
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var batch [][]byte
+	entries, seals := 0, 0
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !IsBatchSeal([]byte(line)) {
+			batch = append(batch, []byte(line))
+			continue
+		}
+		require.Contains(t, line, `"batch_key_id":"k1"`)
+		require.NoError(t, VerifyBatch(pub, batch, []byte(line)))
+		if len(batch) > 1 {
+			swapped := [][]byte{batch[1], batch[0]}
+			swapped = append(swapped, batch[2:]...)
+			require.ErrorIs(t, VerifyBatch(pub, swapped, []byte(line)), ErrBadSignature)
+		}
+		require.Error(t, VerifyBatch(pub, batch[1:], []byte(line)), "dropped entries are detected")
+		entries += len(batch)
+		seals++
+		batch = nil
+	}
+	require.Empty(t, batch, "every batch ends with a trailer")
+	require.Equal(t, 10, entries)
+	require.GreaterOrEqual(t, seals, 3)
+	require.EqualValues(t, 10, l.WriterStats()["stdout"].EntriesWritten, "trailers are not counted as entries")
+}
+
+func TestClockJumpAnnotation(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:           INFO,
+		JSON:               true,
+		Workers:            1,
+		Stdout:             buf,
+		Stderr:             buf,
+		ClockJumpThreshold: time.Second,
+	})
+	lw := l.WithContext(context.Background())
+	require.NoError(t, lw.InfoSync("before"))
+	// Simulate an NTP step of +5s: the wall clock moves ahead of the monotonic clock.
+	l.clock.startWall.Add(-int64(5 * time.Second))
+	require.NoError(t, lw.InfoSync("after"))
+	require.NoError(t, lw.InfoSync("later"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	require.NotContains(t, lines[0], ClockEpochKey, "no annotation before the first jump")
+	var monos []float64
+	for _, line := range lines[1:] {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		fields := m["fields"].(map[string]interface{})
+		require.EqualValues(t, 1, fields[ClockEpochKey])
+		require.InDelta(t, 5000, fields[ClockDriftKey], 50)
+		monos = append(monos, fields[ClockMonoKey].(float64))
+	}
+	require.Less(t, monos[0], monos[1])
+	require.EqualValues(t, 1, l.ClockJumps())
+
+	// An entry taken before the jump but processed after it keeps its original epoch.
+	require.Equal(t, 0, l.clock.epoch(int64(10*time.Millisecond), int64(time.Second)))
+	require.Equal(t, 1, l.clock.epoch(int64(5*time.Second), int64(time.Second)))
+	require.EqualValues(t, 1, l.ClockJumps())
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	out, errOut := &syncBuffer{}, &syncBuffer{}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		JSON:     true,
+		Workers:  1,
+		Stdout:   out,
+		Stderr:   errOut,
+		Sequence: SequenceConfig{Entries: true, Sinks: true},
+		Signing:  &SigningConfig{Key: priv},
+	})
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 6; i++ {
+		if i%3 == 2 {
+			lw.Error("failure %d", i)
+		} else {
+			lw.Info("entry %d", i)
+		}
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	check := func(buf *syncBuffer) (seqs []uint64) {
+		var tracker SeqTracker
+		for i, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			sinkSeq, ok := SinkSeqOf([]byte(line))
+			require.True(t, ok, line)
+			require.EqualValues(t, i+1, sinkSeq, "each sink numbers its own entries")
+			require.Zero(t, tracker.Observe(sinkSeq))
+			require.NoError(t, VerifyEntry(pub, []byte(line)), "sink numbers do not break signatures")
+
+			var m map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &m))
+			seqs = append(seqs, uint64(m["seq"].(float64)))
+		}
+		return seqs
+	}
+	require.Equal(t, []uint64{1, 2, 4, 5}, check(out))
+	require.Equal(t, []uint64{3, 6}, check(errOut))
+
+	var tracker SeqTracker
+	for _, seq := range []uint64{1, 2, 4, 7, 1, 2} {
+		tracker.Observe(seq)
+	}
+	require.EqualValues(t, 3, tracker.Missing)
+	require.Equal(t, 1, tracker.Restarts)
+
+	text, err := (&TextFormatter{}).Format(HookEvent{Level: INFO, Seq: 9, Message: "m"})
+	require.NoError(t, err)
+	require.Contains(t, string(text), " seq=9 ")
+	require.Equal(t, "sink_seq=3 x\n", string(withSinkSeq([]byte("x\n"), 3)))
+	require.Equal(t, "x\n", string(stripSinkSeq(withSinkSeq([]byte("x\n"), 3))))
+}
+
+// ackFailConn fails the first acknowledgment write, losing the ACK of a handled batch.
+type ackFailConn struct {
+	net.Conn
+	fail *atomic.Bool
+}
+
+func (c ackFailConn) Write(p []byte) (int, error) {
+	if c.fail.CompareAndSwap(true, false) {
+		return 0, errors.New("ack lost")
+	}
+	return c.Conn.Write(p)
+}
+
+func TestAckSinkAtLeastOnce(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	var mu sync.Mutex
+	var got []string
+	failHandle := true
+	loseAck := &atomic.Bool{}
+	loseAck.Store(true)
+	recv := &AckReceiver{Handle: func(b AckBatch) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if b.Seq == 2 && failHandle {
+			failHandle = false
+			return errors.New("storage unavailable")
+		}
+		for _, e := range b.Entries {
+			got = append(got, strings.TrimSpace(string(e)))
+		}
+		return nil
+	}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() { _ = recv.ServeConn(ackFailConn{Conn: conn, fail: loseAck}) }()
+		}
+	}()
+
+	sink := NewAckSink(AckSinkConfig{Network: "tcp", Address: ln.Addr().String(), ReconnectBackoff: 10 * time.Millisecond})
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, sink.WriteBatch([][]byte{[]byte(fmt.Sprintf("entry %d\n", i))}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sink.Flush(ctx))
+
+	mu.Lock()
+	require.Equal(t, []string{"entry 1", "entry 2", "entry 3", "entry 4", "entry 5"}, got, "every batch delivered once, in order")
+	mu.Unlock()
+	require.GreaterOrEqual(t, recv.Duplicates(), int64(1), "the batch whose ACK was lost is resent and discarded")
+	st := sink.Stats()
+	require.Zero(t, st.Pending)
+	require.EqualValues(t, 5, st.Acked)
+	require.GreaterOrEqual(t, st.Resent, int64(1))
+	require.GreaterOrEqual(t, st.Reconnects, int64(1))
+	require.NoError(t, sink.Close())
+	require.ErrorIs(t, sink.WriteBatch([][]byte{[]byte("late")}), ErrAckSinkClosed)
+
+	full := NewAckSink(AckSinkConfig{Network: "tcp", Address: "127.0.0.1:1", MaxPending: 1, CloseTimeout: 10 * time.Millisecond})
+	require.NoError(t, full.WriteBatch([][]byte{[]byte("a")}))
+	require.ErrorIs(t, full.WriteBatch([][]byte{[]byte("b")}), ErrAckWindowFull)
+	require.Error(t, full.Close(), "unacknowledged batches are reported")
+}
+
+func TestAgentHandoff(t *testing.T) {
+	for _, shm := range []bool{false, true} {
+		dir := t.TempDir()
+		ln, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var got []string
+		agent := &AgentServer{Handle: func(entries [][]byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, e := range entries {
+				got = append(got, strings.TrimSpace(string(e)))
+			}
+			return nil
+		}}
+		go func() { _ = agent.Serve(ln) }()
+		received := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(got)
+		}
+
+		cfg := AgentConfig{Socket: filepath.Join(dir, "agent.sock")}
+		if shm {
+			// A ring smaller than the logged volume: writers wait for the agent to free space.
+			cfg.SharedMemory = filepath.Join(dir, "ring")
+			cfg.RingSize = 128
+			cfg.BlockTimeout = 2 * time.Second
+		}
+		sink, err := NewAgentSink(cfg)
+		require.NoError(t, err)
+		l := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Stdout: io.Discard, Stderr: io.Discard})
+		l.AddExtraWriter("agent", sink)
+		lw := l.WithContext(context.Background())
+		for i := 0; i < 10; i++ {
+			require.NoError(t, lw.InfoSync("entry %d", i))
+		}
+		require.Eventually(t, func() bool { return received() == 10 }, 2*time.Second, 5*time.Millisecond)
+		if shm {
+			require.Error(t, sink.WriteBatch([][]byte{make([]byte, 200)}), "a batch larger than the ring can never fit")
+		} else {
+			agent.Pause()
+			require.Eventually(t, sink.Paused, 2*time.Second, 5*time.Millisecond)
+			require.ErrorIs(t, sink.WriteBatch([][]byte{[]byte("held back\n")}), ErrAgentBusy)
+			agent.Resume()
+			require.Eventually(t, func() bool { return !sink.Paused() }, 2*time.Second, 5*time.Millisecond)
+			require.NoError(t, sink.WriteBatch([][]byte{[]byte("resumed\n")}))
+			require.Eventually(t, func() bool { return received() == 11 }, 2*time.Second, 5*time.Millisecond)
+		}
+		require.NoError(t, CloseDetached(l, 2*time.Second))
+		require.NoError(t, ln.Close())
+
+		mu.Lock()
+		for i := 0; i < 10; i++ {
+			require.Contains(t, got[i], fmt.Sprintf("entry %d", i))
+		}
+		mu.Unlock()
+	}
+}
+
+func TestMigrationDualWrite(t *testing.T) {
+	oldBuf, newBuf := &syncBuffer{}, &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:  INFO,
+		Workers:   1,
+		Stdout:    io.Discard,
+		Stderr:    io.Discard,
+		Migration: &MigrationConfig{Old: oldBuf, New: newBuf, NewFormatter: &JSONFormatter{}},
+	})
+	lw := l.WithContext(context.Background())
+	require.NoError(t, lw.InfoSync("one"))
+	require.NoError(t, lw.ErrorSync("two"))
+
+	st, ok := l.MigrationStats()
+	require.True(t, ok)
+	require.EqualValues(t, 2, st.Old.Entries)
+	require.EqualValues(t, 2, st.New.Entries)
+	require.Zero(t, st.Divergent)
+	require.EqualValues(t, len(oldBuf.String()), st.Old.Bytes)
+	require.EqualValues(t, len(newBuf.String()), st.New.Bytes)
+	require.Greater(t, st.SizeRatio, 1.0, "JSON entries are larger than text entries")
+	require.Contains(t, oldBuf.String(), "[ERROR]")
+	require.Contains(t, newBuf.String(), `"level":"ERROR"`)
+
+	// A new side whose formatter rejects entries, then one whose sink fails.
+	l.SetMigration(&MigrationConfig{Old: oldBuf, New: newBuf, NewFormatter: &JSONFormatter{Schema: "missing"}})
+	require.NoError(t, lw.InfoSync("three"))
+	st, _ = l.MigrationStats()
+	require.EqualValues(t, 1, st.Old.Entries, "statistics restart with the migration")
+	require.EqualValues(t, 1, st.New.FormatErrors)
+	require.EqualValues(t, 1, st.Divergent)
+
+	l.SetMigration(&MigrationConfig{Old: oldBuf, New: failingWriter{}})
+	require.NoError(t, lw.InfoSync("four"), "migration failures do not fail the entry")
+	st, _ = l.MigrationStats()
+	require.EqualValues(t, 1, st.New.WriteErrors)
+	require.EqualValues(t, 1, st.Divergent)
+
+	l.SetMigration(nil)
+	_, ok = l.MigrationStats()
+	require.False(t, ok)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestShadowConfig(t *testing.T) {
+	out, shadow := &syncBuffer{}, &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		Workers:  1,
+		Stdout:   out,
+		Stderr:   io.Discard,
+		Shadow: &ShadowConfig{
+			Writer:     shadow,
+			Formatter:  &JSONFormatter{},
+			RegexRules: []MaskRuleRegex{{Pattern: regexp.MustCompile(`secret-\w+`), Replacement: "***"}},
+		},
+	})
+	lw := l.WithContext(context.Background())
+	require.NoError(t, lw.InfoSync("token %s", "secret-abc"))
+
+	require.Contains(t, out.String(), "secret-abc", "the primary output keeps its own masking")
+	require.Contains(t, shadow.String(), `"level":"INFO"`)
+	require.Contains(t, shadow.String(), "token ***")
+	st, ok := l.ShadowStats()
+	require.True(t, ok)
+	require.EqualValues(t, 1, st.Seen)
+	require.EqualValues(t, 1, st.Entries)
+	require.EqualValues(t, len(shadow.String()), st.Bytes)
+	require.EqualValues(t, len(out.String()), st.PrimaryBytes)
+	require.Greater(t, st.VolumeRatio, 1.0)
+
+	// A tiny sample rate evaluates the volume of aggressive sampling.
+	l.SetShadow(&ShadowConfig{Writer: shadow, SampleRate: 1e-9})
+	for i := 0; i < 5; i++ {
+		require.NoError(t, lw.InfoSync("sampled"))
+	}
+	st, _ = l.ShadowStats()
+	require.EqualValues(t, 5, st.Seen)
+	require.EqualValues(t, 5, st.SampledOut)
+	require.Zero(t, st.Entries)
+
+	// Promotion through the debug endpoint.
+	l.SetShadow(&ShadowConfig{Writer: shadow, Formatter: &JSONFormatter{}})
+	h := l.DebugHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shadow", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"seen":0`)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shadow/promote", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shadow/promote", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shadow", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	out.Reset()
+	require.NoError(t, lw.InfoSync("promoted"))
+	require.Contains(t, out.String(), `"message":"promoted"`)
+	require.ErrorIs(t, l.PromoteShadow(), ErrNoShadow)
+
+	l.SetShadow(&ShadowConfig{Writer: shadow})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/shadow", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	_, ok = l.ShadowStats()
+	require.False(t, ok)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestUnitFieldHelpers(t *testing.T) {
+	require.Equal(t, Fields{"latency_ms": 1200.5}, DurationMS("latency", 1200500*time.Microsecond))
+	require.Equal(t, Fields{"latency_ms": 2.0}, DurationMS("latency_ms", 2*time.Millisecond), "no doubled suffix")
+	require.Equal(t, Fields{"body_bytes": int64(512)}, Bytes("body", 512))
+	require.Equal(t, Fields{"cache_hit_pct": 33.33}, Percent("cache_hit", 1, 3))
+	require.Equal(t, Fields{"cache_hit_pct": 0.0}, Percent("cache_hit", 1, 0))
+
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Stdout: buf, Stderr: io.Discard, JSON: true})
+	lw := l.WithContext(context.Background()).WithAttrs(MergeFields(
+		DurationMS("latency", 1500*time.Millisecond), Bytes("resp", 2048)))
+	require.NoError(t, lw.InfoSync("served"))
+	require.Contains(t, buf.String(), `"latency_ms":1500`)
+	require.Contains(t, buf.String(), `"resp_bytes":2048`)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestWellKnownFields(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, k := range WellKnownFields() {
+		require.False(t, seen[k], "duplicate key %q", k)
+		require.Equal(t, toSnakeCase(k), k)
+		seen[k] = true
+	}
+	require.True(t, seen[FieldDurationMS])
+	require.Equal(t, Fields{FieldDurationMS: 1.5}, DurationMS("duration", 1500*time.Microsecond))
+}
+
+func TestTee(t *testing.T) {
+	appBuf, auditBuf := &syncBuffer{}, &syncBuffer{}
+	app := NewDetachedLogger(Config{MinLevel: DEBUG, Workers: 1, Stdout: appBuf, Stderr: appBuf})
+	audit := NewDetachedLogger(Config{MinLevel: WARN, Workers: 1, Stdout: auditBuf, Stderr: auditBuf, JSON: true})
+	tee := Tee(app, nil, Tee(audit))
+	lw := tee.WithContext(context.Background())
+
+	require.NoError(t, lw.DebugSync("debug only in app"))
+	require.NoError(t, lw.WarnSync("warn in both"))
+	require.Contains(t, appBuf.String(), "debug only in app")
+	require.Contains(t, appBuf.String(), "warn in both")
+	require.NotContains(t, auditBuf.String(), "debug only in app")
+	require.Contains(t, auditBuf.String(), `"message":"warn in both"`)
+
+	// Buffered groups are split per member according to its own level.
+	b := lw.Buffered()
+	b.Info("buffered info")
+	b.Error("buffered error")
+	b.Commit()
+	require.NoError(t, lw.InfoSync("after commit"))
+	require.Eventually(t, func() bool {
+		return strings.Contains(auditBuf.String(), "buffered error")
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Contains(t, appBuf.String(), "buffered info")
+	require.NotContains(t, auditBuf.String(), "buffered info")
+
+	// Closing the tee leaves the members open.
+	require.NoError(t, CloseDetached(tee, time.Second))
+	require.ErrorIs(t, lw.ErrorSync("after close"), ErrLoggerClosed)
+	require.NoError(t, app.WithContext(context.Background()).InfoSync("still open"))
+	require.NoError(t, CloseDetached(app, 2*time.Second))
+	require.NoError(t, CloseDetached(audit, 2*time.Second))
+}
+
+func TestWithTemporarySink(t *testing.T) {
+	main, job := &syncBuffer{}, &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		Workers:  2,
+		Batch:    BatchConfig{Size: 100, MaxWait: time.Hour},
+		Stdout:   main,
+		Stderr:   main,
+	})
+	lw := l.WithContext(context.Background())
+	lw.Info("before")
+	l.WithTemporarySink(job, func() {
+		for i := 0; i < 10; i++ {
+			lw.Info("job step %d", i)
+		}
+	})
+	// Everything logged during fn was written before the sink was detached, although
+	// the batches would otherwise wait for an hour.
+	for i := 0; i < 10; i++ {
+		require.Contains(t, job.String(), fmt.Sprintf("job step %d", i))
+	}
+	require.NotContains(t, job.String(), "before")
+	require.NoError(t, lw.InfoSync("after"))
+	require.NotContains(t, job.String(), "after")
+	require.Contains(t, main.String(), "after")
+	_, ok := l.WriterStats()[TempSinkName]
+	require.True(t, ok)
+
+	// The sink is detached even if fn panics.
+	require.Panics(t, func() {
+		l.WithTemporarySink(job, func() { panic("job failed") })
+	})
+	l.outputsMu.RLock()
+	require.Empty(t, l.extraW)
+	l.outputsMu.RUnlock()
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	l.WithTemporarySink(job, func() {}) // Returns immediately on a closed logger.
+}
+
+func TestCaptureOutput(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", JSON: true, Buffer: 16, Workers: 2, Stdout: io.Discard, Stderr: io.Discard,
+		Batch: BatchConfig{Size: 64, MaxWait: time.Hour}}
+	l, err := ReinitGlobalLogger(cfg, 2*time.Second)
+	require.NoError(t, err)
+
+	Infof("not captured")
+	got := CaptureOutput(func() {
+		Infof("step %d", 1)
+		WarnW("step 2", Fields{FieldUserID: "u1"})
+		Debugf("filtered")
+	})
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, got, `"message":"step 1"`)
+	require.Contains(t, got, `"user_id":"u1"`)
+	require.Empty(t, CaptureOutput(func() {}))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestNamedMaskingRules(t *testing.T) {
+	l := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Stdout: io.Discard, Stderr: io.Discard})
+	defer func() { _ = CloseDetached(l, 2*time.Second) }()
+
+	require.ErrorIs(t, l.AddRegexRule("", "x", "y"), errEmptyRuleName)
+	require.Error(t, l.AddRegexRule("bad", "(", "y"))
+	require.NoError(t, l.AddRegexRule("card", `\d{16}`, "[card]"))
+	require.NoError(t, l.AddRegexRule("token", `tok_\w+`, "[token]"))
+	require.Equal(t, "[card] [token]", l.applyMasking("4111111111111111 tok_abc", false))
+
+	// Re-adding a name replaces the rule in place; removing leaves the others.
+	require.NoError(t, l.AddRegexRule("card", `\d{16}`, "[pan]"))
+	require.Equal(t, "[pan] [token]", l.applyMasking("4111111111111111 tok_abc", false))
+	require.True(t, l.RemoveRegexRule("token"))
+	require.False(t, l.RemoveRegexRule("token"))
+	require.Equal(t, "[pan] tok_abc", l.applyMasking("4111111111111111 tok_abc", false))
+	require.Len(t, l.GetDynamicConfig().RegexRules, 1)
+
+	require.NoError(t, l.AddFieldRule("auth", []string{"password"}, "***"))
+	require.NoError(t, l.AddFieldRule("pii", []string{"email"}, "[email]"))
+	require.Equal(t, `{"email":"[email]","password":"***"}`, l.applyMasking(`{"password":"p","email":"a@b.c"}`, true))
+	require.True(t, l.RemoveFieldRule("pii"))
+	require.Equal(t, `{"email":"a@b.c","password":"***"}`, l.applyMasking(`{"password":"p","email":"a@b.c"}`, true))
+
+	// Concurrent components adding and removing their own rules never lose each other's.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("c%d", i)
+			for j := 0; j < 50; j++ {
+				require.NoError(t, l.AddRegexRule(name, "x", "y"))
+				_ = l.applyMasking("x", false)
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, l.GetDynamicConfig().RegexRules, 9)
+}
+
+func TestMaskingRulePriority(t *testing.T) {
+	l := NewDetachedLogger(Config{
+		MinLevel:        INFO,
+		Workers:         1,
+		Stdout:          io.Discard,
+		Stderr:          io.Discard,
+		RegexPatternMap: map[string]string{`z{3}`: "[z]", `a{3}`: "[a]", `m{3}`: "[m]"},
+		JSONFieldRules: []MaskFieldRule{
+			{Name: "generic", Keys: []string{"token"}, Replacement: "***"},
+			{Name: "specific", Keys: []string{"token"}, Replacement: "[token]", Priority: 5},
+		},
+	})
+	defer func() { _ = CloseDetached(l, 2*time.Second) }()
+
+	rules := l.MaskingRules()
+	require.Len(t, rules, 5)
+	require.Equal(t, "specific", rules[0].Name, "higher priority first")
+	require.Equal(t, "generic", rules[1].Name)
+	require.Equal(t, []string{"a{3}", "m{3}", "z{3}"}, []string{rules[2].Pattern, rules[3].Pattern, rules[4].Pattern},
+		"patterns from RegexPatternMap in lexical order")
+	require.Equal(t, `{"token":"[token]"}`, l.applyMasking(`{"token":"t"}`, true))
+
+	// Overlapping regexes: the card rule must run before the generic number rule.
+	require.NoError(t, l.AddRegexRule("number", `\d+`, "[num]"))
+	require.NoError(t, l.AddRegexRule("card", `\d{16}`, "[card]"))
+	require.Equal(t, "[num]", l.applyMasking("4111111111111111", false))
+	require.True(t, l.SetMaskRulePriority("card", 10))
+	require.False(t, l.SetMaskRulePriority("missing", 1))
+	require.Equal(t, "[card] [num]", l.applyMasking("4111111111111111 42", false))
+	require.NoError(t, l.AddRegexRule("card", `\d{16}`, "[pan]"))
+	require.Equal(t, "[pan]", l.applyMasking("4111111111111111", false), "replacing a rule keeps its priority")
+	require.Equal(t, "card", l.MaskingRules()[2].Name)
+
+	l.SetJSONFieldRules([]MaskFieldRule{{Keys: []string{"k"}, Replacement: "low"}, {Keys: []string{"k"}, Replacement: "high", Priority: 1}})
+	require.Equal(t, `{"k":"high"}`, l.applyMasking(`{"k":"v"}`, true))
+}
+
+func TestMaskingNestedJSONStrings(t *testing.T) {
+	rules := []MaskFieldRule{{Keys: []string{"password"}, Replacement: "***"}}
+	out, ok := maskJSONFieldsWithRules(`{"body":"{\"password\":\"x\",\"user\":\"u\"}","note":"{not json","raw":" [1, 2] "}`, rules)
+	require.True(t, ok)
+	require.Equal(t, `{"body":"{\"password\":\"***\",\"user\":\"u\"}","note":"{not json","raw":" [1, 2] "}`, out,
+		"nested JSON is masked; other strings keep their exact form")
+
+	// Arrays and several levels of nesting, up to maxNestedJSONDepth.
+	nested := `{"password":"x"}`
+	for i := 1; i < maxNestedJSONDepth; i++ {
+		b, _ := json.Marshal(map[string]string{"inner": nested})
+		nested = string(b)
+	}
+	out, _ = maskJSONFieldsWithRules(`[`+strconv.Quote(nested)+`]`, rules)
+	require.NotContains(t, out, `x\`)
+	require.Contains(t, out, `***`)
+
+	tooDeep, _ := json.Marshal(map[string]string{"inner": nested})
+	out, _ = maskJSONFieldsWithRules(`{"d":`+strconv.Quote(string(tooDeep))+`}`, rules)
+	require.NotContains(t, out, `***`, "nesting beyond the limit is left alone")
+}
+
+func TestMaskXMLAndForm(t *testing.T) {
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO, Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		JSONFieldRules:  []MaskFieldRule{{Keys: []string{"password", "token", "card"}, Replacement: "***"}},
+		RegexPatternMap: map[string]string{`secret-\w+`: "[secret]"},
+	})
+	defer func() { _ = CloseDetached(l, 2*time.Second) }()
+
+	xmlIn := `<?xml version="1.0"?>
+<login user="u1" token='abc'>
+  <password>p&amp;ss</password>
+  <card><number>4111</number></card>
+  <ns:password xmlns:ns="urn:x">x</ns:password>
+  <empty password="a"/><note>secret-1</note>
+</login>`
+	got := l.MaskPayload(xmlIn, "application/xml; charset=utf-8")
+	require.Equal(t, `<?xml version="1.0"?>
+<login user="u1" token="***">
+  <password>***</password>
+  <card>***</card>
+  <ns:password xmlns:ns="urn:x">***</ns:password>
+  <empty password="***"/><note>[secret]</note>
+</login>`, got)
+	require.Equal(t, "<a><password>x</a>", l.MaskPayload("<a><password>x</a>", "text/xml"), "malformed XML is left to the regex rules")
+	require.Equal(t, `<a note='set token="t"' x:token="***"/>`, l.MaskPayload(`<a note='set token="t"' x:token="t"/>`, "text/xml"),
+		"attribute values are not searched for attributes")
+
+	require.Equal(t, "user=u1&password=%2A%2A%2A&to%6Ben=%2A%2A%2A&flag", l.MaskPayload("user=u1&password=p%40ss&to%6Ben=t&flag", "application/x-www-form-urlencoded"))
+	require.Equal(t, "https://h/p?q=1&token=%2A%2A%2A#frag", l.MaskURL("https://h/p?q=1&token=abc#frag"))
+	require.Equal(t, "https://h/p/token=abc", l.MaskURL("https://h/p/token=abc"))
+	require.Equal(t, "a=1&card=%2A%2A%2A", l.MaskURL("a=1&card=4111"))
+	require.Equal(t, `{"password":"***"}`, l.MaskPayload(`{"password":"x"}`, "application/json"))
+	require.Equal(t, "[secret]", l.MaskPayload("secret-abc", "text/plain"))
+}
+
+func TestHeaderMasking(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Add("Cookie", "sid=1")
+	h.Add("Cookie", "theme=dark")
+	h.Set("Accept", "application/json")
+	masked := MaskHeaders(h, nil, "")
+	require.Equal(t, []string{"[REDACTED]", "[REDACTED]"}, masked["Cookie"])
+	require.Equal(t, "[REDACTED]", masked.Get("Authorization"))
+	require.Equal(t, "application/json", masked.Get("Accept"))
+	require.Equal(t, "Bearer secret", h.Get("Authorization"), "the input is not modified")
+	require.Equal(t, "x", MaskHeaders(http.Header{"X-Custom": {"v"}}, []string{"x-custom"}, "x").Get("X-Custom"))
+
+	dump := "POST http://api:8080/login HTTP/1.1\r\nHost: api\r\nx-api-key: k-123\r\nSet-Cookie: a=b\r\n\r\nAuthorization: body text"
+	require.Equal(t,
+		"POST http://api:8080/login HTTP/1.1\r\nHost: api\r\nx-api-key: [REDACTED]\r\nSet-Cookie: [REDACTED]\r\n\r\nAuthorization: body text",
+		MaskHeaderDump(dump, nil, ""))
+	require.Equal(t, "Cookie: ***\n", MaskHeaderDump("Cookie: a=1\n", nil, "***"))
+
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel:        INFO,
+		JSON:            true,
+		Stdout:          buf,
+		Stderr:          buf,
+		RegexPatternMap: map[string]string{`tok_[a-z0-9]+`: "tok_***"},
+	})
+	handler := l.HTTPMiddleware(HTTPConfig{
+		Headers: HeaderCapture{Request: true, Response: true},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "sid=new")
+		w.Header().Set("X-Trace", "tok_abc")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &entry))
+	fields := entry["fields"].(map[string]interface{})
+	require.Equal(t, "Authorization: [REDACTED]\nUser-Agent: test", fields["request_headers"])
+	require.Equal(t, "Set-Cookie: [REDACTED]\nX-Trace: tok_***", fields["response_headers"])
+}
+
+func TestBlobSummaryRules(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO,
+		JSON:     true,
+		Stdout:   buf,
+		Stderr:   buf,
+		BlobRules: []BlobRule{
+			{MaxLen: 32},
+			{Keys: []string{"image", "raw", "bin"}, Binary: true},
+		},
+	})
+	big := strings.Repeat("x", 33)
+	image := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff, 0x00, 0x7f}, 100))
+	ctx := WithAttrs(context.Background(), Fields{
+		"big":   big,
+		"small": strings.Repeat("x", 32),
+		"image": image,
+		"raw":   []byte("tiny"),
+		"bin":   "bad \xff utf8",
+		"count": 7,
+	})
+	l.Info(ctx, "upload")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(buf.String())), &entry))
+	fields := entry["fields"].(map[string]interface{})
+	summary := func(b string) map[string]interface{} {
+		s := SummarizeBlob([]byte(b))
+		return map[string]interface{}{"len": float64(s.Len), "sha256": s.SHA256}
+	}
+	require.Equal(t, summary(big), fields["big"])
+	require.Equal(t, summary(image), fields["image"])
+	require.Equal(t, summary("tiny"), fields["raw"])
+	require.Equal(t, summary("bad \xff utf8"), fields["bin"])
+	require.Equal(t, strings.Repeat("x", 32), fields["small"])
+	require.Equal(t, float64(7), fields["count"])
+
+	sum := SummarizeBlob([]byte("abc"))
+	require.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", sum.SHA256)
+	require.Equal(t, "len=3 sha256="+sum.SHA256, sum.String())
+	require.True(t, isBase64Blob(image))
+	require.False(t, isBase64Blob(strings.Repeat("word ", 60)))
+
+	l.SetBlobRules(nil)
+	require.Nil(t, l.BlobRules())
+}
+
+func TestSinkRateLimit(t *testing.T) {
+	saas, std := &syncBuffer{}, &syncBuffer{}
+	overflow := filepath.Join(t.TempDir(), "overflow.log")
+	l := NewDetachedLogger(Config{
+		MinLevel:    INFO,
+		JSON:        true,
+		Stdout:      std,
+		Stderr:      std,
+		Writers:     []io.Writer{saas},
+		WriterNames: []string{"saas"},
+		SinkRates: map[string]SinkRateLimit{
+			"saas": {PerSecond: 0.01, Burst: 2, Overflow: RotationConfig{Filename: overflow}},
+		},
+	})
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.InfoSync(ctx, "entry %d", i))
+	}
+	require.Equal(t, SinkRateStats{Passed: 2, Diverted: 3}, l.SinkRateStats()["saas"])
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	require.Equal(t, 5, strings.Count(std.String(), "\n"), "other sinks are not limited")
+	lines := strings.Split(strings.TrimSpace(saas.String()), "\n")
+	require.Len(t, lines, 3, "two entries within the burst and one marker")
+	var marker map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &marker))
+	require.Equal(t, "WARN", marker["level"])
+	require.Contains(t, marker["message"], "1 entries diverted")
+	data, err := os.ReadFile(overflow)
+	require.NoError(t, err)
+	require.Equal(t, 3, strings.Count(string(data), "\n"))
+	require.Contains(t, string(data), "entry 4")
+
+	l2 := NewDetachedLogger(Config{MinLevel: INFO, Stdout: std, Stderr: std})
+	l2.SetSinkRateLimit("stdout", &SinkRateLimit{PerSecond: 0.01, Burst: 1})
+	l2.Info(ctx, "a")
+	l2.Info(ctx, "b")
+	require.NoError(t, CloseDetached(l2, 2*time.Second))
+	require.Equal(t, SinkRateStats{Passed: 1, Dropped: 1}, l2.SinkRateStats()["stdout"])
+	l2.SetSinkRateLimit("stdout", nil)
+	require.Nil(t, l2.SinkRateStats())
+}
+
+func TestPriorityQueue(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewDetachedLogger(Config{
+		MinLevel:      DEBUG,
+		Stdout:        w,
+		Stderr:        w,
+		Workers:       1,
+		Buffer:        4,
+		NonBlocking:   true,
+		PriorityQueue: PriorityQueueConfig{Enable: true, Buffer: 2},
+	})
+	ctx := context.Background()
+	l.Info(ctx, "first")
+	require.Eventually(t, func() bool { return l.queueLen() == 0 }, time.Second, time.Millisecond)
+	for i := 1; i <= 5; i++ {
+		l.Info(ctx, "info %d", i)
+	}
+	l.Warn(ctx, "warn 1")
+	l.Error(ctx, "error 1")
+	l.Error(ctx, "error 2")
+	dropped, _, _, _, _, queued, _, _ := StatsDetached(l)
+	require.Equal(t, int64(2), dropped, "one INFO over the main queue, one ERROR over the priority queue")
+	require.Equal(t, 6, queued)
+
+	w.unblock()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(w.buf.String()), "\n") {
+		msgs = append(msgs, line[strings.LastIndex(line, ") ")+2:])
+	}
+	require.Equal(t, []string{"first", "warn 1", "error 1", "info 1", "info 2", "info 3", "info 4"}, msgs)
+}
+
+func TestStructuredFieldAPI(t *testing.T) {
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{MinLevel: DEBUG, JSON: true, Stdout: buf, Stderr: buf, Workers: 1})
+	ctx := WithAttrs(context.Background(), Fields{"tenant": "acme", "order_id": "from-ctx"})
+
+	l.InfoW(ctx, "100% literal", Fields{"a": 1})
+	base := l.With(Fields{"order_id": "o-1", "step": "charge"})
+	base.With(Fields{"step": "refund"}).Warn(ctx, "step %d", 2)
+	base.ErrorW(ctx, "failed", Fields{"step": "capture", FieldError: "declined"})
+	lw := base.WithContext(ctx).With(Fields{"attempt": 3})
+	lw.Info("retrying")
+	require.NoError(t, lw.InfoSync("synced"))
+	b := lw.Buffered()
+	b.Debug("buffered")
+	b.Commit()
+	GetLogger(WithLogger(ctx, l)).With(Fields{"x": true}).DebugW("plain", nil)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	got := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		got[m["message"].(string)] = m["fields"].(map[string]interface{})
+	}
+	require.Equal(t, map[string]interface{}{"tenant": "acme", "order_id": "from-ctx", "a": float64(1)}, got["100% literal"])
+	require.Equal(t, "refund", got["step 2"]["step"])
+	require.Equal(t, "o-1", got["step 2"]["order_id"], "bound fields override context attributes")
+	require.Equal(t, "capture", got["failed"]["step"], "call-site fields override bound fields")
+	require.Equal(t, "declined", got["failed"][FieldError])
+	for _, msg := range []string{"retrying", "synced", "buffered"} {
+		require.Equal(t, float64(3), got[msg]["attempt"], msg)
+		require.Equal(t, "charge", got[msg]["step"], msg)
+		require.Equal(t, "acme", got[msg]["tenant"], msg)
+	}
+	require.Equal(t, true, got["plain"]["x"])
+
+	_, ok := base.fields["attempt"]
+	require.False(t, ok, "With does not modify the parent's fields")
+}
+
+func TestOTLPExport(t *testing.T) {
+	type request struct {
+		header http.Header
+		path   string
+		body   []byte
+		proto  int
+	}
+	var mu sync.Mutex
+	var requests []request
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, request{r.Header.Clone(), r.URL.Path, body, r.ProtoMajor})
+		mu.Unlock()
+		if r.Header.Get("Content-Type") == "application/grpc" {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Trailer", "Grpc-Status")
+			w.WriteHeader(http.StatusOK)
+			w.Header().Set("Grpc-Status", r.Header.Get("X-Want-Status"))
+		}
+	})
+	take := func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		out := requests
+		requests = nil
+		return out
+	}
+	ctx := WithAttrs(WithTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736"),
+		Fields{FieldSpanID: "00f067aa0ba902b7", "count": 3})
+	ctx = context.WithValue(ctx, ctxModuleKey, "billing")
+
+	// OTLP/HTTP with JSON, split by MaxBatch.
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	l := NewDetachedLogger(Config{
+		MinLevel: DEBUG,
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Batch:    BatchConfig{Size: 3, MaxWait: time.Second},
+		OTLP: &OTLPConfig{
+			Endpoint: srv.URL,
+			Protocol: OTLPHTTPJSON,
+			Headers:  map[string]string{"X-Api-Key": "k"},
+			MaxBatch: 2,
+			Resource: Fields{"service.name": "checkout"},
+		},
+	})
+	l.Info(ctx, "hello")
+	l.ErrorW(ctx, "failed", Fields{"ok": false, "ratio": 0.5})
+	l.Warn(context.Background(), "no trace")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	reqs := take()
+	require.Len(t, reqs, 2)
+	require.Equal(t, "/v1/logs", reqs[0].path)
+	require.Equal(t, "application/json", reqs[0].header.Get("Content-Type"))
+	require.Equal(t, "k", reqs[0].header.Get("X-Api-Key"))
+	var payload struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	require.NoError(t, json.Unmarshal(reqs[0].body, &payload))
+	rl := payload.ResourceLogs[0]
+	require.Equal(t, map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "checkout"}}, rl.Resource.Attributes[0])
+	recs := rl.ScopeLogs[0].LogRecords
+	require.Len(t, recs, 2)
+	require.Equal(t, float64(9), recs[0]["severityNumber"])
+	require.Equal(t, map[string]interface{}{"stringValue": "hello"}, recs[0]["body"])
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recs[0]["traceId"])
+	require.Equal(t, "00f067aa0ba902b7", recs[0]["spanId"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"key": "module", "value": map[string]interface{}{"stringValue": "billing"}},
+		map[string]interface{}{"key": "count", "value": map[string]interface{}{"intValue": "3"}},
+	}, recs[0]["attributes"])
+	require.Equal(t, "ERROR", recs[1]["severityText"])
+	require.Contains(t, recs[1]["attributes"], map[string]interface{}{"key": "ratio", "value": map[string]interface{}{"doubleValue": 0.5}})
+	require.Equal(t, int64(3), l.WriterStats()[OTLPSinkName].EntriesWritten)
+
+	// OTLP/HTTP with protobuf.
+	l = NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard, OTLP: &OTLPConfig{Endpoint: srv.URL + "/custom"}})
+	l.Info(ctx, "hello proto")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	reqs = take()
+	require.Len(t, reqs, 1)
+	require.Equal(t, "/custom", reqs[0].path)
+	require.Equal(t, "application/x-protobuf", reqs[0].header.Get("Content-Type"))
+	require.Equal(t, byte(0x0a), reqs[0].body[0], "field 1 of ExportLogsServiceRequest")
+	require.True(t, bytes.Contains(reqs[0].body, []byte("hello proto")))
+	traceID, _ := hex.DecodeString("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.True(t, bytes.Contains(reqs[0].body, append([]byte{9<<3 | 2, 16}, traceID...)))
+
+	// OTLP/gRPC over TLS with HTTP/2, with a failing status.
+	tlsSrv := httptest.NewUnstartedServer(handler)
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsSrv.Certificate())
+	for _, status := range []string{"0", "14"} {
+		l = NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard, OTLP: &OTLPConfig{
+			Endpoint: tlsSrv.Listener.Addr().String(),
+			Protocol: OTLPGRPC,
+			TLS:      &tls.Config{RootCAs: pool},
+			Headers:  map[string]string{"X-Want-Status": status},
+		}})
+		l.Info(ctx, "hello grpc")
+		require.NoError(t, CloseDetached(l, 2*time.Second))
+		reqs = take()
+		require.Len(t, reqs, 1)
+		require.Equal(t, 2, reqs[0].proto)
+		require.Equal(t, otlpGRPCPath, reqs[0].path)
+		body := reqs[0].body
+		require.Equal(t, byte(0), body[0])
+		require.Equal(t, len(body)-5, int(binary.BigEndian.Uint32(body[1:5])))
+		ws := l.WriterStats()[OTLPSinkName]
+		if status == "0" {
+			require.Equal(t, int64(1), ws.EntriesWritten)
+		} else {
+			require.Equal(t, int64(1), ws.Errors)
+			require.ErrorContains(t, ws.LastError, "grpc status 14")
+		}
+	}
+
+	require.Error(t, l.SetOTLP(&OTLPConfig{}))
+	require.Error(t, l.SetOTLP(&OTLPConfig{Endpoint: "ftp://collector"}))
+}
+
+func TestSelfTest(t *testing.T) {
+	var out syncBuffer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	l := NewDetachedLogger(Config{
+		MinLevel: DEBUG,
+		JSON:     true,
+		Stdout:   &out,
+		Stderr:   io.Discard,
+		OTLP:     &OTLPConfig{Endpoint: srv.URL},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	l.AddExtraWriter("broken", failingWriter{})
+	unreachable := NewAckSink(AckSinkConfig{Network: "tcp", Address: "127.0.0.1:1", CloseTimeout: 10 * time.Millisecond})
+	defer unreachable.Close()
+	l.AddExtraWriter("ack", unreachable)
+
+	checks := l.SelfTest(context.Background())
+	byName := map[string]SinkCheck{}
+	var names []string
+	for _, c := range checks {
+		names = append(names, c.Sink)
+		byName[c.Sink] = c
+	}
+	require.Equal(t, []string{"stdout", "stderr", OTLPSinkName, "broken", "ack"}, names)
+	require.True(t, byName["stdout"].OK)
+	require.True(t, byName["stderr"].OK)
+	require.True(t, byName[OTLPSinkName].OK, "%v", byName[OTLPSinkName].Err)
+	require.False(t, byName["broken"].OK)
+	require.EqualError(t, byName["broken"].Err, "sink unavailable")
+	require.False(t, byName["ack"].OK)
+	require.Error(t, byName["ack"].Err)
+
+	// The probe is a regular entry in the output format.
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(out.String())), &entry))
+	require.Equal(t, SelfTestMessage, entry["message"])
+	require.NotContains(t, l.WriterStats(), "stdout")
+
+	// Checks still running when the context is done fail with its error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, c := range l.SelfTest(ctx) {
+		require.ErrorIs(t, c.Err, context.Canceled)
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	ctx := context.Background()
+	l := NewDetachedLogger(Config{
+		Stdout: io.Discard,
+		Stderr: io.Discard,
+		Health: HealthConfig{SinkFailures: 3},
+	})
+	require.NoError(t, l.Healthy())
+	l.AddExtraWriter("broken", failingWriter{})
+	for i := 0; i < 2; i++ {
+		require.Error(t, l.InfoSync(ctx, "entry %d", i))
+	}
+	require.NoError(t, l.Healthy(), "two failures are below the threshold")
+	require.Error(t, l.InfoSync(ctx, "entry 2"))
+	err := l.Healthy()
+	require.ErrorIs(t, err, ErrSinkFailing)
+	require.Contains(t, err.Error(), "broken: 3 consecutive failures: sink unavailable")
+
+	rec := httptest.NewRecorder()
+	l.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	l.SetHealthConfig(HealthConfig{IgnoreSinks: []string{"broken"}})
+	require.NoError(t, l.Healthy())
+	rec = httptest.NewRecorder()
+	l.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ok\n", rec.Body.String())
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.ErrorIs(t, l.Healthy(), ErrLoggerClosed)
+
+	// A saturated queue makes the logger unhealthy until it drains.
+	bw := newBlockingWriter()
+	sat := NewDetachedLogger(Config{Buffer: 4, Workers: 1, Stdout: bw, Stderr: bw,
+		Batch: BatchConfig{Size: 1, MaxWait: time.Second}})
+	defer CloseDetached(sat, 2*time.Second)
+	for i := 0; i < 5; i++ { // One entry is held by the blocked worker.
+		sat.Info(ctx, "entry %d", i)
+	}
+	require.Eventually(t, func() bool { return errors.Is(sat.Healthy(), ErrQueueSaturated) }, time.Second, 5*time.Millisecond)
+	bw.unblock()
+	require.Eventually(t, func() bool { return sat.Healthy() == nil }, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestSyslogSink(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxModuleKey, "billing")
+
+	// RFC 5424 over UDP, one message per datagram.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	l := NewDetachedLogger(Config{
+		MinLevel: DEBUG,
+		JSON:     true,
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Syslog: &SyslogConfig{Network: "udp", Address: pc.LocalAddr().String(),
+			Facility: SyslogLocal0, Tag: "my app", Hostname: "host1"},
+	})
+	require.NoError(t, l.InfoSync(ctx, "hello"))
+	require.NoError(t, l.ErrorSync(context.Background(), "failed"))
+	var got []string
+	buf := make([]byte, 4096)
+	for len(got) < 2 {
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(2*time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		got = append(got, string(buf[:n]))
+	}
+	pid := strconv.Itoa(os.Getpid())
+	require.Regexp(t, `^<134>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z host1 myapp `+pid+` billing - \{.*"message":"hello".*\}$`, got[0])
+	require.Regexp(t, `^<131>1 \S+ host1 myapp `+pid+` - - \{.*"message":"failed".*\}$`, got[1])
+	require.EqualValues(t, 2, l.WriterStats()[SyslogSinkName].EntriesWritten)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	// RFC 3164 over TCP, newline-framed.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	lines := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	l = NewDetachedLogger(Config{
+		MinLevel: DEBUG,
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Syslog:   &SyslogConfig{Network: "tcp", Address: ln.Addr().String(), Tag: "app", Hostname: "host1", Format: SyslogRFC3164},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	l.Debug(ctx, "one")
+	l.Warn(ctx, "two")
+	for _, want := range []string{`^<15>\w{3} [ \d]\d \d\d:\d\d:\d\d host1 app\[` + pid + `\]: .*one$`, `^<12>\S+ +\S+ \S+ host1 app\[` + pid + `\]: .*two$`} {
+		select {
+		case line := <-lines:
+			require.Regexp(t, want, line)
+		case <-time.After(2 * time.Second):
+			t.Fatal("syslog message not received")
+		}
+	}
+
+	_, err = newSyslogSink(SyslogConfig{Network: "tcp"})
+	require.Error(t, err)
+	_, err = newSyslogSink(SyslogConfig{Network: "http", Address: "x"})
+	require.Error(t, err)
+}
+
+func TestNilContextAndDefaults(t *testing.T) {
+	var buf syncBuffer
+	l := NewDetachedLogger(Config{MinLevel: DEBUG, JSON: true, Stdout: &buf, Stderr: &buf})
+	defer CloseDetached(l, 2*time.Second)
+	require.Same(t, l, l.WithDefaults("billing", Fields{"region": "eu", "tier": "gold"}))
+	module, attrs := l.Defaults()
+	require.Equal(t, "billing", module)
+	require.Equal(t, Fields{"region": "eu", "tier": "gold"}, attrs)
+
+	var nilCtx context.Context
+	require.NotPanics(t, func() {
+		require.NoError(t, l.InfoSync(nilCtx, "no context"))
+		l.WithContext(nilCtx).Info("bound nil context")
+		l.Buffered(nilCtx).Commit()
+		_ = WithAttrs(WithFlowID(WithTraceID(nilCtx, "t"), "f"), Fields{"a": 1})
+		_, ok := LoggerFromContext(nilCtx)
+		require.False(t, ok)
+	})
+	require.NoError(t, l.InfoSync(WithAttrs(context.WithValue(context.Background(), ctxModuleKey, "api"),
+		Fields{"tier": "silver"}), "with context"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	entries := map[string]map[string]interface{}{}
+	for _, line := range lines {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		entries[m["message"].(string)] = m
+	}
+	require.Equal(t, "billing", entries["no context"]["module"])
+	require.Equal(t, "billing", entries["bound nil context"]["module"])
+	require.Equal(t, "api", entries["with context"]["module"])
+	fields := entries["with context"]["fields"].(map[string]interface{})
+	require.Equal(t, "eu", fields["region"])
+	require.Equal(t, "silver", fields["tier"])
+
+	// GetLogger completes contexts with the default module instead of "unknown".
+	ctx := GetLogger(WithLogger(nilCtx, l)).Context()
+	require.Equal(t, "billing", ctx.Value(ctxModuleKey))
+
+	l.WithDefaults("", nil)
+	module, attrs = l.Defaults()
+	require.Empty(t, module)
+	require.Nil(t, attrs)
+	require.Equal(t, "unknown", GetLogger(WithLogger(context.Background(), l)).Context().Value(ctxModuleKey))
+}
+
+// closeCounter is a syncBuffer that counts calls to Close.
+type closeCounter struct {
+	syncBuffer
+	closes atomic.Int32
+}
+
+func (c *closeCounter) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+func TestNewDetachedLoggerFrom(t *testing.T) {
+	ctx := context.Background()
+	stdout, extra := &closeCounter{}, &closeCounter{}
+	var hooked atomic.Int32
+	parent := NewDetachedLogger(Config{
+		MinLevel:        WARN,
+		JSON:            true,
+		Stdout:          stdout,
+		Stderr:          stdout,
+		Writers:         []io.Writer{extra},
+		WriterNames:     []string{"audit"},
+		RegexPatternMap: map[string]string{`secret-\d+`: "[MASKED]"},
+		Hooks:           []HookFunc{func(HookEvent) error { hooked.Add(1); return nil }},
+	}).WithDefaults("app", Fields{"region": "eu"})
+	defer CloseDetached(parent, 2*time.Second)
+
+	var own syncBuffer
+	child := NewDetachedLoggerFrom(parent, Config{Buffer: 8, Writers: []io.Writer{&own}, WriterNames: []string{"lib"}})
+	require.Equal(t, 8, child.BufferSize())
+	child.Info(ctx, "below the inherited level")
+	require.NoError(t, child.WarnSync(ctx, "token secret-42"))
+	require.NoError(t, CloseDetached(child, 2*time.Second))
+
+	// The child wrote masked JSON with the defaults of parent to the sinks of both, and
+	// ran the hooks of parent; closing it left the shared sinks open.
+	for _, out := range []string{stdout.String(), extra.String(), own.String()} {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(out)), &entry), out)
+		require.Equal(t, "token [MASKED]", entry["message"])
+		require.Equal(t, "app", entry["module"])
+	}
+	require.EqualValues(t, 1, hooked.Load())
+	require.Zero(t, stdout.closes.Load())
+	require.Zero(t, extra.closes.Load())
+
+	// Overrides replace the inherited settings.
+	var text syncBuffer
+	child = NewDetachedLoggerFrom(parent, Config{MinLevel: ERROR, Formatter: &TextFormatter{}, Stdout: &text, Stderr: &text})
+	child.Warn(ctx, "below the overridden level")
+	require.NoError(t, child.ErrorSync(ctx, "plain"))
+	require.NoError(t, CloseDetached(child, 2*time.Second))
+	require.NotContains(t, text.String(), "below")
+	require.Contains(t, text.String(), "plain")
+	require.False(t, json.Valid([]byte(strings.TrimSpace(text.String()))))
+}
+
+func TestRateSampling(t *testing.T) {
+	var buf syncBuffer
+	l := NewDetachedLogger(Config{
+		MinLevel: DEBUG,
+		Stdout:   &buf,
+		Stderr:   &buf,
+		Sampling: &SamplingConfig{Initial: 2, Thereafter: 3, Tick: time.Hour},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	a := context.WithValue(context.Background(), ctxModuleKey, "a")
+	b := context.WithValue(context.Background(), ctxModuleKey, "b")
+	for i := 1; i <= 10; i++ {
+		l.Info(a, "a info %d", i)
+		l.Warn(a, "a warn %d", i)
+	}
+	l.Info(b, "b info")
+	require.NoError(t, l.InfoSync(b, "barrier"), "synchronous calls are not sampled")
+
+	out := buf.String()
+	for _, i := range []int{1, 2, 5, 8} {
+		require.Contains(t, out, fmt.Sprintf("a info %d\n", i))
+	}
+	for _, i := range []int{3, 4, 6, 7, 9, 10} {
+		require.NotContains(t, out, fmt.Sprintf("a info %d\n", i))
+	}
+	require.Equal(t, 10, strings.Count(out, "a warn"), "levels above MaxLevel are not sampled")
+	require.Contains(t, out, "b info")
+	require.Equal(t, SamplingStats{Passed: 5, SampledOut: 6, ByModule: map[string]int64{"a": 6}}, l.SamplingStats())
+	require.Equal(t, l.SamplingStats(), l.Snapshot().Sampling)
+
+	// With ByMessage, distinct templates have their own counters; a new tick restarts them.
+	l.SetSampling(&SamplingConfig{Initial: 1, ByMessage: true, Tick: 50 * time.Millisecond})
+	l.Info(a, "x")
+	l.Info(a, "y")
+	l.Info(a, "x")
+	require.EqualValues(t, 1, l.SamplingStats().SampledOut)
+	time.Sleep(60 * time.Millisecond)
+	l.Info(a, "x")
+	require.EqualValues(t, 3, l.SamplingStats().Passed)
+
+	l.SetSampling(nil)
+	require.Nil(t, l.GetSampling())
+	require.Equal(t, SamplingStats{}, l.SamplingStats())
+}
+
+func TestDelegate(t *testing.T) {
+	ctx := context.Background()
+	var app syncBuffer
+	parent := NewDetachedLogger(Config{MinLevel: INFO, Stdout: &app, Stderr: &app})
+	defer CloseDetached(parent, 2*time.Second)
+
+	// The child writes every entry to its own debug file and forwards it to parent, which
+	// applies its own level.
+	var debug syncBuffer
+	child := NewDetachedLogger(Config{MinLevel: DEBUG, Delegate: parent, Writers: []io.Writer{&debug}})
+	child.Debug(ctx, "debug detail")
+	b := child.Buffered(ctx)
+	b.Debug("buffered detail")
+	b.Info("buffered info")
+	b.Commit()
+	require.NoError(t, child.InfoSync(ctx, "info"))
+	require.NoError(t, CloseDetached(child, 2*time.Second))
+	require.NoError(t, parent.InfoSync(ctx, "barrier"))
+
+	require.Contains(t, debug.String(), "debug detail")
+	require.Contains(t, debug.String(), "buffered detail")
+	require.Contains(t, debug.String(), "] () info\n")
+	require.NotContains(t, app.String(), "detail")
+	require.Contains(t, app.String(), "buffered info")
+	require.Contains(t, app.String(), "] () info\n")
+	require.Equal(t, 1, strings.Count(app.String(), "] () info\n"), "the child does not default to stdout")
+
+	// DelegateGlobal follows the global logger; derived loggers inherit no sink then.
+	var global syncBuffer
+	_, err := ReinitGlobalLogger(Config{Stdout: &global, Stderr: &global}, time.Second)
+	require.NoError(t, err)
+	var own syncBuffer
+	child = NewDetachedLoggerFrom(parent, Config{DelegateGlobal: true, Writers: []io.Writer{&own}})
+	defer CloseDetached(child, 2*time.Second)
+	require.NoError(t, child.WarnSync(ctx, "to global"))
+	require.Contains(t, global.String(), "to global")
+	require.Contains(t, own.String(), "to global")
+	require.NotContains(t, app.String(), "to global")
+}
+
+func TestConfigFileHotReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logger.yaml")
+	write := func(doc string, n int) {
+		require.NoError(t, os.WriteFile(path, []byte(doc), 0o600))
+		mtime := time.Now().Add(time.Duration(n) * time.Second)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	write("min_level: warn\nbatch:\n  size: 4\n  max_wait: 50ms\nregex_patterns:\n  'secret-\\d+': '[MASKED]'\n", 0)
+
+	cfg, err := LoadConfigFromFile(path)
+	require.NoError(t, err)
+	require.Equal(t, WARN, cfg.MinLevel)
+	require.Equal(t, BatchConfig{Size: 4, MaxWait: 50 * time.Millisecond}, cfg.Batch)
+	require.Equal(t, []string{path}, cfg.ConfigSources)
+	_, err = LoadConfigFromFile(filepath.Join(dir, "missing.json"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	var out syncBuffer
+	base := Config{Stdout: &out, Stderr: &out}
+	cfg, err = loadConfigFile(path, base)
+	require.NoError(t, err)
+	_, err = ReinitGlobalLogger(cfg, time.Second)
+	require.NoError(t, err)
+
+	type reload struct {
+		cfg    Config
+		reinit bool
+	}
+	reloads, errs := make(chan reload, 4), make(chan error, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchConfigFile(ctx, path, ConfigFileWatch{
+			Base:     base,
+			Interval: 5 * time.Millisecond,
+			OnReload: func(cfg Config, reinit bool) { reloads <- reload{cfg, reinit} },
+			OnError:  func(err error) { errs <- err },
+		})
+	}()
+	// Let the watcher read the initial version before it changes.
+	time.Sleep(20 * time.Millisecond)
+
+	// A level change is applied to the running logger; other runtime changes are kept.
+	GlobalLogger().SetJSONFieldRules([]MaskFieldRule{{Keys: []string{"pin"}, Replacement: "***"}})
+	write("min_level: debug\nbatch:\n  size: 4\n  max_wait: 50ms\nregex_patterns:\n  'secret-\\d+': '[MASKED]'\n", 1)
+	r := <-reloads
+	require.False(t, r.reinit)
+	require.Equal(t, DEBUG, GlobalLogger().GetDynamicConfig().MinLevel)
+	require.Len(t, GlobalLogger().GetDynamicConfig().JSONFieldRules, 1)
+
+	// An invalid version is reported and leaves the configuration in effect.
+	write("min_level: loud\n", 2)
+	require.ErrorContains(t, <-errs, "reload config file")
+	require.Equal(t, DEBUG, GlobalLogger().GetDynamicConfig().MinLevel)
+
+	// A version that cannot be applied does not become the baseline: its valid changes
+	// are applied with the next version.
+	write("min_level: warn\ntimezone: Mars/Olympus\n", 3)
+	require.ErrorContains(t, <-errs, "invalid timezone")
+	require.Equal(t, DEBUG, GlobalLogger().GetDynamicConfig().MinLevel, "none of the changes is applied")
+	write("min_level: warn\n", 4)
+	<-reloads
+	require.Equal(t, WARN, GlobalLogger().GetDynamicConfig().MinLevel)
+
+	// Switching to non-blocking mode reinitializes the global logger.
+	prev := GlobalLogger()
+	write("min_level: info\nnon_blocking: true\n", 5)
+	r = <-reloads
+	require.True(t, r.reinit)
+	require.NotSame(t, prev, GlobalLogger())
+	require.Equal(t, INFO, GlobalLogger().GetDynamicConfig().MinLevel)
+	require.NoError(t, GlobalLogger().InfoSync(context.Background(), "token secret-1"))
+	require.Contains(t, out.String(), "token secret-1", "the patterns removed from the file no longer apply")
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Error(t, WatchConfigFile(context.Background(), filepath.Join(dir, "missing.yaml"), ConfigFileWatch{}))
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []LifecycleEvent
+	record := func(ev LifecycleEvent) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	}
+	l := NewDetachedLogger(Config{
+		Stdout:             io.Discard,
+		Stderr:             io.Discard,
+		Retry:              RetryPolicy{MaxRetries: 0},
+		LifecycleListeners: []LifecycleListener{record},
+	})
+	l.SetHealthConfig(HealthConfig{SinkFailures: 2})
+
+	// Listeners may inspect the logger: setters release their locks first.
+	remove := l.OnLifecycle(func(ev LifecycleEvent) {
+		if ev.Type == LifecycleConfigChanged {
+			_ = l.GetDynamicConfig()
+		}
+	})
+	l.SetMinLevel(WARN)
+	remove()
+	require.False(t, l.RemoveRegexRule("missing"), "no-op changes emit nothing")
+	require.NoError(t, l.AddRegexRule("card", `\d{16}`, "[CARD]"))
+	l.AddExtraWriter("broken", failingWriter{})
+	for i := 0; i < 3; i++ {
+		require.Error(t, l.ErrorSync(context.Background(), "boom"))
+	}
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, ev := range events {
+		require.False(t, ev.Time.IsZero())
+		got = append(got, string(ev.Type)+":"+ev.Setting+ev.Sink)
+	}
+	require.Equal(t, []string{
+		"started:", "config-changed:min_level", "config-changed:regex_rules", "config-changed:outputs",
+		"sink-added:broken",
+		"sink-quarantined:broken", "closing:", "closed:",
+	}, got)
+	require.ErrorContains(t, events[5].Err, "sink unavailable")
+}
+
+func TestConfigChangeAudit(t *testing.T) {
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard, MinLevel: INFO})
+	defer CloseDetached(l, 2*time.Second)
+	require.Empty(t, l.ConfigChanges(), "the initial configuration is not recorded")
+
+	var actors []string
+	l.OnLifecycle(func(ev LifecycleEvent) {
+		if ev.Type == LifecycleConfigChanged {
+			actors = append(actors, ev.Actor)
+		}
+	})
+	alice := l.Configure("alice")
+	alice.SetMinLevel(WARN)
+	// A change made meanwhile without the editor is not attributed to alice.
+	l.SetHooks([]HookFunc{func(HookEvent) error { return nil }})
+	require.NoError(t, alice.AddRegexRule("card", `\d{16}`, "[CARD]"))
+	l.SetOutputs(nil, nil, []io.Writer{&syncBuffer{}}, []string{"audit"})
+	require.False(t, l.RemoveFieldRule("missing"))
+
+	changes := l.ConfigChanges()
+	require.Len(t, changes, 4)
+	require.Equal(t, ConfigChange{Time: changes[0].Time, Setting: "min_level", Old: "INFO", New: "WARN", Actor: "alice"}, changes[0])
+	require.Equal(t, []string{"hooks", "0 hooks", "1 hooks", ""}, []string{changes[1].Setting, changes[1].Old, changes[1].New, changes[1].Actor})
+	require.Equal(t, []string{"[]", "[card]", "alice"}, []string{changes[2].Old, changes[2].New, changes[2].Actor})
+	require.Equal(t, "outputs", changes[3].Setting)
+	require.Contains(t, changes[3].New, "audit=*unologger.syncBuffer")
+	require.Equal(t, []string{"alice", "", "alice", ""}, actors)
+	require.Len(t, l.debugSnapshot().ConfigChanges, 4)
+
+	// Only the most recent changes are kept.
+	for i := 0; i < configAuditSize; i++ {
+		l.SetEnableOTEL(i%2 == 0)
+	}
+	changes = l.ConfigChanges()
+	require.Len(t, changes, configAuditSize)
+	require.Equal(t, "otel", changes[0].Setting)
+	require.Equal(t, "false", changes[len(changes)-1].New)
+}
+
+func TestConfigChangeAuditCoversPipelineSettings(t *testing.T) {
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard, MinLevel: INFO, Buffer: 8, Workers: 1})
+	defer CloseDetached(l, 2*time.Second)
+
+	ops := l.Configure("ops")
+	ops.SetWorkers(2)
+	require.NoError(t, ops.SetBufferSize(16))
+	ops.SetHookConfig(HookConfig{Workers: 2, Queue: 8})
+	ops.SetSampling(&SamplingConfig{Initial: 10})
+	ops.SetSampling(nil)
+	require.NoError(t, l.Configure("remote-config").ApplyRemoteConfig(
+		[]byte(`{"budget":{"daily_bytes":1024},"quota":{"default":2048}}`)))
+
+	var got []string
+	for _, c := range l.ConfigChanges() {
+		got = append(got, c.Actor+":"+c.Setting)
+	}
+	require.Equal(t, []string{
+		"ops:workers", "ops:buffer_size", "ops:hook_config", "ops:sampling", "ops:sampling",
+		"remote-config:budget", "remote-config:quota",
+	}, got)
+	changes := l.ConfigChanges()
+	require.Equal(t, []string{"1", "2"}, []string{changes[0].Old, changes[0].New})
+	require.Equal(t, []string{"8", "16"}, []string{changes[1].Old, changes[1].New})
+	require.Equal(t, "off", changes[4].New)
+	require.Contains(t, changes[5].New, "DailyBytes:1024")
+}
+
+func TestDynamicConfigSnapshot(t *testing.T) {
+	l := NewDetachedLogger(Config{
+		Stdout: io.Discard, Stderr: io.Discard, MinLevel: WARN, Timezone: "UTC",
+		Hook:  HookConfig{Async: true, Workers: 2, Queue: 16},
+		Hooks: []HookFunc{func(HookEvent) error { return nil }},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	l.SetHealthConfig(HealthConfig{SinkFailures: 2})
+	l.AddExtraWriter("audit", &syncBuffer{})
+	l.AddExtraWriter("broken", failingWriter{})
+	l.SetEnableOTEL(true)
+	l.SetFormatter(&JSONFormatter{})
+	for i := 0; i < 2; i++ {
+		require.Error(t, l.ErrorSync(context.Background(), "boom"))
+	}
+
+	s := l.GetDynamicConfigSnapshot()
+	require.Equal(t, WARN, s.MinLevel)
+	require.Len(t, s.Hooks, 1)
+	require.Equal(t, "*unologger.JSONFormatter", s.Formatter)
+	require.Equal(t, "UTC", s.Timezone)
+	require.True(t, s.EnableOTel)
+	require.Equal(t, 2, s.HookConfig.Workers)
+	var names []string
+	for _, sk := range s.Sinks {
+		names = append(names, sk.Name+"="+sk.Type)
+	}
+	require.Equal(t, []string{"stdout=io.discard", "stderr=io.discard", "audit=*unologger.syncBuffer",
+		"broken=unologger.failingWriter"}, names)
+	require.False(t, s.Sinks[2].Quarantined)
+	require.EqualValues(t, 2, s.Sinks[2].Stats.EntriesWritten)
+	require.True(t, s.Sinks[3].Quarantined)
+	require.ErrorContains(t, s.Sinks[3].Stats.LastError, "sink unavailable")
+
+	// The snapshot is a copy.
+	s.RegexRules = append(s.RegexRules, MaskRuleRegex{Name: "x"})
+	require.Empty(t, l.GetDynamicConfigSnapshot().RegexRules)
+}
+
+func TestFilteredHooks(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	record := func(name string) HookFunc2 {
+		return func(_ context.Context, ev HookEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, name+":"+ev.Message)
+			return nil
+		}
+	}
+	l := NewDetachedLogger(Config{
+		Stdout: io.Discard, Stderr: io.Discard,
+		FilteredHooks: []FilteredHook{{Hook: record("warn"), Filter: HookFilter{Name: "warn", MinLevel: WARN}}},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	l.AddHook(record("pay"), HookFilter{Name: "pay", MinLevel: ERROR, Modules: []string{"payment"}})
+	l.AddHook(record("match"), HookFilter{Match: func(ev HookEvent) bool { return strings.HasPrefix(ev.Message, "m") }})
+	l.AddHook(record("panic"), HookFilter{Name: "bad", Match: func(HookEvent) bool { panic("boom") }})
+
+	pay := context.WithValue(context.Background(), ctxModuleKey, "payment")
+	require.NoError(t, l.InfoSync(pay, "info"))
+	require.NoError(t, l.ErrorSync(pay, "error"))
+	require.NoError(t, l.ErrorSync(context.Background(), "other"))
+	require.NoError(t, l.WarnSync(context.Background(), "more"))
+	mu.Lock()
+	require.Equal(t, []string{"warn:error", "pay:error", "warn:other", "warn:more", "match:more"}, got)
+	got = nil
+	mu.Unlock()
+	require.Len(t, l.GetHookErrors(), 4)
+	require.ErrorIs(t, l.GetHookErrors()[0].Err, ErrHookPanic)
+
+	// A named hook replaces the hook of the same name, and can be removed.
+	l.AddHook(record("pay2"), HookFilter{Name: "pay", MinLevel: WARN, Modules: []string{"payment"}})
+	require.True(t, l.RemoveHook("bad"))
+	require.True(t, l.RemoveHook("warn"))
+	require.False(t, l.RemoveHook("warn"))
+	require.Len(t, l.GetDynamicConfig().FilteredHooks, 2)
+	require.NoError(t, l.WarnSync(pay, "warning"))
+	mu.Lock()
+	require.Equal(t, []string{"pay2:warning"}, got)
+	mu.Unlock()
+	require.Equal(t, "filtered_hooks", l.ConfigChanges()[0].Setting)
+}
+
+func TestNumericSeverity(t *testing.T) {
+	ev := HookEvent{Time: time.Unix(0, 0).UTC(), Level: ERROR, Module: "api", Message: "failed"}
+
+	b, err := (&TextFormatter{Severity: SeverityTextAndNumber}).Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z [ERROR] (api) severity=17 failed\n", string(b))
+	b, err = (&TextFormatter{Severity: SeverityNumber, SeverityScale: SeverityScaleSyslog}).Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z [3] (api) failed\n", string(b))
+
+	var m map[string]interface{}
+	b, err = (&JSONFormatter{Severity: SeverityTextAndNumber}).Format(ev)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &m))
+	require.Equal(t, "ERROR", m["level"])
+	require.EqualValues(t, 17, m["severity"])
+	b, err = (&JSONFormatter{Severity: SeverityNumber, SeverityScale: SeverityScaleSyslog}).Format(ev)
+	require.NoError(t, err)
+	m = nil
+	require.NoError(t, json.Unmarshal(b, &m))
+	require.NotContains(t, m, "level")
+	require.EqualValues(t, 3, m["severity"])
+
+	require.Equal(t, []int{5, 9, 13, 17, 21}, []int{SeverityScaleOTel.Severity(DEBUG), SeverityScaleOTel.Severity(INFO),
+		SeverityScaleOTel.Severity(WARN), SeverityScaleOTel.Severity(ERROR), SeverityScaleOTel.Severity(FATAL)})
+	require.Equal(t, 7, SeverityScaleSyslog.Severity(DEBUG))
+}
+
+func TestTimestampOptions(t *testing.T) {
+	// The second before the leap second of 2016 is formatted without any locale or
+	// leap-second adjustment.
+	ts := time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC)
+	hcm := timeSettings{loc: time.FixedZone("ICT", 7*3600), cfg: TimestampConfig{TZOffset: true}}
+	fields := Fields{}
+	require.Equal(t, "2017-01-01T06:59:59+07:00", hcm.stamp(ts, fields).Format(time.RFC3339))
+	require.Equal(t, Fields{TZOffsetKey: "+07:00"}, fields)
+
+	hcm.cfg = TimestampConfig{UTC: true}
+	fields = Fields{}
+	require.Equal(t, "2016-12-31T23:59:59Z", hcm.stamp(ts, fields).Format(time.RFC3339))
+	require.Equal(t, Fields{LocalTimeKey: "2017-01-01T06:59:59+07:00"}, fields)
+
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		Stdout: out, Stderr: out, JSON: true, Timezone: "Asia/Ho_Chi_Minh",
+		Timestamps: TimestampConfig{TZOffset: true, UTC: true},
+	})
+	defer CloseDetached(l, 2*time.Second)
+	require.NoError(t, l.InfoSync(context.Background(), "stamped"))
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &m))
+	require.True(t, strings.HasSuffix(m["time"].(string), "Z"))
+	fields = m["fields"].(map[string]interface{})
+	require.Equal(t, "+07:00", fields[TZOffsetKey])
+	require.True(t, strings.HasSuffix(fields[LocalTimeKey].(string), "+07:00"))
+
+	l.SetTimestampConfig(TimestampConfig{})
+	require.Equal(t, TimestampConfig{}, l.GetTimestampConfig())
+	out.Reset()
+	require.NoError(t, l.InfoSync(context.Background(), "plain"))
+	require.NotContains(t, out.String(), TZOffsetKey)
+	require.Contains(t, out.String(), "+07:00")
+	require.Equal(t, "timestamps", l.ConfigChanges()[0].Setting)
+}
+
+func TestNamedHooks(t *testing.T) {
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard})
+	defer CloseDetached(l, 2*time.Second)
+	var mu sync.Mutex
+	calls := map[string]int{}
+	count := func(name string) HookFunc2 {
+		return func(context.Context, HookEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			return nil
+		}
+	}
+	l.AddHook(count("metrics"), HookFilter{Name: "metrics"})
+	l.AddHook(count("tracing"), HookFilter{Name: "tracing"})
+	l.AddHook(count("anon"), HookFilter{})
+	l.SetHooks([]HookFunc{func(HookEvent) error { return nil }}) // Leaves the named hooks in place.
+	require.Equal(t, []string{"metrics", "tracing"}, l.ListHooks())
+
+	l.AddHook(count("metrics2"), HookFilter{Name: "metrics"})
+	require.Equal(t, []string{"tracing", "metrics"}, l.ListHooks())
+	require.True(t, l.RemoveHook("tracing"))
+	require.Equal(t, []string{"metrics"}, l.ListHooks())
+
+	require.NoError(t, l.InfoSync(context.Background(), "x"))
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, map[string]int{"metrics2": 1, "anon": 1}, calls)
+}
+
+func TestEntryTTL(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: out, EntryTTL: EntryTTLConfig{MaxAge: time.Second}})
+	defer CloseDetached(l, 2*time.Second)
+	entry := func(msg string, age time.Duration) *logEntry {
+		e := poolEntry.Get().(*logEntry)
+		e.lvl, e.ctx, e.t, e.tmpl = INFO, context.Background(), time.Now().Add(-age), msg
+		return e
+	}
+
+	l.processBatch([]*logEntry{entry("fresh", 0), entry("old", time.Minute)})
+	require.Contains(t, out.String(), "fresh")
+	require.NotContains(t, out.String(), "old")
+	require.Equal(t, StaleStats{Dropped: 1}, l.StaleStats())
+
+	// Synchronous calls are tagged rather than dropped.
+	waited := entry("waited", time.Minute)
+	waited.ack = make(chan error, 1)
+	l.processBatch([]*logEntry{waited})
+	require.Contains(t, out.String(), "waited")
+	require.Contains(t, out.String(), StaleKey+":true")
+
+	l.SetEntryTTL(EntryTTLConfig{MaxAge: time.Second, Tag: true})
+	out.Reset()
+	l.processBatch([]*logEntry{entry("late", 2*time.Minute)})
+	require.Contains(t, out.String(), "late")
+	require.Contains(t, out.String(), StaleAgeKey+":12")
+	require.Equal(t, StaleStats{Dropped: 1, Tagged: 2}, l.StaleStats())
+
+	l.SetEntryTTL(EntryTTLConfig{})
+	require.Equal(t, EntryTTLConfig{}, l.GetEntryTTL())
+	out.Reset()
+	l.processBatch([]*logEntry{entry("ancient", time.Hour)})
+	require.Contains(t, out.String(), "ancient")
+	require.NotContains(t, out.String(), StaleKey)
+}
+
+func TestPauseResume(t *testing.T) {
+	for _, direct := range []bool{false, true} {
+		out := &syncBuffer{}
+		l := NewDetachedLogger(Config{Stdout: out, Stderr: out, SingleWriter: direct, Pause: PauseConfig{MaxBuffered: 2}})
+		var events []LifecycleEventType
+		l.OnLifecycle(func(ev LifecycleEvent) { events = append(events, ev.Type) })
+		ctx := context.Background()
+
+		l.Info(ctx, "before")
+		l.Pause()
+		l.Pause()
+		require.True(t, l.Paused())
+		require.Contains(t, out.String(), "before", "Pause writes the queued entries")
+		l.Info(ctx, "held-1")
+		l.Info(ctx, "held-2")
+		l.Info(ctx, "overflow")
+		require.ErrorIs(t, l.InfoSync(ctx, "sync"), ErrLoggerPaused)
+		require.NotContains(t, out.String(), "held")
+
+		l.Resume()
+		require.False(t, l.Paused())
+		require.NoError(t, l.InfoSync(ctx, "after"))
+		s := out.String()
+		require.Less(t, strings.Index(s, "held-1"), strings.Index(s, "held-2"))
+		require.Less(t, strings.Index(s, "held-2"), strings.Index(s, "after"))
+		require.NotContains(t, s, "overflow")
+		dropped, _, _, _, _, _, _, _ := StatsDetached(l)
+		require.EqualValues(t, 1, dropped)
+
+		// Entries held when the logger is closed are written.
+		l.SetPauseConfig(PauseConfig{})
+		l.Pause()
+		l.Info(ctx, "held-at-close")
+		require.NoError(t, CloseDetached(l, 2*time.Second))
+		require.Contains(t, out.String(), "held-at-close")
+		require.Equal(t, []LifecycleEventType{LifecyclePaused, LifecycleResumed, LifecyclePaused, LifecycleResumed,
+			LifecycleClosing, LifecycleClosed}, events)
+	}
+
+	// The drop policy discards the entries logged while paused.
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: out, Pause: PauseConfig{Policy: PauseDrop}})
+	defer CloseDetached(l, 2*time.Second)
+	l.Pause()
+	l.Info(context.Background(), "dropped")
+	l.Resume()
+	require.NoError(t, l.InfoSync(context.Background(), "kept"))
+	require.NotContains(t, out.String(), "dropped")
+}
+
+func TestColorMode(t *testing.T) {
+	ev := HookEvent{Time: time.Unix(0, 0).UTC(), Level: ERROR, Message: "failed"}
+	b, err := (&TextFormatter{Color: ColorAlways}).Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z \x1b[31m[ERROR]\x1b[0m () failed\n", string(b))
+
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, ColorAuto.enabled())
+	require.True(t, ColorAlways.enabled(), "an explicit mode overrides NO_COLOR")
+	t.Setenv("NO_COLOR", "")
+	// The standard streams of the test binary are not terminals.
+	require.Equal(t, consoleIsTerminal(), ColorAuto.enabled())
+	require.False(t, ColorNever.enabled())
+}
+
+func TestLevelStyles(t *testing.T) {
+	f := &TextFormatter{Color: ColorAlways, HideModule: true, LevelStyles: map[Level]LevelStyle{
+		ERROR: {Label: "ERR", Color: "1;35", Emoji: "🔥"},
+		INFO:  {Emoji: "ℹ️"},
+	}}
+	ev := HookEvent{Time: time.Unix(0, 0).UTC(), Level: ERROR, Message: "failed"}
+	b, err := f.Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z 🔥 \x1b[1;35m[ERR]\x1b[0m failed\n", string(b))
+
+	ev.Level = INFO
+	b, err = f.Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z ℹ️ \x1b[36m[INFO]\x1b[0m failed\n", string(b))
+
+	f.Color, f.Severity = ColorNever, SeverityNumber
+	ev.Level = ERROR
+	b, err = f.Format(ev)
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z 🔥 [17] failed\n", string(b))
+}
+
+func TestErrorErr(t *testing.T) {
+	out := &syncBuffer{}
+	events := make(chan HookEvent, 2)
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: out, JSON: true,
+		Hooks: []HookFunc{func(ev HookEvent) error { events <- ev; return nil }}})
+	cause := fmt.Errorf("load config: %w", os.ErrNotExist)
+	l.ErrorErr(context.Background(), cause, "startup failed", Fields{"attempt": 2})
+	l.ErrorErr(context.Background(), nil, "no error", nil)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	ev := <-events
+	require.ErrorIs(t, ev.Error, os.ErrNotExist)
+	require.Equal(t, []string{"load config: file does not exist", "file does not exist"}, ev.ErrorChain)
+	require.Empty(t, ev.Stack, "stacks are captured with ErrorStacks only")
+	require.Nil(t, (<-events).Error)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	var entry struct {
+		Message string    `json:"message"`
+		Error   errorJSON `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "startup failed", entry.Message)
+	require.Equal(t, "load config: file does not exist", entry.Error.Message)
+	require.Equal(t, "*fmt.wrapError", entry.Error.Type)
+	require.Equal(t, []string{"file does not exist"}, entry.Error.Chain)
+	require.NotContains(t, lines[1], `"error"`)
+
+	joined := errors.Join(errors.New("a"), fmt.Errorf("b: %w", errors.New("c")))
+	require.Equal(t, []string{"a\nb: c", "a", "b: c", "c"}, ErrorChain(joined))
+	require.Nil(t, ErrorChain(nil))
+
+	f := &TextFormatter{HideModule: true}
+	b, err := f.Format(HookEvent{Time: time.Unix(0, 0).UTC(), Level: ERROR, Message: "failed", Error: cause, Stack: "main.main\n\tmain.go:10"})
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z [ERROR] error=\"load config: file does not exist\" error_type=*fmt.wrapError "+
+		"causes=[\"file does not exist\"] failed\nmain.main\n\tmain.go:10\n", string(b))
+}
+
+func TestDevNotifications(t *testing.T) {
+	var sent []string
+	n := &desktopNotifier{interval: time.Hour, send: func(title, text string) error {
+		sent = append(sent, title+": "+text)
+		return nil
+	}}
+	ctx := context.Background()
+	require.NoError(t, n.hook(ctx, HookEvent{Level: INFO, Message: "ignored"}))
+	require.NoError(t, n.hook(ctx, HookEvent{Level: ERROR, Module: "payment", Message: "charge failed",
+		Error: errors.New("timeout")}))
+	require.NoError(t, n.hook(ctx, HookEvent{Level: ERROR, Message: "suppressed"}))
+	require.NoError(t, n.hook(ctx, HookEvent{Level: FATAL, Message: "suppressed"}))
+	n.last = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, n.hook(ctx, HookEvent{Level: FATAL, Message: "crashed"}))
+	require.Equal(t, []string{"ERROR in payment: charge failed: timeout", "FATAL: crashed (+2 more)"}, sent)
+
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard})
+	defer CloseDetached(l, 2*time.Second)
+	t.Setenv(DevNotifyEnvVar, "")
+	require.False(t, l.EnableDevNotifications())
+	require.Empty(t, l.ListHooks())
+	t.Setenv(DevNotifyEnvVar, "true")
+	require.True(t, l.EnableDevNotifications())
+	require.Equal(t, []string{DevNotifyHookName}, l.ListHooks())
+	require.True(t, l.RemoveHook(DevNotifyHookName))
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	out := &syncBuffer{}
+	errs := &syncBuffer{}
+	events := make(chan HookEvent, 4)
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: errs, JSON: true,
+		Hooks: []HookFunc{func(ev HookEvent) error { events <- ev; return nil }}})
+	ctx := WithModule(WithLogger(context.Background(), l), "jobs").Context()
+
+	func() {
+		defer RecoverAndLog(ctx)
+		panic("boom")
+	}()
+	ev := <-events
+	require.Equal(t, ERROR, ev.Level)
+	require.Equal(t, "jobs", ev.Module)
+	require.Equal(t, "panic: boom", ev.Message)
+	var p *PanicError
+	require.ErrorAs(t, ev.Error, &p)
+	require.Equal(t, "boom", p.Value)
+	require.Contains(t, ev.Fields[FieldStack], "TestRecoverAndLog")
+
+	var handled *PanicError
+	require.PanicsWithValue(t, "again", func() {
+		defer RecoverAndLogWith(ctx, RecoverConfig{Fatal: true, Repanic: true, AllGoroutines: true,
+			OnPanic: func(_ context.Context, p *PanicError) { handled = p }})
+		panic("again")
+	})
+	require.Equal(t, "again", handled.Value)
+	require.Contains(t, errs.String(), `"message":"panic: again"`, "FATAL panics take the emergency path")
+	require.Contains(t, (<-events).Fields[FieldStack], "goroutine ")
+
+	func() {
+		defer RecoverAndLog(ctx)
+	}()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.Empty(t, events, "no panic, nothing logged")
+}
+
+func TestPanicHandler(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: out, JSON: true})
+	defer CloseDetached(l, 2*time.Second)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/boom", func(http.ResponseWriter, *http.Request) { panic("boom") })
+	mux.HandleFunc("/late", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	})
+	mux.HandleFunc("/abort", func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+	handler := l.HTTPMiddleware(HTTPConfig{})(PanicHandler(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NoError(t, l.InfoSync(context.Background(), "barrier"))
+	require.Contains(t, out.String(), `"message":"panic: boom"`)
+	require.Contains(t, out.String(), `"flow_id":"req-7"`)
+	require.Contains(t, out.String(), `"http_path":"/boom"`)
+	require.Contains(t, out.String(), `"message":"GET /boom 500"`)
+	require.Contains(t, out.String(), `"stack":"goroutine `)
+	require.Equal(t, int64(1), l.Panics())
+
+	rec = httptest.NewRecorder()
+	PanicHandler(mux).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/late", nil).WithContext(WithLogger(context.Background(), l)))
+	require.Equal(t, http.StatusAccepted, rec.Code, "a started response is left alone")
+	require.Equal(t, int64(2), l.Panics())
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		PanicHandler(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+	require.Equal(t, int64(2), l.Panics())
+}
+
+func TestLogFlags(t *testing.T) {
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	lf := RegisterFlags(fs)
+	require.Equal(t, Config{MinLevel: INFO}, lf.Config(Config{MinLevel: ERROR}))
+	require.Equal(t, "info", fs.Lookup(FlagLogLevel).DefValue)
+
+	require.NoError(t, fs.Parse([]string{"-log-level=debug", "-log-format", "JSON", "-log-file", "/var/log/tool.log"}))
+	cfg := lf.Config(Config{Rotation: RotationConfig{MaxSizeMB: 10}})
+	require.Equal(t, DEBUG, cfg.MinLevel)
+	require.True(t, cfg.JSON)
+	require.Equal(t, RotationConfig{Enable: true, Filename: "/var/log/tool.log", MaxSizeMB: 10}, cfg.Rotation)
+
+	fs = flag.NewFlagSet("tool", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+	require.ErrorContains(t, fs.Parse([]string{"-log-level=verbose"}), "unknown level")
+	require.ErrorContains(t, fs.Parse([]string{"-log-format=yaml"}), "unknown log format")
+
+	lf = NewLogFlags()
+	defs := lf.Flags()
+	require.Len(t, defs, 3)
+	require.Equal(t, "level", defs[0].Value.Type())
+	require.NoError(t, defs[0].Value.Set("warning"))
+	require.Equal(t, WARN, lf.Level)
+}
+
+// fakeCommand implements Command like *cobra.Command.
+type fakeCommand struct {
+	path string
+	ctx  context.Context
+}
+
+func (c *fakeCommand) CommandPath() string            { return c.path }
+func (c *fakeCommand) Context() context.Context       { return c.ctx }
+func (c *fakeCommand) SetContext(ctx context.Context) { c.ctx = ctx }
+
+func TestCLILifecycle(t *testing.T) {
+	out := &syncBuffer{}
+	lf := NewLogFlags()
+	require.NoError(t, lf.Flags()[1].Value.Set("json"))
+	cli := &CLI{Flags: lf, Base: Config{Stdout: out, Stderr: out}}
+	cmd := &fakeCommand{path: "tool db migrate", ctx: context.Background()}
+
+	require.NoError(t, cli.PreRun(cmd))
+	GetLogger(cmd.Context()).Info("migrating")
+	require.NoError(t, cli.PostRun(cmd))
+	require.NoError(t, cli.Finish())
+	require.Contains(t, out.String(), `"module":"db.migrate"`)
+	require.Contains(t, out.String(), `"message":"migrating"`)
+
+	require.Equal(t, "tool", commandModule("tool"))
+	require.Equal(t, "", commandModule(""))
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: failingWriter{}, MinLevel: DEBUG, Buffer: 16})
+	defer CloseDetached(l, 2*time.Second)
+	ctx := context.Background()
+	require.NoError(t, l.DebugSync(ctx, "d"))
+	require.NoError(t, l.InfoSync(ctx, "i1"))
+	require.NoError(t, l.InfoSync(ctx, "i2"))
+	require.Error(t, l.ErrorSync(ctx, "e"))
+
+	s := l.Snapshot()
+	require.Equal(t, int64(4), s.Written)
+	require.Equal(t, map[Level]int64{TRACE: 0, DEBUG: 1, INFO: 2, WARN: 0, ERROR: 1, FATAL: 0}, s.Levels)
+	require.Equal(t, 16, s.QueueCap)
+	require.Positive(t, s.Uptime)
+	require.Positive(t, s.WriteErrors)
+	require.Positive(t, s.Writers["stderr"].Errors)
+	require.False(t, s.LastWriteError.IsZero())
+	require.Equal(t, s.Writers["stderr"].LastErrorTime, s.LastWriteError)
+	require.True(t, s.LastHookError.IsZero())
+	require.Equal(t, int64(3), s.Writers["stdout"].EntriesWritten)
+}
+
+func TestFatalCode(t *testing.T) {
+	for _, direct := range []bool{false, true} {
+		out := &syncBuffer{}
+		extra := &syncBuffer{}
+		var hooked []string
+		var codes []int
+		l := NewDetachedLogger(Config{Stdout: out, Stderr: out, SingleWriter: direct,
+			Writers: []io.Writer{extra}, WriterNames: []string{"extra"},
+			Fatal:    FatalConfig{WaitForSinks: true, Timeout: time.Second},
+			ExitFunc: func(code int) { codes = append(codes, code) },
+			Hooks:    []HookFunc{func(ev HookEvent) error { hooked = append(hooked, ev.Message); return nil }}})
+
+		l.WithContext(context.Background()).FatalCode(3, "bye %d", 1)
+		require.Equal(t, []int{3}, codes)
+		require.Contains(t, extra.String(), "bye 1", "the extra writer got the entry before the exit")
+		require.Equal(t, []string{"bye 1"}, hooked)
+		require.True(t, l.closed.Load())
+		require.Equal(t, 1, strings.Count(out.String(), "bye 1"))
+	}
+
+	l := NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard})
+	require.Equal(t, FatalConfig{}, l.GetFatalConfig())
+	require.Equal(t, 2*time.Second, l.GetFatalConfig().timeout())
+	var code int
+	l.SetExitFunc(func(c int) { code = c })
+	l.Fatal(context.Background(), "default code")
+	require.Equal(t, 1, code)
+}
+
+func TestFailureHookReceivesFailedEntries(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	failures := make(chan struct{}, 4)
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		FailureHook: func(_ context.Context, f WriteFailure) error {
+			mu.Lock()
+			got = append(got, f.Sink+": "+f.Event.Message+": "+f.Err.Error())
+			mu.Unlock()
+			failures <- struct{}{}
+			return errors.New("fallback failed")
+		}}
+	l := NewDetachedLogger(cfg)
+	l.AddExtraWriter("broken", failingWriter{})
+	lw := l.WithContext(context.Background())
+	require.Error(t, lw.ErrorSync("audit"))
+
+	// With asynchronous hooks, the failure hook runs on the hook workers.
+	l.SetHookConfig(HookConfig{Async: true, Workers: 1, Queue: 8})
+	l.AddHook(func(context.Context, HookEvent) error { return nil }, HookFilter{})
+	require.Error(t, lw.ErrorSync("queued"))
+	<-failures
+	<-failures
+
+	l.SetFailureHook(nil)
+	require.Error(t, lw.ErrorSync("not delivered"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{
+		"broken: audit: unologger: write to broken: sink unavailable",
+		"broken: queued: unologger: write to broken: sink unavailable",
+	}, got)
+	require.Equal(t, int64(2), l.Snapshot().HookErrors)
+}
+
+func TestFlushWritesPendingEntriesWithoutClosing(t *testing.T) {
+	out := &syncBuffer{}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 64, Workers: 2, Stdout: out, Stderr: out,
+		Batch: BatchConfig{Size: 100, MaxWait: time.Hour}}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+	for i := 0; i < 5; i++ {
+		lw.Info("entry %d", i)
+	}
+	require.NoError(t, l.Flush(2*time.Second))
+	for i := 0; i < 5; i++ {
+		require.Contains(t, out.String(), fmt.Sprintf("entry %d", i))
+	}
+
+	lw.Info("after flush")
+	require.NoError(t, l.Flush(0))
+	require.Contains(t, out.String(), "after flush")
+
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.NoError(t, l.Flush(time.Second))
+}
+
+func TestIDBackfill(t *testing.T) {
+	var mu sync.Mutex
+	var events []HookEvent
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		Batch:      BatchConfig{Size: 100, MaxWait: time.Hour},
+		IDBackfill: IDBackfillConfig{Scope: BackfillGoroutine, Generator: func() string { return "g" }},
+		Hooks2: []HookFunc2{func(_ context.Context, ev HookEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, ev)
+			return nil
+		}}}
+	l := NewDetachedLogger(cfg)
+	lw := l.WithContext(context.Background())
+	lw.Info("first")
+	lw.Info("second")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lw.Info("other goroutine")
+	}()
+	<-done
+	l.WithContext(WithFlowID(WithTraceID(context.Background(), "t1"), "f1")).Info("propagated")
+	require.NoError(t, l.Flush(2*time.Second))
+
+	mu.Lock()
+	require.Len(t, events, 4)
+	require.True(t, strings.HasPrefix(events[0].TraceID, "g-"))
+	require.Equal(t, events[0].TraceID, events[0].FlowID)
+	require.Equal(t, events[0].TraceID, events[1].TraceID)
+	require.NotEqual(t, events[0].TraceID, events[2].TraceID)
+	require.Equal(t, true, events[0].Fields[BackfilledKey])
+	require.Equal(t, "t1", events[3].TraceID)
+	require.Equal(t, "f1", events[3].FlowID)
+	require.NotContains(t, events[3].Fields, BackfilledKey)
+	events = nil
+	mu.Unlock()
+
+	// Per batch, the entries written together share one ID, and a partial ID is completed.
+	n := 0
+	l.SetIDBackfill(IDBackfillConfig{Scope: BackfillBatch, Generator: func() string { n++; return fmt.Sprintf("b%d", n) }})
+	lw.Info("a")
+	l.WithContext(WithTraceID(context.Background(), "t2")).Info("b")
+	require.NoError(t, l.Flush(2*time.Second))
+	lw.Info("c")
+	require.NoError(t, l.Flush(2*time.Second))
+
+	l.SetIDBackfill(IDBackfillConfig{})
+	require.Equal(t, BackfillOff, l.GetIDBackfill())
+	lw.Info("off")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 4)
+	require.Equal(t, "b1", events[0].TraceID)
+	require.Equal(t, "t2", events[1].TraceID)
+	require.Equal(t, "b1", events[1].FlowID)
+	require.Equal(t, "b2", events[2].FlowID)
+	require.Empty(t, events[3].TraceID)
+	require.NotContains(t, events[3].Fields, BackfilledKey)
+}
+
+func TestFatalExitHooksAndPanicExit(t *testing.T) {
+	out := &syncBuffer{}
+	var calls []string
+	l := NewDetachedLogger(Config{Stdout: out, Stderr: out, Timezone: "UTC",
+		Fatal:    FatalConfig{ExitCode: 7, Timeout: time.Second},
+		ExitFunc: PanicExit,
+		ExitHooks: []ExitHook{func(code int) {
+			calls = append(calls, fmt.Sprintf("config %d", code))
+		}}})
+	l.OnExit(func(code int) {
+		calls = append(calls, fmt.Sprintf("cleanup %d", code))
+		require.NoError(t, l.WithContext(context.Background()).InfoSync("cleanup done"))
+	})
+	remove := l.OnExit(func(int) { calls = append(calls, "removed") })
+	remove()
+	l.OnExit(func(int) { panic("broken hook") })
+
+	require.PanicsWithValue(t, ExitPanic{Code: 7}, func() {
+		l.Fatal(context.Background(), "boom")
+	})
+	require.Equal(t, []string{"config 7", "cleanup 7"}, calls)
+	require.Contains(t, out.String(), "cleanup done", "exit hooks run before the logger is closed")
+	require.True(t, l.closed.Load())
+	require.Equal(t, "unologger: fatal exit with code 7", ExitPanic{Code: 7}.Error())
+
+	// A hook that does not return in time does not hold up the exit.
+	var code int
+	l = NewDetachedLogger(Config{Stdout: io.Discard, Stderr: io.Discard,
+		Fatal: FatalConfig{Timeout: 50 * time.Millisecond}, ExitFunc: func(c int) { code = c }})
+	block := make(chan struct{})
+	defer close(block)
+	l.OnExit(func(int) { <-block })
+	l.FatalCode(context.Background(), 4, "stuck")
+	require.Equal(t, 4, code)
+}
+
+func TestPackageModule(t *testing.T) {
+	require.Equal(t, "payment", PackageModule("example.com/app/internal/payment"))
+	require.Equal(t, "main", PackageModule("main"))
+	require.Equal(t, "example.com/app/billing", funcPackage("example.com/app/billing.(*Service).Charge"))
+	require.Equal(t, "main", funcPackage("main.main"))
+}
+
+var (
+	testAudit  = MustRegisterLevel("audit", INFO)
+	testNotice = MustRegisterLevel("NOTICE", WARN)
+)
+
+func TestTraceAndCustomLevels(t *testing.T) {
+	require.Equal(t, "AUDIT", testAudit.String())
+	require.True(t, testAudit.IsCustom())
+	require.Equal(t, INFO, testAudit.Base())
+	require.Equal(t, ERROR, ERROR.Base())
+	for name, want := range map[string]Level{"trace": TRACE, "Audit": testAudit, "notice": testNotice} {
+		got, err := ParseLevel(name)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+	_, err := RegisterLevel("info", DEBUG)
+	require.ErrorContains(t, err, "already exists")
+	_, err = RegisterLevel("PANIC", FATAL)
+	require.Error(t, err)
+	_, err = RegisterLevel(" ", INFO)
+	require.Error(t, err)
+	require.Equal(t, 9, SeverityScaleOTel.Severity(testAudit))
+	require.Equal(t, 1, SeverityScaleOTel.Severity(TRACE))
+	require.Equal(t, 4, SeverityScaleSyslog.Severity(testNotice))
+
+	stdout, stderr := &syncBuffer{}, &syncBuffer{}
+	var mu sync.Mutex
+	var audited []string
+	l := NewDetachedLogger(Config{Timezone: "UTC", Stdout: stdout, Stderr: stderr})
+	l.AddHook(func(_ context.Context, ev HookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		audited = append(audited, ev.Message)
+		return nil
+	}, HookFilter{Name: "audit", Levels: []Level{testAudit}})
+	lw := l.WithContext(context.Background())
+
+	lw.Trace("hidden")
+	lw.Log(testAudit, "invoice %d deleted", 7)
+	lw.LogW(testNotice, "disk almost full", Fields{"free": "5%"})
+	require.NoError(t, lw.InfoSync("barrier"))
+	require.NotContains(t, stdout.String(), "hidden", "TRACE is below the default minimum level")
+	require.Contains(t, stdout.String(), "AUDIT")
+	require.Contains(t, stdout.String(), "invoice 7 deleted")
+	require.Contains(t, stderr.String(), "NOTICE", "a custom level above WARN goes to stderr")
+
+	l.SetMinLevel(TRACE)
+	lw.Trace("shown %d", 1)
+	l.SetMinLevel(WARN)
+	lw.Log(testAudit, "filtered as INFO")
+	require.NoError(t, lw.WarnSync("barrier"))
+	require.Contains(t, stdout.String(), "TRACE")
+	require.Contains(t, stdout.String(), "shown 1")
+	require.NotContains(t, stdout.String(), "filtered as INFO")
+	require.Equal(t, int64(1), l.Snapshot().Levels[TRACE])
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"invoice 7 deleted"}, audited)
+}
+
+func TestWithGroupNestsFields(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{Timezone: "UTC", JSON: true, Workers: 1, Stdout: out, Stderr: out})
+	ctx := WithAttrs(WithLogger(context.Background(), l), Fields{"request_id": "r1"})
+	dbCtx := WithAttrs(WithGroup(ctx, "db"), Fields{"table": "users"})
+	httpCtx := WithGroup(ctx, "http")
+
+	GetLogger(WithGroup(dbCtx, "pool")).InfoW("query", Fields{"duration_ms": 3})
+	GetLogger(httpCtx).With(Fields{"route": "/x"}).WithGroup("client").InfoW("request", Fields{"duration_ms": 41})
+	require.NoError(t, GetLogger(httpCtx).InfoSync("no fields"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	var entries []map[string]any
+	for _, line := range lines {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		entries = append(entries, m["fields"].(map[string]any))
+	}
+	require.Equal(t, map[string]any{
+		"request_id": "r1",
+		"db":         map[string]any{"table": "users", "pool": map[string]any{"duration_ms": float64(3)}},
+	}, entries[0])
+	require.Equal(t, map[string]any{
+		"request_id": "r1",
+		"http":       map[string]any{"route": "/x", "client": map[string]any{"duration_ms": float64(41)}},
+	}, entries[1], "fields bound in a group stay in it")
+	require.Equal(t, map[string]any{"request_id": "r1"}, entries[2])
+	_, ok := ctx.Value(ctxFieldsKey).(Fields)["db"]
+	require.False(t, ok, "the parent context is not modified")
+
+	// The text formatter names the fields of a group with a dot.
+	var pairs []string
+	expandField("db", Fields{"pool": Fields{"size": 4}, "table": "users"}, func(k, v string) { pairs = append(pairs, k+"="+v) })
+	require.Equal(t, []string{"db.pool.size=4", "db.table=users"}, pairs)
+}
+
+func TestTypedAttrsKeepNativeTypes(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{Timezone: "UTC", JSON: true, Workers: 1, Stdout: out, Stderr: out})
+	var attrs Fields
+	l.AddHook(func(_ context.Context, ev HookEvent) error {
+		attrs = ev.Attrs
+		return nil
+	}, HookFilter{})
+	ctx := WithLogger(context.Background(), l)
+	ctx = WithString(ctx, "plan", "pro")
+	ctx = WithBool(ctx, "retry", true)
+	ctx = WithInt(ctx, "attempt", 3)
+	ctx = WithFloat(ctx, "amount", 12.5)
+	ctx = WithFloat(ctx, "ratio", math.NaN())
+
+	require.NoError(t, GetLogger(ctx).InfoSync("charged"))
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out.String()), &m))
+	require.Equal(t, map[string]any{
+		"plan": "pro", "retry": true, "attempt": float64(3), "amount": 12.5, "ratio": "NaN",
+	}, m["attrs"])
+	require.Equal(t, int64(3), attrs["attempt"])
+	require.Equal(t, 12.5, attrs["amount"])
+}
+
+func TestCloseReleasesProducersBlockedOnFullQueue(t *testing.T) {
+	bw := newBlockingWriter()
+	defer bw.unblock()
+	l := NewDetachedLogger(Config{MinLevel: INFO, Timezone: "UTC", Buffer: 1, Workers: 1,
+		Batch: BatchConfig{Size: 1, MaxWait: time.Second}, Stdout: bw, Stderr: bw})
+	lw := l.WithContext(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lw.Info("blocked %d", i)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // Let the producers fill the queue and block.
+
+	start := time.Now()
+	_ = CloseDetached(l, 200*time.Millisecond)
+	require.Less(t, time.Since(start), 2*time.Second, "Close honors its timeout")
+	wg.Wait() // The blocked producers are released.
+}
+
+func TestTraceThresholdsAreNotDisabled(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: TRACE, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: out, Stderr: out,
+		Batch:      BatchConfig{Size: 100, MaxWait: time.Hour, FlushOnLevel: true, FlushLevel: TRACE},
+		SpanEvents: true, SpanEventLevel: TRACE,
+	})
+	span := &recordingSpan{}
+	ctx := trace.ContextWithSpan(WithLogger(context.Background(), l), span)
+
+	GetLogger(ctx).Trace("flushes immediately")
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "flushes immediately")
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	require.Equal(t, []string{"log"}, span.events)
+}
+
+func TestZeroHookFilterPassesTrace(t *testing.T) {
+	l := NewDetachedLogger(Config{MinLevel: TRACE, Timezone: "UTC", SingleWriter: true, Stdout: io.Discard, Stderr: io.Discard})
+	var mu sync.Mutex
+	var all, info []string
+	l.AddHook(func(_ context.Context, ev HookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		all = append(all, ev.Message)
+		return nil
+	}, HookFilter{})
+	l.AddHook(func(_ context.Context, ev HookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		info = append(info, ev.Message)
+		return nil
+	}, HookFilter{MinLevel: INFO})
+
+	lw := l.WithContext(context.Background())
+	lw.Trace("trace")
+	lw.Info("info")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"trace", "info"}, all)
+	require.Equal(t, []string{"info"}, info)
+}
+
+func TestGroupedFieldsAreShreddedNormalizedAndValidated(t *testing.T) {
+	store := NewMemoryKeyStore()
+	buf := &syncBuffer{}
+	l := NewDetachedLogger(Config{
+		MinLevel: INFO, Timezone: "UTC", JSON: true, Workers: 1, Stdout: buf, Stderr: buf,
+		Shredding:        &ShreddingConfig{Store: store, SubjectField: "user_id", Fields: []string{"email"}},
+		KeyNormalization: KeyNormalization{SnakeCase: true},
+		Validation: &ValidationSchema{
+			Required: []string{"user.plan"}, Types: map[string]FieldType{"user.age": FieldInt},
+			Action: ValidationFix, Defaults: Fields{"user.plan": "free"},
+		},
+	})
+	ctx := WithAttrs(WithGroup(WithLogger(context.Background(), l), "user"), Fields{"user_id": "u-42", "email": "bob@example.com"})
+	GetLogger(ctx).InfoW("signed up", Fields{"signupSource": "web", "age": "42"})
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	out := buf.String()
+	require.NotContains(t, out, "bob@example.com")
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &m))
+	user := m["fields"].(map[string]interface{})["user"].(map[string]interface{})
+	require.Equal(t, "web", user["signup_source"])
+	require.Equal(t, "free", user["plan"])
+	require.Equal(t, float64(42), user["age"])
+	plain, err := RevealValue(store, "email", user["email"].(string))
+	require.NoError(t, err)
+	require.Equal(t, "bob@example.com", plain)
+	require.Equal(t, "bob@example.com", ctx.Value(ctxFieldsKey).(Fields)["user"].(Fields)["email"],
+		"the group of the context is not modified")
+}
+
+func TestPrintfMessagesAreAlwaysFormatted(t *testing.T) {
+	out := &syncBuffer{}
+	l := NewDetachedLogger(Config{MinLevel: INFO, Timezone: "UTC", JSON: true, Workers: 1, Stdout: out, Stderr: out})
+	lw := l.WithContext(context.Background())
+	lw.Info("done 100%%")
+	lw.InfoW("literal 100%%", nil)
+	require.NoError(t, lw.InfoSync("sync 100%%"))
+	NewStdLogger(lw, INFO).Print("print 100%")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	s := out.String()
+	require.Contains(t, s, `"message":"done 100%"`)
+	require.Contains(t, s, `"message":"literal 100%%"`)
+	require.Contains(t, s, `"message":"sync 100%"`)
+	require.Contains(t, s, `"message":"print 100%"`)
+}
+
+func TestHookTimeoutsRunHooksInline(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	var waited time.Duration
+	var deadlineErr error
+	slow := func(HookEvent) error {
+		time.Sleep(2 * timeout)
+		return nil
+	}
+	watcher := func(ctx context.Context, _ HookEvent) error {
+		start := time.Now()
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		<-ctx.Done()
+		waited, deadlineErr = time.Since(start), ctx.Err()
+		return ctx.Err()
+	}
+	fast := func(context.Context, HookEvent) error { return nil }
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		Hooks: []HookFunc{slow}, Hooks2: []HookFunc2{watcher, fast}, Hook: HookConfig{Timeout: timeout}}
+	l := NewDetachedLogger(cfg)
+	goroutines := runtime.NumGoroutine()
+	require.NoError(t, l.InfoSync(context.Background(), "trigger"))
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "no goroutine is left behind by the overruns")
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	// The legacy hook runs to completion, and the next hook still gets its whole timeout.
+	require.GreaterOrEqual(t, waited, timeout)
+	require.ErrorIs(t, deadlineErr, context.DeadlineExceeded)
+	_, _, _, _, hookErrs, _, _, hlog := StatsDetached(l)
+	require.Equal(t, int64(2), hookErrs)
+	require.ErrorIs(t, hlog[0].Err, ErrHookTimeout)
+	require.ErrorIs(t, hlog[1].Err, ErrHookTimeout)
+}
+
+func TestCloseDiagnosticsShowAHungHook(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	hung := func(HookEvent) error {
+		<-release
+		return nil
+	}
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Workers: 1, Stdout: io.Discard, Stderr: io.Discard,
+		Hooks: []HookFunc{hung}, Hook: HookConfig{Timeout: 10 * time.Millisecond, Async: true}, CloseDiagnostics: true}
+	l := NewDetachedLogger(cfg)
+	l.WithContext(context.Background()).Info("trigger")
+	require.Eventually(t, func() bool { return strings.Contains(pipelineStacks(), "TestCloseDiagnosticsShowAHungHook") },
+		time.Second, time.Millisecond)
+
+	var cte *CloseTimeoutError
+	require.ErrorAs(t, CloseDetached(l, 50*time.Millisecond), &cte)
+	require.Contains(t, cte.Report.Stacks, "TestCloseDiagnosticsShowAHungHook.func1")
+}
+
+func TestTeeHasItsOwnConfiguration(t *testing.T) {
+	appBuf, copyBuf := &syncBuffer{}, &syncBuffer{}
+	app := NewDetachedLogger(Config{MinLevel: DEBUG, Workers: 1, Stdout: appBuf, Stderr: appBuf})
+	tee := Tee(app)
+	tee.SetMinLevel(WARN)
+	tee.AddExtraWriter("copy", copyBuf)
+	tee.SetExitFunc(PanicExit)
+	lw := tee.WithContext(context.Background())
+
+	require.NoError(t, lw.InfoSync("info"))
+	require.NoError(t, lw.WarnSync("warn"))
+	require.Contains(t, appBuf.String(), "info", "the level of the tee does not filter its members")
+	require.NotContains(t, copyBuf.String(), "info")
+	require.Contains(t, copyBuf.String(), "warn")
+	require.Equal(t, int64(1), tee.Snapshot().Levels[WARN])
+
+	require.PanicsWithValue(t, ExitPanic{Code: 1}, func() { lw.Fatal("boom") })
+	require.Contains(t, appBuf.String(), "boom")
+}
+
+func TestDelegationCyclesAreBroken(t *testing.T) {
+	prev := GlobalLogger()
+	defer globalLogger.Store(prev)
+	aBuf, bBuf := &syncBuffer{}, &syncBuffer{}
+	a := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, DelegateGlobal: true, Stdout: aBuf, Stderr: aBuf})
+	b := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Delegate: a, Stdout: bBuf, Stderr: bBuf})
+	globalLogger.Store(b)
+
+	require.NoError(t, a.WithContext(context.Background()).InfoSync("from a"))
+	require.NoError(t, b.WithContext(context.Background()).InfoSync("from b"))
+	b.WithContext(context.Background()).Buffered().Info("buffered")
+	require.Contains(t, aBuf.String(), "from a")
+	require.Contains(t, bBuf.String(), "from b")
+
+	// The FATAL call of a logger delegating to the global one leaves the global logger open.
+	c := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, DelegateGlobal: true})
+	c.SetExitFunc(func(int) {})
+	c.WithContext(context.Background()).Fatal("fatal in c")
+	require.Contains(t, bBuf.String(), "fatal in c")
+	require.NoError(t, b.WithContext(context.Background()).InfoSync("still open"))
+
+	require.NoError(t, CloseDetached(b, 2*time.Second))
+	require.NoError(t, CloseDetached(a, 2*time.Second))
+}
+
+func TestTeeExitsLikeItsMembers(t *testing.T) {
+	buf := &syncBuffer{}
+	app := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Stdout: buf, Stderr: buf})
+	defer func() { _ = CloseDetached(app, 2*time.Second) }()
+	app.SetFatalConfig(FatalConfig{ExitCode: 7})
+	var code atomic.Int64
+	app.SetExitFunc(func(c int) { code.Store(int64(c)) })
+
+	tee := Tee(app)
+	require.Equal(t, 7, tee.GetFatalConfig().ExitCode)
+	tee.WithContext(context.Background()).Fatal("boom")
+	require.Equal(t, int64(7), code.Load(), "the tee uses the exit settings of its member")
+	require.Contains(t, buf.String(), "boom")
+	require.NoError(t, app.WithContext(context.Background()).InfoSync("member still open"))
+
+	own := Tee(app)
+	own.SetFatalConfig(FatalConfig{ExitCode: 3})
+	own.SetExitFunc(PanicExit)
+	require.PanicsWithValue(t, ExitPanic{Code: 3}, func() { own.WithContext(context.Background()).Fatal("own") })
+	require.Equal(t, int64(7), code.Load())
+}
+
+func TestReinitCarriesDynamicSettings(t *testing.T) {
+	cfg := Config{MinLevel: INFO, Timezone: "UTC", Buffer: 16, Workers: 1, Stdout: io.Discard, Stderr: io.Discard}
+	old, err := ReinitGlobalLogger(cfg, 2*time.Second)
+	require.NoError(t, err)
+	old.SetFatalConfig(FatalConfig{ExitCode: 4})
+	old.SetExitFunc(PanicExit)
+	old.SetValidation(&ValidationSchema{Required: []string{FieldUserID}})
+	old.SetHookConfig(HookConfig{Async: true, Workers: 3, Queue: 64, Timeout: time.Second})
+	old.SetModuleFromCaller(true, nil)
+	old.SetIDBackfill(IDBackfillConfig{Scope: BackfillBatch})
+	old.SetErrorStacks(true)
+	old.SetPauseConfig(PauseConfig{Policy: PauseDrop})
+	old.SetEntryTTL(EntryTTLConfig{MaxAge: time.Minute})
+	var events []LifecycleEventType
+	old.OnLifecycle(func(ev LifecycleEvent) { events = append(events, ev.Type) })
+	old.SetReportCaller(true, 2)
+	old.SetSampling(&SamplingConfig{Initial: 5, Thereafter: 10})
+	old.WithDefaults("billing", Fields{"region": "eu"})
+	old.SetHealthConfig(HealthConfig{SinkFailures: 3})
+	old.SetSinkRateLimit("stdout", &SinkRateLimit{PerSecond: 5})
+	old.SetBlobRules([]BlobRule{{MaxLen: 64}})
+	var exitCode int
+	old.OnExit(func(code int) { exitCode = code })
+
+	l, err := ReinitGlobalLoggerWithOptions(cfg, 2*time.Second, ReinitOptions{CarryDynamic: true, CarryHooks: true})
+	require.NoError(t, err)
+	require.Equal(t, 4, l.GetFatalConfig().ExitCode)
+	require.Equal(t, []string{FieldUserID}, l.validation.Load().Required)
+	require.Equal(t, HookConfig{Async: true, Workers: 3, Queue: 64, Timeout: time.Second}, l.GetHookConfig())
+	require.True(t, l.ModuleFromCaller())
+	require.Equal(t, BackfillBatch, l.GetIDBackfill())
+	require.True(t, l.ErrorStacks())
+	require.Equal(t, PauseConfig{Policy: PauseDrop}, l.pause.cfg)
+	require.Equal(t, time.Minute, l.GetEntryTTL().MaxAge)
+	require.Contains(t, events, LifecycleStarted, "the listener hears the new logger start")
+	enabled, skip := l.ReportCaller()
+	require.True(t, enabled)
+	require.Equal(t, 2, skip)
+	require.Equal(t, &SamplingConfig{Initial: 5, Thereafter: 10, Tick: time.Second, MaxLevel: INFO}, l.GetSampling())
+	module, attrs := l.Defaults()
+	require.Equal(t, "billing", module)
+	require.Equal(t, Fields{"region": "eu"}, attrs)
+	require.Equal(t, int64(3), l.healthCfg.Load().SinkFailures)
+	require.Contains(t, l.SinkRateStats(), "stdout")
+	require.False(t, (*l.sinkRates.Load())["stdout"].closed, "closing the old logger leaves the shared limiter open")
+	require.Equal(t, []BlobRule{{MaxLen: 64}}, l.BlobRules())
+	require.PanicsWithValue(t, ExitPanic{Code: 4}, func() { l.WithContext(context.Background()).Fatal("carried") })
+	require.Equal(t, 4, exitCode)
+}
+
+func TestExitCodeZeroAndMemberExitHooks(t *testing.T) {
+	app := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Stdout: io.Discard, Stderr: io.Discard})
+	defer func() { _ = CloseDetached(app, 2*time.Second) }()
+	var order []string
+	app.OnExit(func(int) { order = append(order, "member") })
+
+	tee := Tee(app, app)
+	tee.OnExit(func(int) { order = append(order, "tee") })
+	tee.SetFatalConfig(FatalConfig{ExitCodeSet: true})
+	tee.SetExitFunc(PanicExit)
+	require.PanicsWithValue(t, ExitPanic{Code: 0}, func() { tee.WithContext(context.Background()).Fatal("done") })
+	require.Equal(t, []string{"tee", "member"}, order)
+}
+
+func TestSinkRateLimitReplacedWhileWriting(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDetachedLogger(Config{MinLevel: INFO, Workers: 4, Stdout: io.Discard, Stderr: io.Discard})
+	ctx := context.Background()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					l.Info(ctx, "tick")
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		l.SetSinkRateLimit("stdout", &SinkRateLimit{
+			PerSecond: 1, Overflow: RotationConfig{Filename: filepath.Join(dir, fmt.Sprintf("overflow%d.log", i))},
+		})
+	}
+	close(stop)
+	wg.Wait()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestAutoScaleCountsThePriorityQueue(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewDetachedLogger(Config{
+		MinLevel:      DEBUG,
+		Stdout:        w,
+		Stderr:        w,
+		Workers:       1,
+		Buffer:        8,
+		NonBlocking:   true,
+		PriorityQueue: PriorityQueueConfig{Enable: true, Buffer: 8},
+		AutoScale:     AutoScaleConfig{Enable: true, MaxWorkers: 2, Interval: 10 * time.Millisecond, HighWatermark: 0.5},
+	})
+	ctx := context.Background()
+	l.Info(ctx, "wedges the worker")
+	require.Eventually(t, func() bool { return l.queueLen() == 0 }, time.Second, time.Millisecond)
+	for i := 0; i < 9; i++ {
+		l.Error(ctx, "error %d", i)
+	}
+	require.Eventually(t, func() bool { return l.Workers() == 2 }, time.Second, 5*time.Millisecond,
+		"the entries waiting in the priority queue fill half of the queues")
+
+	w.unblock()
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+}
+
+func TestOTLPRecordsCarryExceptionAttributes(t *testing.T) {
+	cause := fmt.Errorf("query: %w", os.ErrDeadlineExceeded)
+	recs := otlpRecords([]HookEvent{
+		{Level: ERROR, Message: "db failed", Error: cause, Stack: "goroutine 1"},
+		{Level: INFO, Message: "no error"},
+	})
+	require.Len(t, recs, 2)
+	require.Contains(t, recs[0].attrs, otlpKV{"exception.message", "query: i/o timeout"})
+	require.Contains(t, recs[0].attrs, otlpKV{"exception.type", "*fmt.wrapError"})
+	require.Contains(t, recs[0].attrs, otlpKV{"exception.stacktrace", "goroutine 1"})
+	for _, kv := range recs[1].attrs {
+		require.NotContains(t, kv.key, "exception.")
+	}
+}
+
+func TestResumeWritesHeldEntriesFirst(t *testing.T) {
+	w := newBlockingWriter()
+	l := NewDetachedLogger(Config{MinLevel: INFO, Workers: 1, Buffer: 2, Stdout: w, Stderr: w,
+		Pause: PauseConfig{MaxBuffered: 100}})
+	ctx := context.Background()
+	l.Pause()
+	for i := 0; i < 20; i++ {
+		l.Info(ctx, "held %d", i)
+	}
+	// The worker is wedged, so Resume blocks on the full queue while new entries arrive.
+	resumed := make(chan struct{})
+	go func() {
+		l.Resume()
+		close(resumed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			l.Info(ctx, "new %d", i)
+		}
+		close(logged)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	w.unblock()
+	<-resumed
+	<-logged
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSpace(w.buf.String()), "\n") {
+		msgs = append(msgs, line[strings.LastIndex(line, ") ")+2:])
+	}
+	require.Len(t, msgs, 25)
+	for i := 0; i < 20; i++ {
+		require.Equal(t, fmt.Sprintf("held %d", i), msgs[i])
+	}
+}
+
+func TestCloseKeepsProcessStreamsOpen(t *testing.T) {
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	_, outW, err := os.Pipe()
+	require.NoError(t, err)
+	_, errW, err := os.Pipe()
+	require.NoError(t, err)
+	defer outW.Close()
+	defer errW.Close()
+	os.Stdout, os.Stderr = outW, errW
+
+	// A logger writing to the process's streams, like the default global one, must leave
+	// them usable once closed: a reinit closes it while the new logger and the rest of the
+	// process keep writing there.
+	l := NewDetachedLogger(Config{Stdout: os.Stdout, Stderr: os.Stderr})
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	_, err = outW.Write([]byte("still open\n"))
+	require.NoError(t, err)
+	_, err = errW.Write([]byte("still open\n"))
+	require.NoError(t, err)
+
+	// Any other writer that is a Closer is still closed.
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	require.NoError(t, err)
+	l = NewDetachedLogger(Config{Stdout: f, Stderr: errW})
+	require.NoError(t, CloseDetached(l, 2*time.Second))
+	_, err = f.Write([]byte("closed\n"))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
+
 func BenchmarkLogThroughput_NoOp(b *testing.B) {
 	cfg := Config{
 		MinLevel: INFO,
@@ -0,0 +1,308 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file implements the optional validation stage. Each entry is checked against a
+// user-provided ValidationSchema (required fields, field types, allowed modules) before
+// hooks and formatting, and nonconforming entries are flagged, fixed or dropped. It is
+// meant to catch logging contract violations in CI and staging environments.
+
+package unologger
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationAction selects what happens to an entry that violates the ValidationSchema.
+type ValidationAction int
+
+const (
+	// ValidationFlag writes the entry unchanged, with the list of violations added under
+	// the "schema_violations" field.
+	ValidationFlag ValidationAction = iota
+	// ValidationFix repairs the entry where possible: missing required fields are set from
+	// Defaults, mistyped fields are converted or removed, and a module that is not allowed
+	// is replaced by FixModule. Violations that cannot be repaired are flagged.
+	ValidationFix
+	// ValidationDrop discards the entry.
+	ValidationDrop
+)
+
+// FieldType is the expected type of a structured field.
+type FieldType int
+
+const (
+	// FieldAny accepts any value.
+	FieldAny FieldType = iota
+	// FieldString accepts strings.
+	FieldString
+	// FieldInt accepts signed and unsigned integers.
+	FieldInt
+	// FieldNumber accepts integers and floating-point numbers.
+	FieldNumber
+	// FieldBool accepts booleans.
+	FieldBool
+	// FieldTime accepts time.Time values.
+	FieldTime
+)
+
+// String returns the lowercase name of the field type.
+func (t FieldType) String() string {
+	switch t {
+	case FieldString:
+		return "string"
+	case FieldInt:
+		return "int"
+	case FieldNumber:
+		return "number"
+	case FieldBool:
+		return "bool"
+	case FieldTime:
+		return "time"
+	default:
+		return "any"
+	}
+}
+
+// violationsField is the field holding the violations of a flagged entry.
+const violationsField = "schema_violations"
+
+// ErrEntryInvalid is returned by the *Sync logging methods when the entry was dropped
+// by the validation stage.
+var ErrEntryInvalid = errors.New("unologger: log entry violates the validation schema")
+
+// ValidationSchema describes the contract that structured entries must satisfy. Fields
+// are the merged context attributes and call-site fields of the entry. A field of a group
+// (see WithGroup) is named by its dotted path, e.g. "db.duration_ms".
+type ValidationSchema struct {
+	// Required lists the fields every entry must carry.
+	Required []string
+	// Types maps field names to their expected type. Fields that are absent are not
+	// checked unless they are also Required.
+	Types map[string]FieldType
+	// Modules lists the allowed module names. Empty allows any module.
+	Modules []string
+	// Action selects what happens to a nonconforming entry. Defaults to ValidationFlag.
+	Action ValidationAction
+	// Defaults provides the values set for missing required fields by ValidationFix.
+	Defaults Fields
+	// FixModule replaces a module that is not allowed when Action is ValidationFix.
+	// Defaults to "unknown".
+	FixModule string
+	// OnViolation, if set, is called on the worker goroutine for every nonconforming entry,
+	// before any fix, with the event and the joined violations. Tests can use it to fail
+	// on the first contract violation.
+	OnViolation func(ev HookEvent, err error)
+}
+
+// ValidationStats counts the entries that violated the validation schema.
+type ValidationStats struct {
+	Violations int64 `json:"violations"` // Entries with at least one violation.
+	Flagged    int64 `json:"flagged"`    // Entries written with the "schema_violations" field.
+	Fixed      int64 `json:"fixed"`      // Entries fully repaired by ValidationFix.
+	Dropped    int64 `json:"dropped"`    // Entries discarded by ValidationDrop.
+}
+
+// SetValidation installs a validation schema at runtime. A nil schema disables validation.
+func (l *Logger) SetValidation(s *ValidationSchema) {
+	l.validation.Store(s)
+}
+
+// ValidationStats returns the validation counters of the logger.
+func (l *Logger) ValidationStats() ValidationStats {
+	return ValidationStats{
+		Violations: l.validViolations.Load(),
+		Flagged:    l.validFlagged.Load(),
+		Fixed:      l.validFixed.Load(),
+		Dropped:    l.validDropped.Load(),
+	}
+}
+
+// Validate checks an event against the schema and returns the joined violations, or nil
+// if the event conforms.
+func (s *ValidationSchema) Validate(ev HookEvent) error {
+	return errors.Join(s.violations(ev)...)
+}
+
+// violations returns one error per violation of ev, in a stable order.
+func (s *ValidationSchema) violations(ev HookEvent) []error {
+	var errs []error
+	if len(s.Modules) > 0 && !s.moduleAllowed(ev.Module) {
+		errs = append(errs, fmt.Errorf("module %q is not allowed", ev.Module))
+	}
+	for _, k := range s.Required {
+		if _, ok := lookupField(ev.Fields, k); !ok {
+			errs = append(errs, fmt.Errorf("missing required field %q", k))
+		}
+	}
+	for _, k := range sortedKeys(s.Types) {
+		if v, ok := lookupField(ev.Fields, k); ok && !s.Types[k].matches(v) {
+			errs = append(errs, fmt.Errorf("field %q is %T, want %s", k, v, s.Types[k]))
+		}
+	}
+	return errs
+}
+
+// moduleAllowed reports whether module is one of the allowed modules.
+func (s *ValidationSchema) moduleAllowed(module string) bool {
+	for _, m := range s.Modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// fix repairs ev in place as far as possible and returns the remaining violations.
+// The replacement module is accepted even if it is not in Modules.
+func (s *ValidationSchema) fix(ev *HookEvent) []error {
+	fixedModule := false
+	if len(s.Modules) > 0 && !s.moduleAllowed(ev.Module) {
+		ev.Module = s.FixModule
+		if ev.Module == "" {
+			ev.Module = "unknown"
+		}
+		fixedModule = !s.moduleAllowed(ev.Module)
+	}
+	if ev.Fields == nil {
+		ev.Fields = Fields{}
+		ev.Attrs = ev.Fields
+	}
+	for _, k := range s.Required {
+		if _, ok := lookupField(ev.Fields, k); !ok {
+			if v, ok := s.Defaults[k]; ok {
+				setField(ev.Fields, k, v, false)
+			}
+		}
+	}
+	for k, t := range s.Types {
+		v, ok := lookupField(ev.Fields, k)
+		if !ok || t.matches(v) {
+			continue
+		}
+		cv, ok := t.convert(v)
+		setField(ev.Fields, k, cv, !ok)
+	}
+	errs := s.violations(*ev)
+	if fixedModule {
+		errs = errs[1:] // The module violation is always reported first.
+	}
+	return errs
+}
+
+// validateEvent runs the validation stage on ev. It returns ErrEntryInvalid if the
+// entry must be dropped.
+func (l *Logger) validateEvent(ev *HookEvent) error {
+	s := l.validation.Load()
+	if s == nil {
+		return nil
+	}
+	errs := s.violations(*ev)
+	if len(errs) == 0 {
+		return nil
+	}
+	l.validViolations.Add(1)
+	if s.OnViolation != nil {
+		s.OnViolation(*ev, errors.Join(errs...))
+	}
+
+	switch s.Action {
+	case ValidationDrop:
+		l.validDropped.Add(1)
+		return ErrEntryInvalid
+	case ValidationFix:
+		if errs = s.fix(ev); len(errs) == 0 {
+			l.validFixed.Add(1)
+			return nil
+		}
+	}
+	l.validFlagged.Add(1)
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	if ev.Fields == nil {
+		ev.Fields = Fields{}
+		ev.Attrs = ev.Fields
+	}
+	ev.Fields[violationsField] = msgs
+	return nil
+}
+
+// matches reports whether v has the type t.
+func (t FieldType) matches(v interface{}) bool {
+	switch t {
+	case FieldString:
+		_, ok := v.(string)
+		return ok
+	case FieldInt:
+		return isKind(v, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64)
+	case FieldNumber:
+		return FieldInt.matches(v) || isKind(v, reflect.Float32, reflect.Float64)
+	case FieldBool:
+		_, ok := v.(bool)
+		return ok
+	case FieldTime:
+		_, ok := v.(time.Time)
+		return ok
+	default:
+		return true
+	}
+}
+
+// convert attempts to convert v to the type t.
+func (t FieldType) convert(v interface{}) (interface{}, bool) {
+	s := strings.TrimSpace(fmt.Sprint(v))
+	switch t {
+	case FieldString:
+		return fmt.Sprint(v), true
+	case FieldInt:
+		if f, ok := v.(float64); ok && f == float64(int64(f)) {
+			return int64(f), true
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		return n, err == nil
+	case FieldNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	case FieldBool:
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	case FieldTime:
+		tm, err := time.Parse(time.RFC3339Nano, s)
+		return tm, err == nil
+	default:
+		return v, true
+	}
+}
+
+// isKind reports whether v is of one of the given kinds.
+func isKind(v interface{}, kinds ...reflect.Kind) bool {
+	if v == nil {
+		return false
+	}
+	k := reflect.TypeOf(v).Kind()
+	for _, want := range kinds {
+		if k == want {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in lexical order.
+func sortedKeys(m map[string]FieldType) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
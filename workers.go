@@ -0,0 +1,231 @@
+// Copyright (c) 2025 Nguyễn Thanh Phương
+// This source code is licensed under the MIT License found in the LICENSE file.
+
+// Package unologger provides a flexible and feature-rich logging library for Go applications.
+// This file manages the size of the worker pool at runtime. It allows operators to grow or
+// shrink the number of worker goroutines without reinitializing the logger, and provides an
+// optional auto-scaler that adjusts the pool based on queue depth.
+
+package unologger
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// spawnWorkerLocked starts one worker goroutine. The caller must hold workersMu.
+func (l *Logger) spawnWorkerLocked() {
+	w := &workerCtl{
+		quit:  make(chan struct{}),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+		link:  l.queueLink(),
+	}
+	l.workerCtls = append(l.workerCtls, w)
+	l.wg.Add(1)
+	go l.workerLoop(w)
+}
+
+// SetWorkers grows or shrinks the worker pool to n goroutines at runtime. Values below 1
+// are treated as 1. Removed workers flush their current batch before exiting; entries left
+// in the queue are processed by the remaining workers. It returns the resulting pool size,
+// which is 0 for a closed logger or in single-writer mode.
+func (l *Logger) SetWorkers(n int) int {
+	return l.Configure("").SetWorkers(n)
+}
+
+// SetWorkers is Logger.SetWorkers on behalf of the actor of ed.
+func (ed ConfigEditor) SetWorkers(n int) int {
+	c := ed.changing("workers")
+	defer c.commit()
+	old, n := ed.l.resizeWorkers(n)
+	if n == 0 {
+		c.cancel()
+	}
+	c.old, c.new = strconv.Itoa(old), strconv.Itoa(n)
+	return n
+}
+
+// resizeWorkers is SetWorkers without the audit record, for the auto-scaler, whose
+// adjustments are not configuration changes. It returns the previous and the resulting
+// pool sizes.
+func (l *Logger) resizeWorkers(n int) (old, cur int) {
+	if l.direct {
+		return 0, 0
+	}
+	if n < 1 {
+		n = 1
+	}
+	l.workersMu.Lock()
+	defer l.workersMu.Unlock()
+	if l.closed.Load() {
+		return 0, 0
+	}
+	old = l.workers
+	for len(l.workerCtls) < n {
+		l.spawnWorkerLocked()
+	}
+	for len(l.workerCtls) > n {
+		last := len(l.workerCtls) - 1
+		close(l.workerCtls[last].quit)
+		l.workerCtls = l.workerCtls[:last]
+	}
+	l.workers = n
+	return old, n
+}
+
+// Workers returns the current number of worker goroutines.
+func (l *Logger) Workers() int {
+	l.workersMu.Lock()
+	defer l.workersMu.Unlock()
+	return l.workers
+}
+
+// FlushBatchNow makes every worker write its current batch immediately instead of
+// waiting for the batch to fill up or for MaxWait to expire, and returns once all of
+// them are done. Entries still waiting in the queue are not affected. It may block
+// for as long as the slowest sink takes to accept a batch, and is a no-op in
+// single-writer mode and on a closed logger.
+func (l *Logger) FlushBatchNow() {
+	l.workersMu.Lock()
+	ctls := append([]*workerCtl(nil), l.workerCtls...)
+	l.workersMu.Unlock()
+
+	for _, w := range ctls {
+		ack := make(chan struct{})
+		select {
+		case w.flush <- ack:
+		case <-w.done:
+			continue
+		}
+		select {
+		case <-ack:
+		case <-w.done:
+		}
+	}
+}
+
+// normalizeAutoScale applies defaults to an AutoScaleConfig.
+func normalizeAutoScale(as AutoScaleConfig, workers int) AutoScaleConfig {
+	if !as.Enable {
+		return as
+	}
+	if as.MinWorkers <= 0 {
+		as.MinWorkers = workers
+	}
+	if as.MaxWorkers < as.MinWorkers {
+		as.MaxWorkers = 4 * as.MinWorkers
+	}
+	if as.Interval <= 0 {
+		as.Interval = time.Second
+	}
+	if as.HighWatermark <= 0 || as.HighWatermark > 1 {
+		as.HighWatermark = 0.75
+	}
+	if as.LowWatermark < 0 || as.LowWatermark >= as.HighWatermark {
+		as.LowWatermark = 0.10
+	}
+	return as
+}
+
+// startAutoScaler launches the auto-scaling goroutine if auto-scaling is enabled.
+func (l *Logger) startAutoScaler() {
+	if !l.autoScale.Enable || l.ch == nil {
+		return
+	}
+	l.autoStop = make(chan struct{})
+	go l.autoScaleLoop(l.autoScale, l.autoStop)
+}
+
+// stopAutoScaler stops the auto-scaling goroutine, if any.
+func (l *Logger) stopAutoScaler() {
+	if l.autoStop != nil {
+		close(l.autoStop)
+	}
+}
+
+// autoScaleLoop samples the fill ratio of the queues, the priority queue included, every
+// Interval and adds or removes one worker when the ratio crosses the configured watermarks.
+func (l *Logger) autoScaleLoop(as AutoScaleConfig, stop <-chan struct{}) {
+	ticker := time.NewTicker(as.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			size := cap(l.queue()) + cap(l.hi)
+			if size == 0 {
+				continue
+			}
+			ratio := float64(l.queueLen()) / float64(size)
+			n := l.Workers()
+			switch {
+			case ratio >= as.HighWatermark && n < as.MaxWorkers:
+				l.resizeWorkers(n + 1)
+			case ratio <= as.LowWatermark && n > as.MinWorkers:
+				l.resizeWorkers(n - 1)
+			}
+		}
+	}
+}
+
+// ErrSingleWriterMode is returned by operations that require the asynchronous
+// pipeline when the logger runs in single-writer mode.
+var ErrSingleWriterMode = errors.New("unologger: operation not supported in single-writer mode")
+
+// ErrLoggerClosed is returned by operations that cannot be applied to a closed logger.
+var ErrLoggerClosed = errors.New("unologger: logger is closed")
+
+// SetBufferSize replaces the processing queue with a new channel of capacity n at runtime.
+// Producers switch to the new channel immediately, while the workers finish draining the
+// old one before moving over, so no queued entry is lost. Entries already queued may be
+// written after some newer entries while the old channel drains. Values below 1 are
+// treated as 1.
+func (l *Logger) SetBufferSize(n int) error {
+	return l.Configure("").SetBufferSize(n)
+}
+
+// SetBufferSize is Logger.SetBufferSize on behalf of the actor of ed.
+func (ed ConfigEditor) SetBufferSize(n int) error {
+	l := ed.l
+	c := ed.changing("buffer_size")
+	defer c.commit()
+	if l.direct {
+		c.cancel()
+		return ErrSingleWriterMode
+	}
+	if n < 1 {
+		n = 1
+	}
+	l.chMu.Lock()
+	if l.closed.Load() {
+		l.chMu.Unlock()
+		c.cancel()
+		return ErrLoggerClosed
+	}
+	old := l.chLink
+	c.old, c.new = strconv.Itoa(cap(old.ch)), strconv.Itoa(n)
+	l.ch = make(chan *logEntry, n)
+	l.chLink = &queueLink{ch: l.ch}
+	old.next = l.chLink
+	// Closing under the write lock guarantees no producer is sending on `old`.
+	close(old.ch)
+	l.chMu.Unlock()
+	return nil
+}
+
+// queueLink chains a processing channel to the one that replaced it, so that a worker
+// still draining a retired channel moves on to its direct successor rather than to the
+// current queue, and no channel is skipped when the queue is resized several times in
+// a row. next is set under chMu before ch is closed.
+type queueLink struct {
+	ch   chan *logEntry
+	next *queueLink
+}
+
+// BufferSize returns the capacity of the processing queue.
+func (l *Logger) BufferSize() int {
+	return cap(l.queue())
+}
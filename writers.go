@@ -9,59 +9,233 @@
 package unologger
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"math/rand"
+	"net"
+	"os"
+	"slices"
 	"time"
 )
 
-// writeToAll is the central dispatch function for writing a formatted log entry.
-// It writes the log bytes to all configured destinations.
+// BatchWriter is an optional interface for sinks that can accept several formatted
+// entries in one protocol-native call, such as a bulk API request. When a sink implements
+// it, the logger hands over the entries of a batch as separate segments instead of one
+// concatenated buffer. Implementations must not retain the outer slice after WriteBatch
+// returns; the segments themselves are not reused by the logger.
+type BatchWriter interface {
+	io.Writer
+	WriteBatch(entries [][]byte) error
+}
+
+// writeRouted is the central dispatch function for writing formatted log output. The
+// buffers of out hold the pre-routed entries of a batch:
+//  1. `std` (DEBUG and INFO entries) is sent to the `stdout` writer.
+//  2. `errb` (WARN, ERROR and FATAL entries) is sent to the `stderr` writer.
+//  3. `rot` is sent to the rotation writer (if enabled).
+//  4. `extra` is sent to all additional `extra` writers.
+//  5. `retention` is sent to the sink of each retention class.
+//  6. `migOld` and `migNew` are sent to the two sides of a migration.
+//  7. `shadow` is sent to the sink of the shadow configuration.
+//  8. `otlp` is exported to the OpenTelemetry collector.
+//  9. `syslog` is sent to the syslog sink.
 //
-// The routing logic is as follows:
-//  1. If `isError` is true (for ERROR and FATAL levels), the log is sent to the `stderr` writer.
-//  2. Otherwise, it is sent to the `stdout` writer.
-//  3. The log is then sent to the rotation writer (if enabled).
-//  4. Finally, the log is sent to all additional `extra` writers.
+// `rot` and `extra` normally hold the same entries; they differ only when the batch
+// contains emergency entries, which were already written to the rotation file. Empty
+// lists are skipped, so each destination receives at most one write per call.
 //
 // This function is concurrency-safe. It snapshots the writer configuration under a
 // read lock before performing I/O to avoid holding the lock during potentially
 // slow write operations.
-func (l *Logger) writeToAll(p []byte, isError bool) {
+func (l *Logger) writeRouted(out *batchOutput) routeErrors {
+	std, errb, rot, extra := &out.std, &out.errb, &out.rot, &out.extra
+
 	// Snapshot the writer configuration to avoid holding a lock during I/O.
 	l.outputsMu.RLock()
-	std := l.stdOut
+	stdw := l.stdOut
 	errw := l.errOut
 	rotSink := l.rotationSink
 	extras := make([]writerSink, len(l.extraW))
 	copy(extras, l.extraW)
 	l.outputsMu.RUnlock()
 
-	// Write to the primary destination (stdout or stderr).
-	if isError {
-		l.tryWrite("stderr", errw, p)
-	} else {
-		l.tryWrite("stdout", std, p)
+	var errs routeErrors
+	// Write to the primary destinations (stdout and/or stderr).
+	if !std.empty() {
+		errs.std = l.writeSegments("stdout", stdw, std)
+	}
+	if !errb.empty() {
+		errs.errb = l.writeSegments("stderr", errw, errb)
 	}
 
 	// Write to the rotation file sink.
-	if rotSink != nil {
-		l.tryWrite(rotSink.Name, rotSink.Writer, p)
+	if rotSink != nil && !rot.empty() {
+		errs.rot = l.writeSegments(rotSink.Name, rotSink.Writer, rot)
 	}
 
+	// Write to the retention sinks.
+	var retErrs []error
+	if len(out.retention) > 0 {
+		// Sinks are written in lexical order of their class, so that failures and
+		// latencies do not depend on map iteration.
+		for _, class := range slices.Sorted(maps.Keys(out.retention)) {
+			if segs := out.retention[class]; !segs.empty() {
+				sink := l.retentionSinks[class]
+				retErrs = append(retErrs, l.writeSegments(sink.Name, sink.Writer, segs))
+			}
+		}
+	}
+	errs.retained = errors.Join(retErrs...)
+
+	// Write both sides of a migration, the shadow, the OTLP export and syslog. Their errors
+	// only show in their statistics.
+	l.writeMigration(out)
+	l.writeShadow(out)
+	l.writeOTLP(out)
+	l.writeSyslog(out)
+
 	// Write to all additional writers.
+	if extra.empty() {
+		return errs
+	}
+	var extraErrs []error
 	for _, sink := range extras {
-		l.tryWrite(sink.Name, sink.Writer, p)
+		extraErrs = append(extraErrs, l.writeSegments(sink.Name, sink.Writer, extra))
 	}
+	errs.extra = errors.Join(extraErrs...)
+	return errs
+}
+
+// routeErrors holds the final write errors of one writeRouted call per destination
+// group, after retries.
+type routeErrors struct {
+	std, errb, rot, extra, retained error
+}
+
+// forEntry returns the errors of the destinations the entry of a were routed to.
+func (r routeErrors) forEntry(a pendingAck) error {
+	var storage error
+	switch {
+	case a.retained:
+		storage = r.retained
+	case a.emergency:
+		storage = r.extra
+	default:
+		storage = errors.Join(r.rot, r.extra)
+	}
+	if a.emergency {
+		return storage
+	}
+	primary := r.std
+	if a.lvl.Base() >= WARN {
+		primary = r.errb
+	}
+	return errors.Join(primary, storage)
+}
+
+// writeSegments writes the entries of a batch to one sink using the cheapest method the
+// sink supports:
+//   - a BatchWriter receives the entries through a single WriteBatch call;
+//   - a net.Conn receives them through net.Buffers, which uses vectored I/O (writev)
+//     where the platform supports it, without concatenating the entries first;
+//   - any other io.Writer receives one Write call with the concatenated entries.
+//
+// Entries over the rate limit of the sink, if any, are diverted first (see
+// SetSinkRateLimit). All three paths apply the logger's retry policy. The returned error, if any, names the
+// sink and wraps the error of the last attempt.
+func (l *Logger) writeSegments(name string, w io.Writer, s *segments) error {
+	if w == nil {
+		return nil
+	}
+	if s = l.limitSegments(name, s); s.empty() {
+		return nil
+	}
+	if !s.sealed {
+		l.sealSegments(s)
+	}
+	entries := len(s.parts)
+	if s.trailer {
+		entries--
+	}
+	if l.seqSinks.Load() {
+		s = l.stampSinkSeq(name, s)
+	}
+	var err error
+	switch sw := w.(type) {
+	case BatchWriter:
+		err = l.retryWrite(name, func() error { return sw.WriteBatch(s.parts) })
+	case net.Conn:
+		err = l.retryWrite(name, func() error {
+			// WriteTo consumes the slice it is called on, so work on a copy.
+			bufs := make(net.Buffers, len(s.parts))
+			copy(bufs, s.parts)
+			_, err := bufs.WriteTo(sw)
+			return err
+		})
+	default:
+		err = l.tryWrite(name, w, s.bytes())
+	}
+	if err != nil {
+		for _, ev := range s.events {
+			l.reportWriteError(name, err, ev)
+		}
+		return err
+	}
+	l.writerSucceeded(name, s.size(), entries)
+	return nil
+}
+
+// OnWriteError registers a handler that is called for every entry whose write to a sink
+// failed after all retries, so applications can alert or fall back instead of only
+// discovering failures in the counters. Passing nil removes the handler.
+//
+// The handler runs synchronously on the worker goroutine (or on the caller's goroutine
+// for the emergency path and in single-writer mode), so it should return quickly. Logging
+// through the same logger from the handler can deadlock when the queue is full. Panics in
+// the handler are recovered and reported on the process's stderr.
+func (l *Logger) OnWriteError(fn WriteErrorHandler) {
+	if fn == nil {
+		l.writeErrFn.Store(nil)
+		return
+	}
+	l.writeErrFn.Store(&fn)
+}
+
+// reportWriteError invokes the write error handler, if any, with panic recovery, and hands
+// the entry to the failure hook.
+func (l *Logger) reportWriteError(sink string, err error, ev HookEvent) {
+	defer l.deliverFailure(sink, err, ev)
+	fn := l.writeErrFn.Load()
+	if fn == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "unologger: write error handler panic: %v\n", r)
+		}
+	}()
+	(*fn)(sink, err, ev)
 }
 
 // tryWrite attempts to write a byte slice to a single io.Writer, applying a
 // retry policy in case of failure. The `name` parameter is used to track
 // error statistics for this specific writer.
-func (l *Logger) tryWrite(name string, w io.Writer, p []byte) {
+func (l *Logger) tryWrite(name string, w io.Writer, p []byte) error {
 	if w == nil {
-		return
+		return nil
 	}
+	return l.retryWrite(name, func() error {
+		_, err := w.Write(p)
+		return err
+	})
+}
 
+// retryWrite runs write until it succeeds or the retry policy is exhausted, recording
+// every failure in the error statistics of the named writer. It returns the error of
+// the last attempt, wrapped with the writer name, or nil on success.
+func (l *Logger) retryWrite(name string, write func() error) error {
 	// Snapshot the retry policy.
 	l.dynConfig.mu.RLock()
 	rp := l.retryPolicy
@@ -74,19 +248,19 @@ func (l *Logger) tryWrite(name string, w io.Writer, p []byte) {
 
 	var err error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		_, err = w.Write(p)
+		err = write()
 		if err == nil {
 			// Write was successful.
-			return
+			return nil
 		}
 
 		// Write failed; record the error.
 		l.writeErrCount.Add(1)
-		l.incWriterErr(name)
+		l.incWriterErr(name, err)
 
 		if attempt == maxRetries {
 			// All retries have been exhausted.
-			return
+			break
 		}
 
 		// Calculate backoff duration for the next retry.
@@ -101,4 +275,5 @@ func (l *Logger) tryWrite(name string, w io.Writer, p []byte) {
 
 		time.Sleep(delay)
 	}
+	return fmt.Errorf("unologger: write to %s: %w", name, err)
 }